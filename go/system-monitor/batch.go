@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// maxPendingBatches bounds how many batches of snapshots are kept while
+// EYWA is unreachable; the oldest snapshots are dropped beyond that
+const maxPendingBatches = 10
+
+// taskLogBatch accumulates metrics TaskLogs so they are stored with one
+// mutation per batch instead of one per iteration. A batch is due once
+// size logs are pending or the oldest has waited maxWait; a size of 1
+// uploads every snapshot immediately.
+type taskLogBatch struct {
+	size    int
+	maxWait time.Duration
+	upload  func(ctx context.Context, logs []map[string]interface{}) error
+
+	pending []map[string]interface{}
+	since   time.Time // when the oldest pending log was added
+}
+
+// add queues a log and uploads the batch if it is due. Logs of a failed
+// upload stay queued for the next attempt.
+func (b *taskLogBatch) add(ctx context.Context, log map[string]interface{}, now time.Time) error {
+	if len(b.pending) == 0 {
+		b.since = now
+	}
+	b.pending = append(b.pending, log)
+	if limit := b.size * maxPendingBatches; len(b.pending) > limit {
+		b.pending = b.pending[len(b.pending)-limit:]
+	}
+
+	if len(b.pending) < b.size && (b.maxWait <= 0 || now.Sub(b.since) < b.maxWait) {
+		return nil
+	}
+	return b.flush(ctx)
+}
+
+// flush uploads all pending logs
+func (b *taskLogBatch) flush(ctx context.Context) error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	if err := b.upload(ctx, b.pending); err != nil {
+		return err
+	}
+	b.pending = nil
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"system-monitor/monitor"
+
+	eywa "github.com/neyho/eywa-go"
+)
+
+// commandEvent is the TaskLog event operators create to control a
+// running monitor, e.g.
+//
+//	{"event": "SYSTEM_MONITOR_COMMAND", "message": "pause",
+//	 "data": {"command": "pause", "host": "web-1"}}
+const commandEvent = "SYSTEM_MONITOR_COMMAND"
+
+// remoteCommand is the data of a command TaskLog. It targets one task by
+// euuid or every run on one host.
+type remoteCommand struct {
+	Command string `json:"command"`
+	Task    string `json:"task"`
+	Host    string `json:"host"`
+
+	// set_interval
+	Interval Seconds `json:"interval"`
+
+	// set_config: analysis settings to change, named as in
+	// monitor.Config, e.g. {"cpu_threshold": 95}
+	Config json.RawMessage `json:"config"`
+}
+
+// commandPoller fetches command TaskLogs created since the run started
+// and applies those addressed to this run
+type commandPoller struct {
+	mon  *monitor.Monitor
+	live *liveConfig
+	task string
+	host string
+
+	start time.Time // commands from before the run are ignored
+	since time.Time // newest command seen
+	seen  map[string]bool
+
+	// stop ends the run
+	stop func()
+}
+
+func newCommandPoller(mon *monitor.Monitor, live *liveConfig, task, host string, stop func()) *commandPoller {
+	return &commandPoller{
+		mon:   mon,
+		live:  live,
+		task:  task,
+		host:  host,
+		start: time.Now(),
+		since: time.Now(),
+		seen:  map[string]bool{},
+		stop:  stop,
+	}
+}
+
+// run polls every period until ctx is cancelled
+func (p *commandPoller) run(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := p.poll(ctx); err != nil && ctx.Err() == nil {
+			eywa.Warn("Failed to fetch remote commands", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+func (p *commandPoller) poll(ctx context.Context) error {
+	query := `
+		query($since: String) {
+			searchTaskLog(_where: {event: {_eq: "` + commandEvent + `"}, created: {_gt: $since}}, _order_by: {created: asc}) {
+				euuid
+				created
+				data
+			}
+		}
+	`
+
+	// Logs created in the same instant as the newest one seen are fetched
+	// again and skipped by euuid
+	result, err := graphQL(ctx, query, map[string]interface{}{
+		"since": p.since.Add(-time.Second).UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("decode commands: %w", err)
+	}
+	var response struct {
+		SearchTaskLog []struct {
+			Euuid   string          `json:"euuid"`
+			Created time.Time       `json:"created"`
+			Data    json.RawMessage `json:"data"`
+		} `json:"searchTaskLog"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return fmt.Errorf("decode commands: %w", err)
+	}
+
+	for _, entry := range response.SearchTaskLog {
+		if p.seen[entry.Euuid] || entry.Created.Before(p.start) {
+			continue
+		}
+		p.seen[entry.Euuid] = true
+		if entry.Created.After(p.since) {
+			p.since = entry.Created
+		}
+
+		var command remoteCommand
+		if err := json.Unmarshal(entry.Data, &command); err != nil {
+			eywa.Warn("Ignoring malformed remote command", map[string]interface{}{
+				"log":   entry.Euuid,
+				"error": err.Error(),
+			})
+			continue
+		}
+		if !p.addressed(command) {
+			continue
+		}
+
+		details := map[string]interface{}{"log": entry.Euuid, "command": command.Command}
+		if err := p.apply(command); err != nil {
+			details["error"] = err.Error()
+			eywa.Warn(fmt.Sprintf("Remote command %q rejected", command.Command), details)
+			continue
+		}
+		eywa.Info(fmt.Sprintf("Remote command %q applied", command.Command), details)
+	}
+	return nil
+}
+
+// addressed reports whether a command targets this run, by task euuid
+// or host
+func (p *commandPoller) addressed(command remoteCommand) bool {
+	if command.Task != "" {
+		return command.Task == p.task
+	}
+	return command.Host != "" && command.Host == p.host
+}
+
+func (p *commandPoller) apply(command remoteCommand) error {
+	switch command.Command {
+	case "pause":
+		p.mon.Pause()
+	case "resume":
+		p.mon.Resume()
+	case "collect_now":
+		p.mon.CollectNow()
+	case "stop":
+		p.stop()
+	case "set_interval":
+		return p.live.update(func(config *monitor.Config) error {
+			config.Interval = int(command.Interval)
+			return nil
+		})
+	case "set_config":
+		if len(command.Config) == 0 {
+			return fmt.Errorf("set_config needs a config object")
+		}
+		return p.live.update(func(config *monitor.Config) error {
+			if err := json.Unmarshal(command.Config, config); err != nil {
+				return fmt.Errorf("decode config: %w", err)
+			}
+			return nil
+		})
+	default:
+		return fmt.Errorf("unknown command")
+	}
+	return nil
+}
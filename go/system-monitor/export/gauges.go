@@ -0,0 +1,171 @@
+package export
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"system-monitor/monitor"
+)
+
+// MetricPrefix is prepended to every exported metric name
+const MetricPrefix = "system_monitor."
+
+// Gauge is a single point-in-time measurement with its tags
+type Gauge struct {
+	Name  string
+	Value float64
+	Tags  map[string]string
+}
+
+// Gauges flattens a metrics snapshot into the gauges shared by all
+// exporters. Every gauge carries a host tag; disk gauges also carry the
+// mount point.
+func Gauges(metrics *monitor.SystemMetrics, host string) []Gauge {
+	hostTags := map[string]string{"host": host}
+	gauge := func(name string, value float64) Gauge {
+		return Gauge{Name: MetricPrefix + name, Value: value, Tags: hostTags}
+	}
+
+	gauges := []Gauge{
+		gauge("cpu.usage_percent", metrics.CPU.UsagePercent),
+		gauge("cpu.user_percent", metrics.CPU.Breakdown.User),
+		gauge("cpu.system_percent", metrics.CPU.Breakdown.System),
+		gauge("cpu.iowait_percent", metrics.CPU.Breakdown.IOWait),
+		gauge("cpu.steal_percent", metrics.CPU.Breakdown.Steal),
+		gauge("cpu.cores", float64(metrics.CPU.Cores)),
+		gauge("memory.used_percent", metrics.Memory.UsedPercent),
+		gauge("memory.used_gb", metrics.Memory.UsedGB),
+		gauge("memory.available_gb", metrics.Memory.AvailableGB),
+		gauge("memory.swap_percent", metrics.Memory.SwapPercent),
+		gauge("memory.swap_in_per_sec", metrics.Memory.SwapInPerSec),
+		gauge("memory.swap_out_per_sec", metrics.Memory.SwapOutPerSec),
+		gauge("load.load1", metrics.Load.Load1),
+		gauge("load.load5", metrics.Load.Load5),
+		gauge("load.load15", metrics.Load.Load15),
+	}
+
+	for _, disk := range metrics.Disk {
+		tags := map[string]string{"host": host, "mount": disk.MountPoint}
+		gauges = append(gauges,
+			Gauge{Name: MetricPrefix + "disk.used_percent", Value: disk.UsedPercent, Tags: tags},
+			Gauge{Name: MetricPrefix + "disk.free_gb", Value: disk.FreeGB, Tags: tags},
+		)
+		if disk.InodesTotal > 0 {
+			gauges = append(gauges, Gauge{Name: MetricPrefix + "disk.inodes_used_percent", Value: disk.InodesUsedPercent, Tags: tags})
+		}
+	}
+
+	for _, disk := range metrics.DiskHealth {
+		tags := map[string]string{"host": host, "device": disk.Device}
+		passed := 0.0
+		if disk.Passed {
+			passed = 1
+		}
+		gauges = append(gauges,
+			Gauge{Name: MetricPrefix + "smart.passed", Value: passed, Tags: tags},
+			Gauge{Name: MetricPrefix + "smart.reallocated_sectors", Value: float64(disk.ReallocatedSectors), Tags: tags},
+			Gauge{Name: MetricPrefix + "smart.pending_sectors", Value: float64(disk.PendingSectors), Tags: tags},
+			Gauge{Name: MetricPrefix + "smart.wear_percent", Value: disk.WearPercent, Tags: tags},
+		)
+	}
+
+	for _, gpu := range metrics.GPU {
+		tags := map[string]string{"host": host, "gpu": strconv.Itoa(gpu.Index)}
+		gauges = append(gauges,
+			Gauge{Name: MetricPrefix + "gpu.utilization_percent", Value: gpu.UtilizationPercent, Tags: tags},
+			Gauge{Name: MetricPrefix + "gpu.memory_percent", Value: gpu.MemoryPercent, Tags: tags},
+			Gauge{Name: MetricPrefix + "gpu.temperature_c", Value: gpu.TemperatureC, Tags: tags},
+			Gauge{Name: MetricPrefix + "gpu.power_watts", Value: gpu.PowerWatts, Tags: tags},
+		)
+	}
+
+	for _, nic := range metrics.Network {
+		tags := map[string]string{"host": host, "interface": nic.Interface}
+		gauges = append(gauges,
+			Gauge{Name: MetricPrefix + "network.sent_mbps", Value: nic.SentMbps, Tags: tags},
+			Gauge{Name: MetricPrefix + "network.recv_mbps", Value: nic.RecvMbps, Tags: tags},
+		)
+		if nic.SpeedMbps > 0 {
+			gauges = append(gauges, Gauge{Name: MetricPrefix + "network.utilization_percent", Value: nic.UtilizationPercent, Tags: tags})
+		}
+	}
+
+	if connections := metrics.Connections; connections != nil {
+		for _, state := range []string{"ESTABLISHED", "TIME_WAIT", "CLOSE_WAIT", "LISTEN"} {
+			tags := map[string]string{"host": host, "state": strings.ToLower(state)}
+			gauges = append(gauges, Gauge{Name: MetricPrefix + "tcp.connections", Value: float64(connections.States[state]), Tags: tags})
+		}
+		if connections.ConntrackMax > 0 {
+			gauges = append(gauges, gauge("conntrack.used_percent", connections.ConntrackPercent))
+		}
+	}
+
+	for _, container := range metrics.Containers {
+		tags := map[string]string{"host": host, "container": container.Name}
+		gauges = append(gauges,
+			Gauge{Name: MetricPrefix + "container.cpu_percent", Value: container.CPUPercent, Tags: tags},
+			Gauge{Name: MetricPrefix + "container.memory_mb", Value: container.MemoryMB, Tags: tags},
+			Gauge{Name: MetricPrefix + "container.memory_percent", Value: container.MemoryPercent, Tags: tags},
+			Gauge{Name: MetricPrefix + "container.restart_count", Value: float64(container.RestartCount), Tags: tags},
+		)
+	}
+
+	if sensors := metrics.Sensors; sensors != nil {
+		for _, t := range sensors.Temperatures {
+			tags := map[string]string{"host": host, "sensor": t.Sensor}
+			gauges = append(gauges, Gauge{Name: MetricPrefix + "sensor.temperature_c", Value: t.TemperatureC, Tags: tags})
+		}
+		for _, fan := range sensors.Fans {
+			tags := map[string]string{"host": host, "sensor": fan.Sensor}
+			gauges = append(gauges, Gauge{Name: MetricPrefix + "sensor.fan_rpm", Value: fan.RPM, Tags: tags})
+		}
+	}
+
+	if states := metrics.ProcessStates; states != nil {
+		gauges = append(gauges,
+			gauge("processes.total", float64(states.Total)),
+			gauge("processes.running", float64(states.Running)),
+			gauge("processes.blocked", float64(states.Blocked)),
+			gauge("processes.zombie", float64(states.Zombie)),
+		)
+	}
+
+	if fds := metrics.FileDescriptors; fds != nil {
+		gauges = append(gauges, gauge("file_descriptors.used_percent", fds.UsedPercent))
+	}
+
+	return gauges
+}
+
+// Counters returns the snapshot's cumulative totals, which only grow
+// until the host reboots: per-interface bytes, packets, errors and drops.
+// Sinks that distinguish counters from gauges export these as counters.
+func Counters(metrics *monitor.SystemMetrics, host string) []Gauge {
+	var counters []Gauge
+	for _, nic := range metrics.Network {
+		tags := map[string]string{"host": host, "interface": nic.Interface}
+		counter := func(name string, value uint64) Gauge {
+			return Gauge{Name: MetricPrefix + name, Value: float64(value), Tags: tags}
+		}
+		counters = append(counters,
+			counter("network.bytes_sent", nic.BytesSent),
+			counter("network.bytes_recv", nic.BytesRecv),
+			counter("network.packets_sent", nic.PacketsSent),
+			counter("network.packets_recv", nic.PacketsRecv),
+			counter("network.errors_in", nic.ErrorsIn),
+			counter("network.errors_out", nic.ErrorsOut),
+			counter("network.drops_in", nic.DropsIn),
+			counter("network.drops_out", nic.DropsOut),
+		)
+	}
+	return counters
+}
+
+// batch is one snapshot's worth of gauges waiting to be sent. Counters
+// are only filled in by sinks that export them.
+type batch struct {
+	timestamp time.Time
+	gauges    []Gauge
+	counters  []Gauge
+}
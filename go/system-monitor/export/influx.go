@@ -0,0 +1,191 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"system-monitor/monitor"
+)
+
+// Influx writes gauges and counters in InfluxDB line protocol, either to
+// an HTTP write endpoint (InfluxDB, Telegraf's http_listener) or appended
+// to a local file for Telegraf's tail input
+type Influx struct {
+	target     string
+	authHeader string
+	host       string
+	tags       map[string]string
+	client     *http.Client
+	file       *os.File
+	queue      *queue
+
+	// OnError is called for every failed or dropped push
+	OnError func(err error)
+}
+
+// NewInflux creates an Influx sink. A target starting with http:// or
+// https:// is the full write URL, e.g.
+// http://influx:8086/api/v2/write?org=ops&bucket=hosts&precision=ns;
+// anything else is a file path. authHeader, if set, is sent as the
+// Authorization header, e.g. "Token ...". tags are added to every line
+// next to the host tag.
+func NewInflux(target, authHeader, host string, tags map[string]string) (*Influx, error) {
+	i := &Influx{
+		target:     target,
+		authHeader: authHeader,
+		host:       host,
+		tags:       tags,
+	}
+
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		i.client = &http.Client{Timeout: 10 * time.Second}
+	} else {
+		file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("influx: %w", err)
+		}
+		i.file = file
+	}
+
+	i.queue = newQueue(16, i.send, i.reportError)
+	return i, nil
+}
+
+// Push queues a snapshot for sending
+func (i *Influx) Push(metrics *monitor.SystemMetrics) {
+	i.queue.push(batch{
+		timestamp: metrics.Timestamp,
+		gauges:    Gauges(metrics, i.host),
+		counters:  Counters(metrics, i.host),
+	})
+}
+
+// Close flushes pending lines and closes the file, if any
+func (i *Influx) Close(timeout time.Duration) {
+	i.queue.close(timeout)
+	if i.file != nil {
+		i.file.Close()
+	}
+}
+
+func (i *Influx) send(b batch) error {
+	body := influxLines(b, i.tags)
+	if i.file != nil {
+		if _, err := i.file.Write(body); err != nil {
+			return fmt.Errorf("influx: %w", err)
+		}
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, i.target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("influx: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if i.authHeader != "" {
+		req.Header.Set("Authorization", i.authHeader)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("influx: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("influx: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (i *Influx) reportError(err error) {
+	if i.OnError != nil {
+		i.OnError(err)
+	}
+}
+
+// influxLines renders a batch as line protocol with nanosecond timestamps.
+// "system_monitor.disk.free_gb" becomes the field free_gb of the
+// measurement system_monitor_disk; measurements with the same tags share
+// a line. Counters are written as integer fields.
+func influxLines(b batch, extraTags map[string]string) []byte {
+	type line struct {
+		series string
+		fields []string
+	}
+	var lines []*line
+	series := make(map[string]*line)
+
+	add := func(g Gauge, value string) {
+		name := strings.TrimPrefix(g.Name, MetricPrefix)
+		group, field, ok := strings.Cut(name, ".")
+		if !ok {
+			group, field = name, "value"
+		}
+
+		tags := make(map[string]string, len(g.Tags)+len(extraTags))
+		for k, v := range extraTags {
+			tags[k] = v
+		}
+		for k, v := range g.Tags {
+			tags[k] = v
+		}
+
+		var key strings.Builder
+		key.WriteString(influxEscape(strings.ReplaceAll(MetricPrefix, ".", "_")+group, ", "))
+		for _, k := range sortedKeys(tags) {
+			if tags[k] == "" {
+				// Empty tag values are invalid line protocol
+				continue
+			}
+			key.WriteString("," + influxEscape(k, ", =") + "=" + influxEscape(tags[k], ", ="))
+		}
+
+		l, ok := series[key.String()]
+		if !ok {
+			l = &line{series: key.String()}
+			series[l.series] = l
+			lines = append(lines, l)
+		}
+		l.fields = append(l.fields, influxEscape(field, ", =")+"="+value)
+	}
+
+	for _, g := range b.gauges {
+		if math.IsNaN(g.Value) || math.IsInf(g.Value, 0) {
+			// Line protocol has no representation for these
+			continue
+		}
+		add(g, strconv.FormatFloat(g.Value, 'g', -1, 64))
+	}
+	for _, g := range b.counters {
+		add(g, strconv.FormatUint(uint64(g.Value), 10)+"i")
+	}
+
+	var buf bytes.Buffer
+	timestamp := strconv.FormatInt(b.timestamp.UnixNano(), 10)
+	for _, l := range lines {
+		buf.WriteString(l.series + " " + strings.Join(l.fields, ",") + " " + timestamp + "\n")
+	}
+	return buf.Bytes()
+}
+
+// influxEscape backslash-escapes the given special characters
+func influxEscape(s, special string) string {
+	if !strings.ContainsAny(s, special) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,426 @@
+package export
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"system-monitor/monitor"
+	"system-monitor/notify"
+)
+
+// mqttTimeout bounds connecting and waiting for acknowledgements
+const mqttTimeout = 10 * time.Second
+
+// MQTTConfig configures the MQTT publisher. The password is read from the
+// environment variable PasswordEnv (MQTT_PASSWORD by default), never from
+// the task input.
+type MQTTConfig struct {
+	// Broker URL: tcp://host:1883, or ssl://, tls:// or mqtts:// for TLS
+	// on port 8883 by default
+	Broker   string `json:"broker"`
+	ClientID string `json:"client_id"` // system-monitor-<host> by default
+
+	// Topics; {host} is replaced with the host name
+	MetricsTopic string `json:"metrics_topic"` // eywa/robots/{host}/metrics by default
+	AlertsTopic  string `json:"alerts_topic"`  // eywa/robots/{host}/alerts by default
+
+	QoS    int  `json:"qos"`    // 0 or 1
+	Retain bool `json:"retain"` // retain the latest metrics snapshot
+
+	Username    string `json:"username"`
+	PasswordEnv string `json:"password_env"`
+
+	// TLS trust: CAFile adds a private CA, InsecureSkipVerify disables
+	// certificate checks for test brokers
+	CAFile             string `json:"ca_file"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+}
+
+func (c MQTTConfig) withDefaults(host string) MQTTConfig {
+	if c.ClientID == "" {
+		c.ClientID = "system-monitor-" + host
+	}
+	if c.MetricsTopic == "" {
+		c.MetricsTopic = "eywa/robots/{host}/metrics"
+	}
+	if c.AlertsTopic == "" {
+		c.AlertsTopic = "eywa/robots/{host}/alerts"
+	}
+	if c.PasswordEnv == "" {
+		c.PasswordEnv = "MQTT_PASSWORD"
+	}
+	c.MetricsTopic = strings.ReplaceAll(c.MetricsTopic, "{host}", host)
+	c.AlertsTopic = strings.ReplaceAll(c.AlertsTopic, "{host}", host)
+	return c
+}
+
+// Validate checks the broker URL, topics and QoS
+func (c MQTTConfig) Validate() error {
+	var errs []error
+	if _, _, err := c.address(); err != nil {
+		errs = append(errs, err)
+	}
+	for name, topic := range map[string]string{"metrics_topic": c.MetricsTopic, "alerts_topic": c.AlertsTopic} {
+		if strings.ContainsAny(topic, "+#") {
+			errs = append(errs, fmt.Errorf("%s must not contain wildcards, got %q", name, topic))
+		}
+	}
+	if c.QoS != 0 && c.QoS != 1 {
+		errs = append(errs, fmt.Errorf("qos must be 0 or 1, got %d", c.QoS))
+	}
+	return errors.Join(errs...)
+}
+
+// address returns the broker's host:port and whether it uses TLS
+func (c MQTTConfig) address() (string, bool, error) {
+	u, err := url.Parse(c.Broker)
+	if err != nil || u.Host == "" {
+		return "", false, fmt.Errorf("broker must be a URL like tcp://host:1883, got %q", c.Broker)
+	}
+
+	var secure bool
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		secure, port = true, "8883"
+	default:
+		return "", false, fmt.Errorf("unsupported broker scheme %q", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), secure, nil
+}
+
+// mqttMessage is a message waiting to be published
+type mqttMessage struct {
+	topic   string
+	payload []byte
+	retain  bool
+}
+
+// MQTT publishes every snapshot, and through Alerts every alert digest,
+// to an MQTT 3.1.1 broker. The connection is opened on the first message
+// and reopened after failures.
+type MQTT struct {
+	config    MQTTConfig
+	host      string
+	tlsConfig *tls.Config
+	password  string
+
+	messages chan mqttMessage
+	done     chan struct{}
+
+	conn     net.Conn
+	reader   *bufio.Reader
+	packetID uint16
+
+	// OnError is called for every failed or dropped message
+	OnError func(err error)
+}
+
+// NewMQTT creates an MQTT publisher
+func NewMQTT(config MQTTConfig, host string) (*MQTT, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config = config.withDefaults(host)
+
+	m := &MQTT{
+		config:   config,
+		host:     host,
+		password: os.Getenv(config.PasswordEnv),
+		messages: make(chan mqttMessage, 16),
+		done:     make(chan struct{}),
+	}
+
+	address, secure, _ := config.address()
+	if secure {
+		serverName, _, _ := net.SplitHostPort(address)
+		m.tlsConfig = &tls.Config{
+			ServerName:         serverName,
+			InsecureSkipVerify: config.InsecureSkipVerify,
+		}
+		if config.CAFile != "" {
+			pem, err := os.ReadFile(config.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("mqtt: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("mqtt: no certificates in %s", config.CAFile)
+			}
+			m.tlsConfig.RootCAs = pool
+		}
+	}
+
+	go m.run()
+	return m, nil
+}
+
+// Push queues a snapshot for publishing on the metrics topic
+func (m *MQTT) Push(metrics *monitor.SystemMetrics) {
+	payload, err := json.Marshal(struct {
+		Host string `json:"host"`
+		*monitor.SystemMetrics
+	}{m.host, metrics})
+	if err != nil {
+		m.reportError(fmt.Errorf("mqtt: encode metrics: %w", err))
+		return
+	}
+	m.publish(mqttMessage{topic: m.config.MetricsTopic, payload: payload, retain: m.config.Retain})
+}
+
+// Alerts returns a notifier publishing alert digests on the alerts topic.
+// Closing it does nothing; Close the MQTT sink instead.
+func (m *MQTT) Alerts() notify.Notifier {
+	return mqttAlerts{m}
+}
+
+// Close publishes pending messages, waiting up to timeout, and
+// disconnects
+func (m *MQTT) Close(timeout time.Duration) {
+	close(m.messages)
+
+	select {
+	case <-m.done:
+	case <-time.After(timeout):
+		m.reportError(fmt.Errorf("mqtt flush timed out after %s", timeout))
+	}
+}
+
+func (m *MQTT) publish(message mqttMessage) {
+	select {
+	case m.messages <- message:
+	default:
+		m.reportError(fmt.Errorf("mqtt queue full, dropping message for %s", message.topic))
+	}
+}
+
+func (m *MQTT) run() {
+	defer close(m.done)
+
+	for message := range m.messages {
+		if err := m.send(message); err != nil {
+			m.reportError(fmt.Errorf("mqtt: %w", err))
+			m.disconnect(false)
+		}
+	}
+	m.disconnect(true)
+}
+
+// send publishes a message, connecting first if needed. With QoS 1 it
+// waits for the broker's acknowledgement.
+func (m *MQTT) send(message mqttMessage) error {
+	if m.conn == nil {
+		if err := m.connect(); err != nil {
+			return err
+		}
+	}
+
+	flags := byte(0x30)
+	if message.retain {
+		flags |= 0x01
+	}
+	body := mqttString(message.topic)
+	if m.config.QoS == 1 {
+		flags |= 0x02
+		m.packetID++
+		if m.packetID == 0 {
+			m.packetID = 1
+		}
+		body = binary.BigEndian.AppendUint16(body, m.packetID)
+	}
+	body = append(body, message.payload...)
+
+	m.conn.SetDeadline(time.Now().Add(mqttTimeout))
+	if _, err := m.conn.Write(mqttPacket(flags, body)); err != nil {
+		return err
+	}
+	if m.config.QoS == 0 {
+		return nil
+	}
+
+	for {
+		packetType, body, err := m.readPacket()
+		if err != nil {
+			return fmt.Errorf("wait for PUBACK: %w", err)
+		}
+		if packetType == 0x40 && len(body) == 2 && binary.BigEndian.Uint16(body) == m.packetID {
+			return nil
+		}
+	}
+}
+
+// connect opens the connection and sends CONNECT with a clean session
+// and keep-alive disabled, as messages are sent at every interval anyway
+func (m *MQTT) connect() error {
+	address, _, _ := m.config.address()
+	dialer := &net.Dialer{Timeout: mqttTimeout}
+
+	var conn net.Conn
+	var err error
+	if m.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, m.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+	m.conn, m.reader = conn, bufio.NewReader(conn)
+
+	flags := byte(0x02) // clean session
+	payload := mqttString(m.config.ClientID)
+	if m.config.Username != "" {
+		flags |= 0x80
+		payload = append(payload, mqttString(m.config.Username)...)
+		if m.password != "" {
+			flags |= 0x40
+			payload = append(payload, mqttString(m.password)...)
+		}
+	}
+	body := append(mqttString("MQTT"), 4, flags, 0, 0)
+	body = append(body, payload...)
+
+	conn.SetDeadline(time.Now().Add(mqttTimeout))
+	if _, err := conn.Write(mqttPacket(0x10, body)); err != nil {
+		m.disconnect(false)
+		return err
+	}
+
+	packetType, ack, err := m.readPacket()
+	if err != nil {
+		m.disconnect(false)
+		return fmt.Errorf("wait for CONNACK: %w", err)
+	}
+	if packetType != 0x20 || len(ack) != 2 {
+		m.disconnect(false)
+		return fmt.Errorf("unexpected packet 0x%x instead of CONNACK", packetType)
+	}
+	if code := ack[1]; code != 0 {
+		m.disconnect(false)
+		return fmt.Errorf("connection refused: %s", mqttConnectError(code))
+	}
+	return nil
+}
+
+// disconnect closes the connection, saying goodbye to the broker first
+// when graceful
+func (m *MQTT) disconnect(graceful bool) {
+	if m.conn == nil {
+		return
+	}
+	if graceful {
+		m.conn.SetDeadline(time.Now().Add(time.Second))
+		m.conn.Write([]byte{0xe0, 0x00})
+	}
+	m.conn.Close()
+	m.conn, m.reader = nil, nil
+}
+
+// readPacket reads one control packet, returning its type (the upper
+// nibble of the first byte) and its body
+func (m *MQTT) readPacket() (byte, []byte, error) {
+	header, err := m.reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var length, shift int
+	for {
+		b, err := m.reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		if shift += 7; shift > 21 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(m.reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header & 0xf0, body, nil
+}
+
+func (m *MQTT) reportError(err error) {
+	if m.OnError != nil {
+		m.OnError(err)
+	}
+}
+
+// mqttPacket prefixes body with the fixed header: flags and the
+// remaining length as a variable length integer
+func mqttPacket(flags byte, body []byte) []byte {
+	packet := []byte{flags}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		if n /= 128; n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// mqttString encodes a length-prefixed UTF-8 string
+func mqttString(s string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(s))), s...)
+}
+
+func mqttConnectError(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("return code %d", code)
+}
+
+// mqttAlerts publishes digests with alerts or resolutions as JSON
+type mqttAlerts struct {
+	m *MQTT
+}
+
+func (a mqttAlerts) Send(digest notify.Digest) {
+	if len(digest.Alerts) == 0 && len(digest.Resolved) == 0 {
+		return
+	}
+	payload, err := json.Marshal(digest)
+	if err != nil {
+		a.m.reportError(fmt.Errorf("mqtt: encode alerts: %w", err))
+		return
+	}
+	a.m.publish(mqttMessage{topic: a.m.config.AlertsTopic, payload: payload})
+}
+
+func (a mqttAlerts) Close(timeout time.Duration) {}
@@ -0,0 +1,219 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+
+	"system-monitor/monitor"
+)
+
+// OTLP pushes gauges and cumulative counters to an OpenTelemetry
+// collector using OTLP/HTTP with JSON encoding
+type OTLP struct {
+	endpoint string
+	host     string
+	client   *http.Client
+	queue    *queue
+
+	// start is when the counters began counting: the host's boot time,
+	// or the sink's creation if that is unknown
+	start time.Time
+
+	// ResourceAttributes are added to the host.name, os.type, host.arch
+	// and service.name resource attributes, e.g. deployment.environment;
+	// they may override all but host.name
+	ResourceAttributes map[string]string
+
+	// OnError is called for every failed or dropped push
+	OnError func(err error)
+}
+
+// NewOTLP creates an OTLP sink. The endpoint is the collector base URL
+// (e.g. http://collector:4318); "/v1/metrics" is appended if missing.
+func NewOTLP(endpoint, hostname string) *OTLP {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v1/metrics") {
+		endpoint += "/v1/metrics"
+	}
+
+	o := &OTLP{
+		endpoint: endpoint,
+		host:     hostname,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		start:    time.Now(),
+	}
+	if boot, err := host.BootTime(); err == nil {
+		o.start = time.Unix(int64(boot), 0)
+	}
+	o.queue = newQueue(16, o.send, o.reportError)
+	return o
+}
+
+// Push queues a snapshot for sending
+func (o *OTLP) Push(metrics *monitor.SystemMetrics) {
+	o.queue.push(batch{
+		timestamp: metrics.Timestamp,
+		gauges:    Gauges(metrics, o.host),
+		counters:  Counters(metrics, o.host),
+	})
+}
+
+// Close flushes pending gauges
+func (o *OTLP) Close(timeout time.Duration) {
+	o.queue.close(timeout)
+}
+
+func (o *OTLP) send(b batch) error {
+	body, err := json.Marshal(otlpRequest(b, o.resource(), o.start))
+	if err != nil {
+		return fmt.Errorf("otlp: encode: %w", err)
+	}
+
+	resp, err := o.client.Post(o.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("otlp: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("otlp: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// resource returns the resource attributes of every request
+func (o *OTLP) resource() map[string]string {
+	attributes := map[string]string{
+		"service.name": "system-monitor",
+		"os.type":      runtime.GOOS,
+		"host.arch":    runtime.GOARCH,
+	}
+	for key, value := range o.ResourceAttributes {
+		attributes[key] = value
+	}
+	attributes["host.name"] = o.host
+	return attributes
+}
+
+func (o *OTLP) reportError(err error) {
+	if o.OnError != nil {
+		o.OnError(err)
+	}
+}
+
+// otlpTemporalityCumulative is AGGREGATION_TEMPORALITY_CUMULATIVE
+const otlpTemporalityCumulative = 2
+
+// otlpRequest builds an ExportMetricsServiceRequest in OTLP JSON form.
+// Gauges sharing a name become data points of a single gauge metric;
+// counters become monotonic cumulative sums counting from start.
+func otlpRequest(b batch, resource map[string]string, start time.Time) map[string]interface{} {
+	timestamp := strconv.FormatInt(b.timestamp.UnixNano(), 10)
+	startTimestamp := strconv.FormatInt(start.UnixNano(), 10)
+
+	metrics := make([]interface{}, 0, len(b.gauges)+len(b.counters))
+	for _, m := range otlpPoints(b.gauges, func(g Gauge) map[string]interface{} {
+		return map[string]interface{}{"timeUnixNano": timestamp, "asDouble": g.Value}
+	}) {
+		metrics = append(metrics, map[string]interface{}{
+			"name":  m.name,
+			"gauge": map[string]interface{}{"dataPoints": m.points},
+		})
+	}
+	for _, m := range otlpPoints(b.counters, func(g Gauge) map[string]interface{} {
+		return map[string]interface{}{
+			"startTimeUnixNano": startTimestamp,
+			"timeUnixNano":      timestamp,
+			"asInt":             strconv.FormatUint(uint64(g.Value), 10),
+		}
+	}) {
+		metrics = append(metrics, map[string]interface{}{
+			"name": m.name,
+			"sum": map[string]interface{}{
+				"dataPoints":             m.points,
+				"aggregationTemporality": otlpTemporalityCumulative,
+				"isMonotonic":            true,
+			},
+		})
+	}
+
+	resourceAttributes := make([]interface{}, 0, len(resource))
+	for _, key := range sortedKeys(resource) {
+		resourceAttributes = append(resourceAttributes, otlpAttribute(key, resource[key]))
+	}
+
+	return map[string]interface{}{
+		"resourceMetrics": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": resourceAttributes,
+				},
+				"scopeMetrics": []interface{}{
+					map[string]interface{}{
+						"scope":   map[string]interface{}{"name": "system-monitor"},
+						"metrics": metrics,
+					},
+				},
+			},
+		},
+	}
+}
+
+// otlpMetric is the data points of one metric name
+type otlpMetric struct {
+	name   string
+	points []interface{}
+}
+
+// otlpPoints groups measurements by name in order of first appearance,
+// building each data point with point and adding its tags as attributes
+func otlpPoints(measurements []Gauge, point func(Gauge) map[string]interface{}) []otlpMetric {
+	var metrics []otlpMetric
+	index := make(map[string]int)
+	for _, g := range measurements {
+		i, ok := index[g.Name]
+		if !ok {
+			i = len(metrics)
+			index[g.Name] = i
+			metrics = append(metrics, otlpMetric{name: g.Name})
+		}
+
+		// host is a resource attribute, not a data point attribute
+		attributes := []interface{}{}
+		for _, key := range sortedKeys(g.Tags) {
+			if key != "host" {
+				attributes = append(attributes, otlpAttribute(key, g.Tags[key]))
+			}
+		}
+
+		p := point(g)
+		p["attributes"] = attributes
+		metrics[i].points = append(metrics[i].points, p)
+	}
+	return metrics
+}
+
+func otlpAttribute(key, value string) map[string]interface{} {
+	return map[string]interface{}{
+		"key":   key,
+		"value": map[string]interface{}{"stringValue": value},
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,143 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"system-monitor/monitor"
+)
+
+// Prometheus serves the latest snapshot on an HTTP /metrics endpoint in
+// the Prometheus text exposition format. Unlike the push exporters it is
+// scraped, so Push only replaces the gauges being served.
+type Prometheus struct {
+	host   string
+	server *http.Server
+
+	mu      sync.RWMutex
+	gauges  []Gauge
+	updated time.Time
+
+	// OnError is called if the HTTP server fails after starting
+	OnError func(err error)
+}
+
+// NewPrometheus starts serving /metrics on addr (e.g. ":9100")
+func NewPrometheus(addr, host string) (*Prometheus, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus: %w", err)
+	}
+
+	p := &Prometheus{host: host}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", p.serveMetrics)
+	p.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := p.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) && p.OnError != nil {
+			p.OnError(fmt.Errorf("prometheus: %w", err))
+		}
+	}()
+
+	return p, nil
+}
+
+// Push replaces the served gauges with those of the snapshot
+func (p *Prometheus) Push(metrics *monitor.SystemMetrics) {
+	gauges := Gauges(metrics, p.host)
+
+	p.mu.Lock()
+	p.gauges = gauges
+	p.updated = metrics.Timestamp
+	p.mu.Unlock()
+}
+
+// Close stops the HTTP server, waiting up to timeout for in-flight scrapes
+func (p *Prometheus) Close(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	p.server.Shutdown(ctx)
+}
+
+func (p *Prometheus) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	gauges := p.gauges
+	updated := p.updated
+	p.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(prometheusText(gauges, updated, p.host)))
+}
+
+// prometheusText renders gauges in the text exposition format, grouped
+// by metric name as the format requires
+func prometheusText(gauges []Gauge, updated time.Time, host string) string {
+	byName := make(map[string][]Gauge)
+	for _, g := range gauges {
+		name := prometheusName(g.Name)
+		byName[name] = append(byName[name], g)
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", name)
+		for _, g := range byName[name] {
+			fmt.Fprintf(&b, "%s%s %s\n", name, prometheusLabels(g.Tags), strconv.FormatFloat(g.Value, 'g', -1, 64))
+		}
+	}
+
+	// Lets scrapers detect a stalled monitoring loop
+	if !updated.IsZero() {
+		name := prometheusName(MetricPrefix + "last_update_timestamp_seconds")
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", name)
+		fmt.Fprintf(&b, "%s%s %d\n", name, prometheusLabels(map[string]string{"host": host}), updated.Unix())
+	}
+
+	return b.String()
+}
+
+// prometheusName maps a dotted gauge name onto the Prometheus charset
+func prometheusName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == ':' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// labelEscaper escapes label values as the exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func prometheusLabels(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", prometheusName(key), labelEscaper.Replace(tags[key])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
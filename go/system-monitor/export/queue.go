@@ -0,0 +1,62 @@
+package export
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// queue hands batches to a single background sender so exporting never
+// blocks the monitoring loop
+type queue struct {
+	items   chan batch
+	wg      sync.WaitGroup
+	send    func(batch) error
+	onError func(error)
+}
+
+func newQueue(size int, send func(batch) error, onError func(error)) *queue {
+	q := &queue{
+		items:   make(chan batch, size),
+		send:    send,
+		onError: onError,
+	}
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		for b := range q.items {
+			if err := q.send(b); err != nil {
+				q.onError(err)
+			}
+		}
+	}()
+
+	return q
+}
+
+// push queues a batch, dropping it if the sender has fallen behind
+func (q *queue) push(b batch) {
+	select {
+	case q.items <- b:
+	default:
+		q.onError(fmt.Errorf("export queue full, dropping %d gauges", len(b.gauges)))
+	}
+}
+
+// close stops the queue and waits up to timeout for pending batches
+func (q *queue) close(timeout time.Duration) {
+	close(q.items)
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		q.onError(fmt.Errorf("export flush timed out after %s", timeout))
+	}
+}
@@ -0,0 +1,208 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"system-monitor/monitor"
+)
+
+// Record formats
+const (
+	FormatJSONLines = "jsonl"
+	FormatCSV       = "csv"
+)
+
+// DefaultCSVFields are the columns of a CSV export without a field
+// selection; CSV needs fixed columns, so per-mount and per-interface
+// fields must be selected by name
+var DefaultCSVFields = []string{
+	"timestamp", "host",
+	"cpu.usage_percent", "memory.used_percent", "memory.swap_percent",
+	"load.load1", "load.load5", "load.load15",
+}
+
+// RecordWriter writes one record per snapshot as JSON Lines or CSV, for
+// spreadsheets and ad-hoc analysis.
+//
+// Fields are named like the exported gauges without their prefix, e.g.
+// "cpu.usage_percent"; gauges that carry a tag besides host name it in
+// braces, e.g. "disk.used_percent{mount=/}" or
+// "network.bytes_sent{interface=eth0}". "timestamp" and "host" are
+// always available. Without a field selection, JSON Lines records hold
+// the whole snapshot and CSV records DefaultCSVFields.
+type RecordWriter struct {
+	w      io.Writer
+	format string
+	fields []string
+	csv    *csv.Writer
+
+	// header is whether the CSV header is still to be written
+	header bool
+}
+
+// NewRecordWriter creates a writer of the given format. header controls
+// whether a CSV export starts with a header row, which is left out when
+// appending to an existing file.
+func NewRecordWriter(w io.Writer, format string, fields []string, header bool) (*RecordWriter, error) {
+	r := &RecordWriter{w: w, format: format, fields: fields, header: header}
+	switch format {
+	case FormatJSONLines:
+	case FormatCSV:
+		if len(r.fields) == 0 {
+			r.fields = DefaultCSVFields
+		}
+		r.csv = csv.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("unknown record format %q, expected %q or %q", format, FormatJSONLines, FormatCSV)
+	}
+	return r, nil
+}
+
+// Write writes the record of a snapshot. Selected fields the snapshot
+// lacks are written as empty CSV cells or JSON nulls.
+func (r *RecordWriter) Write(metrics *monitor.SystemMetrics, host string) error {
+	if r.format == FormatJSONLines && len(r.fields) == 0 {
+		line, err := json.Marshal(struct {
+			Host string `json:"host"`
+			*monitor.SystemMetrics
+		}{host, metrics})
+		if err != nil {
+			return err
+		}
+		_, err = r.w.Write(append(line, '\n'))
+		return err
+	}
+
+	values := RecordFields(metrics, host)
+	if r.format == FormatJSONLines {
+		record := make(map[string]interface{}, len(r.fields))
+		for _, field := range r.fields {
+			record[field] = values[field]
+		}
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		_, err = r.w.Write(append(line, '\n'))
+		return err
+	}
+
+	if r.header {
+		if err := r.csv.Write(r.fields); err != nil {
+			return err
+		}
+		r.header = false
+	}
+	row := make([]string, len(r.fields))
+	for i, field := range r.fields {
+		switch value := values[field].(type) {
+		case string:
+			row[i] = value
+		case float64:
+			row[i] = strconv.FormatFloat(value, 'f', -1, 64)
+		}
+	}
+	if err := r.csv.Write(row); err != nil {
+		return err
+	}
+	r.csv.Flush()
+	return r.csv.Error()
+}
+
+// RecordFields returns every field of a snapshot by name, see
+// RecordWriter
+func RecordFields(metrics *monitor.SystemMetrics, host string) map[string]interface{} {
+	fields := map[string]interface{}{
+		"timestamp": metrics.Timestamp.UTC().Format(time.RFC3339),
+		"host":      host,
+	}
+	for _, group := range [][]Gauge{Gauges(metrics, host), Counters(metrics, host)} {
+		for _, g := range group {
+			fields[recordFieldName(g)] = g.Value
+		}
+	}
+	return fields
+}
+
+// recordFieldName names a gauge as a record field, e.g.
+// "disk.used_percent{mount=/}"
+func recordFieldName(g Gauge) string {
+	name := strings.TrimPrefix(g.Name, MetricPrefix)
+
+	var tags []string
+	for _, key := range sortedKeys(g.Tags) {
+		if key != "host" {
+			tags = append(tags, key+"="+g.Tags[key])
+		}
+	}
+	if len(tags) > 0 {
+		name += "{" + strings.Join(tags, ",") + "}"
+	}
+	return name
+}
+
+// RecordFile is a Sink appending a record of every snapshot to a file
+type RecordFile struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *RecordWriter
+	host   string
+
+	// OnError is called for every failed write
+	OnError func(err error)
+}
+
+// NewRecordFile creates a RecordFile sink appending to path. An empty
+// format follows the extension: .csv is CSV, anything else JSON Lines.
+// The CSV header is written only if the file is new or empty.
+func NewRecordFile(path, format string, fields []string, host string) (*RecordFile, error) {
+	if format == "" {
+		format = FormatJSONLines
+		if strings.HasSuffix(strings.ToLower(path), ".csv") {
+			format = FormatCSV
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("records: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("records: %w", err)
+	}
+
+	writer, err := NewRecordWriter(file, format, fields, info.Size() == 0)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &RecordFile{file: file, writer: writer, host: host}, nil
+}
+
+// Push appends the snapshot's record. Local writes are quick enough to
+// make in the monitoring loop.
+func (f *RecordFile) Push(metrics *monitor.SystemMetrics) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.writer.Write(metrics, f.host); err != nil && f.OnError != nil {
+		f.OnError(fmt.Errorf("records: %w", err))
+	}
+}
+
+// Close closes the file
+func (f *RecordFile) Close(timeout time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.file.Close()
+}
@@ -0,0 +1,14 @@
+package export
+
+import (
+	"time"
+
+	"system-monitor/monitor"
+)
+
+// Sink pushes every metrics snapshot to an external collector. Push must
+// not block; delivery failures are reported through the sink's OnError.
+type Sink interface {
+	Push(metrics *monitor.SystemMetrics)
+	Close(timeout time.Duration)
+}
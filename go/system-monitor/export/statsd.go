@@ -0,0 +1,155 @@
+package export
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"system-monitor/monitor"
+)
+
+// maxStatsDPacket keeps UDP datagrams below a typical Ethernet MTU
+const maxStatsDPacket = 1432
+
+// StatsD pushes gauges over UDP using the DogStatsD tag extension, or
+// plain StatsD for servers that do not understand tags
+type StatsD struct {
+	conn  net.Conn
+	host  string
+	queue *queue
+
+	// Tags are added to every DogStatsD gauge, e.g. env:prod
+	Tags map[string]string
+
+	// Plain folds the host and tag values into the metric name
+	// ("system_monitor.web-1.disk.used_percent._var") instead of sending
+	// DogStatsD tags; Tags are ignored
+	Plain bool
+
+	// OnError is called for every failed or dropped push
+	OnError func(err error)
+}
+
+// NewStatsD creates a StatsD sink sending to addr ("host:port")
+func NewStatsD(addr, host string) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("statsd: %w", err)
+	}
+
+	s := &StatsD{conn: conn, host: host}
+	s.queue = newQueue(16, s.send, s.reportError)
+	return s, nil
+}
+
+// Push queues a snapshot for sending
+func (s *StatsD) Push(metrics *monitor.SystemMetrics) {
+	s.queue.push(batch{timestamp: metrics.Timestamp, gauges: Gauges(metrics, s.host)})
+}
+
+// Close flushes pending gauges and closes the socket
+func (s *StatsD) Close(timeout time.Duration) {
+	s.queue.close(timeout)
+	s.conn.Close()
+}
+
+func (s *StatsD) send(b batch) error {
+	var packet strings.Builder
+	for _, g := range b.gauges {
+		line := s.line(g)
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxStatsDPacket {
+			if err := s.write(packet.String()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+
+	if packet.Len() > 0 {
+		return s.write(packet.String())
+	}
+	return nil
+}
+
+func (s *StatsD) write(packet string) error {
+	s.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	if _, err := s.conn.Write([]byte(packet)); err != nil {
+		return fmt.Errorf("statsd: %w", err)
+	}
+	return nil
+}
+
+func (s *StatsD) reportError(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// line formats a gauge in the sink's dialect
+func (s *StatsD) line(g Gauge) string {
+	if s.Plain {
+		return plainStatsDLine(g)
+	}
+	if len(s.Tags) == 0 {
+		return statsDLine(g)
+	}
+
+	tags := make(map[string]string, len(g.Tags)+len(s.Tags))
+	for k, v := range s.Tags {
+		tags[k] = v
+	}
+	for k, v := range g.Tags {
+		tags[k] = v
+	}
+	g.Tags = tags
+	return statsDLine(g)
+}
+
+// statsDLine formats a gauge as "name:value|g|#tag:value,..."
+func statsDLine(g Gauge) string {
+	line := fmt.Sprintf("%s:%g|g", g.Name, g.Value)
+	if len(g.Tags) == 0 {
+		return line
+	}
+
+	tags := make([]string, 0, len(g.Tags))
+	for k, v := range g.Tags {
+		tags = append(tags, k+":"+v)
+	}
+	sort.Strings(tags)
+	return line + "|#" + strings.Join(tags, ",")
+}
+
+// plainStatsDLine formats a gauge as "name:value|g", with the host after
+// the metric prefix and the other tag values, in tag key order, appended
+// to the name
+func plainStatsDLine(g Gauge) string {
+	name := g.Name
+	if host := g.Tags["host"]; host != "" {
+		name = MetricPrefix + statsDName(host) + "." + strings.TrimPrefix(name, MetricPrefix)
+	}
+	for _, key := range sortedKeys(g.Tags) {
+		if key != "host" {
+			name += "." + statsDName(g.Tags[key])
+		}
+	}
+	return fmt.Sprintf("%s:%g|g", name, g.Value)
+}
+
+// statsDName makes a tag value safe as a metric name segment: characters
+// other than letters, digits, '-' and '_' become '_'
+func statsDName(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, value)
+}
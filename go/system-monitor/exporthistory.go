@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"system-monitor/export"
+	"system-monitor/monitor/store"
+)
+
+// runExportHistory implements the export-history command, which writes
+// the snapshots of a local store as JSON Lines or CSV without talking to
+// EYWA:
+//
+//	system-monitor export-history -store /var/lib/system-monitor/metrics -since 24h -format csv
+func runExportHistory(args []string) error {
+	flags := flag.NewFlagSet("export-history", flag.ContinueOnError)
+	storeDir := flags.String("store", "", "store_dir of the robot to export from (required)")
+	format := flags.String("format", export.FormatJSONLines, `record format, "jsonl" or "csv"`)
+	fields := flags.String("fields", "", `comma separated fields, e.g. "timestamp,cpu.usage_percent,disk.used_percent{mount=/}"`)
+	since := flags.Duration("since", 24*time.Hour, "export snapshots taken within this long before now, 0 for all")
+	output := flags.String("o", "-", `output file, "-" for stdout`)
+	host := flags.String("host", "", "host name written to records (default: this host)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *storeDir == "" {
+		return fmt.Errorf("-store is required")
+	}
+
+	if *host == "" {
+		*host, _ = os.Hostname()
+	}
+	var selected []string
+	for _, field := range strings.Split(*fields, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			selected = append(selected, field)
+		}
+	}
+
+	metricsStore, err := store.Open(*storeDir, 0)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	from := time.Time{}
+	if *since > 0 {
+		from = now.Add(-*since)
+	}
+	snapshots, err := metricsStore.Range(from, now)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	buffered := bufio.NewWriter(w)
+
+	records, err := export.NewRecordWriter(buffered, *format, selected, true)
+	if err != nil {
+		return err
+	}
+	for i := range snapshots {
+		if err := records.Write(&snapshots[i], *host); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
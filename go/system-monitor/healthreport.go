@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"system-monitor/monitor"
+	"system-monitor/monitor/store"
+	"system-monitor/report"
+
+	eywa "github.com/neyho/eywa-go"
+)
+
+// HealthReportConfig configures the periodic HTML health report. Reports
+// cover the last Period of snapshots in the local store.
+type HealthReportConfig struct {
+	// Period covered by each report, 24h by default; "168h" for weekly
+	Period Seconds `json:"period"`
+
+	// Also convert the report to PDF with wkhtmltopdf or headless Chrome,
+	// whichever is installed
+	PDF bool `json:"pdf"`
+
+	// Keep a copy of each report in this directory as well
+	OutputDir string `json:"output_dir"`
+}
+
+// period returns the span a report covers
+func (c HealthReportConfig) period() time.Duration {
+	if c.Period > 0 {
+		return time.Duration(c.Period) * time.Second
+	}
+	return 24 * time.Hour
+}
+
+// healthReport is a rendered report and where it ended up
+type healthReport struct {
+	Summary monitor.Summary
+	Files   map[string]string // EYWA file euuid by file name
+	Saved   []string          // local copies
+}
+
+// reportFile is one rendering of a report
+type reportFile struct {
+	name        string
+	contentType string
+	data        []byte
+}
+
+// pointsPerChart is how many points a report chart has at most; snapshots
+// are downsampled to fit
+const pointsPerChart = 96
+
+// generateHealthReport renders the report for the period ending at now
+// and uploads it to EYWA, along with the PDF if configured. A failed PDF
+// conversion still uploads the HTML report and is returned as an error.
+func generateHealthReport(ctx context.Context, cfg HealthReportConfig, metricsStore *store.Store, config monitor.Config, host string, now time.Time) (healthReport, error) {
+	result := healthReport{Files: make(map[string]string)}
+
+	snapshots, err := metricsStore.Range(now.Add(-cfg.period()), now)
+	if err != nil {
+		return result, err
+	}
+	if len(snapshots) == 0 {
+		return result, errors.New("no stored snapshots in the report period")
+	}
+	result.Summary = monitor.Summarize(host, snapshots, config, max(cfg.period()/pointsPerChart, time.Minute))
+
+	var html bytes.Buffer
+	if err := report.HTML(&html, result.Summary); err != nil {
+		return result, fmt.Errorf("render report: %w", err)
+	}
+
+	base := fmt.Sprintf("health-report-%s-%s", host, now.UTC().Format("2006-01-02-1504"))
+	files := []reportFile{{base + ".html", "text/html", html.Bytes()}}
+
+	var errs []error
+	if cfg.PDF {
+		pdf, err := convertToPDF(ctx, html.Bytes())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("convert report to PDF: %w", err))
+		} else {
+			files = append(files, reportFile{base + ".pdf", "application/pdf", pdf})
+		}
+	}
+
+	for _, file := range files {
+		if cfg.OutputDir != "" {
+			path := filepath.Join(cfg.OutputDir, file.name)
+			if err := os.WriteFile(path, file.data, 0o644); err != nil {
+				errs = append(errs, fmt.Errorf("save report: %w", err))
+			} else {
+				result.Saved = append(result.Saved, path)
+			}
+		}
+
+		euuid, err := uploadFile(ctx, file.name, file.contentType, file.data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("upload %s: %w", file.name, err))
+			continue
+		}
+		result.Files[file.name] = euuid
+	}
+	return result, errors.Join(errs...)
+}
+
+// pdfTimeout bounds a PDF conversion
+const pdfTimeout = time.Minute
+
+// convertToPDF prints HTML to PDF with the first converter found on the
+// PATH. The report is self-contained, so no network access is needed.
+func convertToPDF(ctx context.Context, html []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "health-report")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "report.html")
+	output := filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(input, html, 0o600); err != nil {
+		return nil, err
+	}
+
+	converters := [][]string{
+		{"wkhtmltopdf", "--quiet", input, output},
+		{"chromium", "--headless", "--disable-gpu", "--no-sandbox", "--print-to-pdf=" + output, input},
+		{"chromium-browser", "--headless", "--disable-gpu", "--no-sandbox", "--print-to-pdf=" + output, input},
+		{"google-chrome", "--headless", "--disable-gpu", "--no-sandbox", "--print-to-pdf=" + output, input},
+	}
+	for _, args := range converters {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, pdfTimeout)
+		out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: %s", args[0], err, bytes.TrimSpace(out))
+		}
+		return os.ReadFile(output)
+	}
+	return nil, errors.New("no converter found, install wkhtmltopdf or chromium")
+}
+
+// uploadFile stores data as an EYWA file and returns its euuid: EYWA
+// hands out a presigned upload URL, the data is PUT there, and the
+// upload is confirmed
+func uploadFile(ctx context.Context, name, contentType string, data []byte) (string, error) {
+	euuid, err := newUUID()
+	if err != nil {
+		return "", err
+	}
+
+	result, err := graphQL(ctx, `
+		mutation($file: FileInput!) {
+			requestUploadURL(file: $file)
+		}
+	`, map[string]interface{}{
+		"file": map[string]interface{}{
+			"euuid":        euuid,
+			"name":         name,
+			"content_type": contentType,
+			"size":         len(data),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	response, _ := result.(map[string]interface{})
+	url, _ := response["requestUploadURL"].(string)
+	if url == "" {
+		return "", errors.New("no upload URL returned")
+	}
+
+	err = graphQLRetry.do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("put file: %w", err)
+	}
+
+	_, err = graphQL(ctx, `
+		mutation($url: String!) {
+			confirmFileUpload(url: $url)
+		}
+	`, map[string]interface{}{
+		"url": url,
+	})
+	if err != nil {
+		return "", fmt.Errorf("confirm upload: %w", err)
+	}
+	return euuid, nil
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// reportData is the data of the EYWA report announcing a health report
+func (r healthReport) reportData() map[string]interface{} {
+	data := map[string]interface{}{
+		"host":           r.Summary.Host,
+		"from":           r.Summary.From,
+		"to":             r.Summary.To,
+		"snapshots":      r.Summary.Snapshots,
+		"average_health": r.Summary.AverageHealth,
+		"worst_health":   r.Summary.WorstHealth,
+		"top_alerts":     r.Summary.TopAlerts,
+		"files":          r.Files,
+	}
+	if len(r.Saved) > 0 {
+		data["saved"] = r.Saved
+	}
+	return data
+}
+
+// publishHealthReport generates a report and announces it as an EYWA
+// report; failures are logged as warnings
+func publishHealthReport(ctx context.Context, cfg HealthReportConfig, metricsStore *store.Store, config monitor.Config, host string, now time.Time) {
+	result, err := generateHealthReport(ctx, cfg, metricsStore, config, host, now)
+	if err != nil {
+		eywa.Warn("Health report incomplete", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if result.Summary.Snapshots == 0 {
+		return
+	}
+	eywa.Report(fmt.Sprintf("Health report for %s: average health %.0f (%s)", host,
+		result.Summary.AverageHealth, monitor.HealthGrade(result.Summary.AverageHealth)), result.reportData(), nil)
+}
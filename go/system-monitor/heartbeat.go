@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	eywa "github.com/neyho/eywa-go"
+)
+
+// heartbeat periodically marks a long-running task as alive in EYWA and
+// reports how far the run has come, so a continuous run is not mistaken
+// for a hung one
+type heartbeat struct {
+	every  time.Duration
+	limits runLimits
+	start  time.Time
+	next   time.Time
+
+	iterations int
+	failures   int
+	alerts     int
+}
+
+func newHeartbeat(every time.Duration, limits runLimits, start time.Time) *heartbeat {
+	return &heartbeat{every: every, limits: limits, start: start, next: start.Add(every)}
+}
+
+// record counts one iteration and the alerts it raised, or a failed
+// collection
+func (h *heartbeat) record(alerts int, failed bool) {
+	if h == nil {
+		return
+	}
+	h.iterations++
+	h.alerts += alerts
+	if failed {
+		h.failures++
+	}
+}
+
+// beat sends a heartbeat if one is due at now
+func (h *heartbeat) beat(now time.Time) {
+	if h == nil || h.every <= 0 || now.Before(h.next) {
+		return
+	}
+	for !h.next.After(now) {
+		h.next = h.next.Add(h.every)
+	}
+
+	progress := h.progress(now)
+	eywa.UpdateTask(eywa.PROCESSING)
+	eywa.Info(h.message(progress), progress)
+}
+
+// progress describes the run so far, with the share of the run done when
+// it is bounded
+func (h *heartbeat) progress(now time.Time) map[string]interface{} {
+	elapsed := now.Sub(h.start)
+	progress := map[string]interface{}{
+		"iterations": h.iterations,
+		"failures":   h.failures,
+		"alerts":     h.alerts,
+		"elapsed":    elapsed.Round(time.Second).String(),
+	}
+
+	done := -1.0
+	if h.limits.maxIterations > 0 {
+		done = float64(h.iterations) / float64(h.limits.maxIterations)
+		progress["remaining_iterations"] = max(h.limits.maxIterations-h.iterations, 0)
+	}
+	if h.limits.maxDuration > 0 {
+		done = max(done, float64(elapsed)/float64(h.limits.maxDuration))
+		progress["remaining"] = max(h.limits.maxDuration-elapsed, 0).Round(time.Second).String()
+	}
+	if done >= 0 {
+		progress["percent"] = min(done*100, 100)
+	}
+	return progress
+}
+
+func (h *heartbeat) message(progress map[string]interface{}) string {
+	message := fmt.Sprintf("Monitoring for %s: %d iterations, %d alerts", progress["elapsed"], h.iterations, h.alerts)
+	if percent, ok := progress["percent"].(float64); ok {
+		message += fmt.Sprintf(" (%.0f%% done)", percent)
+	}
+	return message
+}
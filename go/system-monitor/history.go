@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"system-monitor/monitor"
+	"system-monitor/monitor/store"
+	"time"
+
+	eywa "github.com/neyho/eywa-go"
+)
+
+// eywaHistoryLimit is how many recent snapshots are fetched from EYWA;
+// logs of other hosts sharing the robot are filtered out afterwards, so
+// it is larger than the analyzer's window
+const eywaHistoryLimit = 50
+
+// eywaSeasonalLimit is how many snapshots seasonal baselines are learned
+// from when there is no local store
+const eywaSeasonalLimit = 2000
+
+// loggedSnapshot is the data of a SYSTEM_METRICS TaskLog, see
+// logMetricsToEYWA
+type loggedSnapshot struct {
+	Host string `json:"host"`
+	monitor.SystemMetrics
+}
+
+// fetchEYWAHistory loads this host's recent snapshots from the last limit
+// SYSTEM_METRICS TaskLogs stored by earlier runs, oldest first. Snapshots
+// older than maxAge are skipped; a maxAge of zero keeps everything.
+func fetchEYWAHistory(ctx context.Context, host string, limit int, maxAge time.Duration, now time.Time) ([]monitor.SystemMetrics, error) {
+	query := `
+		query($limit: Int) {
+			searchTaskLog(_where: {event: {_eq: "SYSTEM_METRICS"}}, _order_by: {created: desc}, _limit: $limit) {
+				data
+			}
+		}
+	`
+
+	result, err := graphQL(ctx, query, map[string]interface{}{
+		"limit": limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("decode task logs: %w", err)
+	}
+	var response struct {
+		SearchTaskLog []struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"searchTaskLog"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("decode task logs: %w", err)
+	}
+
+	var history []monitor.SystemMetrics
+	for _, entry := range response.SearchTaskLog {
+		var snapshot loggedSnapshot
+		if err := json.Unmarshal(entry.Data, &snapshot); err != nil || snapshot.Host != host {
+			// Logs written before snapshots named their host are skipped
+			continue
+		}
+		if maxAge > 0 && now.Sub(snapshot.Timestamp) > maxAge {
+			continue
+		}
+		history = append(history, snapshot.SystemMetrics)
+	}
+
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Timestamp.Before(history[j].Timestamp)
+	})
+	return history, nil
+}
+
+// learnSeasonal seeds the analyzer's seasonal baselines from the local
+// store, or failing that from the snapshots earlier runs logged to EYWA,
+// so a new state file doesn't have to wait days for them
+func learnSeasonal(analyzer *monitor.Analyzer, metricsStore *store.Store, fromEYWA bool, host, seasonality string, now time.Time) {
+	span := monitor.SeasonalSpan(seasonality)
+
+	var err error
+	switch {
+	case metricsStore != nil:
+		err = analyzer.LearnSeasonalFromStore(metricsStore, now.Add(-span), now)
+	case fromEYWA:
+		var history []monitor.SystemMetrics
+		history, err = fetchEYWAHistory(context.Background(), host, eywaSeasonalLimit, span, now)
+		analyzer.LearnSeasonal(history)
+	default:
+		return
+	}
+	if err != nil {
+		eywa.Warn("Failed to learn seasonal baselines", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,785 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"system-monitor/export"
+	"system-monitor/monitor"
+	"system-monitor/notify"
+	"time"
+)
+
+// TaskInput is the robot's task input. Optional settings are pointers so
+// an omitted field keeps its default while an explicit 0 is honored.
+type TaskInput struct {
+	Interval        *Seconds `json:"interval"`
+	CPUThreshold    *float64 `json:"cpu_threshold"`
+	MemoryThreshold *float64 `json:"memory_threshold"`
+	DiskThreshold   *float64 `json:"disk_threshold"`
+	InodeThreshold  *float64 `json:"inode_threshold"`
+	IOWaitThreshold *float64 `json:"iowait_threshold"`
+	StealThreshold  *float64 `json:"steal_threshold"`
+	FDThreshold     *float64 `json:"fd_threshold"`
+
+	RunOnce        *bool   `json:"run_once"`
+	MaxIterations  int     `json:"max_iterations"`
+	MaxDuration    Seconds `json:"max_duration"`
+	AlertCooldown  *int    `json:"alert_cooldown"`
+	ProcessWorkers *int    `json:"process_workers"`
+
+	// Ranking of the top processes, name regexps narrowing them, and
+	// how processes are summed into groups
+	ProcessSort    string   `json:"process_sort"`
+	ProcessInclude []string `json:"process_include"`
+	ProcessExclude []string `json:"process_exclude"`
+	ProcessGroupBy string   `json:"process_group_by"`
+
+	// Share of a container's CPU quota periods it may be throttled in
+	CPUThrottleThreshold *float64 `json:"cpu_throttle_threshold"`
+
+	// Progress heartbeat of runs longer than one iteration, 0 disables
+	HeartbeatInterval *Seconds `json:"heartbeat_interval"`
+
+	// Seconds between checks of config_file for changes, 0 reloads only
+	// on SIGHUP
+	ConfigReloadInterval Seconds `json:"config_reload_interval"`
+
+	// Poll EYWA for SYSTEM_MONITOR_COMMAND TaskLogs addressed to this run
+	RemoteCommands      bool     `json:"remote_commands"`
+	CommandPollInterval *Seconds `json:"command_poll_interval"`
+
+	// Enable or disable collectors by name
+	Collectors       map[string]bool `json:"collectors"`
+	CollectorTimeout *Seconds        `json:"collector_timeout"`
+
+	// Opt-in GPU collection
+	CollectGPU       bool     `json:"collect_gpu"`
+	GPUThreshold     *float64 `json:"gpu_threshold"`
+	GPUTempThreshold *float64 `json:"gpu_temperature_threshold"`
+
+	// Per-mount-point disk and inode thresholds and mount point filters (globs)
+	DiskThresholds  map[string]float64 `json:"disk_thresholds"`
+	InodeThresholds map[string]float64 `json:"inode_thresholds"`
+	DiskInclude     []string           `json:"disk_include"`
+	DiskExclude     []string           `json:"disk_exclude"`
+
+	// Disk-full forecast horizon in hours, 0 disables it
+	DiskForecastHours *int `json:"disk_forecast_hours"`
+
+	// Percent of a ZFS pool's free space that may be fragmented
+	PoolFragmentationThreshold *float64 `json:"pool_fragmentation_threshold"`
+
+	// Ping targets, see monitor.PingTarget, with loss (%) and latency (ms)
+	// thresholds
+	PingTargets          []monitor.PingTarget `json:"ping_targets"`
+	PingLossThreshold    *float64             `json:"ping_loss_threshold"`
+	PingLatencyThreshold *float64             `json:"ping_latency_threshold"`
+
+	// Days before a certificate expires to warn and to go critical
+	CertWarningDays  *int `json:"cert_warning_days"`
+	CertCriticalDays *int `json:"cert_critical_days"`
+
+	// Opt-in Docker container collection
+	CollectContainers bool   `json:"collect_containers"`
+	DockerSocket      string `json:"docker_socket"`
+
+	// Opt-in Kubernetes node and pod collection
+	CollectKubernetes bool   `json:"collect_kubernetes"`
+	Kubeconfig        string `json:"kubeconfig"`
+	KubernetesNode    string `json:"kubernetes_node"`
+	PodPendingMinutes *int   `json:"pod_pending_minutes"`
+
+	// Hardware temperature sensor alert threshold (°C)
+	TemperatureThreshold *float64 `json:"temperature_threshold"`
+
+	// Charge percentage of a discharging battery that raises a warning
+	BatteryThreshold *float64 `json:"battery_threshold"`
+
+	// Failed logins within the window (minutes) that raise a warning, and
+	// whether interactive root logins alert
+	AuthFailureThreshold *int `json:"auth_failure_threshold"`
+	AuthFailureWindow    *int `json:"auth_failure_window"`
+	AlertRootLogins      bool `json:"alert_root_logins"`
+
+	// Bits of kernel entropy below which a sustained low pool warns
+	EntropyThreshold *int `json:"entropy_threshold"`
+
+	// Percent of the last minute tasks were stalled on a resource (PSI)
+	PressureThreshold *float64 `json:"pressure_threshold"`
+
+	// Interrupt storm (per core per second) and fork rate (per second)
+	// thresholds
+	InterruptThreshold *float64 `json:"interrupt_threshold"`
+	ForkRateThreshold  *float64 `json:"fork_rate_threshold"`
+
+	// Opt-in SMART disk health collection, and the percent of rated SSD
+	// endurance used that warns
+	CollectSMART       bool     `json:"collect_smart"`
+	SMARTWearThreshold *float64 `json:"smart_wear_threshold"`
+
+	// Opt-in hardware RAID collection through storcli or megacli
+	CollectHardwareRAID bool `json:"collect_hardware_raid"`
+
+	// Opt-in pending OS update collection, and its weekly (or other cron)
+	// alert schedule
+	CollectUpdates      bool   `json:"collect_updates"`
+	UpdateCheckHours    *int   `json:"update_check_hours"`
+	UpdateThreshold     *int   `json:"update_threshold"`
+	UpdateAlertSchedule string `json:"update_alert_schedule"`
+
+	// Percent of link speed at which a NIC counts as saturated
+	BandwidthThreshold *float64 `json:"bandwidth_threshold"`
+
+	// Connection leak and conntrack exhaustion thresholds
+	CloseWaitThreshold *int     `json:"close_wait_threshold"`
+	ConntrackThreshold *float64 `json:"conntrack_threshold"`
+
+	// TCP retransmit percent and listen queue overflows per second
+	RetransmitThreshold     *float64 `json:"retransmit_threshold"`
+	ListenOverflowThreshold *float64 `json:"listen_overflow_threshold"`
+
+	// Partial override of the health score weights
+	HealthWeights json.RawMessage `json:"health_weights"`
+
+	SuppressionWindows []monitor.SuppressionWindow `json:"suppression_windows"`
+
+	// Optional webhook sink for alert digests
+	WebhookURL        string `json:"webhook_url"`
+	WebhookAuthHeader string `json:"webhook_auth_header"`
+	WebhookFormat     string `json:"webhook_format"`
+
+	// Further webhooks, each with its own format or payload template
+	Webhooks []notify.WebhookConfig `json:"webhooks"`
+
+	// Optional SMTP notifier; criticals are mailed at once, warnings in
+	// periodic digests
+	Email *notify.EmailConfig `json:"email"`
+
+	// Optional syslog output of every alert, to the local daemon or a
+	// remote collector
+	Syslog *notify.SyslogConfig `json:"syslog"`
+
+	// Optional SNMP traps, critical alerts only by default
+	SNMP *notify.SNMPConfig `json:"snmp"`
+
+	// Optional push exporters
+	StatsDAddr   string `json:"statsd_addr"`
+	OTLPEndpoint string `json:"otlp_endpoint"`
+
+	// StatsD dialect, "dogstatsd" (default) or "statsd", and tags added
+	// to every DogStatsD gauge
+	StatsDFormat string            `json:"statsd_format"`
+	StatsDTags   map[string]string `json:"statsd_tags"`
+
+	// Extra OpenTelemetry resource attributes, e.g. deployment.environment
+	OTLPResourceAttributes map[string]string `json:"otlp_resource_attributes"`
+
+	// Optional InfluxDB line protocol output, to a write URL or a file,
+	// with tags added to every line
+	InfluxURL        string            `json:"influx_url"`
+	InfluxFile       string            `json:"influx_file"`
+	InfluxAuthHeader string            `json:"influx_auth_header"`
+	InfluxTags       map[string]string `json:"influx_tags"`
+
+	// Optional record of every snapshot as JSON Lines or CSV, with the
+	// fields to include
+	RecordsFile   string   `json:"records_file"`
+	RecordsFormat string   `json:"records_format"`
+	RecordsFields []string `json:"records_fields"`
+
+	// Optional MQTT publishing of every snapshot and alert digest
+	MQTT *export.MQTTConfig `json:"mqtt"`
+
+	// Optional scrape endpoint, e.g. ":9100"
+	PrometheusAddr string `json:"prometheus_addr"`
+
+	// Snapshots kept for trend and anomaly detection
+	HistoryWindow *int `json:"history_window"`
+
+	// Adaptive collection interval, off by default
+	AdaptiveInterval bool     `json:"adaptive_interval"`
+	MinInterval      *Seconds `json:"min_interval"`
+	MaxInterval      *Seconds `json:"max_interval"`
+
+	// Pages swapped per second that count as thrashing, 0 disables
+	SwapRateThreshold *float64 `json:"swap_rate_threshold"`
+
+	// Anomaly sensitivity in standard deviations, overall and per category
+	AnomalySigma  *float64           `json:"anomaly_sigma"`
+	AnomalySigmas map[string]float64 `json:"anomaly_sigmas"`
+
+	// "daily" or "weekly" baselines for anomaly detection
+	Seasonality string `json:"seasonality"`
+
+	// Levels at which raised conditions clear, by category
+	ClearThresholds map[string]float64 `json:"clear_thresholds"`
+
+	// Zombie processes tolerated before alerting, 0 disables
+	ZombieThreshold *int `json:"zombie_threshold"`
+
+	// MB of steady growth at which a process counts as leaking, 0 disables
+	ProcessLeakMB *float64 `json:"process_leak_mb"`
+
+	// Systemd units that must be active
+	SystemdUnits []string `json:"systemd_units"`
+
+	// NTP servers to measure clock drift against, and the offset in ms
+	// that alerts
+	NTPServers           []string `json:"ntp_servers"`
+	ClockOffsetThreshold *float64 `json:"clock_offset_threshold"`
+
+	// Files and directories to watch for size and growth, see
+	// monitor.PathWatch
+	PathWatches []monitor.PathWatch `json:"path_watches"`
+
+	// Logs to watch for error spikes, see monitor.LogWatch
+	LogWatches []monitor.LogWatch `json:"log_watches"`
+
+	// Windows services that must be running, and event logs to watch
+	WindowsServices  []string `json:"windows_services"`
+	WindowsEventLogs []string `json:"windows_event_logs"`
+
+	// Processes that must be running, see monitor.ProcessWatch
+	ProcessWatches []monitor.ProcessWatch `json:"process_watches"`
+
+	// HTTP(S) health checks, see monitor.HTTPProbe
+	HTTPProbes []monitor.HTTPProbe `json:"http_probes"`
+
+	// TCP/UDP port checks, see monitor.PortProbe
+	PortProbes []monitor.PortProbe `json:"port_probes"`
+
+	// Listening port audit against an allowlist such as ["tcp/22", "tcp/443"]
+	AuditPorts            bool     `json:"audit_ports"`
+	AllowedPorts          []string `json:"allowed_ports"`
+	AuditPrivateAddresses bool     `json:"audit_private_addresses"`
+
+	// Name resolution checks, see monitor.DNSProbe
+	DNSProbes []monitor.DNSProbe `json:"dns_probes"`
+
+	// TLS certificates to watch, see monitor.CertificateCheck
+	CertificateChecks []monitor.CertificateCheck `json:"certificate_checks"`
+
+	// User-defined alerts, see monitor.AlertRule
+	AlertRules []monitor.AlertRule `json:"alert_rules"`
+
+	// Thresholds relative to each metric's own history in the store, see
+	// monitor.DynamicThreshold
+	DynamicThresholds []monitor.DynamicThreshold `json:"dynamic_thresholds"`
+
+	// Fold related alerts into composite ones, on by default
+	CompositeAlerts *bool `json:"composite_alerts"`
+
+	// Escalation of persistent warnings to critical
+	Escalation *EscalationInput `json:"escalation"`
+
+	// Redaction of process details before they leave the host
+	CollectCmdline        bool     `json:"collect_cmdline"`
+	HashProcessNames      bool     `json:"hash_process_names"`
+	ProcessAllowlist      []string `json:"process_allowlist"`
+	ProcessDenylist       []string `json:"process_denylist"`
+	CmdlineRedactPatterns []string `json:"cmdline_redact_patterns"`
+
+	// Config file the input was merged over; see ParseTaskInput
+	ConfigFile string `json:"config_file"`
+
+	// Analyzer state carried between runs
+	StateFile   string   `json:"state_file"`
+	StateMaxAge *Seconds `json:"state_max_age"`
+
+	// Seed the analyzer history from SYSTEM_METRICS TaskLogs when no
+	// state file history is available
+	HistoryFromEYWA bool `json:"history_from_eywa"`
+
+	// Local SQLite store of raw snapshots, kept for the retention
+	StoreDir       string   `json:"store_dir"`
+	StoreRetention *Seconds `json:"store_retention"`
+
+	// Periodic HTML/PDF health report over the stored snapshots
+	HealthReport *HealthReportConfig `json:"health_report"`
+
+	// Store metrics in EYWA in batches of this many snapshots, or once the
+	// oldest has waited the interval
+	EYWABatchSize     *int    `json:"eywa_batch_size"`
+	EYWABatchInterval Seconds `json:"eywa_batch_interval"`
+}
+
+// buildConfig applies the task input on top of the default configuration
+// and validates the result
+func buildConfig(input TaskInput) (monitor.Config, error) {
+	config := monitor.DefaultConfig()
+
+	overrides := []struct {
+		target *float64
+		value  *float64
+	}{
+		{&config.CPUThreshold, input.CPUThreshold},
+		{&config.MemoryThreshold, input.MemoryThreshold},
+		{&config.DiskThreshold, input.DiskThreshold},
+		{&config.InodeThreshold, input.InodeThreshold},
+		{&config.IOWaitThreshold, input.IOWaitThreshold},
+		{&config.StealThreshold, input.StealThreshold},
+		{&config.FDThreshold, input.FDThreshold},
+		{&config.CPUThrottleThreshold, input.CPUThrottleThreshold},
+		{&config.GPUThreshold, input.GPUThreshold},
+		{&config.GPUTempThreshold, input.GPUTempThreshold},
+		{&config.BandwidthThreshold, input.BandwidthThreshold},
+		{&config.TemperatureThreshold, input.TemperatureThreshold},
+		{&config.BatteryThreshold, input.BatteryThreshold},
+		{&config.PressureThreshold, input.PressureThreshold},
+		{&config.InterruptThreshold, input.InterruptThreshold},
+		{&config.ForkRateThreshold, input.ForkRateThreshold},
+		{&config.ConntrackThreshold, input.ConntrackThreshold},
+		{&config.RetransmitThreshold, input.RetransmitThreshold},
+		{&config.ListenOverflowThreshold, input.ListenOverflowThreshold},
+		{&config.SwapRateThreshold, input.SwapRateThreshold},
+		{&config.SMARTWearThreshold, input.SMARTWearThreshold},
+		{&config.AnomalySigma, input.AnomalySigma},
+		{&config.ProcessLeakMB, input.ProcessLeakMB},
+		{&config.PingLossThreshold, input.PingLossThreshold},
+		{&config.ClockOffsetThreshold, input.ClockOffsetThreshold},
+		{&config.PoolFragmentationThreshold, input.PoolFragmentationThreshold},
+		{&config.PingLatencyThreshold, input.PingLatencyThreshold},
+	}
+	for _, o := range overrides {
+		if o.value != nil {
+			*o.target = *o.value
+		}
+	}
+	config.Collectors = input.Collectors
+	if input.CollectorTimeout != nil {
+		config.CollectorTimeout = int(*input.CollectorTimeout)
+	}
+	config.CollectGPU = input.CollectGPU
+	config.CollectContainers = input.CollectContainers
+	config.CollectSMART = input.CollectSMART
+	config.CollectHardwareRAID = input.CollectHardwareRAID
+	config.CollectUpdates = input.CollectUpdates
+	if input.UpdateCheckHours != nil {
+		config.UpdateCheckHours = *input.UpdateCheckHours
+	}
+	if input.UpdateThreshold != nil {
+		config.UpdateThreshold = *input.UpdateThreshold
+	}
+	config.UpdateAlertSchedule = input.UpdateAlertSchedule
+	if input.DockerSocket != "" {
+		config.DockerSocket = input.DockerSocket
+	}
+	config.CollectKubernetes = input.CollectKubernetes
+	config.Kubeconfig = input.Kubeconfig
+	config.KubernetesNode = input.KubernetesNode
+	if input.PodPendingMinutes != nil {
+		config.PodPendingMinutes = *input.PodPendingMinutes
+	}
+	if input.AuthFailureThreshold != nil {
+		config.AuthFailureThreshold = *input.AuthFailureThreshold
+	}
+	if input.AuthFailureWindow != nil {
+		config.AuthFailureWindow = *input.AuthFailureWindow
+	}
+	config.AlertRootLogins = input.AlertRootLogins
+	if input.EntropyThreshold != nil {
+		config.EntropyThreshold = *input.EntropyThreshold
+	}
+	if input.AlertCooldown != nil {
+		config.AlertCooldown = *input.AlertCooldown
+	}
+	if input.ProcessWorkers != nil {
+		config.ProcessWorkers = *input.ProcessWorkers
+	}
+	if input.ProcessSort != "" {
+		config.ProcessSort = monitor.ProcessSort(input.ProcessSort)
+	}
+	config.ProcessInclude = input.ProcessInclude
+	config.ProcessExclude = input.ProcessExclude
+	config.ProcessGroupBy = input.ProcessGroupBy
+	config.DiskThresholds = input.DiskThresholds
+	config.InodeThresholds = input.InodeThresholds
+	config.AnomalySigmas = input.AnomalySigmas
+	config.Seasonality = input.Seasonality
+	config.ClearThresholds = input.ClearThresholds
+	config.DiskInclude = input.DiskInclude
+	config.DiskExclude = input.DiskExclude
+	if input.ZombieThreshold != nil {
+		config.ZombieThreshold = *input.ZombieThreshold
+	}
+	if input.CloseWaitThreshold != nil {
+		config.CloseWaitThreshold = *input.CloseWaitThreshold
+	}
+	if input.DiskForecastHours != nil {
+		config.DiskForecastHours = *input.DiskForecastHours
+	}
+	if input.CertWarningDays != nil {
+		config.CertWarningDays = *input.CertWarningDays
+	}
+	if input.CertCriticalDays != nil {
+		config.CertCriticalDays = *input.CertCriticalDays
+	}
+	if input.Interval != nil {
+		config.Interval = int(*input.Interval)
+	}
+	if input.HistoryWindow != nil {
+		config.HistoryWindow = *input.HistoryWindow
+	}
+	config.AdaptiveInterval = input.AdaptiveInterval
+	config.CollectCmdline = input.CollectCmdline
+	config.ProcessWatches = input.ProcessWatches
+	config.SystemdUnits = input.SystemdUnits
+	config.LogWatches = input.LogWatches
+	config.PathWatches = input.PathWatches
+	config.NTPServers = input.NTPServers
+	config.WindowsServices = input.WindowsServices
+	config.WindowsEventLogs = input.WindowsEventLogs
+	config.HTTPProbes = input.HTTPProbes
+	config.PortProbes = input.PortProbes
+	config.AuditPorts = input.AuditPorts
+	config.AllowedPorts = input.AllowedPorts
+	config.AuditPrivateAddresses = input.AuditPrivateAddresses
+	config.DNSProbes = input.DNSProbes
+	config.PingTargets = input.PingTargets
+	config.CertificateChecks = input.CertificateChecks
+	config.AlertRules = input.AlertRules
+	config.DynamicThresholds = input.DynamicThresholds
+	if input.CompositeAlerts != nil {
+		config.CompositeAlerts = *input.CompositeAlerts
+	}
+	if e := input.Escalation; e != nil {
+		config.Escalation = monitor.EscalationPolicy{
+			Snapshots:  e.Snapshots,
+			Seconds:    int(e.After),
+			Categories: e.Categories,
+		}
+	}
+	config.Redaction = monitor.RedactionConfig{
+		ProcessDenylist:  input.ProcessDenylist,
+		ProcessAllowlist: input.ProcessAllowlist,
+		HashProcessNames: input.HashProcessNames,
+		CmdlinePatterns:  input.CmdlineRedactPatterns,
+	}
+	if input.MinInterval != nil {
+		config.MinInterval = int(*input.MinInterval)
+	}
+	if input.MaxInterval != nil {
+		config.MaxInterval = int(*input.MaxInterval)
+	}
+	if input.SuppressionWindows != nil {
+		config.SuppressionWindows = input.SuppressionWindows
+	}
+	if len(input.HealthWeights) > 0 {
+		if err := json.Unmarshal(input.HealthWeights, &config.HealthWeights); err != nil {
+			return config, fmt.Errorf("health_weights: %w", err)
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// validate checks the loop settings that are not part of monitor.Config
+func (input TaskInput) validate() error {
+	var errs []error
+	if input.MaxIterations < 0 {
+		errs = append(errs, fmt.Errorf("max_iterations must not be negative, got %d", input.MaxIterations))
+	}
+	if input.HeartbeatInterval != nil && *input.HeartbeatInterval < 0 {
+		errs = append(errs, fmt.Errorf("heartbeat_interval must not be negative, got %d", int(*input.HeartbeatInterval)))
+	}
+	if input.ConfigReloadInterval < 0 {
+		errs = append(errs, fmt.Errorf("config_reload_interval must not be negative, got %d", int(input.ConfigReloadInterval)))
+	}
+	if input.CommandPollInterval != nil && *input.CommandPollInterval < 1 {
+		errs = append(errs, fmt.Errorf("command_poll_interval must be at least 1, got %d", int(*input.CommandPollInterval)))
+	}
+	if input.MaxDuration < 0 {
+		errs = append(errs, fmt.Errorf("max_duration must not be negative, got %d", int(input.MaxDuration)))
+	}
+	if input.StateMaxAge != nil && *input.StateMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("state_max_age must not be negative, got %d", int(*input.StateMaxAge)))
+	}
+	for i, webhook := range input.Webhooks {
+		if err := webhook.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("webhooks[%d]: %w", i, err))
+		}
+	}
+	if input.Email != nil {
+		if err := input.Email.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+	if input.StatsDFormat != "" && input.StatsDFormat != "dogstatsd" && input.StatsDFormat != "statsd" {
+		errs = append(errs, fmt.Errorf(`statsd_format must be "dogstatsd" or "statsd", got %q`, input.StatsDFormat))
+	}
+	if input.RecordsFormat != "" && input.RecordsFormat != export.FormatJSONLines && input.RecordsFormat != export.FormatCSV {
+		errs = append(errs, fmt.Errorf(`records_format must be "jsonl" or "csv", got %q`, input.RecordsFormat))
+	}
+	if input.InfluxURL != "" && input.InfluxFile != "" {
+		errs = append(errs, errors.New("influx_url and influx_file are mutually exclusive"))
+	}
+	if input.InfluxURL != "" && !strings.HasPrefix(input.InfluxURL, "http://") && !strings.HasPrefix(input.InfluxURL, "https://") {
+		errs = append(errs, fmt.Errorf("influx_url must be an http or https URL, got %q", input.InfluxURL))
+	}
+	if input.StoreRetention != nil && *input.StoreRetention < 0 {
+		errs = append(errs, fmt.Errorf("store_retention must not be negative, got %d", int(*input.StoreRetention)))
+	}
+	if len(input.DynamicThresholds) > 0 && input.StoreDir == "" {
+		errs = append(errs, errors.New("dynamic_thresholds requires store_dir"))
+	}
+	if input.HealthReport != nil {
+		if input.StoreDir == "" {
+			errs = append(errs, errors.New("health_report requires store_dir"))
+		}
+		if input.HealthReport.Period < 0 {
+			errs = append(errs, fmt.Errorf("health_report: period must not be negative, got %d", int(input.HealthReport.Period)))
+		}
+	}
+	if input.Syslog != nil {
+		if err := input.Syslog.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("syslog: %w", err))
+		}
+	}
+	if input.SNMP != nil {
+		if err := input.SNMP.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("snmp: %w", err))
+		}
+	}
+	if input.MQTT != nil {
+		if err := input.MQTT.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("mqtt: %w", err))
+		}
+	}
+	if input.EYWABatchSize != nil && *input.EYWABatchSize < 1 {
+		errs = append(errs, fmt.Errorf("eywa_batch_size must be at least 1, got %d", *input.EYWABatchSize))
+	}
+	if input.EYWABatchInterval < 0 {
+		errs = append(errs, fmt.Errorf("eywa_batch_interval must not be negative, got %d", int(input.EYWABatchInterval)))
+	}
+	return errors.Join(errs...)
+}
+
+// stateMaxAge returns how old saved state may be before it is discarded.
+// Zero keeps state of any age.
+func (input TaskInput) stateMaxAge() time.Duration {
+	if input.StateMaxAge != nil {
+		return time.Duration(*input.StateMaxAge) * time.Second
+	}
+	return time.Hour
+}
+
+// heartbeatInterval returns how often a continuous run reports progress
+func (input TaskInput) heartbeatInterval() time.Duration {
+	if input.HeartbeatInterval != nil {
+		return time.Duration(*input.HeartbeatInterval) * time.Second
+	}
+	return 5 * time.Minute
+}
+
+// configReloadInterval returns how often the config file is checked for
+// changes; zero disables the check
+func (input TaskInput) configReloadInterval() time.Duration {
+	return time.Duration(input.ConfigReloadInterval) * time.Second
+}
+
+// commandPollInterval returns how often remote commands are fetched
+func (input TaskInput) commandPollInterval() time.Duration {
+	if input.CommandPollInterval != nil {
+		return time.Duration(*input.CommandPollInterval) * time.Second
+	}
+	return 15 * time.Second
+}
+
+// storeRetention returns how long the local store keeps snapshots. Zero
+// keeps them indefinitely.
+func (input TaskInput) storeRetention() time.Duration {
+	if input.StoreRetention != nil {
+		return time.Duration(*input.StoreRetention) * time.Second
+	}
+	return 7 * 24 * time.Hour
+}
+
+// webhooks returns every configured webhook, the webhook_url one first
+func (input TaskInput) webhooks() []notify.WebhookConfig {
+	var webhooks []notify.WebhookConfig
+	if input.WebhookURL != "" {
+		webhooks = append(webhooks, notify.WebhookConfig{
+			URL:        input.WebhookURL,
+			AuthHeader: input.WebhookAuthHeader,
+			Format:     input.WebhookFormat,
+		})
+	}
+	return append(webhooks, input.Webhooks...)
+}
+
+// eywaBatchSize returns how many snapshots are stored per EYWA mutation.
+// One, the default, stores every snapshot as it is taken.
+func (input TaskInput) eywaBatchSize() int {
+	if input.EYWABatchSize != nil {
+		return *input.EYWABatchSize
+	}
+	return 1
+}
+
+// ParseTaskInput extracts and decodes the "input" object of an EYWA task,
+// see GetTaskInput
+func ParseTaskInput(task interface{}, configPath string) (TaskInput, error) {
+	return GetTaskInput[TaskInput](task, configPath)
+}
+
+// GetTaskInput extracts and decodes the "input" object of an EYWA task
+// into T. A missing input yields the zero T (all defaults); malformed
+// input is reported, as an *InputError where a single field is at fault,
+// instead of silently falling back to defaults.
+//
+// When the input's config_file, or else configPath, names a config file,
+// its fields are loaded first and the task input overrides them field by
+// field.
+func GetTaskInput[T any](task interface{}, configPath string) (T, error) {
+	var input T
+
+	taskData, ok := task.(map[string]interface{})
+	if !ok {
+		return input, fmt.Errorf("unexpected task format %T", task)
+	}
+
+	var raw []byte
+	switch data := taskData["input"].(type) {
+	case nil:
+		raw = []byte("{}")
+	case string:
+		// Some dispatchers send the input as a JSON encoded string
+		raw = []byte(data)
+	default:
+		var err error
+		raw, err = json.Marshal(data)
+		if err != nil {
+			return input, fmt.Errorf("encode task input: %w", err)
+		}
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return input, fmt.Errorf("task input: %w", err)
+	}
+	if file, ok := fields["config_file"]; ok {
+		if err := json.Unmarshal(file, &configPath); err != nil {
+			return input, &InputError{Field: "config_file", Message: fmt.Sprintf("expected string, got %s", file)}
+		}
+	}
+	if configPath != "" {
+		merged, err := readConfigFile(configPath)
+		if err != nil {
+			return input, err
+		}
+		for key, value := range fields {
+			merged[key] = value
+		}
+		merged["config_file"], _ = json.Marshal(configPath)
+
+		raw, err = json.Marshal(merged)
+		if err != nil {
+			return input, fmt.Errorf("encode task input: %w", err)
+		}
+	}
+
+	if err := json.Unmarshal(raw, &input); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return input, &InputError{Field: typeErr.Field, Message: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)}
+		}
+		return input, fmt.Errorf("task input: %w", err)
+	}
+
+	return input, nil
+}
+
+// InputError is a problem with a single task input field
+type InputError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("task input field %q: %s", e.Field, e.Message)
+}
+
+// inputErrors flattens the errors of parsing and validating the task
+// input into one InputError per problem, so each can be reported against
+// its field. Validation messages start with the field they concern;
+// problems not tied to a field have an empty Field.
+func inputErrors(err error) []InputError {
+	var inputErr *InputError
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case interface{ Unwrap() []error }:
+		var errs []InputError
+		for _, err := range e.Unwrap() {
+			errs = append(errs, inputErrors(err)...)
+		}
+		return errs
+	default:
+		if errors.As(err, &inputErr) {
+			return []InputError{*inputErr}
+		}
+	}
+
+	message := err.Error()
+	field := message
+	if end := strings.IndexAny(message, " [:"); end > 0 {
+		field = message[:end]
+	}
+	if !isTaskInputField(field) {
+		field = ""
+	}
+	return []InputError{{Field: field, Message: message}}
+}
+
+// isTaskInputField reports whether name is a TaskInput JSON field
+func isTaskInputField(name string) bool {
+	fields := reflect.TypeOf(TaskInput{})
+	for i := 0; i < fields.NumField(); i++ {
+		if tag, _, _ := strings.Cut(fields.Field(i).Tag.Get("json"), ","); tag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// EscalationInput is monitor.EscalationPolicy with After as a duration
+type EscalationInput struct {
+	Snapshots  int      `json:"snapshots"`
+	After      Seconds  `json:"after"`
+	Categories []string `json:"categories"`
+}
+
+// Seconds is a duration in whole seconds that accepts either a JSON number
+// (60) or a string holding a number or Go duration ("60", "1m", "1h30m")
+type Seconds int
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *Seconds) UnmarshalJSON(data []byte) error {
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		*s = Seconds(number)
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("expected number of seconds or duration string, got %s", data)
+	}
+
+	text = strings.TrimSpace(text)
+	if number, err := strconv.ParseFloat(text, 64); err == nil {
+		*s = Seconds(number)
+		return nil
+	}
+
+	duration, err := time.ParseDuration(text)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: expected seconds or a duration like \"90s\" or \"5m\"", text)
+	}
+	*s = Seconds(duration / time.Second)
+	return nil
+}
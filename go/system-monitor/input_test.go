@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"system-monitor/monitor"
+)
+
+// parseInput decodes a task input and builds and validates its config
+// the way main does
+func parseInput(t *testing.T, data string) (monitor.Config, error) {
+	t.Helper()
+	var input TaskInput
+	if err := json.Unmarshal([]byte(data), &input); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	if err := input.validate(); err != nil {
+		return monitor.Config{}, err
+	}
+	return buildConfig(input)
+}
+
+func TestTaskInputThresholds(t *testing.T) {
+	defaults := monitor.DefaultConfig()
+	tests := []struct {
+		name    string
+		input   string
+		check   func(monitor.Config) bool
+		wantErr string
+	}{
+		{
+			name:  "omitted threshold keeps the default",
+			input: `{}`,
+			check: func(c monitor.Config) bool { return c.CPUThreshold == defaults.CPUThreshold },
+		},
+		{
+			name:  "explicit 0 alerts on any usage",
+			input: `{"cpu_threshold": 0}`,
+			check: func(c monitor.Config) bool {
+				alerts := monitor.NewAnalyzer(c).AnalyzeMetrics(&monitor.SystemMetrics{CPU: monitor.CPUMetrics{UsagePercent: 1}})
+				for _, alert := range alerts {
+					if alert.Category == "cpu" {
+						return c.CPUThreshold == 0
+					}
+				}
+				return false
+			},
+		},
+		{
+			name:  "explicit 0 for a count",
+			input: `{"zombie_threshold": 0}`,
+			check: func(c monitor.Config) bool { return c.ZombieThreshold == 0 },
+		},
+		{
+			name:  "upper bound",
+			input: `{"disk_threshold": 100}`,
+			check: func(c monitor.Config) bool { return c.DiskThreshold == 100 },
+		},
+		{
+			name:    "above the upper bound",
+			input:   `{"memory_threshold": 100.1}`,
+			wantErr: "memory_threshold must be between 0 and 100",
+		},
+		{
+			name:    "negative threshold",
+			input:   `{"iowait_threshold": -1}`,
+			wantErr: "iowait_threshold must be between 0 and 100",
+		},
+		{
+			name:    "negative count",
+			input:   `{"close_wait_threshold": -1}`,
+			wantErr: "close_wait_threshold must not be negative",
+		},
+		{
+			name:  "interval as a duration",
+			input: `{"interval": "2m"}`,
+			check: func(c monitor.Config) bool { return c.Interval == 120 },
+		},
+		{
+			name:    "zero interval",
+			input:   `{"interval": 0}`,
+			wantErr: "interval must be a positive number of seconds, got 0",
+		},
+		{
+			name:    "negative interval",
+			input:   `{"interval": "-5s"}`,
+			wantErr: "interval must be a positive number of seconds, got -5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parseInput(t, tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if !tt.check(config) {
+				t.Errorf("config from %s not as expected", tt.input)
+			}
+		})
+	}
+}
+
+func TestTaskInputValidate(t *testing.T) {
+	zero, negative := Seconds(0), Seconds(-1)
+	tests := []struct {
+		name    string
+		input   TaskInput
+		wantErr string
+	}{
+		{"empty", TaskInput{}, ""},
+		{"negative max_iterations", TaskInput{MaxIterations: -1}, "max_iterations must not be negative"},
+		{"negative max_duration", TaskInput{MaxDuration: -1}, "max_duration must not be negative"},
+		{"zero heartbeat_interval", TaskInput{HeartbeatInterval: &zero}, ""},
+		{"negative heartbeat_interval", TaskInput{HeartbeatInterval: &negative}, "heartbeat_interval must not be negative"},
+		{"zero command_poll_interval", TaskInput{CommandPollInterval: &zero}, "command_poll_interval must be at least 1, got 0"},
+		{"negative store_retention", TaskInput{StoreRetention: &negative}, "store_retention must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("validate() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
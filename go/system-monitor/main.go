@@ -1,10 +1,21 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"sync/atomic"
+	"syscall"
+	"system-monitor/export"
 	"system-monitor/monitor"
+	"system-monitor/monitor/store"
+	"system-monitor/notify"
 	"time"
 
 	eywa "github.com/neyho/eywa-go"
@@ -22,64 +33,71 @@ func getAvgDiskUsage(disks []monitor.DiskMetrics) float64 {
 	return total / float64(len(disks))
 }
 
-type TaskInput struct {
-	Interval        int     `json:"interval"`
-	CPUThreshold    float64 `json:"cpu_threshold"`
-	MemoryThreshold float64 `json:"memory_threshold"`
-	DiskThreshold   float64 `json:"disk_threshold"`
-	RunOnce         bool    `json:"run_once"`
-}
-
 func main() {
-	// Initialize EYWA pipe
-	go eywa.OpenPipe()
-	time.Sleep(100 * time.Millisecond)
+	// Offline commands run without an EYWA task
+	if len(os.Args) > 1 && os.Args[1] == "export-history" {
+		if err := runExportHistory(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "export-history:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	configPath := flag.String("config", "", "YAML, TOML or JSON config file; task input overrides its fields")
+	startupTimeout := flag.Duration("startup-timeout", 30*time.Second, "how long to wait for the EYWA pipe and the task")
+	flag.Parse()
 
-	// Get task
-	task, err := eywa.GetTask()
+	// Initialize EYWA pipe, and get the task once it is up
+	go eywa.OpenPipe()
+	task, err := getTask(*startupTimeout)
 	if err != nil {
+		// The pipe may be what failed, so say it on stderr as well
+		fmt.Fprintln(os.Stderr, "system-monitor: failed to get task from EYWA:", err)
 		eywa.Error("Failed to get task", map[string]interface{}{
 			"error": err.Error(),
 		})
 		eywa.CloseTask(eywa.ERROR)
-		return
+		os.Exit(1)
 	}
+	defer recoverTask()
 
 	// Update task status
 	eywa.UpdateTask(eywa.PROCESSING)
 	eywa.Info("Starting system monitoring", nil)
 
 	// Parse task input
-	taskData := task.(map[string]interface{})
-	inputData := taskData["input"]
-	
-	var input TaskInput
-	// Default values
-	input.Interval = 30
-	input.RunOnce = true
-	
-	// Parse input if provided
-	if inputData != nil {
-		inputBytes, _ := json.Marshal(inputData)
-		json.Unmarshal(inputBytes, &input)
+	input, err := ParseTaskInput(task, *configPath)
+	if err != nil {
+		eywa.Error("Failed to parse task input", map[string]interface{}{
+			"error": err.Error(),
+			"errors": inputErrors(err),
+		})
+		eywa.CloseTask(eywa.ERROR)
+		return
 	}
 
-	// Configure monitoring
-	config := monitor.DefaultConfig()
-	if input.CPUThreshold > 0 {
-		config.CPUThreshold = input.CPUThreshold
-	}
-	if input.MemoryThreshold > 0 {
-		config.MemoryThreshold = input.MemoryThreshold
-	}
-	if input.DiskThreshold > 0 {
-		config.DiskThreshold = input.DiskThreshold
+	// Configure and validate monitoring
+	config, err := buildConfig(input)
+	err = errors.Join(err, input.validate())
+	if err != nil {
+		eywa.Error("Invalid task input", map[string]interface{}{
+			"error": err.Error(),
+			"errors": inputErrors(err),
+		})
+		eywa.CloseTask(eywa.ERROR)
+		return
 	}
 
+	limits := newRunLimits(input)
+
 	eywa.Info("Monitoring configuration", map[string]interface{}{
 		"config": config,
-		"interval": input.Interval,
-		"run_once": input.RunOnce,
+		"config_file": input.ConfigFile,
+		"interval": config.Interval,
+		"adaptive_interval": config.AdaptiveInterval,
+		"max_iterations": limits.maxIterations,
+		"max_duration": limits.maxDuration.String(),
+		"heartbeat_interval": input.heartbeatInterval().String(),
 	})
 
 	// Get system info
@@ -92,49 +110,424 @@ func main() {
 		eywa.Info("System information", sysInfo)
 	}
 
-	// Initialize collector and analyzer
-	collector := monitor.NewCollector(config)
-	analyzer := monitor.NewAnalyzer(config)
+	// The monitor package collects and analyzes; this robot only wires the
+	// results into EYWA. Swap the clock or source for fakes to replay runs.
+	clock := monitor.SystemClock
+	mon, err := monitor.NewMonitor(config)
+	if err != nil {
+		eywa.Error("Failed to create monitor", map[string]interface{}{
+			"error": err.Error(),
+		})
+		eywa.CloseTask(eywa.ERROR)
+		return
+	}
+	mon.SetClock(clock)
+	analyzer := mon.Analyzer()
+	cooldown := monitor.NewCooldown(time.Duration(config.AlertCooldown) * time.Second)
+	incidentTasks := make(map[string]string) // incident ID -> EYWA task euuid
+	openIncident := ""
 
-	// Main monitoring loop
-	iterations := 0
-	startTime := time.Now()
-	
-	for {
-		iterations++
-		
-		// Collect metrics
-		metrics, err := collector.CollectMetrics()
+	// Optional notifiers, independent of EYWA alert tasks
+	hostname, _ := os.Hostname()
+	var notifiers []notify.Notifier
+	for _, config := range input.webhooks() {
+		webhook, err := config.New()
 		if err != nil {
-			eywa.Error("Failed to collect metrics", map[string]interface{}{
+			eywa.Warn("Failed to set up webhook", map[string]interface{}{
+				"endpoint": config.Endpoint(),
 				"error": err.Error(),
 			})
-			if input.RunOnce {
-				eywa.CloseTask(eywa.ERROR)
-				return
-			}
-			time.Sleep(time.Duration(input.Interval) * time.Second)
 			continue
 		}
+		endpoint := config.Endpoint()
+		webhook.OnError = func(err error) {
+			eywa.Warn("Webhook notification failed", map[string]interface{}{
+				"endpoint": endpoint,
+				"error": err.Error(),
+			})
+		}
+		notifiers = append(notifiers, webhook)
+	}
+	if input.Email != nil {
+		email, err := notify.NewEmail(*input.Email)
+		if err != nil {
+			eywa.Warn("Failed to set up email notifications", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			email.OnError = func(err error) {
+				eywa.Warn("Email notification failed", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+			notifiers = append(notifiers, email)
+		}
+	}
+	if input.Syslog != nil {
+		syslog, err := notify.NewSyslog(*input.Syslog)
+		if err != nil {
+			eywa.Warn("Failed to set up syslog output", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			syslog.OnError = func(err error) {
+				eywa.Warn("Syslog output failed", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+			notifiers = append(notifiers, syslog)
+		}
+	}
+	if input.SNMP != nil {
+		snmp, err := notify.NewSNMP(*input.SNMP)
+		if err != nil {
+			eywa.Warn("Failed to set up SNMP traps", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			snmp.OnError = func(err error) {
+				eywa.Warn("SNMP trap failed", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+			notifiers = append(notifiers, snmp)
+		}
+	}
+
+	// Optional push exporters for external observability pipelines
+	var sinks []export.Sink
+	if input.StatsDAddr != "" {
+		statsd, err := export.NewStatsD(input.StatsDAddr, hostname)
+		if err != nil {
+			eywa.Warn("Failed to set up StatsD exporter", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			statsd.Tags = input.StatsDTags
+			statsd.Plain = input.StatsDFormat == "statsd"
+			statsd.OnError = exportErrorHandler("statsd")
+			sinks = append(sinks, statsd)
+		}
+	}
+	if input.OTLPEndpoint != "" {
+		otlp := export.NewOTLP(input.OTLPEndpoint, hostname)
+		otlp.ResourceAttributes = input.OTLPResourceAttributes
+		otlp.OnError = exportErrorHandler("otlp")
+		sinks = append(sinks, otlp)
+	}
+	if target := input.InfluxURL + input.InfluxFile; target != "" {
+		tags := map[string]string{"robot_id": taskEuuid(task)}
+		for k, v := range input.InfluxTags {
+			tags[k] = v
+		}
+		influx, err := export.NewInflux(target, input.InfluxAuthHeader, hostname, tags)
+		if err != nil {
+			eywa.Warn("Failed to set up InfluxDB exporter", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			influx.OnError = exportErrorHandler("influx")
+			sinks = append(sinks, influx)
+		}
+	}
+	if input.RecordsFile != "" {
+		records, err := export.NewRecordFile(input.RecordsFile, input.RecordsFormat, input.RecordsFields, hostname)
+		if err != nil {
+			eywa.Warn("Failed to open records file", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			records.OnError = exportErrorHandler("records")
+			sinks = append(sinks, records)
+		}
+	}
+	if input.MQTT != nil {
+		mqtt, err := export.NewMQTT(*input.MQTT, hostname)
+		if err != nil {
+			eywa.Warn("Failed to set up MQTT publisher", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			mqtt.OnError = exportErrorHandler("mqtt")
+			sinks = append(sinks, mqtt)
+			notifiers = append(notifiers, mqtt.Alerts())
+		}
+	}
+	if input.PrometheusAddr != "" {
+		prometheus, err := export.NewPrometheus(input.PrometheusAddr, hostname)
+		if err != nil {
+			eywa.Warn("Failed to start Prometheus endpoint", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			prometheus.OnError = exportErrorHandler("prometheus")
+			sinks = append(sinks, prometheus)
+		}
+	}
+
+	// Optional local store of raw snapshots
+	var metricsStore *store.Store
+	if input.StoreDir != "" {
+		metricsStore, err = store.Open(input.StoreDir, input.storeRetention())
+		if err != nil {
+			eywa.Warn("Failed to open metrics store", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			analyzer.UseStore(metricsStore)
+		}
+	}
+
+	// Resume analyzer history and cooldowns saved by a previous run
+	if input.StateFile != "" || input.HistoryFromEYWA || metricsStore != nil {
+		state := monitor.State{Host: hostname}
+		if input.StateFile != "" {
+			state, err = monitor.LoadState(input.StateFile, hostname, input.stateMaxAge(), clock.Now())
+			if err != nil {
+				eywa.Warn("Failed to load monitor state", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}
+		// Fall back to the local store, then to the snapshots earlier runs
+		// logged to EYWA
+		if len(state.History) == 0 && metricsStore != nil {
+			state.History, err = metricsStore.Latest(config.HistoryWindow, input.stateMaxAge(), clock.Now())
+			if err != nil {
+				eywa.Warn("Failed to load history from metrics store", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}
+		if len(state.History) == 0 && input.HistoryFromEYWA {
+			state.History, err = fetchEYWAHistory(context.Background(), hostname, eywaHistoryLimit, input.stateMaxAge(), clock.Now())
+			if err != nil {
+				eywa.Warn("Failed to load history from EYWA", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}
+		analyzer.RestoreState(state)
+		cooldown.RestoreState(state)
+		if config.Seasonality != "" && len(state.Seasonal) == 0 {
+			learnSeasonal(analyzer, metricsStore, input.HistoryFromEYWA, hostname, config.Seasonality, clock.Now())
+		}
+		if state.Incident != nil && state.Incident.Level == "critical" {
+			// The previous run already opened a task for it; find it so it
+			// can be closed once the incident ends
+			euuid, err := findOpenIncidentTask(context.Background(), state.Incident.Fingerprint(hostname))
+			if err != nil {
+				eywa.Warn("Failed to look up restored incident task", map[string]interface{}{
+					"incident_id": state.Incident.ID,
+					"error": err.Error(),
+				})
+			}
+			incidentTasks[state.Incident.ID] = euuid
+			openIncident = state.Incident.ID
+		}
+		eywa.Info("Restored monitor state", map[string]interface{}{
+			"history": len(state.History),
+			"saved_at": state.SavedAt,
+		})
+	}
+
+	uploads := &taskLogBatch{
+		size: input.eywaBatchSize(),
+		maxWait: time.Duration(input.EYWABatchInterval) * time.Second,
+		upload: logMetricsToEYWA,
+	}
+
+	// Main monitoring loop
+	// SIGTERM/SIGINT stop the loop, abandoning any collection in progress,
+	// and still run the shutdown below. A second signal kills the process.
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	go func() {
+		<-signalCtx.Done()
+		stopSignals()
+	}()
+
+	ctx, cancel := context.WithCancel(signalCtx)
+	defer cancel()
+	iterations := 0
+	startTime := clock.Now()
+
+	// Health reports are published every report period during the run,
+	// and at the end of a run that has not published one
+	var nextReport time.Time
+	reported := false
+	if input.HealthReport != nil {
+		nextReport = startTime.Add(input.HealthReport.period())
+	}
+	stopReason := ""
+	failed := false
+
+	// A single-iteration run finishes before any heartbeat is due
+	var pulse *heartbeat
+	if limits.maxIterations != 1 {
+		pulse = newHeartbeat(input.heartbeatInterval(), limits, startTime)
+	}
+
+	// checkLimits stops the loop once a run limit is reached
+	checkLimits := func() {
+		if stop, reason := limits.reached(iterations, clock.Now().Sub(startTime), mon.Interval()); stop {
+			stopReason = reason
+			cancel()
+		}
+	}
+
+	mon.OnIntervalChange = func(previous, next time.Duration) {
+		eywa.Info(fmt.Sprintf("Collection interval changed from %s to %s", previous, next), map[string]interface{}{
+			"previous": previous.Seconds(),
+			"interval": next.Seconds(),
+		})
+	}
+
+	// Thresholds can change while the loop runs, through a config reload
+	// or a remote command
+	live := newLiveConfig(mon)
+	configFile := input.ConfigFile
+	if configFile == "" {
+		configFile = *configPath
+	}
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	reloader := newConfigReloader(task, *configPath, configFile, live)
+	go func() {
+		defer recoverTask()
+		reloader.run(ctx, hangup, input.configReloadInterval())
+	}()
+
+	// Operators can steer the run through command TaskLogs
+	var stoppedByCommand atomic.Bool
+	if input.RemoteCommands {
+		poller := newCommandPoller(mon, live, taskEuuid(task), hostname, func() {
+			stoppedByCommand.Store(true)
+			cancel()
+		})
+		go func() {
+			defer recoverTask()
+			poller.run(ctx, input.commandPollInterval())
+		}()
+	}
+
+	// Limits and heartbeats still apply while paused
+	mon.OnPause = func() {
+		pulse.beat(clock.Now())
+		checkLimits()
+	}
+
+	mon.OnError = func(err error) {
+		iterations++
+		eywa.Error("Failed to collect metrics", map[string]interface{}{
+			"error": err.Error(),
+		})
+		if limits.maxIterations == 1 {
+			failed = true
+			cancel()
+			return
+		}
+		pulse.record(0, true)
+		pulse.beat(clock.Now())
+		checkLimits()
+	}
+
+	mon.Run(ctx, func(metrics *monitor.SystemMetrics, alerts []monitor.Alert) {
+		iterations++
+
+		// The snapshot goes ahead without collectors that failed or hung
+		for _, failure := range metrics.CollectorFailures {
+			details := map[string]interface{}{
+				"collector": failure.Collector,
+				"timed_out": failure.TimedOut,
+			}
+			if failure.Stack != "" {
+				details["stack"] = failure.Stack
+			}
+			eywa.Warn(fmt.Sprintf("Collector %s failed: %s", failure.Collector, failure.Message), details)
+		}
+
+		// Push metrics to external collectors
+		for _, sink := range sinks {
+			sink.Push(metrics)
+		}
 
+		if metricsStore != nil {
+			if err := metricsStore.Append(*metrics); err != nil {
+				eywa.Warn("Failed to store metrics locally", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}
+
+		if input.HealthReport != nil && metricsStore != nil && !clock.Now().Before(nextReport) {
+			publishHealthReport(ctx, *input.HealthReport, metricsStore, config, hostname, clock.Now())
+			nextReport = clock.Now().Add(input.HealthReport.period())
+			reported = true
+		}
 
-		// Log metrics to EYWA
-		err = logMetricsToEYWA(metrics)
+		// Log metrics to EYWA, batched if configured
+		err = uploads.add(ctx, metricsTaskLog(metrics, hostname), clock.Now())
 		if err != nil {
 			eywa.Warn("Failed to log metrics to EYWA", map[string]interface{}{
 				"error": err.Error(),
 			})
 		}
 
-		// Analyze metrics
-		alerts := analyzer.AnalyzeMetrics(metrics)
+		// Track conditions that cleared since the last snapshot
+		resolutions := analyzer.Resolve(metrics, alerts)
+		for _, resolution := range resolutions {
+			eywa.Info(fmt.Sprintf("[%s] resolved after %s: %s", resolution.Category,
+				resolution.Duration().Round(time.Second), resolution.Message), map[string]interface{}{
+				"level": resolution.Level,
+				"category": resolution.Category,
+				"resource": resolution.Resource,
+				"raised_at": resolution.RaisedAt,
+			})
+		}
+
+		// Analyze metrics; alerts suppressed by a quiet window are only logged
+		alerts, suppressed := monitor.PartitionSuppressed(alerts)
+		for _, alert := range suppressed {
+			eywa.Info(fmt.Sprintf("[%s] %s (suppressed by %q)", alert.Category, alert.Message, alert.SuppressedBy), map[string]interface{}{
+				"level": alert.Level,
+				"category": alert.Category,
+				"value": alert.Value,
+				"threshold": alert.Threshold,
+			})
+		}
 		
+		// Group related alerts into a single incident, closing the task of
+		// an incident that has ended
+		incident := analyzer.Correlate(metrics, alerts)
+		if openIncident != "" && (incident == nil || incident.ID != openIncident) {
+			if euuid := incidentTasks[openIncident]; euuid != "" {
+				if err := closeIncidentTask(ctx, euuid, metrics.Timestamp); err != nil {
+					eywa.Warn("Failed to close incident task", map[string]interface{}{
+						"incident_id": openIncident,
+						"error": err.Error(),
+					})
+				}
+			}
+			delete(incidentTasks, openIncident)
+			openIncident = ""
+		}
+		if incident != nil {
+			openIncident = incident.ID
+		}
+
 		// Generate recommendations
 		recommendations := analyzer.GenerateRecommendations(metrics, alerts)
 
+		// Single 0-100 score for dashboards
+		healthScore := monitor.ComputeHealthScore(metrics, alerts, config)
+
 		// Report current status
 		topCPUProcesses := monitor.GetTopProcesses(metrics, false, 5)
 		topMemProcesses := monitor.GetTopProcesses(metrics, true, 5)
+		topIOProcesses := monitor.GetTopIOProcesses(metrics, 5)
+		rankedProcesses := monitor.RankProcesses(metrics.Processes, config.ProcessSort, 5)
 		
 		// Create dynamic report message
 		reportMsg := fmt.Sprintf("System Monitor: CPU %.1f%%, Memory %.1f%%, Disk %.1f%%", 
@@ -157,6 +550,9 @@ func main() {
 		} else {
 			reportMsg += " - All systems normal"
 		}
+		if incident != nil {
+			reportMsg += fmt.Sprintf(" [%s: %s]", incident.ID, incident.PrimaryCause)
+		}
 		
 		eywa.Report(reportMsg, map[string]interface{}{
 			"iteration": iterations,
@@ -164,6 +560,14 @@ func main() {
 			"cpu": map[string]interface{}{
 				"usage_percent": fmt.Sprintf("%.1f", metrics.CPU.UsagePercent),
 				"cores": metrics.CPU.Cores,
+				"breakdown": map[string]interface{}{
+					"user": fmt.Sprintf("%.1f", metrics.CPU.Breakdown.User),
+					"system": fmt.Sprintf("%.1f", metrics.CPU.Breakdown.System),
+					"idle": fmt.Sprintf("%.1f", metrics.CPU.Breakdown.Idle),
+					"iowait": fmt.Sprintf("%.1f", metrics.CPU.Breakdown.IOWait),
+					"steal": fmt.Sprintf("%.1f", metrics.CPU.Breakdown.Steal),
+					"irq": fmt.Sprintf("%.1f", metrics.CPU.Breakdown.IRQ),
+				},
 			},
 			"memory": map[string]interface{}{
 				"total_gb": fmt.Sprintf("%.1f", metrics.Memory.TotalGB),
@@ -176,58 +580,272 @@ func main() {
 				"1min": fmt.Sprintf("%.2f", metrics.Load.Load1),
 				"5min": fmt.Sprintf("%.2f", metrics.Load.Load5),
 				"15min": fmt.Sprintf("%.2f", metrics.Load.Load15),
+				"per_core": fmt.Sprintf("%.2f", metrics.Load.Load1PerCore),
 			},
+			"uptime": metrics.Uptime,
+			"sessions": metrics.Sessions,
+			"entropy": metrics.Entropy,
+			"numa": metrics.NUMA,
+			"pressure": metrics.Pressure,
+			"activity": metrics.Activity,
+			"updates": metrics.Updates,
+			"exposure": metrics.Exposure,
+			"file_descriptors": metrics.FileDescriptors,
+			"gpu": metrics.GPU,
+			"network": metrics.Network,
+			"connections": metrics.Connections,
+			"tcp": metrics.TCP,
+			"sensors": metrics.Sensors,
+			"power": metrics.Power,
+			"containers": metrics.Containers,
+			"kubernetes": metrics.Kubernetes,
+			"cgroup": metrics.Cgroup,
+			"disk_health": metrics.DiskHealth,
+			"raid": metrics.RAID,
+			"pools": metrics.Pools,
+			"custom": metrics.Custom,
+			"collector_failures": metrics.CollectorFailures,
+			"process_states": metrics.ProcessStates,
+			"watched_processes": metrics.WatchedProcesses,
+			"probes": metrics.Probes,
+			"ping": metrics.Ping,
+			"logs": metrics.Logs,
+			"paths": metrics.Paths,
+			"clock": metrics.Clock,
+			"systemd": metrics.Systemd,
+			"windows_services": metrics.WindowsServices,
+			"windows_events": metrics.WindowsEvents,
+			"certificates": metrics.Certificates,
 			"top_cpu_processes": formatProcesses(topCPUProcesses),
 			"top_memory_processes": formatProcesses(topMemProcesses),
+			"top_io_processes": formatProcesses(topIOProcesses),
+			"top_processes": formatProcesses(rankedProcesses),
+			"process_sort": config.ProcessSort,
+			"process_groups": metrics.ProcessGroups,
 			"alerts": len(alerts),
+			"incident": incident,
+			"resolved": resolutions,
+			"health_score": healthScore,
+			"health_grade": monitor.HealthGrade(healthScore),
 			"recommendations": recommendations,
 		}, nil)
 
 		// Process alerts
 		if len(alerts) > 0 {
 			for _, alert := range alerts {
-				eywa.Warn(fmt.Sprintf("[%s] %s", alert.Category, alert.Message), map[string]interface{}{
+				logAlert := eywa.Warn
+				if alert.Level == "info" {
+					// Downgraded by a quiet window
+					logAlert = eywa.Info
+				}
+				data := map[string]interface{}{
 					"level": alert.Level,
 					"category": alert.Category,
 					"value": alert.Value,
 					"threshold": alert.Threshold,
-				})
+				}
+				if incident != nil && alert.Level != "info" {
+					data["incident_id"] = incident.ID
+				}
+				logAlert(fmt.Sprintf("[%s] %s", alert.Category, alert.Message), data)
+			}
+		}
 
-				// Create EYWA task for critical alerts
-				if alert.Level == "critical" {
-					err = createAlertTask(alert)
-					if err != nil {
-						eywa.Error("Failed to create alert task", map[string]interface{}{
-							"error": err.Error(),
+		// Create one EYWA task per critical incident rather than per alert
+		if incident != nil && incident.Level == "critical" {
+			if _, created := incidentTasks[incident.ID]; !created {
+				euuid, reused, err := createIncidentTask(ctx, *incident, hostname)
+				if err != nil {
+					eywa.Error("Failed to create incident task", map[string]interface{}{
+						"incident_id": incident.ID,
+						"error": err.Error(),
+					})
+				} else {
+					if reused {
+						eywa.Info("Updated open incident task instead of creating a duplicate", map[string]interface{}{
+							"incident_id": incident.ID,
+							"task": euuid,
 						})
 					}
+					incidentTasks[incident.ID] = euuid
 				}
 			}
 		}
 
-		// Check if we should continue
-		if input.RunOnce {
-			break
+		// Send a digest of fresh alerts to the notifiers
+		if len(notifiers) > 0 {
+			digest := notify.Digest{
+				Host:      hostname,
+				Timestamp: metrics.Timestamp,
+				Alerts:    cooldown.Filter(escalatingAlerts(alerts)),
+				Resolved:  resolutions,
+			}
+			if incident != nil {
+				digest.Incident = incident.ID
+				digest.PrimaryCause = incident.PrimaryCause
+			}
+			if len(digest.Alerts) > 0 {
+				digest.TopCPU = monitor.GetTopProcesses(metrics, false, 3)
+				digest.TopMemory = monitor.GetTopProcesses(metrics, true, 3)
+			}
+			for _, notifier := range notifiers {
+				notifier.Send(digest)
+			}
 		}
 
-		// Wait for next iteration
-		time.Sleep(time.Duration(input.Interval) * time.Second)
+		pulse.record(len(alerts), false)
+		pulse.beat(clock.Now())
+		checkLimits()
+	})
+
+	if stopReason == "" && stoppedByCommand.Load() {
+		stopReason = "stopped by remote command"
+		eywa.Info("Stopping on remote command", nil)
+	}
+	if stopReason == "" && signalCtx.Err() != nil {
+		stopReason = "received shutdown signal"
+		eywa.Info("Shutting down on signal", nil)
+	}
+
+	if failed {
+		eywa.CloseTask(eywa.ERROR)
+		return
+	}
+
+	// Flush pending notifications, exports and metric uploads before
+	// closing the task
+	if err := uploads.flush(context.Background()); err != nil {
+		eywa.Warn("Failed to log metrics to EYWA", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	for _, notifier := range notifiers {
+		notifier.Close(15 * time.Second)
+	}
+	for _, sink := range sinks {
+		sink.Close(5 * time.Second)
+	}
+	if input.HealthReport != nil && metricsStore != nil && !reported {
+		publishHealthReport(context.Background(), *input.HealthReport, metricsStore, config, hostname, clock.Now())
+	}
+	if metricsStore != nil {
+		metricsStore.Close()
+	}
+
+	// Save state for the next run
+	if input.StateFile != "" {
+		state := monitor.State{Host: hostname, SavedAt: clock.Now()}
+		analyzer.SaveState(&state)
+		cooldown.SaveState(&state)
+		if err := monitor.SaveState(input.StateFile, state); err != nil {
+			eywa.Warn("Failed to save monitor state", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
 	}
 
 	// Final summary
 	eywa.Info("Monitoring completed", map[string]interface{}{
 		"iterations": iterations,
-		"duration": time.Since(startTime).String(),
+		"duration": clock.Now().Sub(startTime).String(),
+		"stop_reason": stopReason,
 	})
 
+	// A run interrupted before its first snapshot produced nothing
+	if iterations == 0 {
+		eywa.CloseTask(eywa.ERROR)
+		return
+	}
 	eywa.CloseTask(eywa.SUCCESS)
 }
 
-func logMetricsToEYWA(metrics *monitor.SystemMetrics) error {
-	// Store metrics as TaskLog
+// runLimits bounds a monitoring run by iteration count and/or wall time.
+// Zero values mean unbounded; whichever limit is hit first ends the run.
+type runLimits struct {
+	maxIterations int
+	maxDuration   time.Duration
+}
+
+func newRunLimits(input TaskInput) runLimits {
+	limits := runLimits{
+		maxIterations: input.MaxIterations,
+		maxDuration:   time.Duration(input.MaxDuration) * time.Second,
+	}
+
+	// run_once is shorthand for max_iterations: 1, and remains the
+	// default when no other bound is given
+	runOnce := input.MaxIterations <= 0 && input.MaxDuration <= 0
+	if input.RunOnce != nil {
+		runOnce = *input.RunOnce
+	}
+	if runOnce {
+		limits.maxIterations = 1
+	}
+
+	return limits
+}
+
+// reached reports whether the run should stop after the given number of
+// iterations. A run stops early rather than start an iteration that
+// would begin after the duration limit.
+func (l runLimits) reached(iterations int, elapsed, interval time.Duration) (bool, string) {
+	if l.maxIterations > 0 && iterations >= l.maxIterations {
+		return true, fmt.Sprintf("reached max_iterations (%d)", l.maxIterations)
+	}
+	next := elapsed + interval
+	if l.maxDuration > 0 && next > l.maxDuration {
+		return true, fmt.Sprintf("reached max_duration (%s)", l.maxDuration)
+	}
+	return false, ""
+}
+
+// metricsTaskLog builds the TaskLog that stores a metrics snapshot
+func metricsTaskLog(metrics *monitor.SystemMetrics, host string) map[string]interface{} {
+	return map[string]interface{}{
+		"event": "SYSTEM_METRICS",
+		"message": "System metrics snapshot",
+		"data": map[string]interface{}{
+			"host": host,
+			"timestamp": metrics.Timestamp,
+			"cpu": metrics.CPU,
+			"memory": metrics.Memory,
+			"disk": metrics.Disk,
+			"load": metrics.Load,
+			"uptime": metrics.Uptime,
+			"sessions": metrics.Sessions,
+			"entropy": metrics.Entropy,
+			"numa": metrics.NUMA,
+			"pressure": metrics.Pressure,
+			"activity": metrics.Activity,
+			"updates": metrics.Updates,
+			"exposure": metrics.Exposure,
+			"file_descriptors": metrics.FileDescriptors,
+			"gpu": metrics.GPU,
+			"network": metrics.Network,
+			"connections": metrics.Connections,
+			"tcp": metrics.TCP,
+			"sensors": metrics.Sensors,
+			"power": metrics.Power,
+			"containers": metrics.Containers,
+			"kubernetes": metrics.Kubernetes,
+			"cgroup": metrics.Cgroup,
+			"disk_health": metrics.DiskHealth,
+			"raid": metrics.RAID,
+			"pools": metrics.Pools,
+			"top_processes": metrics.Processes,
+			"largest_processes": metrics.LargestProcesses,
+			"process_groups": metrics.ProcessGroups,
+			"process_states": metrics.ProcessStates,
+			"self": metrics.Self,
+		},
+	}
+}
+
+func logMetricsToEYWA(ctx context.Context, logs []map[string]interface{}) error {
+	// Store metrics as TaskLogs, one per snapshot
 	mutation := `
-		mutation($data: TaskLogInput) {
-			syncTaskLog(data: $data) {
+		mutation($data: [TaskLogInput]) {
+			syncTaskLogList(data: $data) {
 				euuid
 				created
 			}
@@ -235,31 +853,29 @@ func logMetricsToEYWA(metrics *monitor.SystemMetrics) error {
 	`
 
 	variables := map[string]interface{}{
-		"data": map[string]interface{}{
-			"event": "SYSTEM_METRICS",
-			"message": "System metrics snapshot",
-			"data": map[string]interface{}{
-				"timestamp": metrics.Timestamp,
-				"cpu": metrics.CPU,
-				"memory": metrics.Memory,
-				"disk": metrics.Disk,
-				"load": metrics.Load,
-				"top_processes": metrics.Processes,
-			},
-		},
+		"data": logs,
 	}
 
-	result, err := eywa.GraphQL(mutation, variables)
+	result, err := graphQL(ctx, mutation, variables)
 	if err != nil {
 		return err
 	}
 
-	log.Printf("Stored metrics: %v", result)
+	log.Printf("Stored %d metrics snapshot(s): %v", len(logs), result)
 	return nil
 }
 
-func createAlertTask(alert monitor.Alert) error {
-	// Create a task for critical alerts
+// createIncidentTask opens a task for a critical incident and returns
+// its euuid. An OPEN task for the same problem, left by an earlier run or
+// another incident, is updated instead; reused reports whether it was.
+func createIncidentTask(ctx context.Context, incident monitor.Incident, host string) (euuid string, reused bool, err error) {
+	fingerprint := incident.Fingerprint(host)
+	existing, err := findOpenIncidentTask(ctx, fingerprint)
+	if err != nil {
+		return "", false, err
+	}
+
+	// Create a task for a critical incident, or update the open one
 	mutation := `
 		mutation($data: TaskInput) {
 			syncTask(data: $data) {
@@ -270,36 +886,173 @@ func createAlertTask(alert monitor.Alert) error {
 		}
 	`
 
+	task := map[string]interface{}{
+		"name": fmt.Sprintf("System Incident %s: %s", incident.ID, incident.PrimaryCause),
+		"description": incident.PrimaryCause,
+		"priority": "HIGH",
+		"status": "OPEN",
+		"data": map[string]interface{}{
+			"incident_id": incident.ID,
+			"fingerprint": fingerprint,
+			"host": host,
+			"level": incident.Level,
+			"categories": incident.Categories,
+			"alerts": incident.Alerts,
+			"started_at": incident.StartedAt,
+		},
+	}
+	if existing != "" {
+		task["euuid"] = existing
+	}
+
+	result, err := graphQL(ctx, mutation, map[string]interface{}{"data": task})
+	if err != nil {
+		return "", false, err
+	}
+
+	// The euuid is needed to close the task once the incident ends
+	euuid = existing
+	if data, ok := result.(map[string]interface{}); ok {
+		if task, ok := data["syncTask"].(map[string]interface{}); ok {
+			if id, _ := task["euuid"].(string); id != "" {
+				euuid = id
+			}
+		}
+	}
+	return euuid, existing != "", nil
+}
+
+// taskEuuid returns the euuid of the task this robot is running, empty if
+// the task does not carry one
+func taskEuuid(task interface{}) string {
+	if data, ok := task.(map[string]interface{}); ok {
+		if euuid, ok := data["euuid"].(string); ok {
+			return euuid
+		}
+	}
+	return ""
+}
+
+// findOpenIncidentTask returns the euuid of an OPEN incident task with
+// the given fingerprint, or "" if there is none
+func findOpenIncidentTask(ctx context.Context, fingerprint string) (string, error) {
+	query := `
+		query {
+			searchTask(_where: {status: {_eq: "OPEN"}, name: {_like: "System Incident %"}}) {
+				euuid
+				data
+			}
+		}
+	`
+
+	result, err := graphQL(ctx, query, nil)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("decode open tasks: %w", err)
+	}
+	var response struct {
+		SearchTask []struct {
+			Euuid string `json:"euuid"`
+			Data  struct {
+				Fingerprint string `json:"fingerprint"`
+			} `json:"data"`
+		} `json:"searchTask"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return "", fmt.Errorf("decode open tasks: %w", err)
+	}
+
+	for _, task := range response.SearchTask {
+		if task.Data.Fingerprint == fingerprint {
+			return task.Euuid, nil
+		}
+	}
+	return "", nil
+}
+
+// closeIncidentTask marks the task of an incident whose conditions have
+// all cleared as closed
+func closeIncidentTask(ctx context.Context, euuid string, resolvedAt time.Time) error {
+	mutation := `
+		mutation($data: TaskInput) {
+			syncTask(data: $data) {
+				euuid
+				status
+			}
+		}
+	`
+
 	variables := map[string]interface{}{
 		"data": map[string]interface{}{
-			"name": fmt.Sprintf("System Alert: %s", alert.Category),
-			"description": alert.Message,
-			"priority": "HIGH",
-			"status": "OPEN",
+			"euuid": euuid,
+			"status": "CLOSED",
 			"data": map[string]interface{}{
-				"alert_type": alert.Category,
-				"level": alert.Level,
-				"value": alert.Value,
-				"threshold": alert.Threshold,
-				"timestamp": alert.Timestamp,
+				"resolved_at": resolvedAt,
 			},
 		},
 	}
 
-	_, err := eywa.GraphQL(mutation, variables)
+	_, err := graphQL(ctx, mutation, variables)
 	return err
 }
 
+// recoverTask turns a panic into an ERROR task with the stack trace
+// logged, instead of the process dying and leaving the task stuck in
+// PROCESSING. The goroutines main starts defer it too, since a panic can
+// only be recovered on its own goroutine.
+func recoverTask() {
+	value := recover()
+	if value == nil {
+		return
+	}
+	eywa.Error(fmt.Sprintf("Robot crashed: %v", value), map[string]interface{}{
+		"panic": fmt.Sprint(value),
+		"stack": string(debug.Stack()),
+	})
+	eywa.CloseTask(eywa.ERROR)
+	os.Exit(1)
+}
+
+// exportErrorHandler reports push exporter failures without interrupting
+// monitoring
+func exportErrorHandler(name string) func(error) {
+	return func(err error) {
+		eywa.Warn("Metrics export failed", map[string]interface{}{
+			"exporter": name,
+			"error": err.Error(),
+		})
+	}
+}
+
+// escalatingAlerts drops alerts that a quiet window downgraded to info
+func escalatingAlerts(alerts []monitor.Alert) []monitor.Alert {
+	var escalating []monitor.Alert
+	for _, alert := range alerts {
+		if alert.Level != "info" {
+			escalating = append(escalating, alert)
+		}
+	}
+	return escalating
+}
+
 func getDiskSummary(disks []monitor.DiskMetrics) []map[string]interface{} {
 	summary := make([]map[string]interface{}, 0, len(disks))
 	
 	for _, disk := range disks {
-		summary = append(summary, map[string]interface{}{
+		entry := map[string]interface{}{
 			"mount": disk.MountPoint,
 			"total_gb": fmt.Sprintf("%.1f", disk.TotalGB),
 			"used_gb": fmt.Sprintf("%.1f", disk.UsedGB),
 			"percent": fmt.Sprintf("%.1f", disk.UsedPercent),
-		})
+		}
+		if disk.InodesTotal > 0 {
+			entry["inodes_percent"] = fmt.Sprintf("%.1f", disk.InodesUsedPercent)
+		}
+		summary = append(summary, entry)
 	}
 	
 	return summary
@@ -309,12 +1062,27 @@ func formatProcesses(processes []monitor.ProcessMetrics) []map[string]interface{
 	formatted := make([]map[string]interface{}, 0, len(processes))
 	
 	for _, p := range processes {
-		formatted = append(formatted, map[string]interface{}{
+		entry := map[string]interface{}{
 			"name": p.Name,
 			"pid": p.PID,
 			"cpu_percent": fmt.Sprintf("%.1f", p.CPUPercent),
 			"memory_mb": fmt.Sprintf("%.1f", p.MemoryMB),
-		})
+			"open_fds": p.OpenFDs,
+		}
+		if p.ReadMBPerSec > 0 || p.WriteMBPerSec > 0 {
+			entry["read_mb_per_sec"] = fmt.Sprintf("%.1f", p.ReadMBPerSec)
+			entry["write_mb_per_sec"] = fmt.Sprintf("%.1f", p.WriteMBPerSec)
+		}
+		if p.Threads > 0 {
+			entry["threads"] = p.Threads
+		}
+		if !p.StartedAt.IsZero() {
+			entry["started_at"] = p.StartedAt
+		}
+		if p.Cmdline != "" {
+			entry["cmdline"] = p.Cmdline
+		}
+		formatted = append(formatted, entry)
 	}
 	
 	return formatted
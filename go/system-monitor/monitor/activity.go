@@ -0,0 +1,158 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// ActivityMetrics is the kernel's scheduling and interrupt activity
+// (Linux), which shows interrupt storms and fork bombs that CPU usage
+// alone doesn't explain
+type ActivityMetrics struct {
+	ContextSwitchesPerSec float64 `json:"context_switches_per_sec"`
+	InterruptsPerSec      float64 `json:"interrupts_per_sec"`
+	ForksPerSec           float64 `json:"forks_per_sec"` // processes and threads created
+
+	// Tasks runnable and blocked on I/O right now
+	ProcsRunning int `json:"procs_running"`
+	ProcsBlocked int `json:"procs_blocked"`
+
+	// The interrupt source firing most since the previous snapshot, such
+	// as "IRQ 24 eth0-rx-0" or "LOC Local timer interrupts"
+	TopInterrupt       string  `json:"top_interrupt,omitempty"`
+	TopInterruptPerSec float64 `json:"top_interrupt_per_sec,omitempty"`
+}
+
+// activityCounters is the cumulative counters at a sample
+type activityCounters struct {
+	contextSwitches uint64
+	interrupts      uint64
+	forks           uint64
+	running         int
+	blocked         int
+	irqs            map[string]uint64 // by interrupt source
+	at              time.Time
+}
+
+func (c Config) validateActivity() []error {
+	var errs []error
+	if c.InterruptThreshold < 0 {
+		errs = append(errs, fmt.Errorf("interrupt_threshold must not be negative, got %g", c.InterruptThreshold))
+	}
+	if c.ForkRateThreshold < 0 {
+		errs = append(errs, fmt.Errorf("fork_rate_threshold must not be negative, got %g", c.ForkRateThreshold))
+	}
+	return errs
+}
+
+// collectActivity derives the rates from the counters at the previous
+// snapshot, so the first snapshot reports only the task counts. Other
+// platforms report nothing.
+func (c *Collector) collectActivity(ctx context.Context) (*ActivityMetrics, error) {
+	current, err := readActivity()
+	if current == nil || err != nil {
+		return nil, err
+	}
+	current.at = c.clock.Now()
+	prev := c.prevActivity
+	c.prevActivity = current
+
+	activity := &ActivityMetrics{ProcsRunning: current.running, ProcsBlocked: current.blocked}
+	if prev == nil {
+		return activity, nil
+	}
+	elapsed := current.at.Sub(prev.at).Seconds()
+	if elapsed <= 0 {
+		return activity, nil
+	}
+	rate := func(now, before uint64) float64 {
+		if now < before {
+			return 0
+		}
+		return float64(now-before) / elapsed
+	}
+	activity.ContextSwitchesPerSec = rate(current.contextSwitches, prev.contextSwitches)
+	activity.InterruptsPerSec = rate(current.interrupts, prev.interrupts)
+	activity.ForksPerSec = rate(current.forks, prev.forks)
+	for source, count := range current.irqs {
+		if before, ok := prev.irqs[source]; ok {
+			if r := rate(count, before); r > activity.TopInterruptPerSec {
+				activity.TopInterrupt, activity.TopInterruptPerSec = source, r
+			}
+		}
+	}
+	return activity, nil
+}
+
+// checkActivity warns about an interrupt storm, interrupts above
+// Config.InterruptThreshold per core per second, and a fork bomb,
+// processes created faster than Config.ForkRateThreshold per second,
+// when either held for the last 3 snapshots; critical at five times the
+// threshold
+func (a *Analyzer) checkActivity(metrics *SystemMetrics) []Alert {
+	activity := metrics.Activity
+	if activity == nil {
+		return nil
+	}
+	cores := float64(metrics.CPU.Cores)
+	if cores == 0 {
+		cores = float64(runtime.NumCPU())
+	}
+
+	var alerts []Alert
+	if threshold := a.config.InterruptThreshold; threshold > 0 {
+		perCore := func(m SystemMetrics) float64 {
+			if m.Activity == nil {
+				return 0
+			}
+			return m.Activity.InterruptsPerSec / cores
+		}
+		if a.isSustained(perCore, threshold) {
+			level := "warning"
+			if perCore(*metrics) > threshold*5 {
+				level = "critical"
+			}
+			message := fmt.Sprintf("Interrupt storm: %.0f interrupts/s (%.0f per core)", activity.InterruptsPerSec, perCore(*metrics))
+			if activity.TopInterrupt != "" {
+				message += fmt.Sprintf(", mostly %s at %.0f/s", activity.TopInterrupt, activity.TopInterruptPerSec)
+			}
+			alerts = append(alerts, Alert{
+				Level:     level,
+				Category:  "interrupts",
+				Resource:  "system",
+				Message:   message,
+				Value:     perCore(*metrics),
+				Threshold: threshold,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+
+	if threshold := a.config.ForkRateThreshold; threshold > 0 {
+		forks := func(m SystemMetrics) float64 {
+			if m.Activity == nil {
+				return 0
+			}
+			return m.Activity.ForksPerSec
+		}
+		if a.isSustained(forks, threshold) {
+			level := "warning"
+			if activity.ForksPerSec > threshold*5 {
+				level = "critical"
+			}
+			alerts = append(alerts, Alert{
+				Level:    level,
+				Category: "forks",
+				Resource: "system",
+				Message: fmt.Sprintf("Processes are being created at %.0f/s with %d running, a runaway script or fork bomb",
+					activity.ForksPerSec, activity.ProcsRunning),
+				Value:     activity.ForksPerSec,
+				Threshold: threshold,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,108 @@
+//go:build linux
+
+package monitor
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readActivity reads the cumulative context switch, interrupt and fork
+// counters and the task counts from /proc/stat, and the interrupts by
+// source from /proc/interrupts
+func readActivity() (*activityCounters, error) {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	counters := &activityCounters{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024) // intr lists every IRQ
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "ctxt":
+			counters.contextSwitches = value
+		case "intr":
+			counters.interrupts = value
+		case "processes":
+			counters.forks = value
+		case "procs_running":
+			counters.running = int(value)
+		case "procs_blocked":
+			counters.blocked = int(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	counters.irqs = readInterrupts()
+	return counters, nil
+}
+
+// readInterrupts sums /proc/interrupts over CPUs by source, named by the
+// IRQ and its device, "IRQ 24 eth0-rx-0", or for the architecture's
+// interrupts by their description, "LOC Local timer interrupts". Nil when
+// the file can't be read.
+func readInterrupts() map[string]uint64 {
+	file, err := os.Open("/proc/interrupts")
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	if !scanner.Scan() {
+		return nil
+	}
+	cpus := len(strings.Fields(scanner.Text())) // "CPU0 CPU1 ..."
+
+	irqs := make(map[string]uint64)
+	for scanner.Scan() {
+		// " 24:   1   0  IO-APIC 5-edge  ACPI:Ged"
+		// "LOC:  1895804  1790312  Local timer interrupts"
+		label, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		label = strings.TrimSpace(label)
+		fields := strings.Fields(rest)
+		var total uint64
+		n := 0
+		for ; n < len(fields) && n < cpus; n++ {
+			count, err := strconv.ParseUint(fields[n], 10, 64)
+			if err != nil {
+				break
+			}
+			total += count
+		}
+		if n == 0 {
+			continue
+		}
+		description := fields[n:]
+		var name string
+		if _, err := strconv.Atoi(label); err == nil {
+			// Chip, hardware IRQ and trigger, then the devices sharing it
+			name = "IRQ " + label
+			if len(description) > 0 {
+				name += " " + description[len(description)-1]
+			}
+		} else {
+			name = strings.TrimSpace(label + " " + strings.Join(description, " "))
+		}
+		irqs[name] += total
+	}
+	return irqs
+}
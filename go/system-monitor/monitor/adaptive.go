@@ -0,0 +1,75 @@
+package monitor
+
+import "time"
+
+// Utilization ratios, relative to the alert thresholds, that mark a system
+// as under pressure or calm for the adaptive interval
+const (
+	pressureRatio = 0.9
+	calmRatio     = 0.5
+)
+
+// adaptInterval picks the wait before the next collection when
+// Config.AdaptiveInterval is set. Under pressure the interval halves down
+// to MinInterval; while calm it grows by half up to MaxInterval; in
+// between it returns to the base Interval.
+func (m *Monitor) adaptInterval(metrics *SystemMetrics, alerts []Alert) {
+	if !m.config.AdaptiveInterval {
+		return
+	}
+
+	base := time.Duration(m.config.Interval) * time.Second
+	floor := time.Duration(m.config.MinInterval) * time.Second
+	ceiling := time.Duration(m.config.MaxInterval) * time.Second
+
+	next := base
+	ratio := thresholdRatio(m.config, metrics)
+	switch {
+	case ratio >= pressureRatio || hasActiveAlert(alerts):
+		next = max(m.interval/2, floor)
+	case ratio < calmRatio:
+		next = min(m.interval*3/2, ceiling)
+	}
+
+	if next != m.interval {
+		previous := m.interval
+		m.interval = next
+		if m.OnIntervalChange != nil {
+			m.OnIntervalChange(previous, next)
+		}
+	}
+}
+
+// thresholdRatio returns the highest usage relative to its alert
+// threshold across CPU, memory and disk space and inodes
+func thresholdRatio(config Config, metrics *SystemMetrics) float64 {
+	highest := 0.0
+	check := func(value, threshold float64) {
+		ratio := 1.0
+		if threshold > 0 {
+			ratio = value / threshold
+		}
+		highest = max(highest, ratio)
+	}
+
+	check(metrics.CPU.UsagePercent, config.CPUThreshold)
+	check(metrics.Memory.UsedPercent, config.MemoryThreshold)
+	for _, disk := range metrics.Disk {
+		check(disk.UsedPercent, config.diskThreshold(disk.MountPoint))
+		if disk.InodesTotal > 0 {
+			check(disk.InodesUsedPercent, config.inodeThreshold(disk.MountPoint))
+		}
+	}
+	return highest
+}
+
+// hasActiveAlert reports whether any alert escalates, ignoring alerts
+// silenced or downgraded by a suppression window
+func hasActiveAlert(alerts []Alert) bool {
+	for _, alert := range alerts {
+		if !alert.Suppressed && severityRank(alert.Level) > 0 {
+			return true
+		}
+	}
+	return false
+}
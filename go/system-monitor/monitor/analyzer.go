@@ -2,63 +2,257 @@ package monitor
 
 import (
 	"fmt"
+	"time"
 )
 
 // Analyzer handles anomaly detection and alert generation
 type Analyzer struct {
-	config        Config
-	history       []SystemMetrics
-	historyWindow int
+	config     Config
+	history    history
+	windows    []suppressionWindow
+	clock      Clock
+	incident   *Incident // open incident, see Correlate
+	diskTrends map[string][]DiskSample
+	conditions map[string]Condition // active conditions, see Resolve
+	baselines  map[string]*ewma     // by anomaly category
+
+	// Usual levels by anomaly category and hour, see Config.Seasonality
+	seasonal map[string][]SeasonalSlot
+
+	// Compiled Config.AlertRules, and how many snapshots in a row each
+	// has held
+	rules       []compiledRule
+	ruleStreaks map[string]int
+
+	// Conditions that alerted in the previous snapshot, which clear at
+	// their Config.ClearThresholds level instead of the trigger threshold
+	raised map[string]bool
+
+	// Conditions alerting without a break, see Config.Escalation
+	streaks map[string]warningStreak
+
+	// The host's boot time at the last snapshot seen, and when that was,
+	// for reboot detection
+	bootTime time.Time
+	lastSeen time.Time
+
+	// Percentiles of Config.DynamicThresholds by index, computed from
+	// store at percentilesAt
+	store         SnapshotStore
+	percentiles   map[int]float64
+	percentilesAt time.Time
 }
 
-// NewAnalyzer creates a new metrics analyzer
+// NewAnalyzer creates a new metrics analyzer. Invalid suppression windows
+// and history windows are ignored; use Config.Validate to report them.
 func NewAnalyzer(config Config) *Analyzer {
+	if config.HistoryWindow < 4 {
+		config.HistoryWindow = DefaultConfig().HistoryWindow
+	}
+
 	return &Analyzer{
-		config:        config,
-		historyWindow: 10, // Keep last 10 measurements
-		history:       make([]SystemMetrics, 0, 10),
+		config:  config,
+		history: newHistory(config.HistoryWindow),
+		windows: parseSuppressionWindows(config),
+		rules:   compileRules(config),
+		clock:   SystemClock,
 	}
 }
 
+// SetConfig replaces the thresholds and other settings, keeping the
+// history, baselines and active conditions. The history window keeps its
+// original size.
+func (a *Analyzer) SetConfig(config Config) {
+	config.HistoryWindow = a.config.HistoryWindow
+	a.config = config
+	a.windows = parseSuppressionWindows(config)
+	a.rules = compileRules(config)
+	a.percentiles = nil
+}
+
+// SetClock replaces the clock used to evaluate time-based rules such as
+// suppression windows
+func (a *Analyzer) SetClock(clock Clock) {
+	a.clock = clock
+}
+
 // AnalyzeMetrics analyzes metrics for anomalies and generates alerts
 func (a *Analyzer) AnalyzeMetrics(metrics *SystemMetrics) []Alert {
 	// Add to history
 	a.addToHistory(metrics)
+	a.recordDiskTrends(metrics)
 
 	var alerts []Alert
 
+	// Inside a container CPU and memory are judged against its cgroup
+	// limits instead of the host's totals
+	cgroup := metrics.Cgroup
+	alerts = append(alerts, a.checkCgroup(metrics)...)
+
 	// Check CPU usage
-	if cpuAlert := a.checkCPUUsage(metrics); cpuAlert != nil {
-		alerts = append(alerts, *cpuAlert)
+	if cgroup == nil || cgroup.CPULimitCores == 0 {
+		if cpuAlert := a.checkCPUUsage(metrics); cpuAlert != nil {
+			alerts = append(alerts, *cpuAlert)
+		}
 	}
 
+	// Check CPU time breakdown (iowait, steal)
+	alerts = append(alerts, a.checkCPUBreakdown(metrics)...)
+
 	// Check memory usage
-	if memAlert := a.checkMemoryUsage(metrics); memAlert != nil {
-		alerts = append(alerts, *memAlert)
+	if cgroup == nil || cgroup.MemoryLimitGB == 0 {
+		if memAlert := a.checkMemoryUsage(metrics); memAlert != nil {
+			alerts = append(alerts, *memAlert)
+		}
 	}
 
 	// Check disk usage
 	diskAlerts := a.checkDiskUsage(metrics)
 	alerts = append(alerts, diskAlerts...)
 
-	// Check for anomalies based on historical data
-	if len(a.history) >= 5 {
-		anomalyAlerts := a.detectAnomalies(metrics)
-		alerts = append(alerts, anomalyAlerts...)
+	// Check for filesystems remounted read-only
+	alerts = append(alerts, a.checkReadOnlyFilesystems(metrics)...)
+
+	// Check watched paths for size caps and fast growth
+	alerts = append(alerts, a.checkPaths(metrics)...)
+
+	// Check ZFS pools and Btrfs filesystems
+	alerts = append(alerts, a.checkPools(metrics)...)
+
+	// Predict disks filling up from their usage trend
+	alerts = append(alerts, a.checkDiskForecasts(metrics)...)
+
+	// Check SMART health of the physical disks
+	alerts = append(alerts, a.checkDiskHealth(metrics)...)
+
+	// Check RAID arrays for failed members and rebuilds
+	alerts = append(alerts, a.checkRAID(metrics)...)
+
+	// Check file descriptor usage
+	fdAlerts := a.checkFileDescriptors(metrics)
+	alerts = append(alerts, fdAlerts...)
+
+	// Check GPU utilization and temperature
+	gpuAlerts := a.checkGPUs(metrics)
+	alerts = append(alerts, gpuAlerts...)
+
+	// Check container restarts, OOM kills and memory limits
+	alerts = append(alerts, a.checkContainers(metrics)...)
+
+	// Check the Kubernetes node and its pods
+	alerts = append(alerts, a.checkKubernetes(metrics)...)
+
+	// Check hardware temperature sensors
+	alerts = append(alerts, a.checkSensors(metrics)...)
+
+	// Check for power loss and low batteries
+	alerts = append(alerts, a.checkPower(metrics)...)
+
+	// Check whether the host rebooted since the last snapshot
+	alerts = append(alerts, a.checkReboot(metrics)...)
+
+	// Check for bursts of failed logins and root logins
+	alerts = append(alerts, a.checkSessions(metrics)...)
+
+	// Check for pending security updates and a required reboot
+	alerts = append(alerts, a.checkUpdates(metrics)...)
+
+	// Check for ports exposed that shouldn't be
+	alerts = append(alerts, a.checkExposure(metrics)...)
+
+	// Check for an entropy pool that stays low
+	alerts = append(alerts, a.checkEntropy(metrics)...)
+
+	// Check for a full NUMA node and exhausted or idle huge pages
+	alerts = append(alerts, a.checkNUMA(metrics)...)
+
+	// Check for tasks stalled on CPU, memory or I/O
+	alerts = append(alerts, a.checkPressure(metrics)...)
+
+	// Check for interrupt storms and fork bombs
+	alerts = append(alerts, a.checkActivity(metrics)...)
+
+	// Check network interface saturation
+	alerts = append(alerts, a.checkNetwork(metrics)...)
+
+	// Check for active swapping, as opposed to swap merely occupied
+	if swapAlert := a.checkSwapActivity(metrics); swapAlert != nil {
+		alerts = append(alerts, *swapAlert)
 	}
 
-	return alerts
+	// Check for unreaped zombie processes
+	if zombieAlert := a.checkZombies(metrics); zombieAlert != nil {
+		alerts = append(alerts, *zombieAlert)
+	}
+
+	// Check for individual processes leaking memory
+	alerts = append(alerts, a.checkProcessLeaks(metrics)...)
+
+	// Check watched processes are running and within budget
+	alerts = append(alerts, a.checkWatchedProcesses(metrics)...)
+
+	// Check HTTP endpoints that keep failing
+	alerts = append(alerts, a.checkProbes(metrics)...)
+
+	// Check round-trip time and packet loss to ping targets
+	alerts = append(alerts, a.checkPing(metrics)...)
+
+	// Check the system clock against NTP
+	alerts = append(alerts, a.checkClock(metrics)...)
+
+	// Check TLS certificates for expiry and broken chains
+	alerts = append(alerts, a.checkCertificates(metrics)...)
+
+	// Check required systemd units are active
+	alerts = append(alerts, a.checkSystemd(metrics)...)
+
+	// Check required Windows services are running
+	alerts = append(alerts, a.checkWindowsServices(metrics)...)
+
+	// Check Windows event logs for new errors
+	alerts = append(alerts, a.checkWindowsEvents(metrics)...)
+
+	// Check watched logs for error spikes
+	alerts = append(alerts, a.checkLogs(metrics)...)
+
+	// Check for connection leaks and conntrack exhaustion
+	alerts = append(alerts, a.checkConnections(metrics)...)
+
+	// Check for retransmits, full accept queues and socket memory
+	alerts = append(alerts, a.checkTCP(metrics)...)
+
+	// Check metrics against percentiles of their own history
+	alerts = append(alerts, a.checkDynamic(metrics)...)
+
+	// Evaluate user-defined alert rules
+	alerts = append(alerts, a.checkRules(metrics)...)
+
+	// Check the monitor itself for leaks and slow collections
+	alerts = append(alerts, a.checkSelf(metrics)...)
+
+	// Check for anomalies against each metric's recent baseline
+	alerts = append(alerts, a.detectAnomalies(metrics)...)
+
+	a.recordRaised(alerts)
+
+	// Fold related alerts into composite ones
+	if a.config.CompositeAlerts {
+		alerts = combineAlerts(metrics, alerts)
+	}
+
+	// Escalate warnings that have persisted
+	alerts = a.escalate(metrics, alerts)
+
+	// Apply quiet windows last so every check is covered
+	return applySuppression(a.windows, alerts, a.clock.Now())
 }
 
 func (a *Analyzer) addToHistory(metrics *SystemMetrics) {
-	a.history = append(a.history, *metrics)
-	if len(a.history) > a.historyWindow {
-		a.history = a.history[1:]
-	}
+	a.history.push(*metrics)
 }
 
 func (a *Analyzer) checkCPUUsage(metrics *SystemMetrics) *Alert {
-	if metrics.CPU.UsagePercent > a.config.CPUThreshold {
+	if metrics.CPU.UsagePercent > a.threshold("cpu", "", a.config.CPUThreshold) {
 		level := "warning"
 		if metrics.CPU.UsagePercent > 95 {
 			level = "critical"
@@ -70,8 +264,8 @@ func (a *Analyzer) checkCPUUsage(metrics *SystemMetrics) *Alert {
 			metrics.CPU.UsagePercent, a.config.CPUThreshold)
 		
 		if sustained {
-			message = fmt.Sprintf("Sustained high CPU usage: %.1f%% for %d measurements", 
-				metrics.CPU.UsagePercent, len(a.history))
+			message = fmt.Sprintf("Sustained high CPU usage: %.1f%% for the last 3 measurements",
+				metrics.CPU.UsagePercent)
 			level = "critical"
 		}
 
@@ -87,8 +281,60 @@ func (a *Analyzer) checkCPUUsage(metrics *SystemMetrics) *Alert {
 	return nil
 }
 
+func (a *Analyzer) checkCPUBreakdown(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+	breakdown := metrics.CPU.Breakdown
+
+	// High iowait only matters when it persists; a single flush is normal
+	iowait := func(m SystemMetrics) float64 { return m.CPU.Breakdown.IOWait }
+	threshold := a.threshold("iowait", "", a.config.IOWaitThreshold)
+	if breakdown.IOWait > threshold && a.isSustained(iowait, threshold) {
+		level := "warning"
+		if breakdown.IOWait > 2*a.config.IOWaitThreshold {
+			level = "critical"
+		}
+
+		alerts = append(alerts, Alert{
+			Level:     level,
+			Category:  "iowait",
+			Message:   fmt.Sprintf("Sustained high I/O wait: %.1f%% of CPU time spent waiting on I/O",
+				breakdown.IOWait),
+			Value:     breakdown.IOWait,
+			Threshold: a.config.IOWaitThreshold,
+			Timestamp: metrics.Timestamp,
+		})
+	}
+
+	// Any meaningful steal means the hypervisor is starving this VM. A
+	// noisy neighbor that persists is as bad as a single heavy spike.
+	if breakdown.Steal > a.threshold("steal", "", a.config.StealThreshold) {
+		steal := func(m SystemMetrics) float64 { return m.CPU.Breakdown.Steal }
+		level := "warning"
+		if breakdown.Steal > 4*a.config.StealThreshold || a.isSustained(steal, a.config.StealThreshold) {
+			level = "critical"
+		}
+
+		hypervisor := "the hypervisor"
+		if platform := metrics.CPU.Virtualization; platform != "" {
+			hypervisor = fmt.Sprintf("the hypervisor (%s)", platform)
+		}
+
+		alerts = append(alerts, Alert{
+			Level:     level,
+			Category:  "steal",
+			Message:   fmt.Sprintf("CPU steal time is %.1f%%: %s is withholding CPU from this VM (noisy neighbor)",
+				breakdown.Steal, hypervisor),
+			Value:     breakdown.Steal,
+			Threshold: a.config.StealThreshold,
+			Timestamp: metrics.Timestamp,
+		})
+	}
+
+	return alerts
+}
+
 func (a *Analyzer) checkMemoryUsage(metrics *SystemMetrics) *Alert {
-	if metrics.Memory.UsedPercent > a.config.MemoryThreshold {
+	if metrics.Memory.UsedPercent > a.threshold("memory", "", a.config.MemoryThreshold) {
 		level := "warning"
 		if metrics.Memory.UsedPercent > 95 {
 			level = "critical"
@@ -111,7 +357,8 @@ func (a *Analyzer) checkDiskUsage(metrics *SystemMetrics) []Alert {
 	var alerts []Alert
 
 	for _, disk := range metrics.Disk {
-		if disk.UsedPercent > a.config.DiskThreshold {
+		threshold := a.config.diskThreshold(disk.MountPoint)
+		if disk.UsedPercent > a.threshold("disk", disk.MountPoint, threshold) {
 			level := "warning"
 			if disk.UsedPercent > 95 {
 				level = "critical"
@@ -120,10 +367,32 @@ func (a *Analyzer) checkDiskUsage(metrics *SystemMetrics) []Alert {
 			alerts = append(alerts, Alert{
 				Level:     level,
 				Category:  "disk",
-				Message:   fmt.Sprintf("Disk %s usage is %.1f%% (%.1f GB free)", 
+				Resource:  disk.MountPoint,
+				Message:   fmt.Sprintf("Disk %s space usage is %.1f%% (%.1f GB free)", 
 					disk.MountPoint, disk.UsedPercent, disk.FreeGB),
 				Value:     disk.UsedPercent,
-				Threshold: a.config.DiskThreshold,
+				Threshold: threshold,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+
+		// Inode exhaustion fails writes just like a full disk; skip
+		// filesystems that report no inode data at all
+		inodeThreshold := a.config.inodeThreshold(disk.MountPoint)
+		if disk.InodesTotal > 0 && disk.InodesUsedPercent > a.threshold("inodes", disk.MountPoint, inodeThreshold) {
+			level := "warning"
+			if disk.InodesUsedPercent > 95 {
+				level = "critical"
+			}
+
+			alerts = append(alerts, Alert{
+				Level:     level,
+				Category:  "inodes",
+				Resource:  disk.MountPoint,
+				Message:   fmt.Sprintf("Disk %s inode usage is %.1f%% (%d inodes free)",
+					disk.MountPoint, disk.InodesUsedPercent, disk.InodesFree),
+				Value:     disk.InodesUsedPercent,
+				Threshold: inodeThreshold,
 				Timestamp: metrics.Timestamp,
 			})
 		}
@@ -132,79 +401,264 @@ func (a *Analyzer) checkDiskUsage(metrics *SystemMetrics) []Alert {
 	return alerts
 }
 
-func (a *Analyzer) isSustainedHighCPU() bool {
-	if len(a.history) < 3 {
-		return false
+// checkReadOnlyFilesystems raises critical alerts for filesystems that
+// went read-only. Their usage looks normal while every write fails.
+func (a *Analyzer) checkReadOnlyFilesystems(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+	for _, disk := range metrics.Disk {
+		if !disk.Remounted {
+			continue
+		}
+		alerts = append(alerts, Alert{
+			Level:    "critical",
+			Category: "filesystem",
+			Resource: disk.MountPoint,
+			Message: fmt.Sprintf("Filesystem %s (%s) is mounted read-only, writes to it fail",
+				disk.MountPoint, disk.Device),
+			Timestamp: metrics.Timestamp,
+		})
 	}
+	return alerts
+}
 
-	// Check if last 3 measurements all exceeded threshold
-	count := 0
-	for i := len(a.history) - 3; i < len(a.history); i++ {
-		if a.history[i].CPU.UsagePercent > a.config.CPUThreshold {
-			count++
-		}
+// checkSwapActivity alerts when pages move in and out of swap fast enough
+// to be thrashing, going critical once that has been sustained. Swap that
+// is full of stale pages but idle is harmless and never alerts.
+func (a *Analyzer) checkSwapActivity(metrics *SystemMetrics) *Alert {
+	threshold := a.config.SwapRateThreshold
+	rate := func(m SystemMetrics) float64 {
+		return m.Memory.SwapInPerSec + m.Memory.SwapOutPerSec
+	}
+	if threshold <= 0 || rate(*metrics) <= a.threshold("swap", "system", threshold) {
+		return nil
 	}
 
-	return count >= 3
+	level := "warning"
+	if a.isSustained(rate, threshold) {
+		level = "critical"
+	}
+
+	return &Alert{
+		Level:     level,
+		Category:  "swap",
+		Resource:  "system",
+		Message:   fmt.Sprintf("System is swapping actively: %.0f pages/s in, %.0f pages/s out (swap %.1f%% used)",
+			metrics.Memory.SwapInPerSec, metrics.Memory.SwapOutPerSec, metrics.Memory.SwapPercent),
+		Value:     rate(*metrics),
+		Threshold: threshold,
+		Timestamp: metrics.Timestamp,
+	}
 }
 
-func (a *Analyzer) detectAnomalies(current *SystemMetrics) []Alert {
+func (a *Analyzer) checkFileDescriptors(metrics *SystemMetrics) []Alert {
 	var alerts []Alert
 
-	// Calculate average CPU usage from history
-	avgCPU := a.calculateAverageCPU()
-	cpuDelta := current.CPU.UsagePercent - avgCPU
-
-	// Detect CPU spike (> 30% increase from average)
-	if cpuDelta > 30 && current.CPU.UsagePercent > 50 {
+	if fds := metrics.FileDescriptors; fds != nil && fds.UsedPercent > a.threshold("file_descriptors", "system", a.config.FDThreshold) {
 		alerts = append(alerts, Alert{
-			Level:     "warning",
-			Category:  "cpu",
-			Message:   fmt.Sprintf("CPU spike detected: %.1f%% (%.1f%% above average)", 
-				current.CPU.UsagePercent, cpuDelta),
-			Value:     current.CPU.UsagePercent,
-			Threshold: avgCPU,
-			Timestamp: current.Timestamp,
+			Level:     fdAlertLevel(fds.UsedPercent),
+			Category:  "file_descriptors",
+			Resource:  "system",
+			Message:   fmt.Sprintf("System-wide open files at %.1f%% of limit (%d / %d)",
+				fds.UsedPercent, fds.Open, fds.Max),
+			Value:     fds.UsedPercent,
+			Threshold: a.config.FDThreshold,
+			Timestamp: metrics.Timestamp,
 		})
 	}
 
-	// Detect memory leak pattern (consistently increasing memory usage)
-	if a.isMemoryIncreasing() {
+	// Watched processes are reported under their watch, and not again
+	// when they are also among the top processes
+	watchedPIDs := make(map[int32]bool)
+	for _, watched := range metrics.WatchedProcesses {
+		for _, pid := range watched.PIDs {
+			watchedPIDs[pid] = true
+		}
+		if watched.FDPercent > a.threshold("file_descriptors", watched.Name, a.config.FDThreshold) {
+			alerts = append(alerts, Alert{
+				Level:     fdAlertLevel(watched.FDPercent),
+				Category:  "file_descriptors",
+				Resource:  watched.Name + "/fds",
+				Message:   fmt.Sprintf("Watched process %s is at %.1f%% of its open files limit of %d (%d open across %d processes)",
+					watched.Name, watched.FDPercent, watched.FDLimit, watched.OpenFDs, watched.Count),
+				Value:     watched.FDPercent,
+				Threshold: a.config.FDThreshold,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+
+	for _, p := range metrics.Processes {
+		if p.FDSoftLimit == 0 || p.OpenFDs <= 0 || watchedPIDs[p.PID] {
+			continue
+		}
+
+		usedPercent := float64(p.OpenFDs) / float64(p.FDSoftLimit) * 100
+		if usedPercent > a.threshold("file_descriptors", p.Name, a.config.FDThreshold) {
+			alerts = append(alerts, Alert{
+				Level:     fdAlertLevel(usedPercent),
+				Category:  "file_descriptors",
+				Resource:  p.Name,
+				Message:   fmt.Sprintf("Process %s (PID %d) has %d open files, %.1f%% of its limit of %d",
+					p.Name, p.PID, p.OpenFDs, usedPercent, p.FDSoftLimit),
+				Value:     usedPercent,
+				Threshold: a.config.FDThreshold,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+
+	return alerts
+}
+
+func (a *Analyzer) checkGPUs(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+
+	for _, gpu := range metrics.GPU {
+		resource := fmt.Sprintf("gpu%d", gpu.Index)
+
+		// Short bursts are normal for training jobs; only alert when sustained
+		index := gpu.Index
+		utilization := func(m SystemMetrics) float64 {
+			for _, g := range m.GPU {
+				if g.Index == index {
+					return g.UtilizationPercent
+				}
+			}
+			return 0
+		}
+		threshold := a.threshold("gpu", resource, a.config.GPUThreshold)
+		if gpu.UtilizationPercent > threshold && a.isSustained(utilization, threshold) {
+			alerts = append(alerts, Alert{
+				Level:     "warning",
+				Category:  "gpu",
+				Resource:  resource,
+				Message:   fmt.Sprintf("Sustained high utilization on GPU %d (%s): %.0f%%, memory %.0f / %.0f MB",
+					gpu.Index, gpu.Name, gpu.UtilizationPercent, gpu.MemoryUsedMB, gpu.MemoryTotalMB),
+				Value:     gpu.UtilizationPercent,
+				Threshold: a.config.GPUThreshold,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+
+		if alert := temperatureAlert(resource, fmt.Sprintf("GPU %d (%s)", gpu.Index, gpu.Name),
+			gpu.TemperatureC, a.config.GPUTempThreshold, metrics.Timestamp); alert != nil {
+			alerts = append(alerts, *alert)
+		}
+	}
+
+	return alerts
+}
+
+// checkNetwork raises a warning when an interface's traffic exceeds the
+// bandwidth threshold, going critical once that has been sustained
+func (a *Analyzer) checkNetwork(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+
+	for _, nic := range metrics.Network {
+		if nic.SpeedMbps == 0 || nic.UtilizationPercent <= a.threshold("network", nic.Interface, a.config.BandwidthThreshold) {
+			continue
+		}
+
+		name := nic.Interface
+		utilization := func(m SystemMetrics) float64 {
+			for _, n := range m.Network {
+				if n.Interface == name {
+					return n.UtilizationPercent
+				}
+			}
+			return 0
+		}
+		level := "warning"
+		if a.isSustained(utilization, a.config.BandwidthThreshold) {
+			level = "critical"
+		}
+
 		alerts = append(alerts, Alert{
-			Level:     "warning",
-			Category:  "memory",
-			Message:   "Potential memory leak detected: memory usage consistently increasing",
-			Value:     current.Memory.UsedPercent,
-			Threshold: a.config.MemoryThreshold,
-			Timestamp: current.Timestamp,
+			Level:     level,
+			Category:  "network",
+			Resource:  nic.Interface,
+			Message:   fmt.Sprintf("Interface %s is at %.1f%% of its %.0f Mbps link (%.1f Mbps out, %.1f Mbps in)",
+				nic.Interface, nic.UtilizationPercent, nic.SpeedMbps, nic.SentMbps, nic.RecvMbps),
+			Value:     nic.UtilizationPercent,
+			Threshold: a.config.BandwidthThreshold,
+			Timestamp: metrics.Timestamp,
 		})
 	}
 
 	return alerts
 }
 
-func (a *Analyzer) calculateAverageCPU() float64 {
-	if len(a.history) == 0 {
-		return 0
+// temperatureAlert raises a thermal alert when tempC exceeds threshold,
+// going critical 10°C above it
+func temperatureAlert(resource, label string, tempC, threshold float64, timestamp time.Time) *Alert {
+	if tempC <= threshold {
+		return nil
 	}
 
-	sum := 0.0
-	for _, metrics := range a.history {
-		sum += metrics.CPU.UsagePercent
+	level := "warning"
+	if tempC > threshold+10 {
+		level = "critical"
 	}
 
-	return sum / float64(len(a.history))
+	return &Alert{
+		Level:     level,
+		Category:  "temperature",
+		Resource:  resource,
+		Message:   fmt.Sprintf("%s temperature is %.0f°C (threshold: %.0f°C)", label, tempC, threshold),
+		Value:     tempC,
+		Threshold: threshold,
+		Timestamp: timestamp,
+	}
+}
+
+// fdAlertLevel goes critical when descriptors are nearly exhausted
+func fdAlertLevel(usedPercent float64) string {
+	if usedPercent > 95 {
+		return "critical"
+	}
+	return "warning"
+}
+
+func (a *Analyzer) isSustainedHighCPU() bool {
+	if a.history.len() < 3 {
+		return false
+	}
+
+	// Check if last 3 measurements all exceeded threshold
+	count := 0
+	for i := a.history.len() - 3; i < a.history.len(); i++ {
+		if a.history.at(i).CPU.UsagePercent > a.config.CPUThreshold {
+			count++
+		}
+	}
+
+	return count >= 3
+}
+
+// isSustained reports whether the last 3 measurements all exceeded threshold
+func (a *Analyzer) isSustained(value func(SystemMetrics) float64, threshold float64) bool {
+	if a.history.len() < 3 {
+		return false
+	}
+
+	for i := a.history.len() - 3; i < a.history.len(); i++ {
+		if value(a.history.at(i)) <= threshold {
+			return false
+		}
+	}
+
+	return true
 }
 
 func (a *Analyzer) isMemoryIncreasing() bool {
-	if len(a.history) < 4 {
+	if a.history.len() < 4 {
 		return false
 	}
 
 	// Check if memory usage has been increasing for last 4 measurements
 	increasing := true
-	for i := len(a.history) - 3; i < len(a.history); i++ {
-		if a.history[i].Memory.UsedPercent <= a.history[i-1].Memory.UsedPercent {
+	for i := a.history.len() - 3; i < a.history.len(); i++ {
+		if a.history.at(i).Memory.UsedPercent <= a.history.at(i-1).Memory.UsedPercent {
 			increasing = false
 			break
 		}
@@ -212,8 +666,8 @@ func (a *Analyzer) isMemoryIncreasing() bool {
 
 	// Also check if the increase is significant (> 10% total)
 	if increasing {
-		firstMem := a.history[len(a.history)-4].Memory.UsedPercent
-		lastMem := a.history[len(a.history)-1].Memory.UsedPercent
+		firstMem := a.history.at(a.history.len()-4).Memory.UsedPercent
+		lastMem := a.history.at(a.history.len()-1).Memory.UsedPercent
 		return (lastMem - firstMem) > 10
 	}
 
@@ -222,63 +676,14 @@ func (a *Analyzer) isMemoryIncreasing() bool {
 
 // GetTopProcesses returns the top N processes by CPU or memory usage
 func GetTopProcesses(metrics *SystemMetrics, byMemory bool, count int) []ProcessMetrics {
-	if count > len(metrics.Processes) {
-		count = len(metrics.Processes)
-	}
-
-	processes := make([]ProcessMetrics, len(metrics.Processes))
-	copy(processes, metrics.Processes)
-
-	// Sort by the requested metric
 	if byMemory {
-		// Sort by memory usage
-		for i := 0; i < len(processes); i++ {
-			for j := i + 1; j < len(processes); j++ {
-				if processes[j].MemoryMB > processes[i].MemoryMB {
-					processes[i], processes[j] = processes[j], processes[i]
-				}
-			}
-		}
+		return RankProcesses(metrics.Processes, SortByMemory, count)
 	}
-	// Already sorted by CPU if not byMemory
-
-	return processes[:count]
+	return RankProcesses(metrics.Processes, SortByCPU, count)
 }
 
-// GenerateRecommendations generates recommendations based on system state
-func (a *Analyzer) GenerateRecommendations(metrics *SystemMetrics, alerts []Alert) []string {
-	var recommendations []string
-
-	// High CPU recommendations
-	for _, alert := range alerts {
-		if alert.Category == "cpu" && alert.Level == "critical" {
-			// Find top CPU consumers
-			topProcesses := GetTopProcesses(metrics, false, 3)
-			if len(topProcesses) > 0 {
-				recommendations = append(recommendations, 
-					fmt.Sprintf("Consider terminating or optimizing high CPU process: %s (%.1f%% CPU)", 
-						topProcesses[0].Name, topProcesses[0].CPUPercent))
-			}
-		}
-
-		// Low disk space recommendations
-		if alert.Category == "disk" && alert.Level == "critical" {
-			recommendations = append(recommendations, 
-				"Critical: Clean up disk space immediately to prevent system issues")
-			recommendations = append(recommendations, 
-				"Run disk cleanup tools or remove unnecessary files")
-		}
-
-		// Memory recommendations
-		if alert.Category == "memory" && alert.Level == "warning" {
-			topMemProcesses := GetTopProcesses(metrics, true, 3)
-			if len(topMemProcesses) > 0 {
-				recommendations = append(recommendations, 
-					fmt.Sprintf("High memory consumer: %s (%.1f MB)", 
-						topMemProcesses[0].Name, topMemProcesses[0].MemoryMB))
-			}
-		}
-	}
-
-	return recommendations
+// GetTopIOProcesses returns the top N processes by disk I/O, counting
+// reads and writes together. Processes doing no I/O are left out.
+func GetTopIOProcesses(metrics *SystemMetrics, count int) []ProcessMetrics {
+	return busiestIOProcesses(metrics.Processes, count)
 }
@@ -0,0 +1,171 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// EWMA baselines weigh recent samples with anomalyAlpha, roughly a
+// 10-sample window, and score nothing until anomalyWarmup samples are in
+const (
+	anomalyAlpha  = 0.2
+	anomalyWarmup = 5
+)
+
+// anomalyMetric is a metric tracked against its own recent baseline.
+// Deviations smaller than minStdDev never count, so a metric that has
+// been flat does not alert on noise, and values below minValue are too
+// low to matter however unusual they are.
+type anomalyMetric struct {
+	category  string
+	label     string
+	minStdDev float64
+	minValue  float64
+	value     func(SystemMetrics) float64
+}
+
+var anomalyMetrics = []anomalyMetric{
+	{"cpu", "CPU usage", 2.5, 50, func(m SystemMetrics) float64 { return m.CPU.UsagePercent }},
+	{"memory", "Memory usage", 1, 50, func(m SystemMetrics) float64 { return m.Memory.UsedPercent }},
+	{"iowait", "I/O wait", 1, 10, func(m SystemMetrics) float64 { return m.CPU.Breakdown.IOWait }},
+}
+
+// ewma is an exponentially weighted moving mean and variance
+type ewma struct {
+	mean     float64
+	variance float64
+	samples  int
+}
+
+func (e *ewma) add(value float64) {
+	if e.samples == 0 {
+		e.mean = value
+	} else {
+		diff := value - e.mean
+		increment := anomalyAlpha * diff
+		e.mean += increment
+		e.variance = (1 - anomalyAlpha) * (e.variance + diff*increment)
+	}
+	e.samples++
+}
+
+func (e *ewma) stdDev() float64 {
+	return math.Sqrt(e.variance)
+}
+
+// anomalySigma returns how many standard deviations above its baseline a
+// metric category must rise to alert; 0 disables it
+func (c Config) anomalySigma(category string) float64 {
+	if sigma, ok := c.AnomalySigmas[category]; ok {
+		return sigma
+	}
+	return c.AnomalySigma
+}
+
+// validateAnomaly checks the anomaly sensitivity settings
+func (c Config) validateAnomaly() []error {
+	var errs []error
+	if c.AnomalySigma < 0 {
+		errs = append(errs, fmt.Errorf("anomaly_sigma must not be negative, got %g", c.AnomalySigma))
+	}
+
+	categories := make([]string, 0, len(c.AnomalySigmas))
+	for category := range c.AnomalySigmas {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		known := false
+		for _, metric := range anomalyMetrics {
+			known = known || metric.category == category
+		}
+		if !known {
+			errs = append(errs, fmt.Errorf("anomaly_sigmas: unknown category %q, expected cpu, memory or iowait", category))
+		}
+		if sigma := c.AnomalySigmas[category]; sigma < 0 {
+			errs = append(errs, fmt.Errorf("anomaly_sigmas[%q] must not be negative, got %g", category, sigma))
+		}
+	}
+	return errs
+}
+
+// detectAnomalies scores each tracked metric against its baseline before
+// folding the new value in, so a spike cannot mask itself, and checks
+// memory for a steady climb that a baseline would simply follow
+func (a *Analyzer) detectAnomalies(current *SystemMetrics) []Alert {
+	var alerts []Alert
+
+	for _, metric := range anomalyMetrics {
+		baseline := a.baseline(metric.category)
+		value := metric.value(*current)
+		sigma := a.config.anomalySigma(metric.category)
+
+		// The usual level for this hour, once it is known, beats the
+		// recent average: a nightly batch job is normal at night
+		mean, stdDev, ready := baseline.mean, baseline.stdDev(), baseline.samples >= anomalyWarmup
+		usual := "its recent average of"
+		slot, label := a.seasonalSlot(metric.category, current.Timestamp)
+		if slot != nil && slot.ready() {
+			mean, stdDev, ready = slot.Mean, math.Sqrt(slot.Variance), true
+			usual = fmt.Sprintf("its usual level for %s of", label)
+		}
+
+		if sigma > 0 && ready && value >= metric.minValue {
+			stdDev = max(stdDev, metric.minStdDev)
+			if score := (value - mean) / stdDev; score > sigma {
+				alerts = append(alerts, Alert{
+					Level:    "warning",
+					Category: metric.category,
+					Resource: "anomaly",
+					Message: fmt.Sprintf("%s anomaly: %.1f%% is %.1fσ above %s %.1f%%",
+						metric.label, value, score, usual, mean),
+					Value:     value,
+					Threshold: mean + sigma*stdDev,
+					Timestamp: current.Timestamp,
+				})
+			}
+		}
+
+		baseline.add(value)
+		if slot != nil {
+			slot.add(value, current.Timestamp)
+		}
+	}
+
+	// Detect memory leak pattern (consistently increasing memory usage)
+	if a.isMemoryIncreasing() {
+		alerts = append(alerts, Alert{
+			Level:     "warning",
+			Category:  "memory",
+			Message:   "Potential memory leak detected: memory usage consistently increasing",
+			Value:     current.Memory.UsedPercent,
+			Threshold: a.config.MemoryThreshold,
+			Timestamp: current.Timestamp,
+		})
+	}
+
+	return alerts
+}
+
+func (a *Analyzer) baseline(category string) *ewma {
+	if a.baselines == nil {
+		a.baselines = make(map[string]*ewma)
+	}
+	baseline, ok := a.baselines[category]
+	if !ok {
+		baseline = &ewma{}
+		a.baselines[category] = baseline
+	}
+	return baseline
+}
+
+// rebuildBaselines replays the history into fresh baselines
+func (a *Analyzer) rebuildBaselines() {
+	a.baselines = nil
+	for _, metrics := range a.history.snapshots() {
+		for _, metric := range anomalyMetrics {
+			a.baseline(metric.category).add(metric.value(metrics))
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertificateCheck is a certificate to watch for expiry, either served by
+// a TLS endpoint or stored in a local PEM file
+type CertificateCheck struct {
+	Name     string `json:"name"`     // label used in alerts, unique
+	Endpoint string `json:"endpoint"` // host:port
+	Path     string `json:"path"`     // PEM file, leaf certificate first
+
+	// Host name to verify the certificate against; defaults to the
+	// endpoint's host, and is not checked for files
+	ServerName string `json:"server_name"`
+
+	// PEM file of extra trusted roots, for certificates of a private CA
+	CAFile string `json:"ca_file"`
+}
+
+// CertificateMetrics is the leaf certificate found by a CertificateCheck
+type CertificateMetrics struct {
+	Name       string    `json:"name"`
+	Subject    string    `json:"subject,omitempty"`
+	Issuer     string    `json:"issuer,omitempty"`
+	NotAfter   time.Time `json:"not_after,omitempty"`
+	DaysLeft   float64   `json:"days_left"`
+	ChainValid bool      `json:"chain_valid"`
+	ChainError string    `json:"chain_error,omitempty"`
+	Error      string    `json:"error,omitempty"` // why no certificate was read
+}
+
+func (c CertificateCheck) validate() error {
+	var errs []error
+	if c.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if (c.Endpoint == "") == (c.Path == "") {
+		errs = append(errs, errors.New("exactly one of endpoint and path is required"))
+	}
+	if c.Endpoint != "" {
+		if _, _, err := net.SplitHostPort(c.Endpoint); err != nil {
+			errs = append(errs, fmt.Errorf("endpoint: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c Config) validateCertificates() []error {
+	var errs []error
+	names := make(map[string]bool, len(c.CertificateChecks))
+	for i, check := range c.CertificateChecks {
+		if err := check.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("certificate_checks[%d]: %w", i, err))
+		}
+		if names[check.Name] {
+			errs = append(errs, fmt.Errorf("certificate_checks[%d]: duplicate name %q", i, check.Name))
+		}
+		names[check.Name] = true
+	}
+	if c.CertCriticalDays < 0 || c.CertWarningDays < c.CertCriticalDays {
+		errs = append(errs, fmt.Errorf("cert_warning_days (%d) must be at least cert_critical_days (%d), which must not be negative",
+			c.CertWarningDays, c.CertCriticalDays))
+	}
+	return errs
+}
+
+// collectCertificates reads every configured certificate concurrently
+func (c *Collector) collectCertificates(ctx context.Context) ([]CertificateMetrics, error) {
+	now := c.clock.Now()
+	results := make([]CertificateMetrics, len(c.config.CertificateChecks))
+	var wg sync.WaitGroup
+	for i, check := range c.config.CertificateChecks {
+		wg.Add(1)
+		go func(i int, check CertificateCheck) {
+			defer wg.Done()
+			results[i] = check.inspect(ctx, now)
+		}(i, check)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+func (c CertificateCheck) inspect(ctx context.Context, now time.Time) CertificateMetrics {
+	result := CertificateMetrics{Name: c.Name}
+
+	var chain []*x509.Certificate
+	var err error
+	if c.Endpoint != "" {
+		chain, err = c.fetchChain(ctx)
+	} else {
+		chain, err = readCertificates(c.Path)
+	}
+	if err == nil && len(chain) == 0 {
+		err = errors.New("no certificate found")
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	leaf := chain[0]
+	result.Subject = leaf.Subject.CommonName
+	if result.Subject == "" && len(leaf.DNSNames) > 0 {
+		result.Subject = leaf.DNSNames[0]
+	}
+	result.Issuer = leaf.Issuer.CommonName
+	result.NotAfter = leaf.NotAfter
+	result.DaysLeft = leaf.NotAfter.Sub(now).Hours() / 24
+
+	if err := c.verify(chain, now); err != nil {
+		result.ChainError = err.Error()
+	} else {
+		result.ChainValid = true
+	}
+	return result
+}
+
+// fetchChain returns the certificates an endpoint presents, without
+// verifying them so an expired or broken chain is still reported
+func (c CertificateCheck) fetchChain(ctx context.Context) ([]*x509.Certificate, error) {
+	dialer := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 10 * time.Second},
+		Config:    &tls.Config{ServerName: c.serverName(), InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", c.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.(*tls.Conn).ConnectionState().PeerCertificates, nil
+}
+
+func (c CertificateCheck) serverName() string {
+	if c.ServerName != "" || c.Endpoint == "" {
+		return c.ServerName
+	}
+	host, _, _ := net.SplitHostPort(c.Endpoint)
+	return host
+}
+
+// verify checks the chain leads to a trusted root and, for endpoints,
+// that the leaf is valid for the server name
+func (c CertificateCheck) verify(chain []*x509.Certificate, now time.Time) error {
+	options := x509.VerifyOptions{
+		DNSName:       c.serverName(),
+		CurrentTime:   now,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range chain[1:] {
+		options.Intermediates.AddCert(cert)
+	}
+	if c.CAFile != "" {
+		roots, err := readCertificates(c.CAFile)
+		if err != nil {
+			return fmt.Errorf("ca_file: %w", err)
+		}
+		options.Roots = x509.NewCertPool()
+		for _, root := range roots {
+			options.Roots.AddCert(root)
+		}
+	}
+	_, err := chain[0].Verify(options)
+	return err
+}
+
+// readCertificates parses the certificates of a PEM file, in order
+func readCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// checkCertificates alerts on certificates close to expiry, expired or
+// with a broken chain, and on certificates that could not be read
+func (a *Analyzer) checkCertificates(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+	for _, cert := range metrics.Certificates {
+		alert := Alert{
+			Category:  "certificate",
+			Resource:  cert.Name,
+			Value:     cert.DaysLeft,
+			Timestamp: metrics.Timestamp,
+		}
+		switch {
+		case cert.Error != "":
+			alert.Level = "warning"
+			alert.Message = fmt.Sprintf("Certificate %s could not be checked: %s", cert.Name, cert.Error)
+		case cert.DaysLeft <= 0:
+			alert.Level = "critical"
+			alert.Message = fmt.Sprintf("Certificate %s (%s) expired on %s", cert.Name, cert.Subject, cert.NotAfter.Format("2006-01-02"))
+		case cert.DaysLeft <= float64(a.config.CertCriticalDays):
+			alert.Level = "critical"
+			alert.Threshold = float64(a.config.CertCriticalDays)
+			alert.Message = fmt.Sprintf("Certificate %s (%s) expires in %.1f days, on %s",
+				cert.Name, cert.Subject, cert.DaysLeft, cert.NotAfter.Format("2006-01-02"))
+		case !cert.ChainValid:
+			alert.Level = "critical"
+			alert.Message = fmt.Sprintf("Certificate %s (%s) does not verify: %s", cert.Name, cert.Subject, cert.ChainError)
+		case cert.DaysLeft <= float64(a.config.CertWarningDays):
+			alert.Level = "warning"
+			alert.Threshold = float64(a.config.CertWarningDays)
+			alert.Message = fmt.Sprintf("Certificate %s (%s) expires in %.0f days, on %s",
+				cert.Name, cert.Subject, cert.DaysLeft, cert.NotAfter.Format("2006-01-02"))
+		default:
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
@@ -0,0 +1,225 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cgroupRoot is where the cgroup v2 hierarchy is mounted
+const cgroupRoot = "/sys/fs/cgroup"
+
+// CgroupMetrics is the usage of the container the monitor runs in,
+// relative to its cgroup v2 limits rather than the host's totals
+type CgroupMetrics struct {
+	Path string `json:"path"` // "/" inside a cgroup namespace
+
+	// Memory in use, not counting page cache the kernel can reclaim, and
+	// the limit (memory.max); the limit is zero when unlimited
+	MemoryUsedGB  float64 `json:"memory_used_gb"`
+	MemoryLimitGB float64 `json:"memory_limit_gb,omitempty"`
+	MemoryPercent float64 `json:"memory_percent,omitempty"` // of the limit
+	OOMKills      uint64  `json:"oom_kills"`                // since the cgroup was created
+
+	// The CPU quota in cores (cpu.max), zero when unlimited, and the CPU
+	// used since the previous sample, in cores and as a percentage of it
+	CPULimitCores float64 `json:"cpu_limit_cores,omitempty"`
+	CPUUsedCores  float64 `json:"cpu_used_cores"`
+	CPUPercent    float64 `json:"cpu_percent,omitempty"`
+
+	// Share of the quota's enforcement periods since the previous sample
+	// in which the cgroup was throttled, and the time it spent throttled
+	ThrottledPercent float64 `json:"throttled_percent"`
+	ThrottledSeconds float64 `json:"throttled_seconds"`
+}
+
+// cgroupCPU is a cgroup's cumulative cpu.stat counters at a sample
+type cgroupCPU struct {
+	usageUsec, periods, throttled, throttledUsec uint64
+	at                                           time.Time
+}
+
+// cgroupDir finds the monitor's own cgroup v2 directory. Inside a cgroup
+// namespace, as in most containers, the hierarchy's root is the
+// container's cgroup. A host's root cgroup has no limits, and a service's
+// cgroup on a host is not a container, so neither is reported.
+func cgroupDir() (dir, path string, ok bool) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return "", "", false // cgroup v1, or not Linux
+	}
+	path = readCgroup(int32(os.Getpid()))
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "memory.max")); err == nil {
+		return cgroupRoot, path, true
+	}
+	if path == "" || path == "/" || !inContainer(path) {
+		return "", "", false
+	}
+	return filepath.Join(cgroupRoot, path), path, true
+}
+
+// inContainer tells container runtimes' cgroups, which share the host's
+// hierarchy without a cgroup namespace, from services on the host
+func inContainer(path string) bool {
+	for _, marker := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(marker); err == nil {
+			return true
+		}
+	}
+	for _, runtime := range []string{"docker", "kubepods", "containerd", "libpod", "lxc"} {
+		if strings.Contains(path, runtime) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectCgroup reports nothing outside a container
+func (c *Collector) collectCgroup(ctx context.Context) (*CgroupMetrics, error) {
+	dir, path, ok := cgroupDir()
+	if !ok {
+		return nil, nil
+	}
+	return c.readCgroupMetrics(dir, path), nil
+}
+
+func (c *Collector) readCgroupMetrics(dir, path string) *CgroupMetrics {
+	metrics := &CgroupMetrics{Path: path}
+
+	// Page cache counts toward memory.current but is reclaimed before the
+	// cgroup runs out, so inactive file pages are left out like docker
+	// stats does
+	if current, err := readCgroupValue(dir, "memory.current"); err == nil {
+		used := current
+		if inactive := readCgroupStats(dir, "memory.stat")["inactive_file"]; inactive < used {
+			used -= inactive
+		}
+		metrics.MemoryUsedGB = float64(used) / (1024 * 1024 * 1024)
+	}
+	if limit, err := readCgroupValue(dir, "memory.max"); err == nil && limit > 0 {
+		metrics.MemoryLimitGB = float64(limit) / (1024 * 1024 * 1024)
+		metrics.MemoryPercent = metrics.MemoryUsedGB / metrics.MemoryLimitGB * 100
+	}
+	metrics.OOMKills = readCgroupStats(dir, "memory.events")["oom_kill"]
+
+	// "200000 100000" is a quota of 2 cores, "max 100000" none
+	if data, err := os.ReadFile(filepath.Join(dir, "cpu.max")); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) == 2 && fields[0] != "max" {
+			quota, _ := strconv.ParseFloat(fields[0], 64)
+			period, _ := strconv.ParseFloat(fields[1], 64)
+			if period > 0 {
+				metrics.CPULimitCores = quota / period
+			}
+		}
+	}
+
+	stats := readCgroupStats(dir, "cpu.stat")
+	current := cgroupCPU{
+		usageUsec:     stats["usage_usec"],
+		periods:       stats["nr_periods"],
+		throttled:     stats["nr_throttled"],
+		throttledUsec: stats["throttled_usec"],
+		at:            c.clock.Now(),
+	}
+	prev := c.prevCgroupCPU
+	c.prevCgroupCPU = current
+
+	// Counters only reset when the container restarts, which restarts the
+	// monitor too; skip the rates on the first sample
+	elapsed := current.at.Sub(prev.at).Seconds()
+	if prev.at.IsZero() || elapsed <= 0 || current.usageUsec < prev.usageUsec {
+		return metrics
+	}
+	metrics.CPUUsedCores = float64(current.usageUsec-prev.usageUsec) / 1e6 / elapsed
+	if metrics.CPULimitCores > 0 {
+		metrics.CPUPercent = metrics.CPUUsedCores / metrics.CPULimitCores * 100
+	}
+	if periods := current.periods - prev.periods; periods > 0 && current.throttled >= prev.throttled {
+		metrics.ThrottledPercent = float64(current.throttled-prev.throttled) / float64(periods) * 100
+	}
+	if current.throttledUsec >= prev.throttledUsec {
+		metrics.ThrottledSeconds = float64(current.throttledUsec-prev.throttledUsec) / 1e6
+	}
+	return metrics
+}
+
+// readCgroupValue reads a single-value file; "max" is an error, as there
+// is no limit to report
+func readCgroupValue(dir, file string) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// readCgroupStats reads a flat keyed file such as cpu.stat
+func readCgroupStats(dir, file string) map[string]uint64 {
+	stats := make(map[string]uint64)
+	data, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return stats
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if key, value, ok := strings.Cut(line, " "); ok {
+			stats[key], _ = strconv.ParseUint(value, 10, 64)
+		}
+	}
+	return stats
+}
+
+// checkCgroup judges CPU and memory against the container's limits, in
+// place of the host-wide checks AnalyzeMetrics skips when there are
+// limits, and alerts on CPU throttling and on OOM kills since the
+// previous snapshot
+func (a *Analyzer) checkCgroup(metrics *SystemMetrics) []Alert {
+	cgroup := metrics.Cgroup
+	if cgroup == nil {
+		return nil
+	}
+
+	var alerts []Alert
+	alert := func(level, category, resource, message string, value, threshold float64) {
+		alerts = append(alerts, Alert{
+			Level:     level,
+			Category:  category,
+			Resource:  resource,
+			Message:   message,
+			Value:     value,
+			Threshold: threshold,
+			Timestamp: metrics.Timestamp,
+		})
+	}
+	level := func(value, critical float64) string {
+		if value > critical {
+			return "critical"
+		}
+		return "warning"
+	}
+
+	if cgroup.CPULimitCores > 0 && cgroup.CPUPercent > a.config.CPUThreshold {
+		alert(level(cgroup.CPUPercent, 95), "cpu", "", fmt.Sprintf("Container CPU usage is %.1f%% of its %.2g core quota (threshold: %.1f%%)",
+			cgroup.CPUPercent, cgroup.CPULimitCores, a.config.CPUThreshold), cgroup.CPUPercent, a.config.CPUThreshold)
+	}
+	if threshold := a.config.CPUThrottleThreshold; threshold > 0 && cgroup.ThrottledPercent > threshold {
+		alert(level(cgroup.ThrottledPercent, 2*threshold), "throttling", "cpu", fmt.Sprintf("Container was CPU throttled in %.0f%% of periods, %.1f s in total, by its %.2g core quota",
+			cgroup.ThrottledPercent, cgroup.ThrottledSeconds, cgroup.CPULimitCores), cgroup.ThrottledPercent, threshold)
+	}
+	if cgroup.MemoryLimitGB > 0 && cgroup.MemoryPercent > a.config.MemoryThreshold {
+		alert(level(cgroup.MemoryPercent, 95), "memory", "", fmt.Sprintf("Container memory usage is %.1f%% (%.2f GB / %.2f GB limit)",
+			cgroup.MemoryPercent, cgroup.MemoryUsedGB, cgroup.MemoryLimitGB), cgroup.MemoryPercent, a.config.MemoryThreshold)
+	}
+
+	if n := a.history.len(); n >= 2 {
+		if prev := a.history.at(n - 2).Cgroup; prev != nil && cgroup.OOMKills > prev.OOMKills {
+			kills := cgroup.OOMKills - prev.OOMKills
+			alert("critical", "memory", "oom_kill", fmt.Sprintf("%d processes in the container were OOM killed since the last snapshot (limit %.2f GB)",
+				kills, cgroup.MemoryLimitGB), float64(kills), 0)
+		}
+	}
+	return alerts
+}
@@ -1,9 +1,12 @@
 package monitor
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"runtime"
-	"sort"
+	"runtime/debug"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,134 +15,294 @@ import (
 	"github.com/shirou/gopsutil/v3/host"
 	"github.com/shirou/gopsutil/v3/load"
 	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
 	"github.com/shirou/gopsutil/v3/process"
 )
 
+// processCollectTimeout bounds how long a single process scan may take
+const processCollectTimeout = 10 * time.Second
+
+// pageSize converts gopsutil's swap byte counters back to pages
+const pageSize = 4096
+
 // Collector handles system metrics collection
 type Collector struct {
-	config Config
+	config       Config
+	clock        Clock
+	prevCPUTimes *cpu.TimesStat
+	redactor     *redactor
+	procFilter   processFilter
+
+	// Guest virtualization platform, detected once
+	virtOnce       sync.Once
+	virtualization string
+
+	// Last clean shutdown before this boot, read once
+	shutdownOnce sync.Once
+	lastShutdown time.Time
+
+	// Pending package updates, queried in the background
+	updates updateState
+
+	// Previous swap counters in bytes, for swap activity rates
+	prevSwapIn   uint64
+	prevSwapOut  uint64
+	prevSwapTime time.Time
+
+	// Previous interface counters, for traffic rates
+	prevNetCounters map[string]net.IOCountersStat
+	prevNetTime     time.Time
+
+	// Previous container CPU usage by container ID
+	prevContainerCPU map[string]containerCPU
+
+	// Previous CPU counters of the container's cgroup
+	prevCgroupCPU cgroupCPU
+
+	// Previous numastat counters by NUMA node
+	prevNUMA map[int]numaCounters
+
+	// Previous /proc/stat and /proc/interrupts counters
+	prevActivity *activityCounters
+
+	// Previous /proc/net/snmp and netstat counters
+	prevTCP *tcpCounters
+
+	// Previous process disk I/O counters by PID
+	prevProcIO map[int32]procIO
+
+	// Mount points seen mounted read-write
+	writableMounts map[string]bool
+
+	// Built-in and registered collectors, run concurrently
+	collectors []MetricCollector
+
+	// Compiled Config.ProcessWatches
+	watchers []processWatcher
+
+	// Compiled Config.HTTPProbes
+	probers []httpProber
+
+	// Compiled Config.LogWatches, with their read positions
+	logTails []*logTail
+
+	// End of the span of Windows event logs read so far
+	eventsSince time.Time
+
+	// Collectors whose goroutine has not returned yet
+	runningMu sync.Mutex
+	running   map[string]bool
 }
 
 // NewCollector creates a new metrics collector
 func NewCollector(config Config) *Collector {
-	return &Collector{
-		config: config,
+	c := &Collector{
+		config:     config,
+		clock:      SystemClock,
+		redactor:   newRedactor(config.Redaction),
+		procFilter: newProcessFilter(config.ProcessInclude, config.ProcessExclude),
+		watchers:   newProcessWatchers(config.ProcessWatches),
+		probers:    newHTTPProbers(config.HTTPProbes),
+		logTails:   newLogTails(config.LogWatches),
 	}
+	c.registerBuiltins()
+	return c
+}
+
+// SetClock replaces the clock used to timestamp snapshots
+func (c *Collector) SetClock(clock Clock) {
+	c.clock = clock
 }
 
-// CollectMetrics gathers all system metrics concurrently
-func (c *Collector) CollectMetrics() (*SystemMetrics, error) {
+// CollectMetrics runs all enabled collectors concurrently, each bounded by
+// Config.CollectorTimeout. Collectors that fail or time out are listed in
+// CollectorFailures and returned as a *CollectionError alongside the rest
+// of the snapshot; metrics are only nil when every collector failed.
+// Cancelling ctx abandons collectors that are still running.
+func (c *Collector) CollectMetrics(ctx context.Context) (*SystemMetrics, error) {
+	started := time.Now()
 	metrics := &SystemMetrics{
-		Timestamp: time.Now(),
+		Timestamp: c.clock.Now(),
 	}
+	timeout := time.Duration(c.config.CollectorTimeout) * time.Second
 
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	var errs []error
-
-	// Collect CPU metrics
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if err := c.collectCPUMetrics(metrics, &mu); err != nil {
-			mu.Lock()
-			errs = append(errs, fmt.Errorf("cpu metrics: %w", err))
-			mu.Unlock()
-		}
-	}()
-
-	// Collect memory metrics
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if err := c.collectMemoryMetrics(metrics, &mu); err != nil {
-			mu.Lock()
-			errs = append(errs, fmt.Errorf("memory metrics: %w", err))
-			mu.Unlock()
-		}
-	}()
-
-	// Collect disk metrics
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if err := c.collectDiskMetrics(metrics, &mu); err != nil {
-			mu.Lock()
-			errs = append(errs, fmt.Errorf("disk metrics: %w", err))
-			mu.Unlock()
+	type outcome struct {
+		name   string
+		result interface{}
+		err    error
+	}
+	outcomes := make(chan outcome, len(c.collectors))
+	failures := make(map[string]CollectorFailure)
+	pending := make(map[string]bool)
+	enabled := 0
+
+	for _, collector := range c.collectors {
+		name := collector.Name()
+		if !c.config.collectorEnabled(name) {
+			continue
 		}
-	}()
+		enabled++
 
-	// Collect load metrics
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if err := c.collectLoadMetrics(metrics, &mu); err != nil {
-			mu.Lock()
-			errs = append(errs, fmt.Errorf("load metrics: %w", err))
-			mu.Unlock()
+		// A collector stuck in a call that ignores its context (statfs on a
+		// dead NFS mount) is left alone until it returns
+		if !c.startCollector(name) {
+			failures[name] = CollectorFailure{Collector: name, Message: "still running from a previous collection", TimedOut: true}
+			continue
 		}
-	}()
+		pending[name] = true
+
+		go func() {
+			defer c.finishCollector(name)
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			defer func() {
+				if value := recover(); value != nil {
+					outcomes <- outcome{name: name, err: &PanicError{Value: value, Stack: debug.Stack()}}
+				}
+			}()
+
+			result, err := collector.Collect(ctx)
+			outcomes <- outcome{name: name, result: result, err: err}
+		}()
+	}
 
-	// Collect process metrics
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if err := c.collectProcessMetrics(metrics, &mu); err != nil {
-			mu.Lock()
-			errs = append(errs, fmt.Errorf("process metrics: %w", err))
-			mu.Unlock()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+wait:
+	for len(pending) > 0 {
+		select {
+		case o := <-outcomes:
+			delete(pending, o.name)
+			if o.err != nil {
+				failure := CollectorFailure{
+					Collector: o.name,
+					Message:   o.err.Error(),
+					TimedOut:  errors.Is(o.err, context.DeadlineExceeded),
+				}
+				var panicked *PanicError
+				if errors.As(o.err, &panicked) {
+					failure.Stack = string(panicked.Stack)
+				}
+				failures[o.name] = failure
+				continue
+			}
+			storeResult(metrics, o.name, o.result)
+		case <-deadline.C:
+			break wait
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
-	}()
+	}
+	for name := range pending {
+		failures[name] = CollectorFailure{Collector: name, Message: fmt.Sprintf("timed out after %s", timeout), TimedOut: true}
+	}
 
-	wg.Wait()
+	// Wall time rather than the clock, which may be simulated
+	if metrics.Self != nil {
+		metrics.Self.CollectionSeconds = time.Since(started).Seconds()
+	}
 
-	if len(errs) > 0 {
-		return metrics, fmt.Errorf("collection errors: %v", errs)
+	if len(failures) == 0 {
+		return metrics, nil
 	}
 
-	return metrics, nil
+	// Report failures in registration order
+	for _, collector := range c.collectors {
+		if failure, ok := failures[collector.Name()]; ok {
+			metrics.CollectorFailures = append(metrics.CollectorFailures, failure)
+		}
+	}
+	err := &CollectionError{Failures: metrics.CollectorFailures}
+	if len(failures) == enabled {
+		return nil, err
+	}
+	return metrics, err
 }
 
-func (c *Collector) collectCPUMetrics(metrics *SystemMetrics, mu *sync.Mutex) error {
+func (c *Collector) collectCPUMetrics(ctx context.Context) (CPUMetrics, error) {
+	// Snapshot CPU times so the first sample has a baseline to diff against
+	if c.prevCPUTimes == nil {
+		if times, err := cpu.TimesWithContext(ctx, false); err == nil && len(times) > 0 {
+			c.prevCPUTimes = &times[0]
+		}
+	}
+
 	// Get overall CPU usage
-	overallPercent, err := cpu.Percent(time.Second, false)
+	overallPercent, err := cpu.PercentWithContext(ctx, time.Second, false)
 	if err != nil {
-		return err
+		return CPUMetrics{}, err
 	}
 
 	// Get per-core CPU usage
-	perCorePercent, err := cpu.Percent(time.Second, true)
+	perCorePercent, err := cpu.PercentWithContext(ctx, time.Second, true)
 	if err != nil {
-		return err
+		return CPUMetrics{}, err
 	}
 
-	mu.Lock()
-	metrics.CPU = CPUMetrics{
-		UsagePercent: overallPercent[0],
-		Cores:        runtime.NumCPU(),
-		PerCore:      perCorePercent,
+	// CPU time breakdown is best effort; report zeros where unavailable
+	var breakdown CPUBreakdown
+	if times, err := cpu.TimesWithContext(ctx, false); err == nil && len(times) > 0 {
+		if c.prevCPUTimes != nil {
+			breakdown = cpuBreakdown(*c.prevCPUTimes, times[0])
+		}
+		c.prevCPUTimes = &times[0]
+	}
+
+	c.virtOnce.Do(func() {
+		if system, role, err := host.VirtualizationWithContext(ctx); err == nil && role == "guest" {
+			c.virtualization = system
+		}
+	})
+
+	return CPUMetrics{
+		UsagePercent:   overallPercent[0],
+		Cores:          runtime.NumCPU(),
+		PerCore:        perCorePercent,
+		Breakdown:      breakdown,
+		Virtualization: c.virtualization,
+	}, nil
+}
+
+// cpuBreakdown converts the delta between two cpu.Times samples into
+// percentages. Guest time is already accounted for in user time.
+func cpuBreakdown(prev, cur cpu.TimesStat) CPUBreakdown {
+	user := (cur.User + cur.Nice) - (prev.User + prev.Nice)
+	system := cur.System - prev.System
+	idle := cur.Idle - prev.Idle
+	iowait := cur.Iowait - prev.Iowait
+	steal := cur.Steal - prev.Steal
+	irq := (cur.Irq + cur.Softirq) - (prev.Irq + prev.Softirq)
+
+	total := user + system + idle + iowait + steal + irq
+	if total <= 0 {
+		return CPUBreakdown{}
 	}
-	mu.Unlock()
 
-	return nil
+	return CPUBreakdown{
+		User:   user / total * 100,
+		System: system / total * 100,
+		Idle:   idle / total * 100,
+		IOWait: iowait / total * 100,
+		Steal:  steal / total * 100,
+		IRQ:    irq / total * 100,
+	}
 }
 
-func (c *Collector) collectMemoryMetrics(metrics *SystemMetrics, mu *sync.Mutex) error {
+func (c *Collector) collectMemoryMetrics(ctx context.Context) (MemoryMetrics, error) {
 	// Virtual memory
-	vmStat, err := mem.VirtualMemory()
+	vmStat, err := mem.VirtualMemoryWithContext(ctx)
 	if err != nil {
-		return err
+		return MemoryMetrics{}, err
 	}
 
 	// Swap memory
-	swapStat, err := mem.SwapMemory()
+	swapStat, err := mem.SwapMemoryWithContext(ctx)
 	if err != nil {
-		return err
+		return MemoryMetrics{}, err
 	}
 
-	mu.Lock()
-	metrics.Memory = MemoryMetrics{
+	memory := MemoryMetrics{
 		TotalGB:      float64(vmStat.Total) / (1024 * 1024 * 1024),
 		UsedGB:       float64(vmStat.Used) / (1024 * 1024 * 1024),
 		AvailableGB:  float64(vmStat.Available) / (1024 * 1024 * 1024),
@@ -148,21 +311,38 @@ func (c *Collector) collectMemoryMetrics(metrics *SystemMetrics, mu *sync.Mutex)
 		SwapUsedGB:   float64(swapStat.Used) / (1024 * 1024 * 1024),
 		SwapPercent:  swapStat.UsedPercent,
 	}
-	mu.Unlock()
 
-	return nil
+	// Swap counters are cumulative bytes; report the page rate since the
+	// previous sample
+	now := c.clock.Now()
+	if elapsed := now.Sub(c.prevSwapTime).Seconds(); !c.prevSwapTime.IsZero() && elapsed > 0 &&
+		swapStat.Sin >= c.prevSwapIn && swapStat.Sout >= c.prevSwapOut {
+		memory.SwapInPerSec = float64(swapStat.Sin-c.prevSwapIn) / pageSize / elapsed
+		memory.SwapOutPerSec = float64(swapStat.Sout-c.prevSwapOut) / pageSize / elapsed
+	}
+	c.prevSwapIn, c.prevSwapOut, c.prevSwapTime = swapStat.Sin, swapStat.Sout, now
+
+	return memory, nil
 }
 
-func (c *Collector) collectDiskMetrics(metrics *SystemMetrics, mu *sync.Mutex) error {
-	partitions, err := disk.Partitions(false)
+func (c *Collector) collectDiskMetrics(ctx context.Context) ([]DiskMetrics, error) {
+	partitions, err := disk.PartitionsWithContext(ctx, false)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var diskMetrics []DiskMetrics
+	fstab := readFstabWritable()
+	if c.writableMounts == nil {
+		c.writableMounts = make(map[string]bool)
+	}
 
 	for _, partition := range partitions {
-		usage, err := disk.Usage(partition.Mountpoint)
+		if !c.config.monitorsMount(partition.Mountpoint) {
+			continue
+		}
+
+		usage, err := disk.UsageWithContext(ctx, partition.Mountpoint)
 		if err != nil {
 			continue // Skip inaccessible partitions
 		}
@@ -179,85 +359,323 @@ func (c *Collector) collectDiskMetrics(metrics *SystemMetrics, mu *sync.Mutex) e
 			UsedGB:      float64(usage.Used) / (1024 * 1024 * 1024),
 			FreeGB:      float64(usage.Free) / (1024 * 1024 * 1024),
 			UsedPercent: usage.UsedPercent,
+
+			InodesTotal:       usage.InodesTotal,
+			InodesUsed:        usage.InodesUsed,
+			InodesFree:        usage.InodesFree,
+			InodesUsedPercent: usage.InodesUsedPercent,
 		})
+
+		mounted := &diskMetrics[len(diskMetrics)-1]
+		for _, option := range partition.Opts {
+			if option == "ro" {
+				mounted.ReadOnly = true
+			}
+		}
+		if mounted.ReadOnly {
+			mounted.Remounted = c.writableMounts[mounted.MountPoint] || fstab[mounted.MountPoint]
+		} else {
+			c.writableMounts[mounted.MountPoint] = true
+		}
 	}
 
-	mu.Lock()
-	metrics.Disk = diskMetrics
-	mu.Unlock()
+	return diskMetrics, nil
+}
 
-	return nil
+func (c *Collector) collectLoadMetrics(ctx context.Context) (LoadMetrics, error) {
+	loadStat, err := load.AvgWithContext(ctx)
+	if err != nil {
+		return LoadMetrics{}, err
+	}
+
+	cores := float64(runtime.NumCPU())
+	return LoadMetrics{
+		Load1:         loadStat.Load1,
+		Load5:         loadStat.Load5,
+		Load15:        loadStat.Load15,
+		Load1PerCore:  loadStat.Load1 / cores,
+		Load5PerCore:  loadStat.Load5 / cores,
+		Load15PerCore: loadStat.Load15 / cores,
+	}, nil
 }
 
-func (c *Collector) collectLoadMetrics(metrics *SystemMetrics, mu *sync.Mutex) error {
-	loadStat, err := load.Avg()
+// processSnapshot is the process collector's result: the top processes
+// by CPU and by memory, the watched process groups and the state counts,
+// the latter two taken over all processes
+type processSnapshot struct {
+	top     []ProcessMetrics
+	largest []ProcessMetrics
+	groups  []ProcessGroupMetrics
+	watched []WatchedProcessMetrics
+	states  *ProcessStateMetrics
+}
+
+func (c *Collector) collectProcessMetrics(ctx context.Context) (processSnapshot, error) {
+	processes, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return processSnapshot{}, err
+	}
+
+	// Total memory is read once instead of once per process, which is
+	// what process.MemoryPercent would do
+	vmStat, err := mem.VirtualMemoryWithContext(ctx)
 	if err != nil {
-		return err
+		return processSnapshot{}, err
 	}
 
-	mu.Lock()
-	metrics.Load = LoadMetrics{
-		Load1:  loadStat.Load1,
-		Load5:  loadStat.Load5,
-		Load15: loadStat.Load15,
+	processMetrics, states := c.sampleProcesses(ctx, processes, vmStat.Total)
+	c.addProcessIORates(processMetrics, c.clock.Now())
+	watched := c.matchWatches(ctx, processMetrics)
+
+	// Watches and state counts see every process, the rest only those the
+	// include and exclude patterns let through
+	processMetrics = c.procFilter.apply(processMetrics)
+
+	// Groups sum every process, before the top N cut
+	groups := c.groupProcesses(processMetrics)
+
+	// The largest processes by memory, however idle, for per-process leak
+	// detection
+	var largest []ProcessMetrics
+	if c.config.ProcessLeakMB > 0 {
+		largest = largestProcesses(processMetrics, c.config.TopProcessCount)
+		c.redactor.redactProcesses(largest)
+	}
+
+	// The busiest by disk I/O are reported too, however little CPU they
+	// use, so alerts can name the process hammering the disk
+	busiestIO := busiestIOProcesses(processMetrics, c.config.TopProcessCount)
+
+	// FD counts, thread counts and start times are expensive, so they are
+	// only gathered for every process when the ranking needs them
+	sortBy := c.config.ProcessSort
+	switch sortBy {
+	case SortByFDs:
+		addProcessFileDescriptors(processMetrics)
+	case SortByThreads, SortByAge:
+		addProcessDetails(ctx, processMetrics)
+	}
+
+	top := RankProcesses(processMetrics, sortBy, c.config.TopProcessCount)
+	seen := make(map[int32]bool, len(top))
+	for _, p := range top {
+		seen[p.PID] = true
+	}
+	for _, p := range busiestIO {
+		if !seen[p.PID] {
+			top = append(top, p)
+		}
+	}
+	processMetrics = top
+
+	if sortBy != SortByFDs {
+		addProcessFileDescriptors(processMetrics)
+	}
+	if sortBy != SortByThreads && sortBy != SortByAge {
+		addProcessDetails(ctx, processMetrics)
 	}
-	mu.Unlock()
+	if c.config.CollectCmdline {
+		addProcessCmdlines(ctx, processMetrics)
+	}
+
+	// Nothing sensitive may leave the collector
+	c.redactor.redactProcesses(processMetrics)
 
-	return nil
+	return processSnapshot{top: processMetrics, largest: largest, groups: groups, watched: watched, states: states}, nil
 }
 
-func (c *Collector) collectProcessMetrics(metrics *SystemMetrics, mu *sync.Mutex) error {
-	processes, err := process.Processes()
-	if err != nil {
-		return err
+// sampleProcesses reads per-process metrics and states using a bounded
+// pool of workers so the per-process syscalls overlap. Processes still
+// being read when processCollectTimeout expires are left out of the result.
+func (c *Collector) sampleProcesses(ctx context.Context, processes []*process.Process, totalMemory uint64) ([]ProcessMetrics, *ProcessStateMetrics) {
+	ctx, cancel := context.WithTimeout(ctx, processCollectTimeout)
+	defer cancel()
+
+	var (
+		resultsMu sync.Mutex
+		results   = make([]ProcessMetrics, 0, len(processes))
+		states    processStates
+	)
+
+	// A process whose sampling panics is skipped like one that exited,
+	// rather than taking the whole process list down with it
+	readProcess := func(p *process.Process) (state string, parent int32, pm ProcessMetrics, ok bool) {
+		defer func() {
+			if recover() != nil {
+				state, parent, ok = "", 0, false
+			}
+		}()
+		state, parent = states.read(ctx, p)
+		pm, ok = sampleProcess(ctx, p, totalMemory)
+		if ok {
+			c.readGroupKey(ctx, p, &pm)
+		}
+		return state, parent, pm, ok
 	}
 
-	var processMetrics []ProcessMetrics
+	forEachConcurrently(ctx, processes, c.config.ProcessWorkers, func(p *process.Process) {
+		state, parent, pm, ok := readProcess(p)
 
-	for _, p := range processes {
-		name, _ := p.Name()
-		if name == "" {
-			continue
+		resultsMu.Lock()
+		states.add(state, parent)
+		if ok {
+			results = append(results, pm)
 		}
+		resultsMu.Unlock()
+	})
 
-		cpuPercent, err := p.CPUPercent()
-		if err != nil {
-			continue
+	resultsMu.Lock()
+	defer resultsMu.Unlock()
+	return append([]ProcessMetrics(nil), results...), states.metrics(ctx, c.redactor)
+}
+
+// forEachConcurrently calls visit for every item on a pool of workers. It
+// returns once every item was visited or ctx is done; items not handed
+// out by then are skipped, and visits still running finish in the
+// background, so visit must do its own locking.
+func forEachConcurrently[T any](ctx context.Context, items []T, workers int, visit func(T)) {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	jobs := make(chan T)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				visit(item)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, item := range items {
+			select {
+			case jobs <- item:
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
 
-		memInfo, err := p.MemoryInfo()
-		if err != nil {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// sampleProcess reads a single process. It reports false if the process
+// has no name or any of the reads fail (e.g. it exited meanwhile).
+func sampleProcess(ctx context.Context, p *process.Process, totalMemory uint64) (ProcessMetrics, bool) {
+	name, _ := p.NameWithContext(ctx)
+	if name == "" {
+		return ProcessMetrics{}, false
+	}
+
+	cpuPercent, err := p.CPUPercentWithContext(ctx)
+	if err != nil {
+		return ProcessMetrics{}, false
+	}
+
+	memInfo, err := p.MemoryInfoWithContext(ctx)
+	if err != nil {
+		return ProcessMetrics{}, false
+	}
+
+	var memPercent float64
+	if totalMemory > 0 {
+		memPercent = float64(memInfo.RSS) / float64(totalMemory) * 100
+	}
+
+	pm := ProcessMetrics{
+		PID:           p.Pid,
+		Name:          name,
+		CPUPercent:    cpuPercent,
+		MemoryMB:      float64(memInfo.RSS) / (1024 * 1024),
+		MemoryPercent: memPercent,
+	}
+
+	// Bytes actually read from and written to storage, not through the
+	// page cache; other users' counters need root
+	if io, err := p.IOCountersWithContext(ctx); err == nil {
+		pm.ioRead, pm.ioWrite, pm.ioKnown = io.ReadBytes, io.WriteBytes, true
+	}
+	return pm, true
+}
+
+func (c *Collector) collectNetworkMetrics(ctx context.Context) ([]NetworkMetrics, error) {
+	counters, err := net.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	now := c.clock.Now()
+	elapsed := now.Sub(c.prevNetTime).Seconds()
+
+	var networkMetrics []NetworkMetrics
+	current := make(map[string]net.IOCountersStat, len(counters))
+	for _, counter := range counters {
+		if isLoopback(counter.Name) {
 			continue
 		}
+		current[counter.Name] = counter
+
+		nic := NetworkMetrics{
+			Interface:   counter.Name,
+			BytesSent:   counter.BytesSent,
+			BytesRecv:   counter.BytesRecv,
+			PacketsSent: counter.PacketsSent,
+			PacketsRecv: counter.PacketsRecv,
+			ErrorsIn:    counter.Errin,
+			ErrorsOut:   counter.Errout,
+			DropsIn:     counter.Dropin,
+			DropsOut:    counter.Dropout,
+			SpeedMbps:   linkSpeedMbps(counter.Name),
+		}
 
-		memPercent, err := p.MemoryPercent()
-		if err != nil {
-			continue
+		// Counters reset when an interface is re-created; skip the rate then
+		if prev, ok := c.prevNetCounters[counter.Name]; ok && elapsed > 0 &&
+			counter.BytesSent >= prev.BytesSent && counter.BytesRecv >= prev.BytesRecv {
+			nic.SentMbps = float64(counter.BytesSent-prev.BytesSent) * 8 / 1e6 / elapsed
+			nic.RecvMbps = float64(counter.BytesRecv-prev.BytesRecv) * 8 / 1e6 / elapsed
+			if nic.SpeedMbps > 0 {
+				nic.UtilizationPercent = max(nic.SentMbps, nic.RecvMbps) / nic.SpeedMbps * 100
+			}
 		}
 
-		processMetrics = append(processMetrics, ProcessMetrics{
-			PID:           p.Pid,
-			Name:          name,
-			CPUPercent:    cpuPercent,
-			MemoryMB:      float64(memInfo.RSS) / (1024 * 1024),
-			MemoryPercent: float64(memPercent),
-		})
+		networkMetrics = append(networkMetrics, nic)
 	}
 
-	// Sort by CPU usage and take top N
-	sort.Slice(processMetrics, func(i, j int) bool {
-		return processMetrics[i].CPUPercent > processMetrics[j].CPUPercent
-	})
+	c.prevNetCounters = current
+	c.prevNetTime = now
 
-	if len(processMetrics) > c.config.TopProcessCount {
-		processMetrics = processMetrics[:c.config.TopProcessCount]
-	}
+	return networkMetrics, nil
+}
 
-	mu.Lock()
-	metrics.Processes = processMetrics
-	mu.Unlock()
+// isLoopback reports whether an interface name is a loopback device
+func isLoopback(name string) bool {
+	return name == "lo" || strings.HasPrefix(name, "lo0") || strings.HasPrefix(name, "Loopback")
+}
 
-	return nil
+// addProcessCmdlines reads the command lines of the given processes.
+// Processes that exited meanwhile keep an empty command line.
+func addProcessCmdlines(ctx context.Context, processes []ProcessMetrics) {
+	for i := range processes {
+		p, err := process.NewProcessWithContext(ctx, processes[i].PID)
+		if err != nil {
+			continue
+		}
+		processes[i].Cmdline, _ = p.CmdlineWithContext(ctx)
+	}
 }
 
 // GetSystemInfo returns basic system information
@@ -274,5 +692,6 @@ func GetSystemInfo() (map[string]interface{}, error) {
 		"os":              hostInfo.OS,
 		"kernel_version":  hostInfo.KernelVersion,
 		"uptime_hours":    float64(hostInfo.Uptime) / 3600,
+		"virtualization":  map[string]string{"system": hostInfo.VirtualizationSystem, "role": hostInfo.VirtualizationRole},
 	}, nil
 }
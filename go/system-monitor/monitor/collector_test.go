@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syntheticProcesses is a process list with many ties in every ranked
+// metric, so only the PID tie-break decides the order among them
+func syntheticProcesses(n int) []ProcessMetrics {
+	boot := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	processes := make([]ProcessMetrics, n)
+	for i := range processes {
+		pid := int32(n - i) // listed in reverse, as readers may finish in any order
+		processes[i] = ProcessMetrics{
+			PID:          pid,
+			Name:         fmt.Sprintf("proc-%d", pid),
+			CPUPercent:   float64(pid % 7),
+			MemoryMB:     float64(pid%11) * 64,
+			ReadMBPerSec: float64(pid % 5),
+			OpenFDs:      int32(pid % 13),
+			Threads:      int32(pid % 3),
+			StartedAt:    boot.Add(time.Duration(pid%17) * time.Minute),
+		}
+	}
+	return processes
+}
+
+// sampleSynthetic reads the synthetic processes on workers goroutines
+// the way sampleProcesses does, each read taking latency as the /proc
+// reads of a real process would
+func sampleSynthetic(source []ProcessMetrics, workers int, latency time.Duration) []ProcessMetrics {
+	var (
+		mu      sync.Mutex
+		results = make([]ProcessMetrics, 0, len(source))
+	)
+	forEachConcurrently(context.Background(), source, workers, func(p ProcessMetrics) {
+		if latency > 0 {
+			time.Sleep(latency)
+		}
+		mu.Lock()
+		results = append(results, p)
+		mu.Unlock()
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]ProcessMetrics(nil), results...)
+}
+
+func TestRankProcessesIndependentOfWorkers(t *testing.T) {
+	source := syntheticProcesses(500)
+	for _, by := range []ProcessSort{SortByCPU, SortByMemory, SortByIO, SortByFDs, SortByThreads, SortByAge} {
+		want := RankProcesses(sampleSynthetic(source, 1, 0), by, 20)
+		for _, workers := range []int{2, 8, 32} {
+			for run := 0; run < 5; run++ {
+				got := RankProcesses(sampleSynthetic(source, workers, time.Microsecond), by, 20)
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("sort %q with %d workers: top processes differ from 1 worker", by, workers)
+				}
+			}
+		}
+	}
+}
+
+func TestForEachConcurrentlyVisitsEveryItem(t *testing.T) {
+	source := syntheticProcesses(1000)
+	for _, workers := range []int{0, 1, 8} {
+		if got := len(sampleSynthetic(source, workers, 0)); got != len(source) {
+			t.Errorf("%d workers visited %d of %d processes", workers, got, len(source))
+		}
+	}
+}
+
+// BenchmarkCollectProcesses samples and ranks a synthetic list of 500
+// processes whose reads each block for 50µs
+func BenchmarkCollectProcesses(b *testing.B) {
+	source := syntheticProcesses(500)
+	for _, workers := range []int{1, 4, DefaultConfig().ProcessWorkers, 32} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				RankProcesses(sampleSynthetic(source, workers, 50*time.Microsecond), SortByCPU, DefaultConfig().TopProcessCount)
+			}
+		})
+	}
+}
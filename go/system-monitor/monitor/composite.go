@@ -0,0 +1,142 @@
+package monitor
+
+import "fmt"
+
+// compositeCheck recognises a higher-level condition from alerts raised
+// together. It returns the composite alert and the indexes of the alerts it
+// replaces, or nil when the condition is not present.
+type compositeCheck func(metrics *SystemMetrics, alerts []Alert) (*Alert, []int)
+
+// compositeChecks run in order; an alert folded into one composite is not
+// available to the next
+var compositeChecks = []compositeCheck{
+	diskSaturation,
+	memoryExhaustion,
+	cpuStarvation,
+}
+
+// combineAlerts replaces alerts that describe one underlying problem with a
+// single composite alert listing them as its contributing conditions
+func combineAlerts(metrics *SystemMetrics, alerts []Alert) []Alert {
+	for _, check := range compositeChecks {
+		composite, replaced := check(metrics, alerts)
+		if composite == nil {
+			continue
+		}
+
+		drop := make(map[int]bool, len(replaced))
+		for _, i := range replaced {
+			drop[i] = true
+			composite.Contributing = append(composite.Contributing, alerts[i])
+			if severityRank(alerts[i].Level) > severityRank(composite.Level) {
+				composite.Level = alerts[i].Level
+			}
+		}
+
+		kept := make([]Alert, 0, len(alerts)-len(replaced)+1)
+		for i, alert := range alerts {
+			if !drop[i] {
+				kept = append(kept, alert)
+			}
+		}
+		alerts = append(kept, *composite)
+	}
+	return alerts
+}
+
+// findAlert returns the index of the most severe, then highest-valued,
+// alert of one of the categories, or -1
+func findAlert(alerts []Alert, categories ...string) int {
+	found := -1
+	for i, alert := range alerts {
+		match := false
+		for _, category := range categories {
+			match = match || alert.Category == category
+		}
+		if !match {
+			continue
+		}
+		if found < 0 || severityRank(alert.Level) > severityRank(alerts[found].Level) ||
+			(alert.Level == alerts[found].Level && alert.Value > alerts[found].Value) {
+			found = i
+		}
+	}
+	return found
+}
+
+// diskSaturation: processes queueing on I/O (high load and iowait) while a
+// mount is critically full, typically a runaway writer or a log storm. The
+// load itself never alerts, so it is listed as a contributing condition
+// without replacing anything.
+func diskSaturation(metrics *SystemMetrics, alerts []Alert) (*Alert, []int) {
+	if metrics.CPU.Cores == 0 {
+		return nil, nil
+	}
+	loadPerCore := metrics.Load.Load1 / float64(metrics.CPU.Cores)
+	if loadPerCore <= loadPerCoreWarning {
+		return nil, nil
+	}
+
+	iowait := findAlert(alerts, "iowait")
+	disk := findAlert(alerts, "disk", "inodes")
+	if iowait < 0 || disk < 0 || alerts[disk].Level != "critical" {
+		return nil, nil
+	}
+
+	full := alerts[disk]
+	return &Alert{
+		Level:    "critical",
+		Category: "disk_saturation",
+		Resource: full.Resource,
+		Message: fmt.Sprintf("Disk saturation on %s: load %.1f per core with %.1f%% I/O wait while %s is %.1f%% full",
+			full.Resource, loadPerCore, alerts[iowait].Value, full.Resource, full.Value),
+		Value:     full.Value,
+		Threshold: full.Threshold,
+		Timestamp: metrics.Timestamp,
+		Contributing: []Alert{{
+			Level:     "warning",
+			Category:  "load",
+			Message:   fmt.Sprintf("Load is %.1f per core", loadPerCore),
+			Value:     loadPerCore,
+			Threshold: loadPerCoreWarning,
+			Timestamp: metrics.Timestamp,
+		}},
+	}, []int{iowait, disk}
+}
+
+// memoryExhaustion: memory is full and the kernel is thrashing swap to
+// make room
+func memoryExhaustion(metrics *SystemMetrics, alerts []Alert) (*Alert, []int) {
+	memory := findAlert(alerts, "memory")
+	swap := findAlert(alerts, "swap")
+	if memory < 0 || swap < 0 {
+		return nil, nil
+	}
+
+	return &Alert{
+		Category: "memory_exhaustion",
+		Message: fmt.Sprintf("Memory exhausted: %.1f%% used and swapping %.0f pages/s",
+			alerts[memory].Value, alerts[swap].Value),
+		Value:     alerts[memory].Value,
+		Threshold: alerts[memory].Threshold,
+		Timestamp: metrics.Timestamp,
+	}, []int{memory, swap}
+}
+
+// cpuStarvation: the CPU looks busy because the hypervisor withholds it
+func cpuStarvation(metrics *SystemMetrics, alerts []Alert) (*Alert, []int) {
+	cpu := findAlert(alerts, "cpu")
+	steal := findAlert(alerts, "steal")
+	if cpu < 0 || steal < 0 {
+		return nil, nil
+	}
+
+	return &Alert{
+		Category: "cpu_starvation",
+		Message: fmt.Sprintf("CPU starved: %.1f%% busy while the hypervisor steals %.1f%%",
+			alerts[cpu].Value, alerts[steal].Value),
+		Value:     alerts[steal].Value,
+		Threshold: alerts[steal].Threshold,
+		Timestamp: metrics.Timestamp,
+	}, []int{cpu, steal}
+}
@@ -0,0 +1,180 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate checks that all thresholds and limits are within range. All
+// problems are reported together rather than just the first one.
+func (c Config) Validate() error {
+	var errs []error
+
+	percent := func(name string, value float64) {
+		if value < 0 || value > 100 {
+			errs = append(errs, fmt.Errorf("%s must be between 0 and 100, got %g", name, value))
+		}
+	}
+	percent("cpu_threshold", c.CPUThreshold)
+	percent("memory_threshold", c.MemoryThreshold)
+	percent("disk_threshold", c.DiskThreshold)
+	percent("inode_threshold", c.InodeThreshold)
+	percent("iowait_threshold", c.IOWaitThreshold)
+	percent("steal_threshold", c.StealThreshold)
+	percent("fd_threshold", c.FDThreshold)
+	percent("cpu_throttle_threshold", c.CPUThrottleThreshold)
+	percent("battery_threshold", c.BatteryThreshold)
+	percent("pressure_threshold", c.PressureThreshold)
+	percent("gpu_threshold", c.GPUThreshold)
+	percent("bandwidth_threshold", c.BandwidthThreshold)
+	percent("conntrack_threshold", c.ConntrackThreshold)
+	percent("retransmit_threshold", c.RetransmitThreshold)
+	percent("smart_wear_threshold", c.SMARTWearThreshold)
+	percent("pool_fragmentation_threshold", c.PoolFragmentationThreshold)
+	if c.GPUTempThreshold <= 0 {
+		errs = append(errs, fmt.Errorf("gpu_temperature_threshold must be positive, got %g", c.GPUTempThreshold))
+	}
+	if c.TemperatureThreshold <= 0 {
+		errs = append(errs, fmt.Errorf("temperature_threshold must be positive, got %g", c.TemperatureThreshold))
+	}
+
+	if c.SwapRateThreshold < 0 {
+		errs = append(errs, fmt.Errorf("swap_rate_threshold must not be negative, got %g", c.SwapRateThreshold))
+	}
+	if c.ProcessLeakMB < 0 {
+		errs = append(errs, fmt.Errorf("process_leak_mb must not be negative, got %g", c.ProcessLeakMB))
+	}
+	if c.ZombieThreshold < 0 {
+		errs = append(errs, fmt.Errorf("zombie_threshold must not be negative, got %d", c.ZombieThreshold))
+	}
+	if c.CloseWaitThreshold < 0 {
+		errs = append(errs, fmt.Errorf("close_wait_threshold must not be negative, got %d", c.CloseWaitThreshold))
+	}
+
+	if c.TopProcessCount <= 0 {
+		errs = append(errs, fmt.Errorf("top_process_count must be positive, got %d", c.TopProcessCount))
+	}
+	if c.ProcessWorkers <= 0 {
+		errs = append(errs, fmt.Errorf("process_workers must be positive, got %d", c.ProcessWorkers))
+	}
+	if c.CollectorTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("collector_timeout must be a positive number of seconds, got %d", c.CollectorTimeout))
+	}
+	if c.HistoryWindow < 4 {
+		// The memory leak check compares the last 4 snapshots
+		errs = append(errs, fmt.Errorf("history_window must be at least 4, got %d", c.HistoryWindow))
+	}
+	if c.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("interval must be a positive number of seconds, got %d", c.Interval))
+	}
+	if c.AdaptiveInterval {
+		if c.MinInterval <= 0 {
+			errs = append(errs, fmt.Errorf("min_interval must be a positive number of seconds, got %d", c.MinInterval))
+		}
+		if c.MinInterval > c.Interval || c.Interval > c.MaxInterval {
+			errs = append(errs, fmt.Errorf("adaptive interval requires min_interval <= interval <= max_interval, got %d <= %d <= %d",
+				c.MinInterval, c.Interval, c.MaxInterval))
+		}
+	}
+	errs = append(errs, c.Redaction.validate()...)
+	errs = append(errs, c.validateMounts()...)
+	errs = append(errs, c.validateAnomaly()...)
+	errs = append(errs, c.validateSeasonality()...)
+	errs = append(errs, c.validateHysteresis()...)
+	errs = append(errs, c.validateCertificates()...)
+	errs = append(errs, c.validatePing()...)
+	errs = append(errs, c.validateSystemd()...)
+	errs = append(errs, c.validateWindows()...)
+	errs = append(errs, c.validateLogs()...)
+	errs = append(errs, c.validatePaths()...)
+	errs = append(errs, c.validateNTP()...)
+	errs = append(errs, c.validateProcessRanking()...)
+	errs = append(errs, c.validateProcessGroups()...)
+	errs = append(errs, c.validateKubernetes()...)
+	errs = append(errs, c.validateSessions()...)
+	errs = append(errs, c.validateUpdates()...)
+	errs = append(errs, c.validateExposure()...)
+	errs = append(errs, c.validateEntropy()...)
+	errs = append(errs, c.validateActivity()...)
+	errs = append(errs, c.validateTCP()...)
+	errs = append(errs, c.Escalation.validate()...)
+	if c.DiskForecastHours < 0 {
+		errs = append(errs, fmt.Errorf("disk_forecast_hours must not be negative, got %d", c.DiskForecastHours))
+	}
+	if c.AlertCooldown < 0 {
+		errs = append(errs, fmt.Errorf("alert_cooldown must not be negative, got %d", c.AlertCooldown))
+	}
+
+	watches := make(map[string]bool, len(c.ProcessWatches))
+	for i, watch := range c.ProcessWatches {
+		if err := watch.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("process_watches[%d]: %w", i, err))
+		}
+		if watches[watch.Name] {
+			errs = append(errs, fmt.Errorf("process_watches[%d]: duplicate name %q", i, watch.Name))
+		}
+		watches[watch.Name] = true
+	}
+
+	// HTTP, port and DNS probes share one namespace in alerts and reports
+	probes := make(map[string]bool, len(c.HTTPProbes)+len(c.PortProbes)+len(c.DNSProbes))
+	for i, probe := range c.HTTPProbes {
+		if err := probe.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("http_probes[%d]: %w", i, err))
+		}
+		if probes[probe.Name] {
+			errs = append(errs, fmt.Errorf("http_probes[%d]: duplicate name %q", i, probe.Name))
+		}
+		probes[probe.Name] = true
+	}
+	for i, probe := range c.PortProbes {
+		if err := probe.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("port_probes[%d]: %w", i, err))
+		}
+		if probes[probe.Name] {
+			errs = append(errs, fmt.Errorf("port_probes[%d]: duplicate name %q", i, probe.Name))
+		}
+		probes[probe.Name] = true
+	}
+	for i, probe := range c.DNSProbes {
+		if err := probe.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("dns_probes[%d]: %w", i, err))
+		}
+		if probes[probe.Name] {
+			errs = append(errs, fmt.Errorf("dns_probes[%d]: duplicate name %q", i, probe.Name))
+		}
+		probes[probe.Name] = true
+	}
+
+	for i, threshold := range c.DynamicThresholds {
+		if err := threshold.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("dynamic_thresholds[%d]: %w", i, err))
+		}
+	}
+
+	rules := make(map[string]bool, len(c.AlertRules))
+	for i, rule := range c.AlertRules {
+		if err := rule.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("alert_rules[%d]: %w", i, err))
+		}
+		if rules[rule.Name] {
+			errs = append(errs, fmt.Errorf("alert_rules[%d]: duplicate name %q", i, rule.Name))
+		}
+		rules[rule.Name] = true
+	}
+
+	for i, window := range c.SuppressionWindows {
+		if _, err := parseSuppressionWindow(window); err != nil {
+			errs = append(errs, fmt.Errorf("suppression_windows[%d]: %w", i, err))
+		}
+	}
+
+	w := c.HealthWeights
+	if w.CPU < 0 || w.Memory < 0 || w.Disk < 0 || w.Load < 0 || w.Alerts < 0 {
+		errs = append(errs, fmt.Errorf("health_weights must not be negative"))
+	} else if w.CPU+w.Memory+w.Disk+w.Load+w.Alerts == 0 {
+		errs = append(errs, fmt.Errorf("health_weights must not all be zero"))
+	}
+
+	return errors.Join(errs...)
+}
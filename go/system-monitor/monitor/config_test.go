@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string // empty when the config is valid
+	}{
+		{"defaults", func(c *Config) {}, ""},
+
+		{"threshold 0 alerts on any usage", func(c *Config) { c.CPUThreshold = 0 }, ""},
+		{"threshold 100", func(c *Config) { c.MemoryThreshold = 100 }, ""},
+		{"threshold above 100", func(c *Config) { c.DiskThreshold = 100.5 }, "disk_threshold must be between 0 and 100, got 100.5"},
+		{"negative threshold", func(c *Config) { c.CPUThreshold = -1 }, "cpu_threshold must be between 0 and 100, got -1"},
+		{"negative retransmit threshold", func(c *Config) { c.RetransmitThreshold = -5 }, "retransmit_threshold must be between 0 and 100"},
+
+		{"negative count", func(c *Config) { c.ZombieThreshold = -1 }, "zombie_threshold must not be negative, got -1"},
+		{"zero count", func(c *Config) { c.CloseWaitThreshold = 0 }, ""},
+		{"negative rate", func(c *Config) { c.SwapRateThreshold = -0.5 }, "swap_rate_threshold must not be negative, got -0.5"},
+		{"zero temperature", func(c *Config) { c.TemperatureThreshold = 0 }, "temperature_threshold must be positive, got 0"},
+
+		{"zero interval", func(c *Config) { c.Interval = 0 }, "interval must be a positive number of seconds, got 0"},
+		{"negative interval", func(c *Config) { c.Interval = -30 }, "interval must be a positive number of seconds, got -30"},
+		{"interval of 1", func(c *Config) { c.Interval = 1 }, ""},
+		{"zero workers", func(c *Config) { c.ProcessWorkers = 0 }, "process_workers must be positive, got 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.modify(&config)
+			err := config.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Validate() = %v, want nil", err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("Validate() = nil, want %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestZeroUsageThresholdAlertsOnAnyUsage(t *testing.T) {
+	tests := []struct {
+		category string
+		modify   func(*Config)
+		metrics  SystemMetrics
+	}{
+		{"cpu", func(c *Config) { c.CPUThreshold = 0 }, SystemMetrics{CPU: CPUMetrics{UsagePercent: 1}}},
+		{"memory", func(c *Config) { c.MemoryThreshold = 0 }, SystemMetrics{Memory: MemoryMetrics{UsedPercent: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.modify(&config)
+			if err := config.Validate(); err != nil {
+				t.Fatalf("Validate() = %v, want nil", err)
+			}
+			for _, alert := range NewAnalyzer(config).AnalyzeMetrics(&tt.metrics) {
+				if alert.Category == tt.category {
+					return
+				}
+			}
+			t.Errorf("no %s alert at 1%% usage with a threshold of 0", tt.category)
+		})
+	}
+}
+
+func TestConfigValidateReportsEveryError(t *testing.T) {
+	config := DefaultConfig()
+	config.CPUThreshold = -1
+	config.MemoryThreshold = 101
+	config.Interval = 0
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want errors")
+	}
+	for _, want := range []string{"cpu_threshold", "memory_threshold", "interval"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, missing %s", err, want)
+		}
+	}
+}
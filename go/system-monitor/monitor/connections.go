@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// ConnectionMetrics holds TCP socket states and connection tracking usage
+type ConnectionMetrics struct {
+	// TCP sockets (IPv4 and IPv6) by state, e.g. "ESTABLISHED", "TIME_WAIT"
+	States         map[string]int `json:"states"`
+	ListeningPorts []uint32       `json:"listening_ports,omitempty"`
+
+	// Netfilter connection tracking table, zero where conntrack is not
+	// loaded and on non-Linux platforms
+	ConntrackCount   uint64  `json:"conntrack_count,omitempty"`
+	ConntrackMax     uint64  `json:"conntrack_max,omitempty"`
+	ConntrackPercent float64 `json:"conntrack_percent,omitempty"`
+}
+
+func (c *Collector) collectConnectionMetrics(ctx context.Context) (*ConnectionMetrics, error) {
+	connections, err := readConnections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(connections.ListeningPorts, func(i, j int) bool {
+		return connections.ListeningPorts[i] < connections.ListeningPorts[j]
+	})
+
+	connections.ConntrackCount, connections.ConntrackMax = readConntrack()
+	if connections.ConntrackMax > 0 {
+		connections.ConntrackPercent = float64(connections.ConntrackCount) / float64(connections.ConntrackMax) * 100
+	}
+
+	return connections, nil
+}
+
+// checkConnections alerts on sockets piling up in CLOSE_WAIT, which means
+// an application is not closing connections its peers already closed,
+// and on a nearly full conntrack table, which drops new connections
+func (a *Analyzer) checkConnections(metrics *SystemMetrics) []Alert {
+	connections := metrics.Connections
+	if connections == nil {
+		return nil
+	}
+
+	var alerts []Alert
+
+	threshold := float64(a.config.CloseWaitThreshold)
+	closeWait := func(m SystemMetrics) float64 {
+		if m.Connections == nil {
+			return 0
+		}
+		return float64(m.Connections.States["CLOSE_WAIT"])
+	}
+	if a.config.CloseWaitThreshold > 0 && closeWait(*metrics) > threshold {
+		level := "warning"
+		if a.isSustained(closeWait, threshold) {
+			level = "critical"
+		}
+
+		alerts = append(alerts, Alert{
+			Level:    level,
+			Category: "connections",
+			Resource: "close_wait",
+			Message: fmt.Sprintf("%d TCP connections stuck in CLOSE_WAIT (%d established)",
+				connections.States["CLOSE_WAIT"], connections.States["ESTABLISHED"]),
+			Value:     closeWait(*metrics),
+			Threshold: threshold,
+			Timestamp: metrics.Timestamp,
+		})
+	}
+
+	if connections.ConntrackMax > 0 && connections.ConntrackPercent > a.config.ConntrackThreshold {
+		level := "warning"
+		if connections.ConntrackPercent > 95 {
+			level = "critical"
+		}
+
+		alerts = append(alerts, Alert{
+			Level:    level,
+			Category: "conntrack",
+			Resource: "nf_conntrack",
+			Message: fmt.Sprintf("Connection tracking table is %.1f%% full (%d / %d)",
+				connections.ConntrackPercent, connections.ConntrackCount, connections.ConntrackMax),
+			Value:     connections.ConntrackPercent,
+			Threshold: a.config.ConntrackThreshold,
+			Timestamp: metrics.Timestamp,
+		})
+	}
+
+	return alerts
+}
@@ -0,0 +1,99 @@
+//go:build linux
+
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// tcpStates maps the hex states of /proc/net/tcp to their names
+var tcpStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+}
+
+// readConnections counts TCP sockets straight from /proc/net/tcp and
+// tcp6, which is far cheaper than mapping every socket to its process
+func readConnections(ctx context.Context) (*ConnectionMetrics, error) {
+	connections := &ConnectionMetrics{States: make(map[string]int)}
+	listening := make(map[uint32]bool)
+
+	read := 0
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		file, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // IPv6 disabled
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Scan() // header
+		for scanner.Scan() {
+			// sl local_address rem_address st ...
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 4 {
+				continue
+			}
+			state, ok := tcpStates[fields[3]]
+			if !ok {
+				continue
+			}
+			connections.States[state]++
+
+			if state == "LISTEN" {
+				if i := strings.LastIndexByte(fields[1], ':'); i >= 0 {
+					if port, err := strconv.ParseUint(fields[1][i+1:], 16, 16); err == nil {
+						listening[uint32(port)] = true
+					}
+				}
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		read++
+	}
+	if read == 0 {
+		return nil, errors.New("no /proc/net/tcp tables")
+	}
+
+	for port := range listening {
+		connections.ListeningPorts = append(connections.ListeningPorts, port)
+	}
+	return connections, nil
+}
+
+// readConntrack reads the netfilter connection tracking table usage. Both
+// are zero when the nf_conntrack module is not loaded.
+func readConntrack() (count, max uint64) {
+	read := func(name string) uint64 {
+		data, err := os.ReadFile("/proc/sys/net/netfilter/" + name)
+		if err != nil {
+			return 0
+		}
+		value, _ := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		return value
+	}
+	return read("nf_conntrack_count"), read("nf_conntrack_max")
+}
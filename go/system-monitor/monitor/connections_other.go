@@ -0,0 +1,39 @@
+//go:build !linux
+
+package monitor
+
+import (
+	"context"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// readConnections counts TCP sockets through gopsutil
+func readConnections(ctx context.Context) (*ConnectionMetrics, error) {
+	stats, err := net.ConnectionsWithContext(ctx, "tcp")
+	if err != nil {
+		return nil, err
+	}
+
+	connections := &ConnectionMetrics{States: make(map[string]int)}
+	listening := make(map[uint32]bool)
+	for _, stat := range stats {
+		if stat.Status == "" {
+			continue
+		}
+		connections.States[stat.Status]++
+		if stat.Status == "LISTEN" {
+			listening[stat.Laddr.Port] = true
+		}
+	}
+
+	for port := range listening {
+		connections.ListeningPorts = append(connections.ListeningPorts, port)
+	}
+	return connections, nil
+}
+
+// readConntrack is only supported on Linux
+func readConntrack() (count, max uint64) {
+	return 0, 0
+}
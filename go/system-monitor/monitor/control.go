@@ -0,0 +1,129 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Control requests may be made from any goroutine while Run is looping.
+// They are queued and applied by Run between collections, so they never
+// race with a collection or analysis in progress; requests made before
+// Run starts are applied after its first collection.
+
+// Pause stops scheduled collections until Resume; OnPause is called at
+// every interval instead. CollectNow still takes a snapshot while paused.
+func (m *Monitor) Pause() {
+	m.request(func() { m.paused = true })
+}
+
+// Resume restarts scheduled collections from the next interval
+func (m *Monitor) Resume() {
+	m.request(func() { m.paused = false })
+}
+
+// CollectNow takes a snapshot right away instead of waiting for the rest
+// of the interval
+func (m *Monitor) CollectNow() {
+	m.request(func() { m.collectNow = true })
+}
+
+// SetInterval changes the base collection interval. The wait already in
+// progress is shortened or extended to match.
+func (m *Monitor) SetInterval(interval time.Duration) error {
+	if interval < time.Second {
+		return fmt.Errorf("interval must be at least 1s, got %s", interval)
+	}
+	m.request(func() {
+		m.config.Interval = int(interval / time.Second)
+		m.analyzer.config.Interval = m.config.Interval
+		m.setInterval(interval)
+	})
+	return nil
+}
+
+// SetConfig replaces the thresholds and other analysis settings without
+// losing the analyzer's history. Collector settings, such as enabled
+// collectors and process watches, and the history window keep the values
+// the monitor was created with.
+func (m *Monitor) SetConfig(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	m.request(func() {
+		base := config.Interval != m.config.Interval
+		m.config = config
+		m.analyzer.SetConfig(config)
+		if base {
+			m.setInterval(time.Duration(config.Interval) * time.Second)
+		}
+	})
+	return nil
+}
+
+func (m *Monitor) request(fn func()) {
+	m.controlMu.Lock()
+	m.pending = append(m.pending, fn)
+	m.controlMu.Unlock()
+
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// setInterval changes the current interval, moving the next collection
+// by the difference
+func (m *Monitor) setInterval(next time.Duration) {
+	if next == m.interval {
+		return
+	}
+	previous := m.interval
+	m.due = m.due.Add(next - previous)
+	m.interval = next
+	if m.OnIntervalChange != nil {
+		m.OnIntervalChange(previous, next)
+	}
+}
+
+// wait blocks until the next collection is due, applying control
+// requests as they arrive
+func (m *Monitor) wait(ctx context.Context) error {
+	m.due = m.clock.Now().Add(m.interval)
+	for {
+		if m.applyRequests() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-m.clock.After(m.due.Sub(m.clock.Now())):
+			if !m.paused {
+				return nil
+			}
+			m.due = m.clock.Now().Add(m.interval)
+			if m.OnPause != nil {
+				m.OnPause()
+			}
+		case <-m.wake:
+		}
+	}
+}
+
+// applyRequests applies queued control requests and reports whether one
+// asked to collect right away
+func (m *Monitor) applyRequests() bool {
+	m.controlMu.Lock()
+	pending := m.pending
+	m.pending = nil
+	m.controlMu.Unlock()
+
+	for _, fn := range pending {
+		fn()
+	}
+
+	now := m.collectNow
+	m.collectNow = false
+	return now
+}
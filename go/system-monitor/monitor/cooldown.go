@@ -0,0 +1,49 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// Cooldown rate-limits notifications so the same alert is only passed
+// through once per window. Alerts are identified by Alert.Key, so an
+// escalation from warning to critical is never held back.
+type Cooldown struct {
+	window   time.Duration
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+// NewCooldown creates a cooldown tracker with the given window
+func NewCooldown(window time.Duration) *Cooldown {
+	return &Cooldown{
+		window:   window,
+		lastSent: make(map[string]time.Time),
+	}
+}
+
+// Allow reports whether the alert may be sent, recording it if so.
+// The alert's own timestamp is used as the current time.
+func (c *Cooldown) Allow(alert Alert) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := alert.Key()
+	if last, ok := c.lastSent[key]; ok && alert.Timestamp.Sub(last) < c.window {
+		return false
+	}
+
+	c.lastSent[key] = alert.Timestamp
+	return true
+}
+
+// Filter returns the subset of alerts that are not in cooldown
+func (c *Cooldown) Filter(alerts []Alert) []Alert {
+	var allowed []Alert
+	for _, alert := range alerts {
+		if c.Allow(alert) {
+			allowed = append(allowed, alert)
+		}
+	}
+	return allowed
+}
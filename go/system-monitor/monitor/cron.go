@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed five-field cron expression: minute, hour, day
+// of month, month and day of week
+type cronSchedule struct {
+	minute, hour, dom, month, dow map[int]bool
+
+	// As in cron, when both day fields are restricted a day matching
+	// either of them matches
+	domAny, dowAny bool
+}
+
+// cronFields are the ranges of the five fields
+var cronFields = [5]struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are Sunday
+}
+
+// parseCron parses expressions such as "0 2 * * sun" or "*/15 8-18 * * 1-5".
+// Fields take *, numbers, ranges (a-b), steps (*/n, a-b/n) and lists of
+// those; the day of week also takes names (sun..sat).
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron %q must have 5 fields (minute hour day month weekday), got %d", expr, len(fields))
+	}
+
+	var sets [5]map[int]bool
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i].min, cronFields[i].max, i == 4)
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %s: %w", expr, cronFields[i].name, err)
+		}
+		sets[i] = set
+	}
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+
+	return &cronSchedule{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domAny: fields[2] == "*", dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int, weekday bool) (map[int]bool, error) {
+	value := func(text string) (int, error) {
+		if weekday {
+			if day, ok := weekdayNames[strings.ToLower(text)]; ok {
+				return int(day), nil
+			}
+		}
+		n, err := strconv.Atoi(text)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("%q is not a number from %d to %d", text, min, max)
+		}
+		return n, nil
+	}
+
+	set := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		from, to := min, max
+		if rangePart != "*" {
+			low, high, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if from, err = value(low); err != nil {
+				return nil, err
+			}
+			to = from
+			if isRange {
+				if to, err = value(high); err != nil {
+					return nil, err
+				}
+			} else if stepped {
+				to = max
+			}
+			if from > to {
+				return nil, fmt.Errorf("invalid range %q", rangePart)
+			}
+		}
+
+		for n := from; n <= to; n += step {
+			set[n] = true
+		}
+	}
+	return set, nil
+}
+
+// matches reports whether the schedule fires in the minute of t
+func (c *cronSchedule) matches(t time.Time) bool {
+	if !c.minute[t.Minute()] || !c.hour[t.Hour()] || !c.month[int(t.Month())] {
+		return false
+	}
+
+	dom, dow := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// firedWithin reports whether the schedule fired in the span ending at t,
+// checking minute by minute in loc
+func (c *cronSchedule) firedWithin(t time.Time, span time.Duration, loc *time.Location) bool {
+	t = t.In(loc).Truncate(time.Minute)
+	for start := t; t.Sub(start) < span; start = start.Add(-time.Minute) {
+		if c.matches(start) {
+			return true
+		}
+	}
+	return false
+}
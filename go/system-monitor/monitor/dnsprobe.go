@@ -0,0 +1,136 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DNSProbe resolves a name, through the system resolver or a given
+// server, and optionally checks the addresses it resolves to
+type DNSProbe struct {
+	Name   string `json:"name"`   // label used in alerts, unique among all probes
+	Host   string `json:"host"`   // name to resolve
+	Server string `json:"server"` // e.g. "10.0.0.2:53"; the system resolver by default
+
+	// Addresses the answer must include; any answer passes when empty
+	Expect []string `json:"expect"`
+
+	// Slower lookups count as failures; 0 means no limit
+	MaxLatencyMs float64 `json:"max_latency_ms"`
+
+	TimeoutSeconds int `json:"timeout"` // 5 by default
+
+	// Consecutive failed snapshots before alerting, 3 by default
+	Failures int `json:"failures"`
+}
+
+func (p DNSProbe) failures() int {
+	if p.Failures <= 0 {
+		return 3
+	}
+	return p.Failures
+}
+
+func (p DNSProbe) target() string {
+	if p.Server == "" {
+		return p.Host
+	}
+	return p.Host + " @" + p.Server
+}
+
+func (p DNSProbe) validate() error {
+	var errs []error
+	if p.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if p.Host == "" {
+		errs = append(errs, errors.New("host is required"))
+	}
+	if p.Server != "" {
+		if _, _, err := net.SplitHostPort(p.Server); err != nil {
+			errs = append(errs, fmt.Errorf("server: %w", err))
+		}
+	}
+	for _, address := range p.Expect {
+		if net.ParseIP(address) == nil {
+			errs = append(errs, fmt.Errorf("expect: %q is not an IP address", address))
+		}
+	}
+	if p.MaxLatencyMs < 0 || p.TimeoutSeconds < 0 || p.Failures < 0 {
+		errs = append(errs, errors.New("max_latency_ms, timeout and failures must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+func (p DNSProbe) run(ctx context.Context) ProbeMetrics {
+	result := ProbeMetrics{Name: p.Name, Type: "dns", Target: p.target()}
+	if p.validate() != nil {
+		result.Error = "invalid probe"
+		return result
+	}
+	timeout := 5 * time.Second
+	if p.TimeoutSeconds > 0 {
+		timeout = time.Duration(p.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resolver := net.DefaultResolver
+	if p.Server != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, p.Server)
+			},
+		}
+	}
+
+	start := time.Now()
+	addresses, err := resolver.LookupHost(ctx, p.Host)
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		var dnsErr *net.DNSError
+		switch {
+		case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+			result.Error = "NXDOMAIN"
+		case errors.As(err, &dnsErr) && dnsErr.IsTimeout:
+			result.Error = "timeout"
+		case errors.As(err, &dnsErr):
+			// The lookup error names the system resolver even when
+			// the probe asked another server
+			result.Error = dnsErr.Err
+		default:
+			result.Error = err.Error()
+		}
+		return result
+	}
+	result.Answers = addresses
+
+	for _, expected := range p.Expect {
+		if !resolvesTo(addresses, expected) {
+			result.Error = fmt.Sprintf("resolved to %s, missing %s", strings.Join(addresses, ", "), expected)
+			return result
+		}
+	}
+	if p.MaxLatencyMs > 0 && result.LatencyMs > p.MaxLatencyMs {
+		result.Error = fmt.Sprintf("took %.0f ms, over %.0f ms", result.LatencyMs, p.MaxLatencyMs)
+		return result
+	}
+
+	result.Up = true
+	return result
+}
+
+func resolvesTo(addresses []string, expected string) bool {
+	for _, address := range addresses {
+		if net.ParseIP(address).Equal(net.ParseIP(expected)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// dockerTimeout bounds a full container scan
+const dockerTimeout = 10 * time.Second
+
+// ContainerMetrics holds resource usage for a single running container.
+// CPU is averaged since the previous sample and zero on the first one.
+type ContainerMetrics struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	Image         string  `json:"image"`
+	CPUPercent    float64 `json:"cpu_percent"` // of the whole host, like `docker stats`
+	MemoryMB      float64 `json:"memory_mb"`
+	MemoryLimitMB float64 `json:"memory_limit_mb"`
+	MemoryPercent float64 `json:"memory_percent"` // of the container's limit
+	RestartCount  int     `json:"restart_count"`
+	OOMKilled     bool    `json:"oom_killed"` // the last exit was an OOM kill
+}
+
+// containerCPU is the cumulative CPU usage of a container at a sample
+type containerCPU struct {
+	total  uint64
+	system uint64
+}
+
+// dockerClient talks to the Docker Engine API over its unix socket
+type dockerClient struct {
+	http *http.Client
+}
+
+func newDockerClient(socket string) *dockerClient {
+	return &dockerClient{
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+func (d *dockerClient) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker"+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("docker %s: unexpected status %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+type dockerContainer struct {
+	ID    string   `json:"Id"`
+	Names []string `json:"Names"`
+	Image string   `json:"Image"`
+}
+
+type dockerInspect struct {
+	RestartCount int `json:"RestartCount"`
+	State        struct {
+		OOMKilled bool `json:"OOMKilled"`
+	} `json:"State"`
+}
+
+type dockerStats struct {
+	CPUStats struct {
+		CPUUsage struct {
+			TotalUsage uint64 `json:"total_usage"`
+		} `json:"cpu_usage"`
+		SystemUsage uint64 `json:"system_cpu_usage"`
+		OnlineCPUs  int    `json:"online_cpus"`
+	} `json:"cpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+}
+
+// collectContainerMetrics reports running Docker containers. Hosts
+// without Docker, or where the socket is not accessible, report none.
+func (c *Collector) collectContainerMetrics(ctx context.Context) ([]ContainerMetrics, error) {
+	ctx, cancel := context.WithTimeout(ctx, dockerTimeout)
+	defer cancel()
+
+	client := newDockerClient(c.config.DockerSocket)
+
+	var containers []dockerContainer
+	if err := client.get(ctx, "/containers/json", &containers); err != nil {
+		return nil, nil
+	}
+
+	current := make(map[string]containerCPU, len(containers))
+	containerMetrics := make([]ContainerMetrics, 0, len(containers))
+	for _, container := range containers {
+		cm := ContainerMetrics{
+			ID:    container.ID[:min(12, len(container.ID))],
+			Name:  container.ID,
+			Image: container.Image,
+		}
+		if len(container.Names) > 0 {
+			cm.Name = strings.TrimPrefix(container.Names[0], "/")
+		}
+
+		var inspect dockerInspect
+		if err := client.get(ctx, "/containers/"+container.ID+"/json", &inspect); err == nil {
+			cm.RestartCount = inspect.RestartCount
+			cm.OOMKilled = inspect.State.OOMKilled
+		}
+
+		var stats dockerStats
+		if err := client.get(ctx, "/containers/"+container.ID+"/stats?stream=false&one-shot=true", &stats); err == nil {
+			cpu := containerCPU{total: stats.CPUStats.CPUUsage.TotalUsage, system: stats.CPUStats.SystemUsage}
+			current[container.ID] = cpu
+
+			if prev, ok := c.prevContainerCPU[container.ID]; ok && cpu.system > prev.system && cpu.total >= prev.total {
+				cpus := float64(max(stats.CPUStats.OnlineCPUs, 1))
+				cm.CPUPercent = float64(cpu.total-prev.total) / float64(cpu.system-prev.system) * cpus * 100
+			}
+
+			// Page cache is reclaimable; subtract it like `docker stats` does
+			// (cgroup v1 "cache", cgroup v2 "inactive_file")
+			usage := stats.MemoryStats.Usage
+			if cache := stats.MemoryStats.Stats["inactive_file"] + stats.MemoryStats.Stats["cache"]; cache < usage {
+				usage -= cache
+			}
+			cm.MemoryMB = float64(usage) / (1024 * 1024)
+			cm.MemoryLimitMB = float64(stats.MemoryStats.Limit) / (1024 * 1024)
+			if stats.MemoryStats.Limit > 0 {
+				cm.MemoryPercent = float64(usage) / float64(stats.MemoryStats.Limit) * 100
+			}
+		}
+
+		containerMetrics = append(containerMetrics, cm)
+	}
+
+	c.prevContainerCPU = current
+
+	return containerMetrics, nil
+}
+
+// checkContainers alerts on containers that restarted since the previous
+// sample, critically so when they were OOM killed, and on containers close
+// to their memory limit
+func (a *Analyzer) checkContainers(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+
+	previous := make(map[string]int)
+	if a.history.len() >= 2 {
+		for _, container := range a.history.at(a.history.len() - 2).Containers {
+			previous[container.ID] = container.RestartCount
+		}
+	}
+
+	for _, container := range metrics.Containers {
+		if before, ok := previous[container.ID]; ok && container.RestartCount > before {
+			alert := Alert{
+				Level:    "warning",
+				Category: "container",
+				Resource: container.Name,
+				Message: fmt.Sprintf("Container %s (%s) restarted %d time(s) since the last sample, %d in total",
+					container.Name, container.Image, container.RestartCount-before, container.RestartCount),
+				Value:     float64(container.RestartCount - before),
+				Timestamp: metrics.Timestamp,
+			}
+			if container.OOMKilled {
+				alert.Level = "critical"
+				alert.Message = fmt.Sprintf("Container %s (%s) was OOM killed and restarted (limit %.0f MB)",
+					container.Name, container.Image, container.MemoryLimitMB)
+			}
+			alerts = append(alerts, alert)
+		}
+
+		// Containers without a limit report the host's memory as their
+		// limit, which checkMemoryUsage already covers
+		limited := container.MemoryLimitMB > 0 && container.MemoryLimitMB < metrics.Memory.TotalGB*1024
+		if limited && container.MemoryPercent > a.config.MemoryThreshold {
+			alerts = append(alerts, Alert{
+				Level:    "warning",
+				Category: "container",
+				Resource: container.Name,
+				Message: fmt.Sprintf("Container %s is using %.1f%% of its memory limit (%.0f / %.0f MB)",
+					container.Name, container.MemoryPercent, container.MemoryMB, container.MemoryLimitMB),
+				Value:     container.MemoryPercent,
+				Threshold: a.config.MemoryThreshold,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+
+	return alerts
+}
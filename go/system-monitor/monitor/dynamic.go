@@ -0,0 +1,152 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// DynamicThreshold alerts when a metric rises a margin above a percentile
+// of its own recent history, for hosts whose normal level varies too much
+// for a fixed threshold. Percentiles are computed from the SnapshotStore
+// given to Analyzer.UseStore.
+type DynamicThreshold struct {
+	Metric     string  `json:"metric"`     // a SeriesValueFor name, e.g. "cpu" or "disk:/var"
+	Percentile float64 `json:"percentile"` // 95 by default
+	Window     int     `json:"window"`     // seconds of history, 86400 (24h) by default
+	Margin     float64 `json:"margin"`     // in the metric's unit, e.g. percentage points
+	Level      string  `json:"level"`      // "warning" (default) or "critical"
+}
+
+const (
+	// dynamicRefresh is how often percentiles are recomputed from the store
+	dynamicRefresh = 15 * time.Minute
+
+	// dynamicMinSamples is the history a percentile needs to be trusted
+	dynamicMinSamples = 60
+)
+
+func (t DynamicThreshold) withDefaults() DynamicThreshold {
+	if t.Percentile == 0 {
+		t.Percentile = 95
+	}
+	if t.Window == 0 {
+		t.Window = 24 * 60 * 60
+	}
+	if t.Level == "" {
+		t.Level = "warning"
+	}
+	return t
+}
+
+func (t DynamicThreshold) validate() error {
+	var errs []error
+	if _, err := SeriesValueFor(t.Metric); err != nil {
+		errs = append(errs, fmt.Errorf("metric: %w", err))
+	}
+	if t.Percentile < 0 || t.Percentile > 100 {
+		errs = append(errs, fmt.Errorf("percentile must be between 0 and 100, got %g", t.Percentile))
+	}
+	if t.Window < 0 {
+		errs = append(errs, fmt.Errorf("window must not be negative, got %d", t.Window))
+	}
+	if t.Margin < 0 {
+		errs = append(errs, fmt.Errorf("margin must not be negative, got %g", t.Margin))
+	}
+	if t.Level != "" && t.Level != "warning" && t.Level != "critical" {
+		errs = append(errs, fmt.Errorf(`level must be "warning" or "critical", got %q`, t.Level))
+	}
+	return errors.Join(errs...)
+}
+
+// UseStore lets the analyzer compute Config.DynamicThresholds from the
+// snapshots in store
+func (a *Analyzer) UseStore(store SnapshotStore) {
+	a.store = store
+	a.percentiles, a.percentilesAt = nil, time.Time{}
+}
+
+// checkDynamic alerts on metrics above their dynamic thresholds
+func (a *Analyzer) checkDynamic(metrics *SystemMetrics) []Alert {
+	if a.store == nil || len(a.config.DynamicThresholds) == 0 {
+		return nil
+	}
+	if a.percentiles == nil || metrics.Timestamp.Sub(a.percentilesAt) >= dynamicRefresh {
+		a.refreshPercentiles(metrics.Timestamp)
+	}
+
+	var alerts []Alert
+	for i, threshold := range a.config.DynamicThresholds {
+		threshold = threshold.withDefaults()
+		p, ok := a.percentiles[i]
+		value, err := SeriesValueFor(threshold.Metric)
+		if !ok || err != nil {
+			continue
+		}
+		current, ok := value(*metrics)
+		if !ok || current <= p+threshold.Margin {
+			continue
+		}
+
+		window := formatETA(time.Duration(threshold.Window) * time.Second)
+		alerts = append(alerts, Alert{
+			Level:    threshold.Level,
+			Category: "dynamic",
+			Resource: threshold.Metric,
+			Message: fmt.Sprintf("%s is %.1f, %.1f above its p%g of %.1f over the last %s",
+				threshold.Metric, current, current-p, threshold.Percentile, p, window),
+			Value:     current,
+			Threshold: p + threshold.Margin,
+			Timestamp: metrics.Timestamp,
+		})
+	}
+	return alerts
+}
+
+// refreshPercentiles recomputes every dynamic threshold's percentile in a
+// single pass over the store. Thresholds with too little history get
+// none.
+func (a *Analyzer) refreshPercentiles(now time.Time) {
+	a.percentilesAt = now
+	a.percentiles = make(map[int]float64)
+
+	thresholds := make([]DynamicThreshold, len(a.config.DynamicThresholds))
+	values := make([]SeriesValue, len(thresholds))
+	samples := make([][]float64, len(thresholds))
+	longest := time.Duration(0)
+	for i, threshold := range a.config.DynamicThresholds {
+		thresholds[i] = threshold.withDefaults()
+		values[i], _ = SeriesValueFor(threshold.Metric)
+		longest = max(longest, time.Duration(thresholds[i].Window)*time.Second)
+	}
+
+	err := a.store.Each(now.Add(-longest), now, func(metrics SystemMetrics) {
+		for i, threshold := range thresholds {
+			if values[i] == nil || now.Sub(metrics.Timestamp) > time.Duration(threshold.Window)*time.Second {
+				continue
+			}
+			if v, ok := values[i](metrics); ok {
+				samples[i] = append(samples[i], v)
+			}
+		}
+	})
+	if err != nil {
+		return
+	}
+
+	for i, threshold := range thresholds {
+		if len(samples[i]) >= dynamicMinSamples {
+			a.percentiles[i] = percentile(samples[i], threshold.Percentile)
+		}
+	}
+}
+
+// percentile returns the nearest-rank percentile p of values, sorting
+// them in place
+func percentile(values []float64, p float64) float64 {
+	sort.Float64s(values)
+	rank := int(math.Ceil(p / 100 * float64(len(values))))
+	return values[min(max(rank-1, 0), len(values)-1)]
+}
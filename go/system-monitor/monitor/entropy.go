@@ -0,0 +1,75 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+)
+
+// entropyDaemons feed the kernel's entropy pool from hardware or timing
+// jitter
+var entropyDaemons = []string{"rngd", "haveged", "jitterentropy-rngd"}
+
+// EntropyMetrics is the kernel's random number generator state (Linux)
+type EntropyMetrics struct {
+	// Bits of entropy available, out of PoolSize. Since kernel 5.18 both
+	// are fixed at 256 and the generator never runs dry.
+	Available int `json:"available"`
+	PoolSize  int `json:"pool_size"`
+
+	// The entropy daemon running, empty for none
+	Daemon string `json:"daemon,omitempty"`
+
+	// The hardware RNG feeding the pool, such as "virtio_rng.0" or
+	// "tpm-rng-0", empty for none
+	HardwareRNG string `json:"hardware_rng,omitempty"`
+}
+
+func (c Config) validateEntropy() []error {
+	if c.EntropyThreshold < 0 {
+		return []error{fmt.Errorf("entropy_threshold must not be negative, got %d", c.EntropyThreshold)}
+	}
+	return nil
+}
+
+// collectEntropy reads the entropy pool, and which daemon and hardware
+// RNG feed it. Other platforms report nothing.
+func (c *Collector) collectEntropy(ctx context.Context) (*EntropyMetrics, error) {
+	return readEntropy(ctx)
+}
+
+// checkEntropy warns when the available entropy stayed below
+// Config.EntropyThreshold bits for the last 3 snapshots. Headless VMs
+// without a virtio RNG gather entropy slowly, and on kernels before 5.6
+// reads of /dev/random, and TLS handshakes of software that uses it, stall
+// until there is enough.
+func (a *Analyzer) checkEntropy(metrics *SystemMetrics) []Alert {
+	entropy := metrics.Entropy
+	threshold := a.config.EntropyThreshold
+	if entropy == nil || threshold <= 0 || a.history.len() < 3 {
+		return nil
+	}
+	for i := a.history.len() - 3; i < a.history.len(); i++ {
+		if e := a.history.at(i).Entropy; e == nil || e.Available >= threshold {
+			return nil
+		}
+	}
+
+	message := fmt.Sprintf("Kernel entropy has stayed low at %d of %d bits", entropy.Available, entropy.PoolSize)
+	switch {
+	case entropy.Daemon != "":
+		message += fmt.Sprintf(" despite %s running", entropy.Daemon)
+	case entropy.HardwareRNG == "":
+		message += " with no entropy daemon or hardware RNG"
+	default:
+		message += " with no entropy daemon running"
+	}
+	return []Alert{{
+		Level:     "warning",
+		Category:  "entropy",
+		Resource:  "random",
+		Message:   message,
+		Value:     float64(entropy.Available),
+		Threshold: float64(threshold),
+		Timestamp: metrics.Timestamp,
+	}}
+}
@@ -0,0 +1,46 @@
+//go:build linux
+
+package monitor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readEntropy reads /proc/sys/kernel/random, the current hardware RNG,
+// and looks for an entropy daemon among the running processes
+func readEntropy(ctx context.Context) (*EntropyMetrics, error) {
+	available, ok := readSysfsFloat("/proc/sys/kernel/random/entropy_avail")
+	if !ok {
+		return nil, nil
+	}
+	entropy := &EntropyMetrics{Available: int(available)}
+	if size, ok := readSysfsFloat("/proc/sys/kernel/random/poolsize"); ok {
+		entropy.PoolSize = int(size)
+	}
+	if data, err := os.ReadFile("/sys/class/misc/hw_random/rng_current"); err == nil {
+		if rng := strings.TrimSpace(string(data)); rng != "none" {
+			entropy.HardwareRNG = rng
+		}
+	}
+
+	// comm is truncated to 15 characters
+	daemons := make(map[string]string, len(entropyDaemons))
+	for _, name := range entropyDaemons {
+		daemons[name[:min(len(name), 15)]] = name
+	}
+	comms, _ := filepath.Glob("/proc/[0-9]*/comm")
+	for _, path := range comms {
+		if ctx.Err() != nil {
+			break
+		}
+		data, err := os.ReadFile(path)
+		if daemon := daemons[strings.TrimSpace(string(data))]; err == nil && daemon != "" {
+			entropy.Daemon = daemon
+			break
+		}
+	}
+	return entropy, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package monitor
+
+import "context"
+
+// readEntropy is only supported on Linux
+func readEntropy(ctx context.Context) (*EntropyMetrics, error) {
+	return nil, nil
+}
@@ -0,0 +1,91 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+)
+
+// EscalationPolicy turns a warning that persists into a critical alert,
+// so it reaches the task and notification path. A warning escalates once
+// either limit is reached; a zero limit is not checked.
+type EscalationPolicy struct {
+	Snapshots  int      `json:"snapshots"`  // consecutive snapshots
+	Seconds    int      `json:"seconds"`    // time since the warning was first raised
+	Categories []string `json:"categories"` // empty means every category
+}
+
+// warningStreak is how long a condition has been alerting without a break
+type warningStreak struct {
+	since     time.Time
+	snapshots int
+}
+
+func (p EscalationPolicy) enabled() bool {
+	return p.Snapshots > 0 || p.Seconds > 0
+}
+
+func (p EscalationPolicy) validate() []error {
+	var errs []error
+	if p.Snapshots < 0 {
+		errs = append(errs, fmt.Errorf("escalation: snapshots must not be negative, got %d", p.Snapshots))
+	}
+	if p.Seconds < 0 {
+		errs = append(errs, fmt.Errorf("escalation: seconds must not be negative, got %d", p.Seconds))
+	}
+	return errs
+}
+
+func (p EscalationPolicy) covers(category string) bool {
+	if len(p.Categories) == 0 {
+		return true
+	}
+	for _, c := range p.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// escalate raises warnings that have persisted past the escalation policy
+// to critical. Streaks count every snapshot a condition alerts in, at any
+// level, and end with the first snapshot it doesn't.
+func (a *Analyzer) escalate(metrics *SystemMetrics, alerts []Alert) []Alert {
+	policy := a.config.Escalation
+	if !policy.enabled() {
+		a.streaks = nil
+		return alerts
+	}
+
+	streaks := make(map[string]warningStreak, len(alerts))
+	for i, alert := range alerts {
+		if severityRank(alert.Level) == 0 || !policy.covers(alert.Category) {
+			continue
+		}
+
+		// Alerts sharing a condition count once per snapshot
+		key := conditionKey(alert)
+		streak, counted := streaks[key]
+		if !counted {
+			var ok bool
+			if streak, ok = a.streaks[key]; !ok {
+				streak.since = metrics.Timestamp
+			}
+			streak.snapshots++
+			streaks[key] = streak
+		}
+
+		if alert.Level != "warning" {
+			continue
+		}
+		duration := metrics.Timestamp.Sub(streak.since)
+		if (policy.Snapshots > 0 && streak.snapshots >= policy.Snapshots) ||
+			(policy.Seconds > 0 && duration >= time.Duration(policy.Seconds)*time.Second) {
+			alerts[i].Level = "critical"
+			alerts[i].Message += fmt.Sprintf(" (escalated: warning for %d snapshots over %s)",
+				streak.snapshots, duration.Round(time.Second))
+		}
+	}
+	a.streaks = streaks
+	return alerts
+}
@@ -0,0 +1,198 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExposureMetrics is the sockets listening on public addresses, or every
+// interface, which other hosts can reach unless a firewall stops them
+type ExposureMetrics struct {
+	Listeners  []ListeningSocket `json:"listeners"`
+	Unexpected int               `json:"unexpected"` // listeners not in Config.AllowedPorts
+}
+
+// ListeningSocket is one TCP listener or bound UDP socket
+type ListeningSocket struct {
+	Protocol string `json:"protocol"` // "tcp" or "udp"
+	Address  string `json:"address"`  // "0.0.0.0" and "::" for every interface
+	Port     uint32 `json:"port"`
+	Expected bool   `json:"expected"`
+
+	// The process that owns the socket, resolved for unexpected
+	// listeners; on Linux only for processes the monitor may inspect. The
+	// name is redacted like process names.
+	PID     int32  `json:"pid,omitempty"`
+	Process string `json:"process,omitempty"`
+
+	inode string // Linux socket inode, to find the owner by
+}
+
+// portRange is one parsed Config.AllowedPorts entry
+type portRange struct {
+	protocol string // empty for both
+	from, to uint32
+}
+
+// parsePortRange parses "22", "tcp/443", "udp/53" and "tcp/8000-8100";
+// a port without a protocol allows both
+func parsePortRange(spec string) (portRange, error) {
+	var r portRange
+	ports := spec
+	if protocol, rest, ok := strings.Cut(spec, "/"); ok {
+		if protocol != "tcp" && protocol != "udp" {
+			return r, fmt.Errorf(`%q: protocol must be "tcp" or "udp"`, spec)
+		}
+		r.protocol, ports = protocol, rest
+	}
+	from, to, isRange := strings.Cut(ports, "-")
+	if !isRange {
+		to = from
+	}
+	lo, err := strconv.ParseUint(from, 10, 16)
+	if err != nil || lo == 0 {
+		return r, fmt.Errorf("%q: expected a port or range such as tcp/22 or tcp/8000-8100", spec)
+	}
+	hi, err := strconv.ParseUint(to, 10, 16)
+	if err != nil || hi < lo {
+		return r, fmt.Errorf("%q: expected a port or range such as tcp/22 or tcp/8000-8100", spec)
+	}
+	r.from, r.to = uint32(lo), uint32(hi)
+	return r, nil
+}
+
+func (r portRange) allows(protocol string, port uint32) bool {
+	return (r.protocol == "" || r.protocol == protocol) && port >= r.from && port <= r.to
+}
+
+func (c Config) validateExposure() []error {
+	var errs []error
+	for _, spec := range c.AllowedPorts {
+		if _, err := parsePortRange(spec); err != nil {
+			errs = append(errs, fmt.Errorf("allowed_ports: %w", err))
+		}
+	}
+	return errs
+}
+
+// collectExposure lists the listeners reachable from other hosts, marks
+// those Config.AllowedPorts doesn't cover, and finds out which process
+// owns each of those. Listeners on private and link-local addresses are
+// left out unless Config.AuditPrivateAddresses is set, and so are
+// unconnected UDP sockets in the ephemeral port range, which are clients
+// waiting for replies rather than services.
+func (c *Collector) collectExposure(ctx context.Context) (*ExposureMetrics, error) {
+	listeners, err := readListeners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var allowed []portRange
+	for _, spec := range c.config.AllowedPorts {
+		if r, err := parsePortRange(spec); err == nil {
+			allowed = append(allowed, r)
+		}
+	}
+	ephemeral := ephemeralPorts()
+
+	exposure := &ExposureMetrics{Listeners: []ListeningSocket{}}
+	seen := make(map[string]bool)
+	for _, listener := range listeners {
+		ip := net.ParseIP(listener.Address)
+		if ip == nil || ip.IsLoopback() {
+			continue
+		}
+		if !c.config.AuditPrivateAddresses && (ip.IsPrivate() || ip.IsLinkLocalUnicast()) {
+			continue
+		}
+		if listener.Protocol == "udp" && ephemeral.allows("udp", listener.Port) {
+			continue
+		}
+		key := listener.Protocol + " " + net.JoinHostPort(listener.Address, strconv.Itoa(int(listener.Port)))
+		if seen[key] {
+			continue // SO_REUSEPORT listeners of one service
+		}
+		seen[key] = true
+
+		for _, r := range allowed {
+			if r.allows(listener.Protocol, listener.Port) {
+				listener.Expected = true
+				break
+			}
+		}
+		if !listener.Expected {
+			exposure.Unexpected++
+		}
+		exposure.Listeners = append(exposure.Listeners, listener)
+	}
+	if exposure.Unexpected > 0 {
+		resolveSocketOwners(ctx, exposure.Listeners)
+		for i := range exposure.Listeners {
+			if exposure.Listeners[i].Process != "" {
+				exposure.Listeners[i].Process, _ = c.redactor.name(exposure.Listeners[i].Process)
+			}
+		}
+	}
+
+	sort.Slice(exposure.Listeners, func(i, j int) bool {
+		a, b := exposure.Listeners[i], exposure.Listeners[j]
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		return a.Address < b.Address
+	})
+	return exposure, nil
+}
+
+// checkExposure raises a warning for every audited listening port that
+// Config.AllowedPorts doesn't allow, such as a database
+// bound to all interfaces by a package's default configuration
+func (a *Analyzer) checkExposure(metrics *SystemMetrics) []Alert {
+	exposure := metrics.Exposure
+	if exposure == nil || exposure.Unexpected == 0 {
+		return nil
+	}
+
+	// One alert per port, however many addresses it listens on
+	byPort := make(map[string][]ListeningSocket)
+	var ports []string
+	for _, listener := range exposure.Listeners {
+		if listener.Expected {
+			continue
+		}
+		resource := fmt.Sprintf("%s/%d", listener.Protocol, listener.Port)
+		if byPort[resource] == nil {
+			ports = append(ports, resource)
+		}
+		byPort[resource] = append(byPort[resource], listener)
+	}
+
+	var alerts []Alert
+	for _, resource := range ports {
+		listeners := byPort[resource]
+		addresses := make([]string, len(listeners))
+		for i, listener := range listeners {
+			addresses[i] = listener.Address
+		}
+		message := fmt.Sprintf("Unexpected %s port %d listening on %s", listeners[0].Protocol, listeners[0].Port, strings.Join(addresses, ", "))
+		if owner := listeners[0]; owner.Process != "" {
+			message += fmt.Sprintf(" by %s (PID %d)", owner.Process, owner.PID)
+		}
+		alerts = append(alerts, Alert{
+			Level:     "warning",
+			Category:  "exposure",
+			Resource:  resource,
+			Message:   message,
+			Value:     float64(listeners[0].Port),
+			Timestamp: metrics.Timestamp,
+		})
+	}
+	return alerts
+}
@@ -0,0 +1,165 @@
+//go:build linux
+
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Socket states of /proc/net/tcp and udp that mean listening: LISTEN for
+// TCP, and CLOSE, which is what an unconnected bound UDP socket is in
+const (
+	tcpListen = "0A"
+	udpBound  = "07"
+)
+
+// readListeners reads the TCP listeners and bound UDP sockets from
+// /proc/net, without their owners
+func readListeners(ctx context.Context) ([]ListeningSocket, error) {
+	var listeners []ListeningSocket
+	read := 0
+	for _, table := range []struct{ path, protocol, state string }{
+		{"/proc/net/tcp", "tcp", tcpListen},
+		{"/proc/net/tcp6", "tcp", tcpListen},
+		{"/proc/net/udp", "udp", udpBound},
+		{"/proc/net/udp6", "udp", udpBound},
+	} {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		file, err := os.Open(table.path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // IPv6 disabled
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Scan() // header
+		for scanner.Scan() {
+			// sl local_address rem_address st tx:rx tr:when retrnsmt uid timeout inode
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 || fields[3] != table.state {
+				continue
+			}
+			local, port, ok := parseProcNetAddress(fields[1])
+			if !ok {
+				continue
+			}
+			if table.protocol == "udp" {
+				// Connected UDP sockets have a peer and are clients
+				if _, remotePort, _ := parseProcNetAddress(fields[2]); remotePort != 0 {
+					continue
+				}
+			}
+			listeners = append(listeners, ListeningSocket{
+				Protocol: table.protocol,
+				Address:  local.String(),
+				Port:     port,
+				inode:    fields[9],
+			})
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		read++
+	}
+	if read == 0 {
+		return nil, errors.New("no /proc/net socket tables")
+	}
+	return listeners, nil
+}
+
+// parseProcNetAddress parses "0100007F:0016": the address as 32-bit
+// words in host byte order, and the port, both in hex
+func parseProcNetAddress(s string) (net.IP, uint32, bool) {
+	addr, portHex, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, 0, false
+	}
+	raw, err := hex.DecodeString(addr)
+	if err != nil || len(raw)%4 != 0 {
+		return nil, 0, false
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return nil, 0, false
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.NativeEndian.Uint32(raw[i:]))
+	}
+	if v4 := ip.To4(); v4 != nil && len(raw) == 16 {
+		ip = v4 // IPv4-mapped listeners of dual-stack sockets
+	}
+	return ip, uint32(port), true
+}
+
+// resolveSocketOwners finds the processes owning the unexpected listeners
+// by scanning /proc/*/fd for their socket inodes. Other users' processes
+// are only visible to root.
+func resolveSocketOwners(ctx context.Context, listeners []ListeningSocket) {
+	wanted := make(map[string][]int)
+	for i, listener := range listeners {
+		if !listener.Expected && listener.inode != "" && listener.inode != "0" {
+			key := "socket:[" + listener.inode + "]"
+			wanted[key] = append(wanted[key], i)
+		}
+	}
+
+	pids, _ := filepath.Glob("/proc/[0-9]*")
+	for _, dir := range pids {
+		if len(wanted) == 0 || ctx.Err() != nil {
+			return
+		}
+		fds, err := os.ReadDir(dir + "/fd")
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(dir + "/fd/" + fd.Name())
+			if err != nil || wanted[target] == nil {
+				continue
+			}
+			pid, _ := strconv.ParseInt(filepath.Base(dir), 10, 32)
+			comm, _ := os.ReadFile(dir + "/comm")
+			for _, i := range wanted[target] {
+				listeners[i].PID = int32(pid)
+				listeners[i].Process = strings.TrimSpace(string(comm))
+			}
+			delete(wanted, target)
+		}
+	}
+}
+
+// ephemeralPorts is the local port range the kernel assigns to client
+// sockets
+func ephemeralPorts() portRange {
+	r := portRange{from: 32768, to: 60999}
+	data, err := os.ReadFile("/proc/sys/net/ipv4/ip_local_port_range")
+	if err != nil {
+		return r
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return r
+	}
+	from, err1 := strconv.ParseUint(fields[0], 10, 16)
+	to, err2 := strconv.ParseUint(fields[1], 10, 16)
+	if err1 != nil || err2 != nil {
+		return r
+	}
+	return portRange{from: uint32(from), to: uint32(to)}
+}
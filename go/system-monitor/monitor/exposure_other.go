@@ -0,0 +1,67 @@
+//go:build !linux
+
+package monitor
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// readListeners lists the TCP listeners and unconnected UDP sockets
+// through gopsutil, which reports their owners too
+func readListeners(ctx context.Context) ([]ListeningSocket, error) {
+	stats, err := net.ConnectionsWithContext(ctx, "inet")
+	if err != nil {
+		return nil, err
+	}
+
+	var listeners []ListeningSocket
+	for _, stat := range stats {
+		var protocol string
+		switch {
+		case stat.Type == syscall.SOCK_STREAM && stat.Status == "LISTEN":
+			protocol = "tcp"
+		case stat.Type == syscall.SOCK_DGRAM && stat.Raddr.Port == 0:
+			protocol = "udp"
+		default:
+			continue
+		}
+		// lsof, which gopsutil runs on macOS, writes "*" for every
+		// interface
+		address := stat.Laddr.IP
+		if address == "*" {
+			address = "0.0.0.0"
+			if stat.Family == syscall.AF_INET6 {
+				address = "::"
+			}
+		}
+		listeners = append(listeners, ListeningSocket{
+			Protocol: protocol,
+			Address:  address,
+			Port:     stat.Laddr.Port,
+			PID:      stat.Pid,
+		})
+	}
+	return listeners, nil
+}
+
+// resolveSocketOwners names the processes gopsutil found owning the
+// unexpected listeners
+func resolveSocketOwners(ctx context.Context, listeners []ListeningSocket) {
+	for i, listener := range listeners {
+		if listener.Expected || listener.PID <= 0 {
+			continue
+		}
+		if proc, err := process.NewProcessWithContext(ctx, listener.PID); err == nil {
+			listeners[i].Process, _ = proc.NameWithContext(ctx)
+		}
+	}
+}
+
+// ephemeralPorts is the IANA dynamic port range Windows and macOS use
+func ephemeralPorts() portRange {
+	return portRange{from: 49152, to: 65535}
+}
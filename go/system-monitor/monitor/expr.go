@@ -0,0 +1,589 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Rule expressions are evaluated against a snapshot's JSON form:
+//
+//	cpu.usage_percent > 90 && load.load1 > cores * 2
+//	max(disk.percent) >= 95 || disk[mount_point="/var"].inodes_percent > 80
+//	count(processes) == 0
+//
+// Paths use the snapshot's JSON field names. A path through a list, such
+// as disk.percent, yields every element's value and must be reduced with
+// max, min, avg, sum or count; [field="value"] keeps only the elements
+// whose field equals value, and a path whose selectors leave one element
+// needs no reduction. Comparisons and ! yield 1 or 0, && and ||
+// treat any non-zero number as true, and == and != also compare strings.
+
+// errMissing is returned for paths absent from the snapshot, e.g. GPU
+// metrics on a host without GPUs
+var errMissing = errors.New("no such metric")
+
+// exprAliases are shorthands for common paths
+var exprAliases = map[string]string{
+	"cores": "cpu.cores",
+}
+
+// expr is a compiled expression
+type expr interface {
+	eval(root map[string]interface{}) (interface{}, error)
+}
+
+// compileExpr parses an expression
+func compileExpr(source string) (expr, error) {
+	tokens, err := lexExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEnd {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.peek().text, p.peek().pos)
+	}
+	return e, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenPath
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// twoCharOps are checked before single characters
+var twoCharOps = []string{"&&", "||", "==", "!=", ">=", "<="}
+
+func lexExpr(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c >= '0' && c <= '9' || c == '.':
+			start := i
+			for i < len(source) && (source[i] >= '0' && source[i] <= '9' || source[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, source[start:i], start})
+
+		case c == '"':
+			text, n, err := lexString(source[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%w at offset %d", err, i)
+			}
+			tokens = append(tokens, token{tokenString, text, i})
+			i += n
+
+		case c == '_' || unicode.IsLetter(c):
+			// A path runs through dots and [field="value"] selectors
+			start := i
+			for i < len(source) {
+				ch := rune(source[i])
+				if ch == '_' || ch == '.' || unicode.IsLetter(ch) || unicode.IsDigit(ch) {
+					i++
+					continue
+				}
+				if ch != '[' {
+					break
+				}
+				end := strings.IndexByte(source[i:], ']')
+				if end < 0 {
+					return nil, fmt.Errorf("unclosed [ at offset %d", i)
+				}
+				i += end + 1
+			}
+			tokens = append(tokens, token{tokenPath, source[start:i], start})
+
+		default:
+			op := string(c)
+			for _, two := range twoCharOps {
+				if strings.HasPrefix(source[i:], two) {
+					op = two
+				}
+			}
+			if !strings.Contains("&&||==!=>=<=+-*/()!<>,", op) {
+				return nil, fmt.Errorf("unexpected %q at offset %d", op, i)
+			}
+			tokens = append(tokens, token{tokenOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{tokenEnd, "end of expression", len(source)}), nil
+}
+
+// lexString reads a double quoted string, returning its value and length
+func lexString(source string) (string, int, error) {
+	for i := 1; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			i++
+		case '"':
+			text, err := strconv.Unquote(source[:i+1])
+			return text, i + 1, err
+		}
+	}
+	return "", 0, errors.New("unterminated string")
+}
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) binary(next func() (expr, error), ops ...string) (expr, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op, left, right}
+	}
+}
+
+func (p *exprParser) or() (expr, error) {
+	return p.binary(p.and, "||")
+}
+
+func (p *exprParser) and() (expr, error) {
+	return p.binary(p.comparison, "&&")
+}
+
+func (p *exprParser) comparison() (expr, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept(">", ">=", "<", "<=", "==", "!=")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	return binaryExpr{op, left, right}, nil
+}
+
+func (p *exprParser) additive() (expr, error) {
+	return p.binary(p.multiplicative, "+", "-")
+}
+
+func (p *exprParser) multiplicative() (expr, error) {
+	return p.binary(p.unary, "*", "/")
+}
+
+func (p *exprParser) unary() (expr, error) {
+	if op, ok := p.accept("!", "-"); ok {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryExpr{op, operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (expr, error) {
+	t := p.peek()
+	p.pos++
+
+	switch t.kind {
+	case tokenNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at offset %d", t.text, t.pos)
+		}
+		return literalExpr{n}, nil
+
+	case tokenString:
+		return literalExpr{t.text}, nil
+
+	case tokenPath:
+		if _, ok := p.accept("("); ok {
+			return p.call(t)
+		}
+		return parsePath(t)
+
+	case tokenOp:
+		if t.text == "(" {
+			e, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, fmt.Errorf("expected ) at offset %d", p.peek().pos)
+			}
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+}
+
+// call parses the argument of a function whose name and ( were read
+func (p *exprParser) call(name token) (expr, error) {
+	// count is handled by callExpr, as it needs no numbers
+	if _, ok := aggregates[name.text]; !ok && name.text != "count" {
+		return nil, fmt.Errorf("unknown function %q at offset %d", name.text, name.pos)
+	}
+	arg, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept(")"); !ok {
+		return nil, fmt.Errorf("%s takes one argument, expected ) at offset %d", name.text, p.peek().pos)
+	}
+	return callExpr{name.text, arg}, nil
+}
+
+type literalExpr struct {
+	value interface{}
+}
+
+func (e literalExpr) eval(map[string]interface{}) (interface{}, error) {
+	return e.value, nil
+}
+
+// pathSegment is one field of a path with its element selectors
+type pathSegment struct {
+	field     string
+	selectors map[string]string
+}
+
+type pathExpr struct {
+	source   string
+	segments []pathSegment
+}
+
+func parsePath(t token) (expr, error) {
+	source := t.text
+	if alias, ok := exprAliases[source]; ok {
+		source = alias
+	}
+
+	e := pathExpr{source: source}
+	rest := source
+	for rest != "" {
+		var segment pathSegment
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		segment.field, rest = rest[:end], rest[end:]
+		if segment.field == "" {
+			return nil, fmt.Errorf("invalid path %q at offset %d", t.text, t.pos)
+		}
+
+		for strings.HasPrefix(rest, "[") {
+			close := strings.IndexByte(rest, ']')
+			key, value, ok := strings.Cut(rest[1:close], "=")
+			unquoted, err := strconv.Unquote(strings.TrimSpace(value))
+			if !ok || err != nil {
+				return nil, fmt.Errorf(`invalid selector %q in %q, expected [field="value"]`, rest[:close+1], t.text)
+			}
+			if segment.selectors == nil {
+				segment.selectors = map[string]string{}
+			}
+			segment.selectors[strings.TrimSpace(key)] = unquoted
+			rest = rest[close+1:]
+		}
+
+		e.segments = append(e.segments, segment)
+		if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+			if rest == "" {
+				return nil, fmt.Errorf("invalid path %q at offset %d", t.text, t.pos)
+			}
+		}
+	}
+	return e, nil
+}
+
+// eval returns the value at the path, or a []interface{} of values when
+// the path runs through a list. A selector that narrows every list on the
+// path to a single element yields that element's value.
+func (e pathExpr) eval(root map[string]interface{}) (interface{}, error) {
+	values := []interface{}{root}
+	list, selected := false, true
+
+	for _, segment := range e.segments {
+		var next []interface{}
+		for _, v := range values {
+			object, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			child, ok := object[segment.field]
+			if !ok || child == nil {
+				continue
+			}
+			if items, ok := child.([]interface{}); ok {
+				list = true
+				selected = selected && segment.selectors != nil
+				for _, item := range items {
+					if matchesSelectors(item, segment.selectors) {
+						next = append(next, item)
+					}
+				}
+				continue
+			}
+			if segment.selectors == nil {
+				next = append(next, child)
+			}
+		}
+		values = next
+	}
+
+	if list && !(selected && len(values) == 1) {
+		if selected && len(values) == 0 {
+			return nil, fmt.Errorf("%s: %w", e.source, errMissing)
+		}
+		return values, nil
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%s: %w", e.source, errMissing)
+	}
+	return values[0], nil
+}
+
+func matchesSelectors(item interface{}, selectors map[string]string) bool {
+	object, ok := item.(map[string]interface{})
+	if !ok {
+		return len(selectors) == 0
+	}
+	for key, want := range selectors {
+		if fmt.Sprint(object[key]) != want {
+			return false
+		}
+	}
+	return true
+}
+
+type unaryExpr struct {
+	op      string
+	operand expr
+}
+
+func (e unaryExpr) eval(root map[string]interface{}) (interface{}, error) {
+	v, err := evalNumber(e.operand, root)
+	if err != nil {
+		return nil, err
+	}
+	if e.op == "-" {
+		return -v, nil
+	}
+	return boolNumber(v == 0), nil
+}
+
+type binaryExpr struct {
+	op          string
+	left, right expr
+}
+
+func (e binaryExpr) eval(root map[string]interface{}) (interface{}, error) {
+	switch e.op {
+	case "&&", "||":
+		left, err := evalNumber(e.left, root)
+		if err != nil {
+			return nil, err
+		}
+		// Short-circuit, so guards like count(gpu) > 0 && ... work
+		if (e.op == "&&") == (left == 0) {
+			return boolNumber(left != 0), nil
+		}
+		right, err := evalNumber(e.right, root)
+		if err != nil {
+			return nil, err
+		}
+		return boolNumber(right != 0), nil
+
+	case "==", "!=":
+		left, err := e.left.eval(root)
+		if err != nil {
+			return nil, err
+		}
+		right, err := e.right.eval(root)
+		if err != nil {
+			return nil, err
+		}
+		if ls, ok := left.(string); ok {
+			rs, ok := right.(string)
+			return boolNumber(ok && (ls == rs) == (e.op == "==")), nil
+		}
+	}
+
+	left, err := evalNumber(e.left, root)
+	if err != nil {
+		return nil, err
+	}
+	right, err := evalNumber(e.right, root)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/":
+		if right == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return left / right, nil
+	case ">":
+		return boolNumber(left > right), nil
+	case ">=":
+		return boolNumber(left >= right), nil
+	case "<":
+		return boolNumber(left < right), nil
+	case "<=":
+		return boolNumber(left <= right), nil
+	case "==":
+		return boolNumber(left == right), nil
+	case "!=":
+		return boolNumber(left != right), nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", e.op)
+}
+
+// aggregates reduce the values of a path through a list
+var aggregates = map[string]func(values []float64) float64{
+	"max": func(values []float64) float64 {
+		m := math.Inf(-1)
+		for _, v := range values {
+			m = max(m, v)
+		}
+		return m
+	},
+	"min": func(values []float64) float64 {
+		m := math.Inf(1)
+		for _, v := range values {
+			m = min(m, v)
+		}
+		return m
+	},
+	"sum": func(values []float64) float64 {
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum
+	},
+	"avg": func(values []float64) float64 {
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	},
+}
+
+type callExpr struct {
+	name string
+	arg  expr
+}
+
+func (e callExpr) eval(root map[string]interface{}) (interface{}, error) {
+	v, err := e.arg.eval(root)
+	if errors.Is(err, errMissing) && e.name == "count" {
+		return 0.0, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items, ok := v.([]interface{})
+	if !ok {
+		items = []interface{}{v}
+	}
+	if e.name == "count" {
+		return float64(len(items)), nil
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%s of no values: %w", e.name, errMissing)
+	}
+
+	values := make([]float64, len(items))
+	for i, item := range items {
+		if values[i], err = toNumber(item); err != nil {
+			return nil, err
+		}
+	}
+	return aggregates[e.name](values), nil
+}
+
+func evalNumber(e expr, root map[string]interface{}) (float64, error) {
+	v, err := e.eval(root)
+	if err != nil {
+		return 0, err
+	}
+	return toNumber(v)
+}
+
+func toNumber(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case bool:
+		return boolNumber(v), nil
+	case []interface{}:
+		return 0, errors.New("a list of values needs max(), min(), avg(), sum() or count()")
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
+
+func boolNumber(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// readFileDescriptorMetrics reads system-wide file handle usage from
+// /proc/sys/fs/file-nr ("allocated unused max")
+func readFileDescriptorMetrics() (*FileDescriptorMetrics, error) {
+	data, err := os.ReadFile("/proc/sys/fs/file-nr")
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected file-nr format: %q", string(data))
+	}
+
+	var values [3]uint64
+	for i, field := range fields {
+		values[i], err = strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse file-nr: %w", err)
+		}
+	}
+
+	fds := &FileDescriptorMetrics{
+		Open: values[0] - values[1],
+		Max:  values[2],
+	}
+	if fds.Max > 0 {
+		fds.UsedPercent = float64(fds.Open) / float64(fds.Max) * 100
+	}
+
+	return fds, nil
+}
+
+// addProcessFileDescriptors fills in open FD counts and RLIMIT_NOFILE for
+// the given processes. Processes we lack permission to inspect are left at zero.
+func addProcessFileDescriptors(processes []ProcessMetrics) {
+	for i := range processes {
+		p, err := process.NewProcess(processes[i].PID)
+		if err != nil {
+			continue
+		}
+
+		numFDs, err := p.NumFDs()
+		if err != nil {
+			continue
+		}
+		processes[i].OpenFDs = numFDs
+
+		limits, err := p.Rlimit()
+		if err != nil {
+			continue
+		}
+		for _, limit := range limits {
+			if limit.Resource == process.RLIMIT_NOFILE {
+				processes[i].FDSoftLimit = limit.Soft
+				processes[i].FDHardLimit = limit.Hard
+			}
+		}
+	}
+}
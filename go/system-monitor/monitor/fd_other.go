@@ -0,0 +1,11 @@
+//go:build !linux
+
+package monitor
+
+// readFileDescriptorMetrics is only supported on Linux
+func readFileDescriptorMetrics() (*FileDescriptorMetrics, error) {
+	return nil, nil
+}
+
+// addProcessFileDescriptors is only supported on Linux
+func addProcessFileDescriptors(processes []ProcessMetrics) {}
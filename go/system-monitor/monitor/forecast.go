@@ -0,0 +1,136 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+)
+
+// Disk usage trend settings. The trend window is far longer than the
+// analyzer's metrics history since disks fill over hours, not minutes.
+const (
+	diskTrendWindow     = 24 * time.Hour
+	diskTrendMaxSamples = 2000
+	diskTrendMinSamples = 5
+	diskTrendMinSpan    = 10 * time.Minute
+	diskForecastUrgent  = 6 * time.Hour // forecasts closer than this are critical
+)
+
+// DiskSample is one disk usage observation in a mount point's trend
+type DiskSample struct {
+	Timestamp time.Time `json:"t"`
+	UsedGB    float64   `json:"used_gb"`
+}
+
+// recordDiskTrends appends the snapshot's disk usage to each mount point's
+// trend and drops samples that fell out of the window
+func (a *Analyzer) recordDiskTrends(metrics *SystemMetrics) {
+	if a.diskTrends == nil {
+		a.diskTrends = make(map[string][]DiskSample)
+	}
+
+	seen := make(map[string]bool, len(metrics.Disk))
+	for _, disk := range metrics.Disk {
+		seen[disk.MountPoint] = true
+
+		samples := append(a.diskTrends[disk.MountPoint], DiskSample{Timestamp: metrics.Timestamp, UsedGB: disk.UsedGB})
+		start := 0
+		for start < len(samples) && metrics.Timestamp.Sub(samples[start].Timestamp) > diskTrendWindow {
+			start++
+		}
+		start = max(start, len(samples)-diskTrendMaxSamples)
+		a.diskTrends[disk.MountPoint] = samples[start:]
+	}
+
+	// Forget unmounted filesystems
+	for mount := range a.diskTrends {
+		if !seen[mount] {
+			delete(a.diskTrends, mount)
+		}
+	}
+}
+
+// forecastDiskFull fits a least-squares line through the samples and
+// returns how long until usage reaches totalGB. It reports false when
+// there is too little data or usage is not growing.
+func forecastDiskFull(samples []DiskSample, totalGB float64) (time.Duration, bool) {
+	if len(samples) < diskTrendMinSamples {
+		return 0, false
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	if last.Timestamp.Sub(first.Timestamp) < diskTrendMinSpan {
+		return 0, false
+	}
+
+	// Regress used GB on seconds since the first sample
+	n := float64(len(samples))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.Timestamp.Sub(first.Timestamp).Seconds()
+		sumX += x
+		sumY += s.UsedGB
+		sumXY += x * s.UsedGB
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+	slope := (n*sumXY - sumX*sumY) / denominator // GB per second
+	if slope <= 0 {
+		return 0, false
+	}
+
+	remaining := totalGB - last.UsedGB
+	if remaining <= 0 {
+		return 0, true
+	}
+	return time.Duration(remaining / slope * float64(time.Second)), true
+}
+
+// checkDiskForecasts raises a predictive alert for disks whose trend
+// reaches 100% within the forecast horizon, before the usage threshold
+// may even trip
+func (a *Analyzer) checkDiskForecasts(metrics *SystemMetrics) []Alert {
+	if a.config.DiskForecastHours <= 0 {
+		return nil
+	}
+	horizon := time.Duration(a.config.DiskForecastHours) * time.Hour
+
+	var alerts []Alert
+	for _, disk := range metrics.Disk {
+		eta, ok := forecastDiskFull(a.diskTrends[disk.MountPoint], disk.TotalGB)
+		if !ok || eta > horizon {
+			continue
+		}
+
+		level := "warning"
+		if eta < diskForecastUrgent {
+			level = "critical"
+		}
+
+		alerts = append(alerts, Alert{
+			Level:    level,
+			Category: "disk_forecast",
+			Resource: disk.MountPoint,
+			Message: fmt.Sprintf("Disk %s will be full in ~%s at the current rate (now %.1f%% used, %.1f GB free)",
+				disk.MountPoint, formatETA(eta), disk.UsedPercent, disk.FreeGB),
+			Value:     eta.Hours(),
+			Threshold: float64(a.config.DiskForecastHours),
+			Timestamp: metrics.Timestamp,
+		})
+	}
+
+	return alerts
+}
+
+// formatETA renders a forecast in the largest sensible unit
+func formatETA(eta time.Duration) string {
+	switch {
+	case eta >= 48*time.Hour:
+		return fmt.Sprintf("%.0f days", eta.Hours()/24)
+	case eta >= 2*time.Hour:
+		return fmt.Sprintf("%.0f hours", eta.Hours())
+	default:
+		return fmt.Sprintf("%.0f minutes", eta.Minutes())
+	}
+}
@@ -0,0 +1,98 @@
+package monitor
+
+import (
+	"context"
+	"encoding/csv"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gpuQueryTimeout bounds a single nvidia-smi or rocm-smi invocation
+const gpuQueryTimeout = 5 * time.Second
+
+// nvidiaSMIFields are the columns requested from nvidia-smi, in order
+var nvidiaSMIFields = []string{
+	"index", "name", "utilization.gpu", "memory.used", "memory.total", "temperature.gpu",
+	"power.draw", "power.limit",
+}
+
+func (c *Collector) collectGPUMetrics(ctx context.Context) ([]GPUMetrics, error) {
+	// AMD cards are numbered after NVIDIA ones so indexes stay unique on
+	// mixed hosts
+	gpus := queryNvidiaSMI(ctx)
+	offset := len(gpus)
+	for _, gpu := range queryROCmSMI(ctx) {
+		gpu.Index += offset
+		gpus = append(gpus, gpu)
+	}
+
+	return gpus, nil
+}
+
+// queryNvidiaSMI reads per-GPU metrics from nvidia-smi. Hosts without the
+// tool, a driver or a GPU simply report no GPUs.
+func queryNvidiaSMI(ctx context.Context) []GPUMetrics {
+	path, err := exec.LookPath("nvidia-smi")
+	if err != nil {
+		return []GPUMetrics{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, gpuQueryTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path,
+		"--query-gpu="+strings.Join(nvidiaSMIFields, ","),
+		"--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return []GPUMetrics{}
+	}
+
+	return parseNvidiaSMI(string(out))
+}
+
+func parseNvidiaSMI(output string) []GPUMetrics {
+	reader := csv.NewReader(strings.NewReader(output))
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return []GPUMetrics{}
+	}
+
+	gpus := make([]GPUMetrics, 0, len(records))
+	for _, record := range records {
+		if len(record) != len(nvidiaSMIFields) {
+			continue
+		}
+
+		gpu := GPUMetrics{
+			Index:              int(parseGPUValue(record[0])),
+			Name:               record[1],
+			Vendor:             "nvidia",
+			UtilizationPercent: parseGPUValue(record[2]),
+			MemoryUsedMB:       parseGPUValue(record[3]),
+			MemoryTotalMB:      parseGPUValue(record[4]),
+			TemperatureC:       parseGPUValue(record[5]),
+			PowerWatts:         parseGPUValue(record[6]),
+			PowerLimitWatts:    parseGPUValue(record[7]),
+		}
+		if gpu.MemoryTotalMB > 0 {
+			gpu.MemoryPercent = gpu.MemoryUsedMB / gpu.MemoryTotalMB * 100
+		}
+		gpus = append(gpus, gpu)
+	}
+
+	return gpus
+}
+
+// parseGPUValue parses a numeric nvidia-smi field; "[N/A]" and similar
+// placeholders read as zero
+func parseGPUValue(field string) float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// rocmSMIArgs request everything we report from rocm-smi as JSON
+var rocmSMIArgs = []string{
+	"--showproductname", "--showuse", "--showmeminfo", "vram", "--showtemp", "--showpower", "--json",
+}
+
+// queryROCmSMI reads per-GPU metrics for AMD cards from rocm-smi. Like
+// queryNvidiaSMI, hosts without the tool or a card report no GPUs.
+func queryROCmSMI(ctx context.Context) []GPUMetrics {
+	path, err := exec.LookPath("rocm-smi")
+	if err != nil {
+		return []GPUMetrics{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, gpuQueryTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, rocmSMIArgs...).Output()
+	if err != nil {
+		return []GPUMetrics{}
+	}
+
+	return parseROCmSMI(out)
+}
+
+// parseROCmSMI parses rocm-smi JSON output, which maps "cardN" to a flat
+// object of human readable keys. Key names vary between rocm-smi
+// releases, so fields are matched by their distinctive parts.
+func parseROCmSMI(output []byte) []GPUMetrics {
+	var cards map[string]map[string]string
+	if err := json.Unmarshal(output, &cards); err != nil {
+		return []GPUMetrics{}
+	}
+
+	gpus := make([]GPUMetrics, 0, len(cards))
+	for card, fields := range cards {
+		index, err := strconv.Atoi(strings.TrimPrefix(card, "card"))
+		if err != nil {
+			continue // e.g. the "system" section
+		}
+
+		gpu := GPUMetrics{Index: index, Vendor: "amd"}
+		var vramTotal, vramUsed float64
+		for key, value := range fields {
+			k := strings.ToLower(key)
+			switch {
+			case k == "card series" || k == "card model" && gpu.Name == "":
+				gpu.Name = value
+			case strings.HasPrefix(k, "gpu use"):
+				gpu.UtilizationPercent = parseGPUValue(value)
+			case strings.Contains(k, "vram total used memory"):
+				vramUsed = parseGPUValue(value)
+			case strings.Contains(k, "vram total memory"):
+				vramTotal = parseGPUValue(value)
+			case strings.HasPrefix(k, "temperature") && (strings.Contains(k, "edge") || gpu.TemperatureC == 0):
+				gpu.TemperatureC = parseGPUValue(value)
+			case strings.Contains(k, "package power") || strings.HasPrefix(k, "current socket graphics package power"):
+				gpu.PowerWatts = parseGPUValue(value)
+			}
+		}
+
+		// rocm-smi reports VRAM in bytes
+		gpu.MemoryUsedMB = vramUsed / (1024 * 1024)
+		gpu.MemoryTotalMB = vramTotal / (1024 * 1024)
+		if gpu.MemoryTotalMB > 0 {
+			gpu.MemoryPercent = gpu.MemoryUsedMB / gpu.MemoryTotalMB * 100
+		}
+		gpus = append(gpus, gpu)
+	}
+
+	sort.Slice(gpus, func(i, j int) bool { return gpus[i].Index < gpus[j].Index })
+	return gpus
+}
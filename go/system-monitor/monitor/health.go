@@ -0,0 +1,118 @@
+package monitor
+
+import "math"
+
+// HealthWeights sets how much each component contributes to the health
+// score. Weights are relative; they do not need to sum to 1.
+type HealthWeights struct {
+	CPU    float64 `json:"cpu"`
+	Memory float64 `json:"memory"`
+	Disk   float64 `json:"disk"`
+	Load   float64 `json:"load"`
+	Alerts float64 `json:"alerts"`
+}
+
+// DefaultHealthWeights returns the default health score weights
+func DefaultHealthWeights() HealthWeights {
+	return HealthWeights{
+		CPU:    0.25,
+		Memory: 0.25,
+		Disk:   0.20,
+		Load:   0.15,
+		Alerts: 0.15,
+	}
+}
+
+// Score caps applied when alerts are active, so a single resource at
+// capacity can't hide behind a healthy-looking average
+const (
+	healthCapCritical = 40.0
+	healthCapWarning  = 80.0
+)
+
+// ComputeHealthScore blends the current metrics and alerts into a single
+// 0-100 score, where 100 is an idle, alert-free host. Each component is
+// scored 0-100:
+//
+//   - cpu:    100 - CPU usage percent
+//   - memory: 100 - memory usage percent
+//   - disk:   100 - the worst space or inode usage percent of any disk
+//   - load:   100 - 50 per unit of 1-minute load per core (0 at 2x cores)
+//   - alerts: 100 - 10 per warning - 40 per critical
+//
+// The score is the weighted average of the components, rounded to one
+// decimal, then capped at 40 if any alert is critical or 80 if any alert
+// is a warning.
+func ComputeHealthScore(metrics *SystemMetrics, alerts []Alert, cfg Config) float64 {
+	weights := cfg.HealthWeights
+
+	worstDisk := 0.0
+	for _, disk := range metrics.Disk {
+		worstDisk = math.Max(worstDisk, math.Max(disk.UsedPercent, disk.InodesUsedPercent))
+	}
+
+	loadPerCore := 0.0
+	if metrics.CPU.Cores > 0 {
+		loadPerCore = metrics.Load.Load1 / float64(metrics.CPU.Cores)
+	}
+
+	warnings, criticals := 0, 0
+	for _, alert := range alerts {
+		switch alert.Level {
+		case "critical":
+			criticals++
+		case "warning":
+			warnings++
+		}
+	}
+
+	components := []struct {
+		weight float64
+		score  float64
+	}{
+		{weights.CPU, 100 - metrics.CPU.UsagePercent},
+		{weights.Memory, 100 - metrics.Memory.UsedPercent},
+		{weights.Disk, 100 - worstDisk},
+		{weights.Load, 100 - 50*loadPerCore},
+		{weights.Alerts, 100 - 10*float64(warnings) - 40*float64(criticals)},
+	}
+
+	var sum, totalWeight float64
+	for _, c := range components {
+		sum += c.weight * clampPercent(c.score)
+		totalWeight += c.weight
+	}
+	if totalWeight == 0 {
+		return 0
+	}
+
+	score := math.Round(sum/totalWeight*10) / 10
+	if criticals > 0 {
+		score = math.Min(score, healthCapCritical)
+	} else if warnings > 0 {
+		score = math.Min(score, healthCapWarning)
+	}
+
+	return score
+}
+
+// HealthGrade converts a health score into a letter grade:
+// A >= 90, B >= 80, C >= 70, D >= 60, F below 60
+func HealthGrade(score float64) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	default:
+		return "F"
+	}
+}
+
+func clampPercent(value float64) float64 {
+	return math.Max(0, math.Min(100, value))
+}
@@ -0,0 +1,48 @@
+package monitor
+
+// history is a fixed-capacity ring buffer of recent snapshots. Once full,
+// each push overwrites the oldest snapshot in place, so hour-scale windows
+// cost nothing extra per sample.
+type history struct {
+	samples []SystemMetrics
+	start   int // index of the oldest snapshot once full
+}
+
+func newHistory(capacity int) history {
+	return history{samples: make([]SystemMetrics, 0, capacity)}
+}
+
+// push appends a snapshot, evicting the oldest one when full
+func (h *history) push(metrics SystemMetrics) {
+	if len(h.samples) < cap(h.samples) {
+		h.samples = append(h.samples, metrics)
+		return
+	}
+	h.samples[h.start] = metrics
+	h.start = (h.start + 1) % len(h.samples)
+}
+
+func (h *history) len() int {
+	return len(h.samples)
+}
+
+// at returns the i-th snapshot, oldest first
+func (h *history) at(i int) SystemMetrics {
+	return h.samples[(h.start+i)%len(h.samples)]
+}
+
+// snapshots returns a copy of the history, oldest first
+func (h *history) snapshots() []SystemMetrics {
+	out := make([]SystemMetrics, 0, len(h.samples))
+	out = append(out, h.samples[h.start:]...)
+	return append(out, h.samples[:h.start]...)
+}
+
+// reset replaces the history with the newest snapshots that fit
+func (h *history) reset(snapshots []SystemMetrics) {
+	if len(snapshots) > cap(h.samples) {
+		snapshots = snapshots[len(snapshots)-cap(h.samples):]
+	}
+	h.samples = append(h.samples[:0], snapshots...)
+	h.start = 0
+}
@@ -0,0 +1,60 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// clearThresholdCategories are the alert categories that accept a clear
+// threshold in Config.ClearThresholds
+var clearThresholdCategories = map[string]bool{
+	"cpu": true, "memory": true, "disk": true, "inodes": true, "iowait": true, "steal": true,
+	"swap": true, "file_descriptors": true, "gpu": true, "network": true,
+}
+
+// threshold returns the level a metric must exceed to alert: the trigger
+// threshold, or while the condition is raised, its lower clear threshold,
+// so that a value hovering around the trigger doesn't flap
+func (a *Analyzer) threshold(category, resource string, trigger float64) float64 {
+	clear, ok := a.config.ClearThresholds[category]
+	if !ok || clear >= trigger || !a.raised[category+"/"+resource] {
+		return trigger
+	}
+	return clear
+}
+
+// recordRaised remembers the conditions alerting in this snapshot.
+// Suppressed alerts count: a quiet window doesn't clear a condition.
+func (a *Analyzer) recordRaised(alerts []Alert) {
+	a.raised = make(map[string]bool, len(alerts))
+	for _, alert := range alerts {
+		a.raised[conditionKey(alert)] = true
+	}
+}
+
+func (c Config) validateHysteresis() []error {
+	var known []string
+	for category := range clearThresholdCategories {
+		known = append(known, category)
+	}
+	sort.Strings(known)
+
+	categories := make([]string, 0, len(c.ClearThresholds))
+	for category := range c.ClearThresholds {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	var errs []error
+	for _, category := range categories {
+		if !clearThresholdCategories[category] {
+			errs = append(errs, fmt.Errorf("clear_thresholds: unknown category %q, expected one of %s",
+				category, strings.Join(known, ", ")))
+		}
+		if clear := c.ClearThresholds[category]; clear < 0 {
+			errs = append(errs, fmt.Errorf("clear_thresholds[%q] must not be negative, got %g", category, clear))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,255 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Incident groups the alerts raised together during an interval, and in
+// following intervals within the alert cooldown, into a single event with
+// a best guess at its primary cause
+type Incident struct {
+	ID           string    `json:"id"`
+	Level        string    `json:"level"` // most severe level of its alerts
+	PrimaryCause string    `json:"primary_cause"`
+	Categories   []string  `json:"categories"`
+	Alerts       []Alert   `json:"alerts"`
+	StartedAt    time.Time `json:"started_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// causePriority orders categories from most to least likely root cause.
+// Resource starvation (I/O, steal, memory) commonly shows up as CPU and
+// load symptoms, so it ranks first.
+var causePriority = []string{
+	"disk_saturation", "memory_exhaustion", "cpu_starvation", "pressure", "forks", "interrupts", "power", "reboot", "raid", "pool", "smart", "filesystem", "iowait", "steal", "throttling", "memory", "numa", "hugepages", "memory_leak", "swap", "process", "systemd", "windows_service", "login", "exposure", "kubernetes", "container", "disk", "inodes", "disk_forecast", "path", "file_descriptors", "zombies", "conntrack", "connections", "tcp", "network", "cpu", "temperature", "gpu", "eventlog", "log", "dns", "ping", "clock", "entropy", "updates", "certificate", "probe",
+}
+
+// Correlate folds the escalating alerts of this interval into the open
+// incident, starting a new one if none is open. An incident stays open
+// while alerts keep arriving within the alert cooldown window and is
+// closed once the window passes without any. It returns the open
+// incident, or nil if there is none.
+func (a *Analyzer) Correlate(metrics *SystemMetrics, alerts []Alert) *Incident {
+	now := metrics.Timestamp
+	window := time.Duration(a.config.AlertCooldown) * time.Second
+
+	var escalating []Alert
+	for _, alert := range alerts {
+		if !alert.Suppressed && severityRank(alert.Level) > 0 {
+			escalating = append(escalating, alert)
+		}
+	}
+
+	if a.incident != nil && now.Sub(a.incident.UpdatedAt) > window {
+		a.incident = nil
+	}
+
+	if len(escalating) == 0 {
+		if a.incident == nil {
+			return nil
+		}
+		incident := *a.incident
+		return &incident
+	}
+
+	if a.incident == nil {
+		a.incident = &Incident{
+			ID:        "INC-" + now.UTC().Format("20060102-150405"),
+			StartedAt: now,
+		}
+	}
+
+	incident := a.incident
+	incident.UpdatedAt = now
+	incident.Alerts = mergeAlerts(incident.Alerts, escalating)
+
+	incident.Level = "warning"
+	seen := make(map[string]bool)
+	incident.Categories = nil
+	for _, alert := range incident.Alerts {
+		if alert.Level == "critical" {
+			incident.Level = "critical"
+		}
+		if !seen[alert.Category] {
+			seen[alert.Category] = true
+			incident.Categories = append(incident.Categories, alert.Category)
+		}
+	}
+	incident.PrimaryCause = primaryCause(metrics, incident.Alerts)
+
+	result := *incident
+	return &result
+}
+
+// Fingerprint identifies what an incident is about independently of when
+// it started: the host plus the category and resource of its primary
+// cause. Incidents with the same fingerprint describe the same problem.
+func (i Incident) Fingerprint(host string) string {
+	alert := primaryAlert(i.Alerts)
+	return host + "/" + alert.Category + "/" + alert.Resource
+}
+
+// primaryAlert returns the alert primaryCause describes
+func primaryAlert(alerts []Alert) Alert {
+	byCategory := worstByCategory(alerts)
+	for _, category := range causePriority {
+		if alert, ok := byCategory[category]; ok {
+			return alert
+		}
+	}
+	if len(alerts) == 0 {
+		return Alert{}
+	}
+	return alerts[0]
+}
+
+// worstByCategory keeps the highest-valued alert of each category
+func worstByCategory(alerts []Alert) map[string]Alert {
+	byCategory := make(map[string]Alert)
+	for _, alert := range alerts {
+		if current, ok := byCategory[alert.Category]; !ok || alert.Value > current.Value {
+			byCategory[alert.Category] = alert
+		}
+	}
+	return byCategory
+}
+
+// mergeAlerts replaces older alerts with newer ones of the same key
+func mergeAlerts(existing, fresh []Alert) []Alert {
+	index := make(map[string]int)
+	merged := make([]Alert, 0, len(existing)+len(fresh))
+	for _, alert := range append(append([]Alert(nil), existing...), fresh...) {
+		key := conditionKey(alert)
+		if i, ok := index[key]; ok {
+			merged[i] = alert
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, alert)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return severityRank(merged[i].Level) > severityRank(merged[j].Level)
+	})
+	return merged
+}
+
+// primaryCause picks the most likely root cause among the alerts and
+// describes it, naming the offending process or resource where possible
+func primaryCause(metrics *SystemMetrics, alerts []Alert) string {
+	byCategory := worstByCategory(alerts)
+
+	topCPU := "unknown process"
+	if group, ok := TopProcessGroup(metrics, false); ok {
+		topCPU = fmt.Sprintf("%s (%d processes)", group.Name, group.Processes)
+	} else if top := GetTopProcesses(metrics, false, 1); len(top) > 0 {
+		topCPU = top[0].Name
+	}
+	topMemory := "unknown process"
+	if group, ok := TopProcessGroup(metrics, true); ok {
+		topMemory = fmt.Sprintf("%s (%d processes)", group.Name, group.Processes)
+	} else if top := GetTopProcesses(metrics, true, 1); len(top) > 0 {
+		topMemory = top[0].Name
+	}
+	busiestIO := topCPU
+	if top := GetTopIOProcesses(metrics, 1); len(top) > 0 {
+		busiestIO = top[0].Name
+	}
+
+	for _, category := range causePriority {
+		alert, ok := byCategory[category]
+		if !ok {
+			continue
+		}
+
+		switch category {
+		case "disk_saturation", "memory_exhaustion", "cpu_starvation":
+			return alert.Message
+		case "raid", "pool", "filesystem":
+			return alert.Message
+		case "smart":
+			return fmt.Sprintf("Failing disk: %s", alert.Message)
+		case "iowait":
+			return fmt.Sprintf("I/O-bound workload: %.1f%% iowait with load %.2f, busiest process %s",
+				alert.Value, metrics.Load.Load1, busiestIO)
+		case "steal":
+			return fmt.Sprintf("CPU starved by the hypervisor: %.1f%% steal", alert.Value)
+		case "throttling":
+			return fmt.Sprintf("Container CPU throttled by its quota in %.0f%% of periods, busiest process %s", alert.Value, topCPU)
+		case "memory":
+			return fmt.Sprintf("Memory exhaustion: %.1f%% used, largest consumer %s", alert.Value, topMemory)
+		case "memory_leak":
+			return alert.Message
+		case "swap":
+			return fmt.Sprintf("Swap thrashing: %.0f pages/s, largest consumer %s", alert.Value, topMemory)
+		case "disk":
+			return fmt.Sprintf("Disk %s running out of space", alert.Resource)
+		case "path":
+			return fmt.Sprintf("Runaway growth: %s", alert.Message)
+		case "disk_forecast":
+			return fmt.Sprintf("Disk %s filling up: %s", alert.Resource, alert.Message)
+		case "inodes":
+			return fmt.Sprintf("Disk %s running out of inodes", alert.Resource)
+		case "file_descriptors":
+			return fmt.Sprintf("File descriptor exhaustion in %s", strings.TrimSuffix(alert.Resource, "/fds"))
+		case "cpu":
+			return fmt.Sprintf("CPU saturation by process %s", topCPU)
+		case "temperature":
+			return fmt.Sprintf("Overheating: %s", alert.Message)
+		case "power":
+			return fmt.Sprintf("Power: %s", alert.Message)
+		case "reboot":
+			return alert.Message
+		case "kubernetes":
+			return alert.Message
+		case "container":
+			return fmt.Sprintf("Container %s: %s", alert.Resource, alert.Message)
+		case "process":
+			return alert.Message
+		case "systemd", "windows_service":
+			return alert.Message
+		case "login":
+			return fmt.Sprintf("Logins: %s", alert.Message)
+		case "updates":
+			return alert.Message
+		case "exposure", "entropy":
+			return alert.Message
+		case "numa", "hugepages":
+			return alert.Message
+		case "pressure":
+			return fmt.Sprintf("Resource pressure: %s", alert.Message)
+		case "forks", "interrupts":
+			return alert.Message
+		case "eventlog", "log":
+			return fmt.Sprintf("Errors logged: %s", alert.Message)
+		case "zombies":
+			return fmt.Sprintf("Unreaped child processes: %s", alert.Message)
+		case "network":
+			return fmt.Sprintf("Network interface %s saturated", alert.Resource)
+		case "connections":
+			return fmt.Sprintf("Connection leak: %s", alert.Message)
+		case "conntrack":
+			return "Connection tracking table exhaustion"
+		case "tcp":
+			return fmt.Sprintf("TCP degradation: %s", alert.Message)
+		case "gpu":
+			return fmt.Sprintf("GPU %s saturated", alert.Resource)
+		case "dns":
+			return fmt.Sprintf("Name resolution failing: %s", alert.Message)
+		case "ping":
+			return fmt.Sprintf("Network connectivity: %s", alert.Message)
+		case "clock":
+			return fmt.Sprintf("Clock skew: %s", alert.Message)
+		case "certificate":
+			return alert.Message
+		case "probe":
+			return fmt.Sprintf("Endpoint %s down: %s", alert.Resource, alert.Message)
+		}
+	}
+
+	// Fall back to the most severe alert
+	return alerts[0].Message
+}
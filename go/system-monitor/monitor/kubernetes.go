@@ -0,0 +1,510 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KubernetesMetrics is the state of the Kubernetes node the monitor runs
+// on and of the pods scheduled to it. Only pods that restarted or are not
+// running normally are listed.
+type KubernetesMetrics struct {
+	Node  string `json:"node"`
+	Ready bool   `json:"ready"`
+
+	// Why the node is not ready, from its Ready condition
+	NotReadyReason string `json:"not_ready_reason,omitempty"`
+
+	// Node conditions other than Ready that are true, such as
+	// "MemoryPressure" or "DiskPressure"
+	Conditions []string `json:"conditions,omitempty"`
+
+	PodCount int          `json:"pod_count"`
+	Pods     []PodMetrics `json:"pods,omitempty"`
+}
+
+// PodMetrics is a pod on the local node that restarted or is not running
+// normally
+type PodMetrics struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Phase     string `json:"phase"`
+
+	// Why the pod is not running: a container's waiting reason such as
+	// "CrashLoopBackOff" or "ImagePullBackOff", or the pod's own reason
+	// such as "Evicted"
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	Restarts int `json:"restarts"` // summed over the pod's containers
+
+	// Reason a container last terminated, such as "OOMKilled" or "Error"
+	LastTermination string `json:"last_termination,omitempty"`
+
+	Created time.Time `json:"created"`
+}
+
+const (
+	kubernetesTimeout = 10 * time.Second
+
+	// Where the service account is mounted when running in a pod
+	kubeServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+// errKubeUnavailable means no cluster configuration was found, so the
+// host is not (known to be) a Kubernetes node
+var errKubeUnavailable = errors.New("no in-cluster configuration or kubeconfig found")
+
+func (c Config) validateKubernetes() []error {
+	var errs []error
+	if c.PodPendingMinutes < 0 {
+		errs = append(errs, fmt.Errorf("pod_pending_minutes must not be negative, got %d", c.PodPendingMinutes))
+	}
+	if c.Kubeconfig != "" && !filepath.IsAbs(c.Kubeconfig) {
+		errs = append(errs, fmt.Errorf("kubeconfig must be an absolute path, got %q", c.Kubeconfig))
+	}
+	return errs
+}
+
+// kubeClient talks to the Kubernetes API server
+type kubeClient struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+// newKubeClient configures a client from the given kubeconfig or, when
+// that is empty, from the pod's service account, $KUBECONFIG or
+// ~/.kube/config, in that order
+func newKubeClient(kubeconfig string) (*kubeClient, error) {
+	if kubeconfig != "" {
+		return kubeClientFromConfig(kubeconfig)
+	}
+	if host := os.Getenv("KUBERNETES_SERVICE_HOST"); host != "" {
+		return kubeClientInCluster(host, os.Getenv("KUBERNETES_SERVICE_PORT"))
+	}
+	var candidates []string
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		candidates = filepath.SplitList(env)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		candidates = []string{filepath.Join(home, ".kube", "config")}
+	}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return kubeClientFromConfig(path)
+		}
+	}
+	return nil, errKubeUnavailable
+}
+
+func kubeClientInCluster(host, port string) (*kubeClient, error) {
+	token, err := os.ReadFile(filepath.Join(kubeServiceAccountDir, "token"))
+	if err != nil {
+		return nil, fmt.Errorf("service account: %w", err)
+	}
+	ca, err := os.ReadFile(filepath.Join(kubeServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("service account: %w", err)
+	}
+	if port == "" {
+		port = "443"
+	}
+	tlsConfig := &tls.Config{RootCAs: x509.NewCertPool()}
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+		return nil, errors.New("service account: no certificates in ca.crt")
+	}
+	return &kubeClient{
+		server: "https://" + net.JoinHostPort(host, port),
+		token:  strings.TrimSpace(string(token)),
+		http:   &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}},
+	}, nil
+}
+
+// kubeconfig is the part of a kubeconfig file the monitor understands.
+// Exec and auth-provider credential plugins are not supported.
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Clusters []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string      `yaml:"token"`
+			TokenFile             string      `yaml:"tokenFile"`
+			ClientCertificate     string      `yaml:"client-certificate"`
+			ClientCertificateData string      `yaml:"client-certificate-data"`
+			ClientKey             string      `yaml:"client-key"`
+			ClientKeyData         string      `yaml:"client-key-data"`
+			Exec                  interface{} `yaml:"exec"`
+			AuthProvider          interface{} `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+func kubeClientFromConfig(path string) (*kubeClient, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config kubeconfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("kubeconfig %s: %w", path, err)
+	}
+
+	// Paths in a kubeconfig are relative to the file
+	dir := filepath.Dir(path)
+	read := func(file, inline string) ([]byte, error) {
+		if inline != "" {
+			return base64.StdEncoding.DecodeString(inline)
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		return os.ReadFile(file)
+	}
+	fail := func(format string, args ...interface{}) (*kubeClient, error) {
+		return nil, fmt.Errorf("kubeconfig %s: "+format, append([]interface{}{path}, args...)...)
+	}
+
+	var clusterName, userName string
+	for _, context := range config.Contexts {
+		if context.Name == config.CurrentContext {
+			clusterName, userName = context.Context.Cluster, context.Context.User
+		}
+	}
+	if clusterName == "" {
+		return fail("current context %q not found", config.CurrentContext)
+	}
+
+	client := &kubeClient{}
+	tlsConfig := &tls.Config{}
+	for _, cluster := range config.Clusters {
+		if cluster.Name != clusterName {
+			continue
+		}
+		client.server = strings.TrimSuffix(cluster.Cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = cluster.Cluster.InsecureSkipTLSVerify
+		if cluster.Cluster.CertificateAuthority != "" || cluster.Cluster.CertificateAuthorityData != "" {
+			ca, err := read(cluster.Cluster.CertificateAuthority, cluster.Cluster.CertificateAuthorityData)
+			if err != nil {
+				return fail("certificate authority: %v", err)
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+				return fail("no certificates in the certificate authority")
+			}
+		}
+	}
+	if client.server == "" {
+		return fail("cluster %q not found", clusterName)
+	}
+
+	for _, user := range config.Users {
+		if user.Name != userName {
+			continue
+		}
+		switch {
+		case user.User.Exec != nil || user.User.AuthProvider != nil:
+			return fail("user %q authenticates through a credential plugin, which is not supported; use a token or client certificate", userName)
+		case user.User.Token != "":
+			client.token = user.User.Token
+		case user.User.TokenFile != "":
+			token, err := read(user.User.TokenFile, "")
+			if err != nil {
+				return fail("token: %v", err)
+			}
+			client.token = strings.TrimSpace(string(token))
+		}
+		if user.User.ClientCertificate != "" || user.User.ClientCertificateData != "" {
+			cert, err := read(user.User.ClientCertificate, user.User.ClientCertificateData)
+			if err != nil {
+				return fail("client certificate: %v", err)
+			}
+			key, err := read(user.User.ClientKey, user.User.ClientKeyData)
+			if err != nil {
+				return fail("client key: %v", err)
+			}
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return fail("client certificate: %v", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+	}
+
+	client.http = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	return client, nil
+}
+
+func (k *kubeClient) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.server+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if k.token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.token)
+	}
+
+	resp, err := k.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var status struct {
+			Message string `json:"message"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&status) == nil && status.Message != "" {
+			return fmt.Errorf("kubernetes %s: %s: %s", path, resp.Status, status.Message)
+		}
+		return fmt.Errorf("kubernetes %s: unexpected status %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+type kubeNode struct {
+	Status struct {
+		Conditions []struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+type kubeContainerState struct {
+	Waiting *struct {
+		Reason string `json:"reason"`
+	} `json:"waiting"`
+	Terminated *struct {
+		Reason string `json:"reason"`
+	} `json:"terminated"`
+}
+
+type kubePodList struct {
+	Items []struct {
+		Metadata struct {
+			Namespace         string    `json:"namespace"`
+			Name              string    `json:"name"`
+			CreationTimestamp time.Time `json:"creationTimestamp"`
+		} `json:"metadata"`
+		Status struct {
+			Phase      string `json:"phase"`
+			Reason     string `json:"reason"`
+			Message    string `json:"message"`
+			Conditions []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+				Reason string `json:"reason"`
+			} `json:"conditions"`
+			ContainerStatuses []struct {
+				RestartCount int                `json:"restartCount"`
+				State        kubeContainerState `json:"state"`
+				LastState    kubeContainerState `json:"lastState"`
+			} `json:"containerStatuses"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// kubernetesNodeName is the node to report on: the configured name, the
+// NODE_NAME variable a pod spec usually sets from spec.nodeName, or the
+// host name
+func (c Config) kubernetesNodeName() (string, error) {
+	if c.KubernetesNode != "" {
+		return c.KubernetesNode, nil
+	}
+	if name := os.Getenv("NODE_NAME"); name != "" {
+		return name, nil
+	}
+	return os.Hostname()
+}
+
+// collectKubernetes reports the local node's conditions and its troubled
+// pods. Hosts without a cluster configuration report nothing.
+func (c *Collector) collectKubernetes(ctx context.Context) (*KubernetesMetrics, error) {
+	ctx, cancel := context.WithTimeout(ctx, kubernetesTimeout)
+	defer cancel()
+
+	// The client is set up on every snapshot, as service account tokens
+	// are rotated
+	client, err := newKubeClient(c.config.Kubeconfig)
+	if errors.Is(err, errKubeUnavailable) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	nodeName, err := c.config.kubernetesNodeName()
+	if err != nil {
+		return nil, err
+	}
+
+	var node kubeNode
+	if err := client.get(ctx, "/api/v1/nodes/"+url.PathEscape(nodeName), &node); err != nil {
+		return nil, err
+	}
+	result := &KubernetesMetrics{Node: nodeName}
+	for _, condition := range node.Status.Conditions {
+		switch {
+		case condition.Type == "Ready":
+			result.Ready = condition.Status == "True"
+			if !result.Ready {
+				result.NotReadyReason = strings.TrimSpace(condition.Reason + ": " + condition.Message)
+			}
+		case condition.Status == "True":
+			result.Conditions = append(result.Conditions, condition.Type)
+		}
+	}
+
+	var pods kubePodList
+	query := url.Values{"fieldSelector": {"spec.nodeName=" + nodeName}}
+	if err := client.get(ctx, "/api/v1/pods?"+query.Encode(), &pods); err != nil {
+		return nil, err
+	}
+	result.PodCount = len(pods.Items)
+	for _, pod := range pods.Items {
+		metrics := PodMetrics{
+			Namespace: pod.Metadata.Namespace,
+			Name:      pod.Metadata.Name,
+			Phase:     pod.Status.Phase,
+			Reason:    pod.Status.Reason,
+			Message:   truncate(pod.Status.Message, logSampleLength),
+			Created:   pod.Metadata.CreationTimestamp,
+		}
+		for _, container := range pod.Status.ContainerStatuses {
+			metrics.Restarts += container.RestartCount
+			if waiting := container.State.Waiting; waiting != nil && metrics.Reason == "" {
+				metrics.Reason = waiting.Reason
+			}
+			if terminated := container.LastState.Terminated; terminated != nil && metrics.LastTermination == "" {
+				metrics.LastTermination = terminated.Reason
+			}
+		}
+		// Pods not yet scheduled name the reason in their conditions
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == "PodScheduled" && condition.Status == "False" && metrics.Reason == "" {
+				metrics.Reason = condition.Reason
+			}
+		}
+
+		healthy := (metrics.Phase == "Running" || metrics.Phase == "Succeeded") && metrics.Reason == ""
+		if !healthy || metrics.Restarts > 0 {
+			result.Pods = append(result.Pods, metrics)
+		}
+	}
+	return result, nil
+}
+
+// checkKubernetes alerts on the node not being ready or under pressure,
+// and on its pods restarting, crash looping, being evicted or pending for
+// longer than PodPendingMinutes
+func (a *Analyzer) checkKubernetes(metrics *SystemMetrics) []Alert {
+	k := metrics.Kubernetes
+	if k == nil {
+		return nil
+	}
+	var alerts []Alert
+	alert := func(level, resource, message string, value float64) {
+		alerts = append(alerts, Alert{
+			Level:     level,
+			Category:  "kubernetes",
+			Resource:  resource,
+			Message:   message,
+			Value:     value,
+			Timestamp: metrics.Timestamp,
+		})
+	}
+
+	if !k.Ready {
+		alert("critical", "node/"+k.Node, fmt.Sprintf("Kubernetes node %s is not ready: %s", k.Node, k.NotReadyReason), 0)
+	}
+	if len(k.Conditions) > 0 {
+		alert("warning", "node/"+k.Node+"/conditions", fmt.Sprintf("Kubernetes node %s reports %s", k.Node, strings.Join(k.Conditions, ", ")),
+			float64(len(k.Conditions)))
+	}
+
+	// Restarts are counted against the previous snapshot; pods created
+	// since then restarted from zero
+	var previous map[string]int
+	var previousTime time.Time
+	if a.history.len() >= 2 {
+		if snapshot := a.history.at(a.history.len() - 2); snapshot.Kubernetes != nil {
+			previous = make(map[string]int)
+			previousTime = snapshot.Timestamp
+			for _, pod := range snapshot.Kubernetes.Pods {
+				previous[pod.Namespace+"/"+pod.Name] = pod.Restarts
+			}
+		}
+	}
+
+	pending := time.Duration(a.config.PodPendingMinutes) * time.Minute
+	for _, pod := range k.Pods {
+		resource := pod.Namespace + "/" + pod.Name
+		before, known := previous[resource]
+		restarted := previous != nil && (known || pod.Created.Before(previousTime)) && pod.Restarts > before
+
+		switch {
+		case pod.Reason == "Evicted":
+			alert("warning", resource, fmt.Sprintf("Pod %s was evicted: %s", resource, pod.Message), 0)
+		case pod.Reason == "CrashLoopBackOff" || restarted:
+			level := "warning"
+			message := fmt.Sprintf("Pod %s restarted %d time(s) since the last sample, %d in total", resource, pod.Restarts-before, pod.Restarts)
+			value := float64(pod.Restarts - before)
+			if !restarted {
+				message = fmt.Sprintf("Pod %s is crash looping after %d restarts", resource, pod.Restarts)
+				value = float64(pod.Restarts)
+			}
+			if pod.LastTermination != "" {
+				message += "; last terminated: " + pod.LastTermination
+			}
+			if pod.LastTermination == "OOMKilled" {
+				level = "critical"
+			}
+			alert(level, resource, message, value)
+		case pod.Phase == "Pending" && pending > 0 && metrics.Timestamp.Sub(pod.Created) > pending:
+			message := fmt.Sprintf("Pod %s has been pending for %s", resource, metrics.Timestamp.Sub(pod.Created).Round(time.Minute))
+			if pod.Reason != "" {
+				message += ": " + pod.Reason
+			}
+			alert("warning", resource, message, metrics.Timestamp.Sub(pod.Created).Minutes())
+		case pod.Phase == "Failed":
+			message := fmt.Sprintf("Pod %s failed", resource)
+			if pod.Reason != "" {
+				message += ": " + pod.Reason
+			}
+			alert("warning", resource, message, 0)
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,64 @@
+package monitor
+
+import "fmt"
+
+// largestProcesses returns copies of the n processes using the most
+// memory, largest first
+func largestProcesses(processes []ProcessMetrics, n int) []ProcessMetrics {
+	return RankProcesses(processes, SortByMemory, n)
+}
+
+// checkProcessLeaks alerts on processes whose memory never shrank over the
+// whole history window and grew by more than Config.ProcessLeakMB. A
+// process is followed by PID and name, so a reused PID starts over, and
+// one that drops out of the largest processes at any point is not judged.
+func (a *Analyzer) checkProcessLeaks(metrics *SystemMetrics) []Alert {
+	n := a.history.len()
+	if a.config.ProcessLeakMB <= 0 || n < 4 {
+		return nil
+	}
+
+	var alerts []Alert
+	for _, process := range metrics.LargestProcesses {
+		first, previous := 0.0, 0.0
+		steady := true
+		for i := 0; i < n && steady; i++ {
+			sample, ok := findProcess(a.history.at(i).LargestProcesses, process)
+			steady = ok && (i == 0 || sample.MemoryMB >= previous)
+			if i == 0 {
+				first = sample.MemoryMB
+			}
+			previous = sample.MemoryMB
+		}
+		growth := process.MemoryMB - first
+		if !steady || growth <= a.config.ProcessLeakMB {
+			continue
+		}
+
+		level := "warning"
+		if growth > 4*a.config.ProcessLeakMB {
+			level = "critical"
+		}
+		alerts = append(alerts, Alert{
+			Level:    level,
+			Category: "memory_leak",
+			Resource: fmt.Sprintf("%s/%d", process.Name, process.PID),
+			Message: fmt.Sprintf("Process %s (PID %d) is leaking memory: %.0f MB, up %.0f MB over the last %d snapshots without ever shrinking",
+				process.Name, process.PID, process.MemoryMB, growth, n),
+			Value:     growth,
+			Threshold: a.config.ProcessLeakMB,
+			Timestamp: metrics.Timestamp,
+		})
+	}
+	return alerts
+}
+
+// findProcess finds the process with the same PID and name
+func findProcess(processes []ProcessMetrics, want ProcessMetrics) (ProcessMetrics, bool) {
+	for _, p := range processes {
+		if p.PID == want.PID && p.Name == want.Name {
+			return p, true
+		}
+	}
+	return ProcessMetrics{}, false
+}
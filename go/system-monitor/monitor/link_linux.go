@@ -0,0 +1,24 @@
+//go:build linux
+
+package monitor
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// linkSpeedMbps reads the negotiated link speed from sysfs. Virtual and
+// disconnected interfaces report -1 or fail the read; both yield zero.
+func linkSpeedMbps(iface string) float64 {
+	data, err := os.ReadFile("/sys/class/net/" + iface + "/speed")
+	if err != nil {
+		return 0
+	}
+
+	speed, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil || speed <= 0 {
+		return 0
+	}
+	return speed
+}
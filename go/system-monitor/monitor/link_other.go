@@ -0,0 +1,8 @@
+//go:build !linux
+
+package monitor
+
+// linkSpeedMbps is only supported on Linux
+func linkSpeedMbps(iface string) float64 {
+	return 0
+}
@@ -0,0 +1,334 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LogWatch counts error and warning lines written to a log file, or to the
+// systemd journal, between snapshots
+type LogWatch struct {
+	Name    string `json:"name"` // label used in alerts, unique
+	Path    string `json:"path"` // file to tail
+	Journal bool   `json:"journal"`
+	Unit    string `json:"unit"` // narrows the journal to one systemd unit
+
+	// Regular expressions for error and warning lines; a line matching
+	// both counts as an error. Journal entries also count by priority.
+	ErrorPattern   string `json:"error_pattern"`
+	WarningPattern string `json:"warning_pattern"`
+
+	// Errors in one snapshot that raise a warning; 0 disables
+	MaxErrors int `json:"max_errors"`
+
+	// How many times its recent average the error count must reach to
+	// count as a spike, 5 by default
+	SpikeFactor float64 `json:"spike_factor"`
+}
+
+// LogMetrics is what a LogWatch counted since the previous snapshot
+type LogMetrics struct {
+	Name      string `json:"name"`
+	Lines     int    `json:"lines"`
+	Errors    int    `json:"errors"`
+	Warnings  int    `json:"warnings"`
+	LastError string `json:"last_error,omitempty"` // redacted and truncated
+	Skipped   int64  `json:"skipped_bytes,omitempty"`
+	Error     string `json:"error,omitempty"` // why the log could not be read
+}
+
+const (
+	defaultLogErrorPattern   = `(?i)\b(error|fatal|panic|critical)\b`
+	defaultLogWarningPattern = `(?i)\bwarn(ing)?\b`
+
+	// logReadLimit caps how much of a file is read per snapshot; a log
+	// growing faster is read from its newest logReadLimit bytes
+	logReadLimit = 16 << 20
+
+	// logSpikeMinErrors keeps a quiet log's first few errors from
+	// counting as a spike
+	logSpikeMinErrors = 10
+
+	// logSampleLength caps LogMetrics.LastError
+	logSampleLength = 300
+)
+
+func (w LogWatch) spikeFactor() float64 {
+	if w.SpikeFactor <= 0 {
+		return 5
+	}
+	return w.SpikeFactor
+}
+
+func (w LogWatch) validate() error {
+	var errs []error
+	if w.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if (w.Path == "") == !w.Journal {
+		errs = append(errs, errors.New("exactly one of path and journal is required"))
+	}
+	if w.Unit != "" && !w.Journal {
+		errs = append(errs, errors.New("unit only applies to the journal"))
+	}
+	for _, pattern := range []string{w.ErrorPattern, w.WarningPattern} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("pattern %q: %w", pattern, err))
+		}
+	}
+	if w.MaxErrors < 0 || w.SpikeFactor < 0 {
+		errs = append(errs, errors.New("max_errors and spike_factor must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+func (c Config) validateLogs() []error {
+	var errs []error
+	names := make(map[string]bool, len(c.LogWatches))
+	for i, watch := range c.LogWatches {
+		if err := watch.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("log_watches[%d]: %w", i, err))
+		}
+		if names[watch.Name] {
+			errs = append(errs, fmt.Errorf("log_watches[%d]: duplicate name %q", i, watch.Name))
+		}
+		names[watch.Name] = true
+	}
+	return errs
+}
+
+// logTail is a compiled LogWatch and how far it has read
+type logTail struct {
+	watch            LogWatch
+	errors, warnings *regexp.Regexp
+
+	// Files are followed by offset, and start over when rotated or
+	// truncated; the journal is followed by cursor
+	file   os.FileInfo
+	offset int64
+	cursor string
+}
+
+// newLogTails compiles the watches. Invalid watches are skipped; use
+// Config.Validate to report them.
+func newLogTails(watches []LogWatch) []*logTail {
+	var tails []*logTail
+	for _, watch := range watches {
+		if watch.validate() != nil {
+			continue
+		}
+		tail := &logTail{watch: watch}
+		tail.errors = regexp.MustCompile(defaultLogErrorPattern)
+		if watch.ErrorPattern != "" {
+			tail.errors = regexp.MustCompile(watch.ErrorPattern)
+		}
+		tail.warnings = regexp.MustCompile(defaultLogWarningPattern)
+		if watch.WarningPattern != "" {
+			tail.warnings = regexp.MustCompile(watch.WarningPattern)
+		}
+		tails = append(tails, tail)
+	}
+	return tails
+}
+
+// collectLogs counts the lines logged since the previous snapshot. The
+// first snapshot only finds the end of each log.
+func (c *Collector) collectLogs(ctx context.Context) ([]LogMetrics, error) {
+	results := make([]LogMetrics, 0, len(c.logTails))
+	for _, tail := range c.logTails {
+		result := LogMetrics{Name: tail.watch.Name}
+		count := func(line string, priority int) {
+			result.Lines++
+			switch {
+			case (priority >= 0 && priority <= 3) || tail.errors.MatchString(line):
+				result.Errors++
+				result.LastError = c.redactor.cmdline(truncate(strings.TrimSpace(line), logSampleLength))
+			case priority == 4 || tail.warnings.MatchString(line):
+				result.Warnings++
+			}
+		}
+
+		var err error
+		if tail.watch.Journal {
+			err = tail.readJournal(ctx, count)
+		} else {
+			result.Skipped, err = tail.readFile(func(line string) { count(line, -1) })
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// readFile passes the complete lines appended since the last read to fn,
+// and returns how many bytes were skipped to keep up
+func (t *logTail) readFile(fn func(line string)) (int64, error) {
+	f, err := os.Open(t.watch.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if t.file == nil {
+		t.file, t.offset = info, info.Size()
+		return 0, nil
+	}
+	if !os.SameFile(t.file, info) || info.Size() < t.offset {
+		t.offset = 0
+	}
+	t.file = info
+
+	var skipped int64
+	if behind := info.Size() - t.offset; behind > logReadLimit {
+		skipped = behind - logReadLimit
+		t.offset += skipped
+	}
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return skipped, err
+	}
+
+	reader := bufio.NewReader(io.LimitReader(f, info.Size()-t.offset))
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// A line still being written is read whole next time
+			return skipped, nil
+		}
+		t.offset += int64(len(line))
+		fn(line)
+	}
+}
+
+// readJournal passes the journal entries logged since the last read to
+// fn, with their syslog priority
+func (t *logTail) readJournal(ctx context.Context, fn func(line string, priority int)) error {
+	path, err := exec.LookPath("journalctl")
+	if err != nil {
+		return errors.New("journalctl not found")
+	}
+
+	args := []string{"--quiet", "--no-pager", "--show-cursor", "--output=json", "--output-fields=MESSAGE,PRIORITY"}
+	if t.cursor == "" {
+		// Only the cursor of the newest entry is wanted
+		args = append(args, "--lines=1")
+	} else {
+		args = append(args, "--after-cursor="+t.cursor)
+	}
+	if t.watch.Unit != "" {
+		args = append(args, "--unit="+t.watch.Unit)
+	}
+	out, err := exec.CommandContext(ctx, path, args...).Output()
+	if err != nil {
+		return fmt.Errorf("journalctl: %w", err)
+	}
+
+	first := t.cursor == ""
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		if cursor, ok := bytes.CutPrefix(line, []byte("-- cursor: ")); ok {
+			t.cursor = string(cursor)
+			continue
+		}
+		var entry struct {
+			Message  interface{} `json:"MESSAGE"`
+			Priority string      `json:"PRIORITY"`
+		}
+		if first || len(line) == 0 || json.Unmarshal(line, &entry) != nil {
+			continue
+		}
+		// Binary messages are arrays of bytes; they are counted, not matched
+		message, _ := entry.Message.(string)
+		priority, err := strconv.Atoi(entry.Priority)
+		if err != nil {
+			priority = -1
+		}
+		fn(message, priority)
+	}
+	return nil
+}
+
+func truncate(text string, length int) string {
+	if len(text) <= length {
+		return text
+	}
+	return text[:length] + "..."
+}
+
+// checkLogs alerts on logs whose error count reached max_errors or spiked
+// above its recent average, and on logs that could not be read
+func (a *Analyzer) checkLogs(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+	for _, result := range metrics.Logs {
+		var watch LogWatch
+		for _, w := range a.config.LogWatches {
+			if w.Name == result.Name {
+				watch = w
+				break
+			}
+		}
+		if watch.Name == "" {
+			continue
+		}
+
+		alert := Alert{
+			Level:     "warning",
+			Category:  "log",
+			Resource:  result.Name,
+			Value:     float64(result.Errors),
+			Timestamp: metrics.Timestamp,
+		}
+		average, samples := a.logErrorAverage(result.Name)
+		switch {
+		case result.Error != "":
+			alert.Message = fmt.Sprintf("Log %s could not be read: %s", result.Name, result.Error)
+		case watch.MaxErrors > 0 && result.Errors >= watch.MaxErrors:
+			alert.Threshold = float64(watch.MaxErrors)
+			alert.Message = fmt.Sprintf("Log %s logged %d errors since the last sample (threshold: %d)",
+				result.Name, result.Errors, watch.MaxErrors)
+		case samples >= 3 && result.Errors >= logSpikeMinErrors && float64(result.Errors) >= watch.spikeFactor()*max(average, 1):
+			alert.Threshold = watch.spikeFactor() * max(average, 1)
+			alert.Message = fmt.Sprintf("Log %s error rate spiked: %d errors since the last sample, against %.1f on average",
+				result.Name, result.Errors, average)
+		default:
+			continue
+		}
+		if result.LastError != "" && result.Error == "" {
+			alert.Message += "; latest: " + result.LastError
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+// logErrorAverage is a log's average error count over the history before
+// this snapshot
+func (a *Analyzer) logErrorAverage(name string) (float64, int) {
+	total, samples := 0, 0
+	for i := 0; i < a.history.len()-1; i++ {
+		for _, result := range a.history.at(i).Logs {
+			if result.Name == name && result.Error == "" {
+				total += result.Errors
+				samples++
+			}
+		}
+	}
+	if samples == 0 {
+		return 0, 0
+	}
+	return float64(total) / float64(samples), samples
+}
@@ -0,0 +1,146 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Monitor ties a metrics source and an analyzer into a reusable monitoring
+// loop. The package has no EYWA dependencies, so a Monitor can be embedded
+// in any Go service; the robot's main.go is just one adapter around it.
+type Monitor struct {
+	config   Config
+	clock    Clock
+	source   MetricsSource
+	analyzer *Analyzer
+	interval time.Duration
+
+	// Control requests, see control.go
+	controlMu  sync.Mutex
+	pending    []func()
+	wake       chan struct{}
+	paused     bool
+	collectNow bool
+	due        time.Time
+
+	// OnError is called when a collection fails. Run keeps going and
+	// retries on the next interval.
+	OnError func(err error)
+
+	// OnPause is called at every skipped collection while paused, see
+	// Pause. Run keeps going unless the context is cancelled.
+	OnPause func()
+
+	// OnIntervalChange is called when the adaptive interval changes
+	OnIntervalChange func(previous, next time.Duration)
+}
+
+// NewMonitor creates a monitor backed by the system Collector. It returns
+// an error if the configuration is invalid.
+func NewMonitor(config Config) (*Monitor, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Monitor{
+		config:   config,
+		clock:    SystemClock,
+		source:   NewCollector(config),
+		analyzer: NewAnalyzer(config),
+		interval: time.Duration(config.Interval) * time.Second,
+		wake:     make(chan struct{}, 1),
+	}, nil
+}
+
+// SetClock replaces the clock used by the loop, the collector and the
+// analyzer
+func (m *Monitor) SetClock(clock Clock) {
+	m.clock = clock
+	m.analyzer.SetClock(clock)
+	if collector, ok := m.source.(*Collector); ok {
+		collector.SetClock(clock)
+	}
+}
+
+// Register adds a collector to the system Collector; see
+// Collector.Register
+func (m *Monitor) Register(collector MetricCollector) error {
+	c, ok := m.source.(*Collector)
+	if !ok {
+		return fmt.Errorf("register collector %q: source is not a Collector", collector.Name())
+	}
+	return c.Register(collector)
+}
+
+// SetSource replaces the metrics source, e.g. with a ScriptedSource
+func (m *Monitor) SetSource(source MetricsSource) {
+	m.source = source
+}
+
+// Config returns the monitor's configuration
+func (m *Monitor) Config() Config {
+	return m.config
+}
+
+// Analyzer returns the analyzer, for recommendations and incident
+// correlation on top of Analyze
+func (m *Monitor) Analyzer() *Analyzer {
+	return m.analyzer
+}
+
+// Interval returns the wait before the next collection. It only differs
+// from Config.Interval in adaptive mode.
+func (m *Monitor) Interval() time.Duration {
+	return m.interval
+}
+
+// Collect takes a single metrics snapshot. The snapshot may be partial,
+// see Collector.CollectMetrics.
+func (m *Monitor) Collect(ctx context.Context) (*SystemMetrics, error) {
+	return m.source.CollectMetrics(ctx)
+}
+
+// Analyze checks a snapshot against the configured thresholds and the
+// analyzer's history. Alerts silenced by a suppression window are
+// included with Suppressed set.
+func (m *Monitor) Analyze(metrics *SystemMetrics) []Alert {
+	return m.analyzer.AnalyzeMetrics(metrics)
+}
+
+// Run collects and analyzes metrics every Interval and passes each
+// snapshot with its alerts to fn, until ctx is cancelled. The next
+// interval is already decided when fn is called. Snapshots where only
+// some collectors failed are analyzed as usual and list the failures in
+// CollectorFailures; collections that produced no snapshot at all are
+// reported through OnError and skipped. The loop can be paused, resumed
+// and reconfigured while it runs, see Pause. Run returns the context's
+// error.
+func (m *Monitor) Run(ctx context.Context, fn func(*SystemMetrics, []Alert)) error {
+	for {
+		metrics, err := m.Collect(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if metrics == nil {
+			if m.OnError != nil {
+				m.OnError(err)
+			}
+		} else {
+			alerts := m.Analyze(metrics)
+			m.adaptInterval(metrics, alerts)
+			fn(metrics, alerts)
+		}
+
+		// fn or OnError may have cancelled the loop
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err := m.wait(ctx); err != nil {
+			return err
+		}
+	}
+}
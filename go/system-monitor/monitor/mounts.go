@@ -0,0 +1,137 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// matchMount reports whether a path.Match pattern matches the mount point
+// or one of its parent directories, so "/snap/*" also covers
+// /snap/core22/1380. The root directory only matches itself.
+func matchMount(pattern, mount string) bool {
+	for p := mount; ; p = path.Dir(p) {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		parent := path.Dir(p)
+		if parent == p || parent == "/" || parent == "." {
+			return false
+		}
+	}
+}
+
+// monitorsMount reports whether the mount point passes DiskInclude (when
+// set) and is not excluded by DiskExclude
+func (c Config) monitorsMount(mount string) bool {
+	if len(c.DiskInclude) > 0 {
+		included := false
+		for _, pattern := range c.DiskInclude {
+			if matchMount(pattern, mount) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, pattern := range c.DiskExclude {
+		if matchMount(pattern, mount) {
+			return false
+		}
+	}
+	return true
+}
+
+// diskThreshold returns the space usage threshold for a mount point
+func (c Config) diskThreshold(mount string) float64 {
+	return mountThreshold(c.DiskThresholds, c.DiskThreshold, mount)
+}
+
+// inodeThreshold returns the inode usage threshold for a mount point
+func (c Config) inodeThreshold(mount string) float64 {
+	return mountThreshold(c.InodeThresholds, c.InodeThreshold, mount)
+}
+
+// mountThreshold picks an exact override for the mount point, else the
+// longest matching pattern, else the default
+func mountThreshold(overrides map[string]float64, fallback float64, mount string) float64 {
+	if threshold, ok := overrides[mount]; ok {
+		return threshold
+	}
+
+	best, threshold := "", fallback
+	for pattern, value := range overrides {
+		if len(pattern) > len(best) && matchMount(pattern, mount) {
+			best, threshold = pattern, value
+		}
+	}
+	return threshold
+}
+
+// validateMounts checks the per-mount thresholds and the mount patterns
+func (c Config) validateMounts() []error {
+	var errs []error
+
+	thresholds := func(name string, overrides map[string]float64) {
+		patterns := make([]string, 0, len(overrides))
+		for pattern := range overrides {
+			patterns = append(patterns, pattern)
+		}
+		sort.Strings(patterns)
+		for _, pattern := range patterns {
+			if threshold := overrides[pattern]; threshold < 0 || threshold > 100 {
+				errs = append(errs, fmt.Errorf("%s[%q] must be between 0 and 100, got %g", name, pattern, threshold))
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid pattern %q", name, pattern))
+			}
+		}
+	}
+	thresholds("disk_thresholds", c.DiskThresholds)
+	thresholds("inode_thresholds", c.InodeThresholds)
+
+	globs := func(name string, list []string) {
+		for _, pattern := range list {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid pattern %q", name, pattern))
+			}
+		}
+	}
+	globs("disk_include", c.DiskInclude)
+	globs("disk_exclude", c.DiskExclude)
+
+	return errs
+}
+
+// readFstabWritable returns the mount points /etc/fstab mounts read-write.
+// Hosts without an fstab have none.
+func readFstabWritable() map[string]bool {
+	data, err := os.ReadFile("/etc/fstab")
+	if err != nil {
+		return nil
+	}
+
+	writable := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		// Spaces in mount points are escaped as \040
+		mount := strings.ReplaceAll(fields[1], `\040`, " ")
+		readOnly := false
+		for _, option := range strings.Split(fields[3], ",") {
+			if option == "ro" {
+				readOnly = true
+			}
+		}
+		if !readOnly {
+			writable[mount] = true
+		}
+	}
+	return writable
+}
@@ -0,0 +1,204 @@
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ClockMetrics is the system clock's offset from one NTP server. A
+// positive offset means the system clock is behind the server.
+type ClockMetrics struct {
+	Server   string  `json:"server"`
+	OffsetMs float64 `json:"offset_ms"`
+	RTTMs    float64 `json:"rtt_ms"`
+	Stratum  int     `json:"stratum,omitempty"`
+	Error    string  `json:"error,omitempty"`
+}
+
+const (
+	ntpTimeout = 5 * time.Second
+
+	// Seconds from the NTP epoch (1900) to the Unix epoch
+	ntpEpochOffset = 2208988800
+)
+
+func (c Config) validateNTP() []error {
+	var errs []error
+	for i, server := range c.NTPServers {
+		if server == "" {
+			errs = append(errs, fmt.Errorf("ntp_servers[%d]: server is required", i))
+		}
+	}
+	if c.ClockOffsetThreshold < 0 {
+		errs = append(errs, fmt.Errorf("clock_offset_threshold must not be negative, got %g", c.ClockOffsetThreshold))
+	}
+	return errs
+}
+
+// collectClock queries every NTP server concurrently. It measures against
+// the wall clock, never the collector's clock, which may be simulated.
+func (c *Collector) collectClock(ctx context.Context) ([]ClockMetrics, error) {
+	results := make([]ClockMetrics, len(c.config.NTPServers))
+	var wg sync.WaitGroup
+	for i, server := range c.config.NTPServers {
+		wg.Add(1)
+		go func(i int, server string) {
+			defer wg.Done()
+			results[i] = queryNTP(ctx, server)
+		}(i, server)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// queryNTP sends one SNTP request (RFC 4330) and computes the clock
+// offset from the four timestamps of the exchange
+func queryNTP(ctx context.Context, server string) ClockMetrics {
+	result := ClockMetrics{Server: server}
+	address := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		address = net.JoinHostPort(server, "123")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ntpTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	request := make([]byte, 48)
+	request[0] = 0x23 // leap indicator 0, version 4, mode 3 (client)
+	sent := time.Now()
+	putNTPTime(request[40:], sent) // transmit timestamp, echoed back as the originate timestamp
+	if _, err := conn.Write(request); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	response := make([]byte, 48)
+	n, err := conn.Read(response)
+	received := time.Now()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if err := checkNTPResponse(response[:n], request[40:48]); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	serverReceived := ntpTime(response[32:])
+	serverSent := ntpTime(response[40:])
+	offset := (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2
+	rtt := received.Sub(sent) - serverSent.Sub(serverReceived)
+
+	result.OffsetMs = math.Round(float64(offset.Microseconds())) / 1000
+	result.RTTMs = math.Round(float64(rtt.Microseconds())) / 1000
+	result.Stratum = int(response[1])
+	return result
+}
+
+func checkNTPResponse(response, originate []byte) error {
+	switch {
+	case len(response) < 48:
+		return fmt.Errorf("short response of %d bytes", len(response))
+	case response[0]&0x7 != 4:
+		return fmt.Errorf("not a server response (mode %d)", response[0]&0x7)
+	case response[1] == 0:
+		return errors.New("kiss-of-death response, the server refuses to answer")
+	case response[0]>>6 == 3:
+		return errors.New("server clock is not synchronized")
+	case string(response[24:32]) != string(originate):
+		return errors.New("response does not match the request")
+	}
+	return nil
+}
+
+func putNTPTime(b []byte, t time.Time) {
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / 1e9
+	binary.BigEndian.PutUint64(b, seconds<<32|fraction)
+}
+
+func ntpTime(b []byte) time.Time {
+	value := binary.BigEndian.Uint64(b)
+	seconds := int64(value>>32) - ntpEpochOffset
+	nanos := int64((value & 0xffffffff) * 1e9 >> 32)
+	return time.Unix(seconds, nanos)
+}
+
+// checkClock alerts when the median offset across the servers that
+// answered exceeds the threshold, critically beyond ten times it, and
+// when no server answered
+func (a *Analyzer) checkClock(metrics *SystemMetrics) []Alert {
+	if len(metrics.Clock) == 0 || a.config.ClockOffsetThreshold <= 0 {
+		return nil
+	}
+
+	var offsets []float64
+	for _, server := range metrics.Clock {
+		if server.Error == "" {
+			offsets = append(offsets, server.OffsetMs)
+		}
+	}
+	if len(offsets) == 0 {
+		return []Alert{{
+			Level:     "warning",
+			Category:  "clock",
+			Resource:  "ntp",
+			Message:   fmt.Sprintf("Clock offset unknown: none of %d NTP servers answered (%s)", len(metrics.Clock), metrics.Clock[0].Error),
+			Timestamp: metrics.Timestamp,
+		}}
+	}
+
+	// The median is robust against a single misbehaving server
+	sort.Float64s(offsets)
+	offset := offsets[len(offsets)/2]
+	if len(offsets)%2 == 0 {
+		offset = (offsets[len(offsets)/2-1] + offset) / 2
+	}
+	threshold := a.config.ClockOffsetThreshold
+	if math.Abs(offset) <= threshold {
+		return nil
+	}
+
+	level := "warning"
+	if math.Abs(offset) > 10*threshold {
+		level = "critical"
+	}
+	direction := "behind"
+	if offset < 0 {
+		direction = "ahead of"
+	}
+	return []Alert{{
+		Level:    level,
+		Category: "clock",
+		Resource: "ntp",
+		Message: fmt.Sprintf("System clock is %s %s NTP time (threshold: %s)",
+			formatOffset(offset), direction, formatOffset(threshold)),
+		Value:     offset,
+		Threshold: threshold,
+		Timestamp: metrics.Timestamp,
+	}}
+}
+
+func formatOffset(ms float64) string {
+	ms = math.Abs(ms)
+	if ms >= 1000 {
+		return fmt.Sprintf("%.1f s", ms/1000)
+	}
+	return fmt.Sprintf("%.0f ms", ms)
+}
@@ -0,0 +1,175 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NUMAMetrics is memory per NUMA node and huge page usage (Linux).
+// System-wide memory percent hides a node running full while another has
+// plenty free, which makes the kernel allocate on the remote node, slower
+// for every access after.
+type NUMAMetrics struct {
+	// Only reported on hosts with more than one node
+	Nodes []NUMANodeMetrics `json:"nodes,omitempty"`
+
+	// Sizes with huge pages allocated
+	HugePages []HugePageMetrics `json:"huge_pages,omitempty"`
+
+	// Transparent huge pages mode: "always", "madvise" or "never".
+	// Several databases recommend "never" or "madvise".
+	TransparentHugePages string `json:"transparent_huge_pages,omitempty"`
+}
+
+// NUMANodeMetrics is one NUMA node's memory
+type NUMANodeMetrics struct {
+	Node    int     `json:"node"`
+	TotalGB float64 `json:"total_gb"`
+	FreeGB  float64 `json:"free_gb"`
+
+	// Used like Memory.UsedPercent, counting page cache and reclaimable
+	// slab as available
+	UsedPercent float64 `json:"used_percent"`
+
+	// Pages meant for this node that were allocated on another because
+	// it was short of free memory
+	ForeignPagesPerSec float64 `json:"foreign_pages_per_sec"`
+
+	HugePagesGB     float64 `json:"huge_pages_gb,omitempty"`
+	HugePagesFreeGB float64 `json:"huge_pages_free_gb,omitempty"`
+}
+
+// HugePageMetrics is the pool of persistent huge pages of one size
+type HugePageMetrics struct {
+	SizeKB   int `json:"size_kb"`
+	Total    int `json:"total"`
+	Free     int `json:"free"`
+	Reserved int `json:"reserved"` // promised to mappings, not yet faulted in
+	Surplus  int `json:"surplus"`  // allocated over Total through overcommit
+}
+
+// numaCounters is a node's numastat counters at a sample
+type numaCounters struct {
+	foreign uint64
+	at      time.Time
+}
+
+// collectNUMA reads the NUMA nodes and huge page pools, and derives each
+// node's foreign allocation rate from the previous sample. Other
+// platforms report nothing.
+func (c *Collector) collectNUMA(ctx context.Context) (*NUMAMetrics, error) {
+	numa, foreign, err := readNUMA()
+	if numa == nil || err != nil {
+		return nil, err
+	}
+
+	now := c.clock.Now()
+	current := make(map[int]numaCounters, len(foreign))
+	for i := range numa.Nodes {
+		node := &numa.Nodes[i]
+		count, ok := foreign[node.Node]
+		if !ok {
+			continue
+		}
+		current[node.Node] = numaCounters{foreign: count, at: now}
+		prev, ok := c.prevNUMA[node.Node]
+		if elapsed := now.Sub(prev.at).Seconds(); ok && elapsed > 0 && count >= prev.foreign {
+			node.ForeignPagesPerSec = float64(count-prev.foreign) / elapsed
+		}
+	}
+	c.prevNUMA = current
+	return numa, nil
+}
+
+// checkNUMA warns about a NUMA node over Config.MemoryThreshold while the
+// host as a whole is under it, so the memory check stays quiet, and about
+// huge page pools that are exhausted, or that hold 1 GB or more nobody
+// used for the last 3 snapshots
+func (a *Analyzer) checkNUMA(metrics *SystemMetrics) []Alert {
+	numa := metrics.NUMA
+	if numa == nil {
+		return nil
+	}
+
+	var alerts []Alert
+	if metrics.Memory.UsedPercent <= a.config.MemoryThreshold {
+		for _, node := range numa.Nodes {
+			if node.UsedPercent <= a.config.MemoryThreshold {
+				continue
+			}
+			var others []string
+			for _, other := range numa.Nodes {
+				if other.Node != node.Node {
+					others = append(others, fmt.Sprintf("node %d at %.0f%%", other.Node, other.UsedPercent))
+				}
+			}
+			message := fmt.Sprintf("NUMA node %d memory is %.1f%% used (%.1f GB free) while %s",
+				node.Node, node.UsedPercent, node.FreeGB, strings.Join(others, ", "))
+			if node.ForeignPagesPerSec > 0 {
+				message += fmt.Sprintf("; %.0f pages/s meant for it are allocated remotely", node.ForeignPagesPerSec)
+			}
+			alerts = append(alerts, Alert{
+				Level:     "warning",
+				Category:  "numa",
+				Resource:  fmt.Sprintf("node%d", node.Node),
+				Message:   message,
+				Value:     node.UsedPercent,
+				Threshold: a.config.MemoryThreshold,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+
+	for _, pool := range numa.HugePages {
+		resource := fmt.Sprintf("hugepages-%dkB", pool.SizeKB)
+		switch {
+		case pool.Total > 0 && pool.Free-pool.Reserved <= 0:
+			alerts = append(alerts, Alert{
+				Level:    "warning",
+				Category: "hugepages",
+				Resource: resource,
+				Message: fmt.Sprintf("All %d huge pages of %d kB are in use or reserved; further huge page mappings will fail",
+					pool.Total, pool.SizeKB),
+				Value:     float64(pool.Total - pool.Free + pool.Reserved),
+				Threshold: float64(pool.Total),
+				Timestamp: metrics.Timestamp,
+			})
+		case float64(pool.Total)*float64(pool.SizeKB) >= 1024*1024 && a.hugePagesUnused(pool.SizeKB):
+			alerts = append(alerts, Alert{
+				Level:    "warning",
+				Category: "hugepages",
+				Resource: resource,
+				Message: fmt.Sprintf("%.1f GB of huge pages (%d pages of %d kB) are allocated but unused, and unavailable to anything else",
+					float64(pool.Total)*float64(pool.SizeKB)/(1024*1024), pool.Total, pool.SizeKB),
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+	return alerts
+}
+
+// hugePagesUnused reports whether the huge page pool of the size was
+// entirely free and unreserved in the last 3 snapshots
+func (a *Analyzer) hugePagesUnused(sizeKB int) bool {
+	if a.history.len() < 3 {
+		return false
+	}
+	for i := a.history.len() - 3; i < a.history.len(); i++ {
+		numa := a.history.at(i).NUMA
+		if numa == nil {
+			return false
+		}
+		unused := false
+		for _, pool := range numa.HugePages {
+			if pool.SizeKB == sizeKB {
+				unused = pool.Free == pool.Total && pool.Reserved == 0
+			}
+		}
+		if !unused {
+			return false
+		}
+	}
+	return true
+}
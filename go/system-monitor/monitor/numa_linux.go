@@ -0,0 +1,124 @@
+//go:build linux
+
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	nodeDir     = "/sys/devices/system/node"
+	hugePageDir = "/sys/kernel/mm/hugepages"
+)
+
+// readNUMA reads the node memory of multi-node hosts from sysfs, the huge
+// page pools, and the transparent huge pages mode, along with each node's
+// cumulative numa_foreign counter
+func readNUMA() (*NUMAMetrics, map[int]uint64, error) {
+	numa := &NUMAMetrics{}
+	foreign := make(map[int]uint64)
+
+	dirs, _ := filepath.Glob(nodeDir + "/node[0-9]*")
+	if len(dirs) > 1 {
+		for _, dir := range dirs {
+			id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+			if err != nil {
+				continue
+			}
+			node, ok := readNUMANode(dir, id)
+			if !ok {
+				continue
+			}
+			numa.Nodes = append(numa.Nodes, node)
+			if count, ok := readCgroupStats(dir, "numastat")["numa_foreign"]; ok {
+				foreign[id] = count
+			}
+		}
+		sort.Slice(numa.Nodes, func(i, j int) bool { return numa.Nodes[i].Node < numa.Nodes[j].Node })
+	}
+
+	pools, _ := filepath.Glob(hugePageDir + "/hugepages-*kB")
+	for _, dir := range pools {
+		size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(dir), "hugepages-"), "kB"))
+		if err != nil {
+			continue
+		}
+		pool := HugePageMetrics{
+			SizeKB:   size,
+			Total:    readSysfsInt(dir + "/nr_hugepages"),
+			Free:     readSysfsInt(dir + "/free_hugepages"),
+			Reserved: readSysfsInt(dir + "/resv_hugepages"),
+			Surplus:  readSysfsInt(dir + "/surplus_hugepages"),
+		}
+		if pool.Total > 0 || pool.Surplus > 0 {
+			numa.HugePages = append(numa.HugePages, pool)
+		}
+	}
+	sort.Slice(numa.HugePages, func(i, j int) bool { return numa.HugePages[i].SizeKB < numa.HugePages[j].SizeKB })
+
+	// "always [madvise] never"
+	if data, err := os.ReadFile("/sys/kernel/mm/transparent_hugepage/enabled"); err == nil {
+		if start := bytes.IndexByte(data, '['); start >= 0 {
+			if end := bytes.IndexByte(data[start:], ']'); end > 0 {
+				numa.TransparentHugePages = string(data[start+1 : start+end])
+			}
+		}
+	}
+	return numa, foreign, nil
+}
+
+// readNUMANode reads a node's meminfo ("Node 0 MemTotal:  6147400 kB")
+// and its huge page pools
+func readNUMANode(dir string, id int) (NUMANodeMetrics, bool) {
+	node := NUMANodeMetrics{Node: id}
+	file, err := os.Open(dir + "/meminfo")
+	if err != nil {
+		return node, false
+	}
+	defer file.Close()
+
+	kb := make(map[string]float64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[3], 64)
+		if err == nil {
+			kb[strings.TrimSuffix(fields[2], ":")] = value
+		}
+	}
+	total := kb["MemTotal"]
+	if total <= 0 {
+		return node, false
+	}
+	const gb = 1024 * 1024
+	node.TotalGB = total / gb
+	node.FreeGB = kb["MemFree"] / gb
+	available := kb["MemFree"] + kb["FilePages"] - kb["Shmem"] + kb["SReclaimable"]
+	node.UsedPercent = (total - available) / total * 100
+
+	pools, _ := filepath.Glob(dir + "/hugepages/hugepages-*kB")
+	for _, pool := range pools {
+		size, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(pool), "hugepages-"), "kB"), 64)
+		if err != nil {
+			continue
+		}
+		node.HugePagesGB += float64(readSysfsInt(pool+"/nr_hugepages")) * size / gb
+		node.HugePagesFreeGB += float64(readSysfsInt(pool+"/free_hugepages")) * size / gb
+	}
+	return node, true
+}
+
+// readSysfsInt reads a sysfs file holding an integer, zero if unreadable
+func readSysfsInt(path string) int {
+	value, _ := readSysfsFloat(path)
+	return int(value)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package monitor
+
+// readNUMA is only supported on Linux
+func readNUMA() (*NUMAMetrics, map[int]uint64, error) {
+	return nil, nil, nil
+}
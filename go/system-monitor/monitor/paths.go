@@ -0,0 +1,173 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// PathWatch is a file or directory whose size and growth are watched, such
+// as /var/log or a dump directory
+type PathWatch struct {
+	Path string `json:"path"`
+
+	// Size at which a critical alert is raised; 0 disables
+	MaxSizeMB float64 `json:"max_size_mb"`
+
+	// Growth rate, measured across the history window, at which a
+	// warning is raised; 0 disables
+	MaxGrowthMBPerHour float64 `json:"max_growth_mb_per_hour"`
+}
+
+// PathMetrics is the size of a watched path, counting every regular file
+// below a directory. Symbolic links are not followed.
+type PathMetrics struct {
+	Path       string  `json:"path"`
+	SizeMB     float64 `json:"size_mb"`
+	Files      int     `json:"files"`
+	Unreadable int     `json:"unreadable,omitempty"` // entries that could not be read
+	Error      string  `json:"error,omitempty"`
+}
+
+// pathGrowthMinSpan is the history a growth rate needs, so a single large
+// write doesn't extrapolate to an absurd hourly rate
+const pathGrowthMinSpan = 5 * time.Minute
+
+func (w PathWatch) validate() error {
+	var errs []error
+	if w.Path == "" || !filepath.IsAbs(w.Path) {
+		errs = append(errs, fmt.Errorf("path must be absolute, got %q", w.Path))
+	}
+	if w.MaxSizeMB < 0 || w.MaxGrowthMBPerHour < 0 {
+		errs = append(errs, errors.New("max_size_mb and max_growth_mb_per_hour must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+func (c Config) validatePaths() []error {
+	var errs []error
+	paths := make(map[string]bool, len(c.PathWatches))
+	for i, watch := range c.PathWatches {
+		if err := watch.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("path_watches[%d]: %w", i, err))
+		}
+		if paths[watch.Path] {
+			errs = append(errs, fmt.Errorf("path_watches[%d]: duplicate path %q", i, watch.Path))
+		}
+		paths[watch.Path] = true
+	}
+	return errs
+}
+
+// collectPaths measures every watched path
+func (c *Collector) collectPaths(ctx context.Context) ([]PathMetrics, error) {
+	results := make([]PathMetrics, 0, len(c.config.PathWatches))
+	for _, watch := range c.config.PathWatches {
+		result, err := measurePath(ctx, watch.Path)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func measurePath(ctx context.Context, root string) (PathMetrics, error) {
+	result := PathMetrics{Path: root}
+	var bytes int64
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			result.Unreadable++
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			result.Unreadable++
+			return nil
+		}
+		bytes += info.Size()
+		result.Files++
+		return nil
+	})
+	result.SizeMB = float64(bytes) / 1024 / 1024
+	return result, err
+}
+
+// checkPaths alerts on watched paths over their size cap or growing faster
+// than allowed
+func (a *Analyzer) checkPaths(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+	for _, result := range metrics.Paths {
+		var watch PathWatch
+		for _, w := range a.config.PathWatches {
+			if w.Path == result.Path {
+				watch = w
+				break
+			}
+		}
+		if watch.Path == "" || result.Error != "" {
+			continue
+		}
+
+		if watch.MaxSizeMB > 0 && result.SizeMB > watch.MaxSizeMB {
+			alerts = append(alerts, Alert{
+				Level:    "critical",
+				Category: "path",
+				Resource: result.Path,
+				Message: fmt.Sprintf("%s is %.0f MB in %d files (cap: %.0f MB)",
+					result.Path, result.SizeMB, result.Files, watch.MaxSizeMB),
+				Value:     result.SizeMB,
+				Threshold: watch.MaxSizeMB,
+				Timestamp: metrics.Timestamp,
+			})
+			continue
+		}
+
+		if rate, ok := a.pathGrowth(result, metrics.Timestamp); ok && watch.MaxGrowthMBPerHour > 0 && rate > watch.MaxGrowthMBPerHour {
+			alerts = append(alerts, Alert{
+				Level:    "warning",
+				Category: "path",
+				Resource: result.Path,
+				Message: fmt.Sprintf("%s is growing %.1f MB/hour, now %.0f MB in %d files (threshold: %.1f MB/hour)",
+					result.Path, rate, result.SizeMB, result.Files, watch.MaxGrowthMBPerHour),
+				Value:     rate,
+				Threshold: watch.MaxGrowthMBPerHour,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+	return alerts
+}
+
+// pathGrowth is a path's growth in MB per hour since the oldest snapshot
+// in the history that measured it
+func (a *Analyzer) pathGrowth(current PathMetrics, now time.Time) (float64, bool) {
+	for i := 0; i < a.history.len(); i++ {
+		snapshot := a.history.at(i)
+		span := now.Sub(snapshot.Timestamp)
+		if span < pathGrowthMinSpan {
+			break
+		}
+		for _, earlier := range snapshot.Paths {
+			if earlier.Path == current.Path && earlier.Error == "" {
+				return (current.SizeMB - earlier.SizeMB) / span.Hours(), true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,286 @@
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// PingTarget is a host whose round-trip time and packet loss are measured
+// on every snapshot
+type PingTarget struct {
+	Name string `json:"name"` // label used in alerts, unique
+	Host string `json:"host"`
+
+	// "icmp", "tcp", or empty to use ICMP where the monitor may send it
+	// and fall back to TCP otherwise
+	Method string `json:"method"`
+
+	Port           int `json:"port"`    // TCP port, 443 by default
+	Count          int `json:"count"`   // packets per snapshot, 3 by default
+	TimeoutSeconds int `json:"timeout"` // per packet, 1 by default
+}
+
+// PingMetrics is the outcome of one PingTarget
+type PingMetrics struct {
+	Name        string  `json:"name"`
+	Method      string  `json:"method"` // "icmp" or "tcp"
+	Sent        int     `json:"sent"`
+	Received    int     `json:"received"`
+	LossPercent float64 `json:"loss_percent"`
+	MinMs       float64 `json:"min_ms"`
+	AvgMs       float64 `json:"avg_ms"`
+	MaxMs       float64 `json:"max_ms"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// pingID tells apart the echo requests of targets pinged concurrently
+var pingID atomic.Uint32
+
+func (t PingTarget) validate() error {
+	var errs []error
+	if t.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if t.Host == "" {
+		errs = append(errs, errors.New("host is required"))
+	}
+	if t.Method != "" && t.Method != "icmp" && t.Method != "tcp" {
+		errs = append(errs, fmt.Errorf(`method must be "icmp" or "tcp", got %q`, t.Method))
+	}
+	if t.Port < 0 || t.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port must be between 1 and 65535, got %d", t.Port))
+	}
+	if t.Count < 0 || t.TimeoutSeconds < 0 {
+		errs = append(errs, errors.New("count and timeout must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+func (c Config) validatePing() []error {
+	var errs []error
+	names := make(map[string]bool, len(c.PingTargets))
+	for i, target := range c.PingTargets {
+		if err := target.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("ping_targets[%d]: %w", i, err))
+		}
+		if names[target.Name] {
+			errs = append(errs, fmt.Errorf("ping_targets[%d]: duplicate name %q", i, target.Name))
+		}
+		names[target.Name] = true
+	}
+	if c.PingLossThreshold < 0 || c.PingLossThreshold > 100 {
+		errs = append(errs, fmt.Errorf("ping_loss_threshold must be between 0 and 100, got %g", c.PingLossThreshold))
+	}
+	if c.PingLatencyThreshold < 0 {
+		errs = append(errs, fmt.Errorf("ping_latency_threshold must not be negative, got %g", c.PingLatencyThreshold))
+	}
+	return errs
+}
+
+// collectPing pings every target concurrently
+func (c *Collector) collectPing(ctx context.Context) ([]PingMetrics, error) {
+	results := make([]PingMetrics, len(c.config.PingTargets))
+	var wg sync.WaitGroup
+	for i, target := range c.config.PingTargets {
+		wg.Add(1)
+		go func(i int, target PingTarget) {
+			defer wg.Done()
+			results[i] = target.ping(ctx)
+		}(i, target)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+func (t PingTarget) ping(ctx context.Context) PingMetrics {
+	result := PingMetrics{Name: t.Name, Method: t.Method, Sent: t.Count}
+	if result.Sent <= 0 {
+		result.Sent = 3
+	}
+	timeout := time.Second
+	if t.TimeoutSeconds > 0 {
+		timeout = time.Duration(t.TimeoutSeconds) * time.Second
+	}
+
+	var rtts []time.Duration
+	var err error
+	if t.Method != "tcp" {
+		rtts, err = t.pingICMP(ctx, result.Sent, timeout)
+		result.Method = "icmp"
+	}
+	if t.Method == "tcp" || (t.Method == "" && errors.Is(err, errICMPUnavailable)) {
+		rtts, err = t.pingTCP(ctx, result.Sent, timeout)
+		result.Method = "tcp"
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	result.Received = len(rtts)
+	result.LossPercent = float64(result.Sent-result.Received) / float64(result.Sent) * 100
+	for i, rtt := range rtts {
+		ms := float64(rtt.Microseconds()) / 1000
+		if i == 0 || ms < result.MinMs {
+			result.MinMs = ms
+		}
+		result.MaxMs = max(result.MaxMs, ms)
+		result.AvgMs += ms / float64(len(rtts))
+	}
+	result.AvgMs = math.Round(result.AvgMs*1000) / 1000
+	return result
+}
+
+// errICMPUnavailable means the monitor may not send ICMP echo requests to
+// the target, as opposed to the target not answering them
+var errICMPUnavailable = errors.New("ICMP unavailable")
+
+// pingICMP sends count echo requests, one at a time, and returns the
+// round-trip times of those answered. ICMP needs a raw socket (root or
+// CAP_NET_RAW) or, on Linux, a group allowed by net.ipv4.ping_group_range;
+// only IPv4 targets are supported.
+func (t PingTarget) pingICMP(ctx context.Context, count int, timeout time.Duration) ([]time.Duration, error) {
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip4", t.Host)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errICMPUnavailable, err)
+	}
+	ip := ips[0]
+
+	// A raw socket sees every ICMP packet the host receives, so replies
+	// are matched by source and identifier too. On an unprivileged socket
+	// the kernel picks the identifier and routes replies itself.
+	var addr net.Addr = &net.IPAddr{IP: ip}
+	raw := true
+	conn, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		addr, raw = &net.UDPAddr{IP: ip}, false
+		if conn, err = listenUnprivilegedICMP(); err != nil {
+			return nil, fmt.Errorf("%w: %v", errICMPUnavailable, err)
+		}
+	}
+	defer conn.Close()
+
+	id := uint16(pingID.Add(1))
+	var rtts []time.Duration
+	buf := make([]byte, 1500)
+	for seq := uint16(0); int(seq) < count; seq++ {
+		if ctx.Err() != nil {
+			return rtts, ctx.Err()
+		}
+		start := time.Now()
+		if _, err := conn.WriteTo(echoRequest(id, seq), addr); err != nil {
+			return rtts, err
+		}
+		conn.SetReadDeadline(start.Add(timeout))
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				break // lost
+			}
+			// Echo reply: type 0, code 0, checksum, identifier, sequence
+			if n < 8 || buf[0] != 0 || binary.BigEndian.Uint16(buf[6:]) != seq {
+				continue
+			}
+			if raw && (binary.BigEndian.Uint16(buf[4:]) != id || !from.(*net.IPAddr).IP.Equal(ip)) {
+				continue
+			}
+			rtts = append(rtts, time.Since(start))
+			break
+		}
+	}
+	return rtts, nil
+}
+
+// echoRequest builds an ICMP echo request
+func echoRequest(id, seq uint16) []byte {
+	msg := make([]byte, 16)
+	msg[0] = 8 // echo request
+	binary.BigEndian.PutUint16(msg[4:], id)
+	binary.BigEndian.PutUint16(msg[6:], seq)
+	copy(msg[8:], "eywa-mon")
+
+	var sum uint32
+	for i := 0; i < len(msg); i += 2 {
+		sum += uint32(msg[i])<<8 | uint32(msg[i+1])
+	}
+	sum = sum>>16 + sum&0xffff
+	sum += sum >> 16
+	binary.BigEndian.PutUint16(msg[2:], ^uint16(sum))
+	return msg
+}
+
+// pingTCP times count TCP handshakes. A refused connection still answers,
+// so it counts as a reply.
+func (t PingTarget) pingTCP(ctx context.Context, count int, timeout time.Duration) ([]time.Duration, error) {
+	port := t.Port
+	if port == 0 {
+		port = 443
+	}
+	address := net.JoinHostPort(t.Host, strconv.Itoa(port))
+
+	var rtts []time.Duration
+	var lastErr error
+	for i := 0; i < count; i++ {
+		dialer := net.Dialer{Timeout: timeout}
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		rtt := time.Since(start)
+		switch {
+		case err == nil:
+			conn.Close()
+			fallthrough
+		case errors.Is(err, syscall.ECONNREFUSED):
+			rtts = append(rtts, rtt)
+		default:
+			lastErr = err
+		}
+	}
+	if len(rtts) == 0 {
+		return nil, lastErr
+	}
+	return rtts, nil
+}
+
+// checkPing alerts on targets losing packets or answering slowly. A
+// target that answers nothing is critical.
+func (a *Analyzer) checkPing(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+	for _, ping := range metrics.Ping {
+		alert := Alert{
+			Category:  "ping",
+			Resource:  ping.Name,
+			Timestamp: metrics.Timestamp,
+		}
+		switch {
+		case ping.Received == 0:
+			alert.Level = "critical"
+			alert.Value, alert.Threshold = ping.LossPercent, a.config.PingLossThreshold
+			alert.Message = fmt.Sprintf("%s is unreachable: no %s replies to %d packets", ping.Name, ping.Method, ping.Sent)
+			if ping.Error != "" {
+				alert.Message += ": " + ping.Error
+			}
+		case a.config.PingLossThreshold > 0 && ping.LossPercent >= a.config.PingLossThreshold:
+			alert.Level = "warning"
+			alert.Value, alert.Threshold = ping.LossPercent, a.config.PingLossThreshold
+			alert.Message = fmt.Sprintf("%s is losing %.0f%% of %s packets (threshold: %.0f%%)",
+				ping.Name, ping.LossPercent, ping.Method, a.config.PingLossThreshold)
+		case a.config.PingLatencyThreshold > 0 && ping.AvgMs >= a.config.PingLatencyThreshold:
+			alert.Level = "warning"
+			alert.Value, alert.Threshold = ping.AvgMs, a.config.PingLatencyThreshold
+			alert.Message = fmt.Sprintf("%s round trip averages %.1f ms (threshold: %.0f ms)",
+				ping.Name, ping.AvgMs, a.config.PingLatencyThreshold)
+		default:
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
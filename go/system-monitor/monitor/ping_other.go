@@ -0,0 +1,14 @@
+//go:build !linux
+
+package monitor
+
+import (
+	"errors"
+	"net"
+)
+
+// listenUnprivilegedICMP is only supported on Linux; elsewhere ICMP needs
+// a raw socket
+func listenUnprivilegedICMP() (net.PacketConn, error) {
+	return nil, errors.New("unprivileged ICMP sockets are only supported on Linux")
+}
@@ -0,0 +1,292 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// poolToolTimeout bounds a single zpool or btrfs invocation
+const poolToolTimeout = 10 * time.Second
+
+// PoolMetrics is the health and real capacity of a ZFS pool or Btrfs
+// filesystem, which disk usage misstates: ZFS datasets share the pool's
+// free space, and Btrfs can run out of unallocated space while df still
+// shows free space
+type PoolMetrics struct {
+	Name string `json:"name"` // ZFS pool name, or the Btrfs mount point
+	Type string `json:"type"` // "zfs" or "btrfs"
+
+	// ZFS pool state ("ONLINE", "DEGRADED", "FAULTED", ...), or "ONLINE"
+	// and "DEGRADED" for Btrfs
+	Health string `json:"health"`
+
+	SizeGB      float64 `json:"size_gb"`
+	FreeGB      float64 `json:"free_gb"` // estimated for Btrfs
+	UsedPercent float64 `json:"used_percent"`
+
+	// ZFS free space fragmentation
+	FragmentationPercent float64 `json:"fragmentation_percent,omitempty"`
+
+	// Device error counters since they were last cleared, summed over
+	// the pool's disks
+	ReadErrors     int64 `json:"read_errors"`
+	WriteErrors    int64 `json:"write_errors"`
+	ChecksumErrors int64 `json:"checksum_errors"`
+
+	// Errors the last scrub found, and ZFS files with permanent errors
+	ScrubErrors int64 `json:"scrub_errors"`
+	DataErrors  int64 `json:"data_errors,omitempty"`
+
+	Error string `json:"error,omitempty"` // why health could not be read
+}
+
+var (
+	// "scan: scrub repaired 0B in 00:01:02 with 3 errors on ..."
+	zpoolScrub = regexp.MustCompile(`scrub repaired .* with (\d+) errors`)
+
+	// "errors: 2 data errors, use '-v' for a list"
+	zpoolDataErrors = regexp.MustCompile(`^errors: (\d+) data errors`)
+
+	// btrfs scrub status: "Error summary:    csum=3" with
+	// "Uncorrectable: 3", or "... with 3 errors" from older versions
+	btrfsScrubErrors = regexp.MustCompile(`(?:Uncorrectable:\s*|with )(\d+)(?: errors)?`)
+)
+
+// collectPools reports the ZFS pools zpool knows and the mounted Btrfs
+// filesystems. Hosts with neither report none.
+func (c *Collector) collectPools(ctx context.Context) ([]PoolMetrics, error) {
+	ctx, cancel := context.WithTimeout(ctx, poolToolTimeout)
+	defer cancel()
+
+	var pools []PoolMetrics
+	if path, err := exec.LookPath("zpool"); err == nil {
+		zfs, err := collectZFSPools(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		pools = append(pools, zfs...)
+	}
+
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	btrfs, btrfsErr := exec.LookPath("btrfs")
+	seen := make(map[string]bool)
+	for _, partition := range partitions {
+		// Subvolumes mount the same filesystem more than once
+		if partition.Fstype != "btrfs" || seen[partition.Device] || !c.config.monitorsMount(partition.Mountpoint) {
+			continue
+		}
+		seen[partition.Device] = true
+		pool := PoolMetrics{Name: partition.Mountpoint, Type: "btrfs", Health: "ONLINE"}
+		for _, option := range partition.Opts {
+			if option == "degraded" {
+				pool.Health = "DEGRADED"
+			}
+		}
+		if btrfsErr != nil {
+			pool.Error = "btrfs not found"
+		} else {
+			readBtrfs(ctx, btrfs, &pool)
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+func collectZFSPools(ctx context.Context, path string) ([]PoolMetrics, error) {
+	out, err := exec.CommandContext(ctx, path, "list", "-Hp", "-o", "name,size,free,frag,cap,health").Output()
+	if err != nil {
+		return nil, fmt.Errorf("zpool list: %w", err)
+	}
+
+	var pools []PoolMetrics
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 6 {
+			continue
+		}
+		pool := PoolMetrics{Name: fields[0], Type: "zfs", Health: fields[5]}
+		size, _ := strconv.ParseFloat(fields[1], 64)
+		free, _ := strconv.ParseFloat(fields[2], 64)
+		pool.SizeGB, pool.FreeGB = size/(1024*1024*1024), free/(1024*1024*1024)
+		// Fragmentation is "-" for pools that don't track it
+		pool.FragmentationPercent, _ = strconv.ParseFloat(strings.TrimSuffix(fields[3], "%"), 64)
+		pool.UsedPercent, _ = strconv.ParseFloat(strings.TrimSuffix(fields[4], "%"), 64)
+
+		status, err := exec.CommandContext(ctx, path, "status", "-p", pool.Name).Output()
+		if err != nil {
+			pool.Error = fmt.Sprintf("zpool status: %v", err)
+		} else {
+			parseZpoolStatus(status, &pool)
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+// parseZpoolStatus reads the scrub result, the data error count and the
+// error counters of the leaf devices, which the vdevs above them don't
+// repeat
+func parseZpoolStatus(status []byte, pool *PoolMetrics) {
+	type row struct {
+		indent int
+		counts [3]int64
+	}
+	var rows []row
+	inConfig := false
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case zpoolScrub.MatchString(line):
+			pool.ScrubErrors, _ = strconv.ParseInt(zpoolScrub.FindStringSubmatch(line)[1], 10, 64)
+		case zpoolDataErrors.MatchString(trimmed):
+			pool.DataErrors, _ = strconv.ParseInt(zpoolDataErrors.FindStringSubmatch(trimmed)[1], 10, 64)
+		case strings.HasPrefix(trimmed, "NAME") && strings.Contains(trimmed, "CKSUM"):
+			inConfig = true
+		case inConfig && trimmed == "":
+			inConfig = false
+		case inConfig:
+			fields := strings.Fields(trimmed)
+			if len(fields) < 5 {
+				continue
+			}
+			r := row{indent: len(line) - len(strings.TrimLeft(line, " \t"))}
+			for i := range r.counts {
+				r.counts[i], _ = strconv.ParseInt(fields[2+i], 10, 64)
+			}
+			rows = append(rows, r)
+		}
+	}
+
+	for i, r := range rows {
+		if i+1 < len(rows) && rows[i+1].indent > r.indent {
+			continue // not a leaf
+		}
+		pool.ReadErrors += r.counts[0]
+		pool.WriteErrors += r.counts[1]
+		pool.ChecksumErrors += r.counts[2]
+	}
+}
+
+// readBtrfs fills in a Btrfs filesystem's real capacity, device error
+// counters and last scrub result. Most of it needs root.
+func readBtrfs(ctx context.Context, path string, pool *PoolMetrics) {
+	usage, err := exec.CommandContext(ctx, path, "filesystem", "usage", "-b", pool.Name).Output()
+	if err != nil {
+		pool.Error = fmt.Sprintf("btrfs filesystem usage: %v", err)
+		return
+	}
+	var size, free float64
+	for _, line := range strings.Split(string(usage), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Device size":
+			size, _ = strconv.ParseFloat(fields[0], 64)
+		case "Free (estimated)":
+			free, _ = strconv.ParseFloat(fields[0], 64)
+		}
+	}
+	pool.SizeGB, pool.FreeGB = size/(1024*1024*1024), free/(1024*1024*1024)
+	if size > 0 {
+		pool.UsedPercent = (size - free) / size * 100
+	}
+
+	// "[/dev/sda].write_io_errs    0"
+	stats, err := exec.CommandContext(ctx, path, "device", "stats", pool.Name).Output()
+	if err != nil {
+		pool.Error = fmt.Sprintf("btrfs device stats: %v", err)
+		return
+	}
+	for _, line := range strings.Split(string(stats), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		count, _ := strconv.ParseInt(fields[1], 10, 64)
+		switch {
+		case strings.HasSuffix(fields[0], ".read_io_errs"):
+			pool.ReadErrors += count
+		case strings.HasSuffix(fields[0], ".write_io_errs"), strings.HasSuffix(fields[0], ".flush_io_errs"):
+			pool.WriteErrors += count
+		case strings.HasSuffix(fields[0], ".corruption_errs"), strings.HasSuffix(fields[0], ".generation_errs"):
+			pool.ChecksumErrors += count
+		}
+	}
+
+	// A filesystem never scrubbed has no result, which isn't an error
+	scrub, _ := exec.CommandContext(ctx, path, "scrub", "status", pool.Name).Output()
+	if match := btrfsScrubErrors.FindSubmatch(scrub); match != nil {
+		pool.ScrubErrors, _ = strconv.ParseInt(string(match[1]), 10, 64)
+	}
+}
+
+// checkPools raises critical alerts for pools that are not healthy or
+// hold permanently damaged data, and warnings for device and scrub errors,
+// pools filling up and, on ZFS, fragmented free space. Btrfs pools are
+// named by path, so the resource's aspect follows a colon.
+func (a *Analyzer) checkPools(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+	for _, pool := range metrics.Pools {
+		alert := func(level, resource, message string, value, threshold float64) {
+			alerts = append(alerts, Alert{
+				Level:     level,
+				Category:  "pool",
+				Resource:  resource,
+				Message:   message,
+				Value:     value,
+				Threshold: threshold,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+		label := fmt.Sprintf("%s pool %s", strings.ToUpper(pool.Type), pool.Name)
+		if pool.Type == "btrfs" {
+			label = "Btrfs filesystem " + pool.Name
+		}
+
+		if pool.Health != "ONLINE" {
+			alert("critical", pool.Name, fmt.Sprintf("%s is %s", label, pool.Health), 0, 0)
+		}
+		if pool.DataErrors > 0 {
+			alert("critical", pool.Name+":data", fmt.Sprintf("%s has permanent errors in %d files", label, pool.DataErrors),
+				float64(pool.DataErrors), 0)
+		}
+		if pool.Error != "" {
+			alert("warning", pool.Name, fmt.Sprintf("%s health unknown: %s", label, pool.Error), 0, 0)
+			continue
+		}
+
+		if count := pool.ReadErrors + pool.WriteErrors + pool.ChecksumErrors + pool.ScrubErrors; count > 0 {
+			alert("warning", pool.Name+":errors", fmt.Sprintf("%s has device errors: %d read, %d write, %d checksum, %d found by the last scrub",
+				label, pool.ReadErrors, pool.WriteErrors, pool.ChecksumErrors, pool.ScrubErrors), float64(count), 0)
+		}
+		if threshold := a.config.diskThreshold(pool.Name); pool.UsedPercent > threshold {
+			alert("warning", pool.Name+":capacity", fmt.Sprintf("%s is %.1f%% full, %.1f GB free of %.1f GB",
+				label, pool.UsedPercent, pool.FreeGB, pool.SizeGB), pool.UsedPercent, threshold)
+		}
+		if threshold := a.config.PoolFragmentationThreshold; threshold > 0 && pool.FragmentationPercent > threshold {
+			alert("warning", pool.Name+":fragmentation", fmt.Sprintf("%s free space is %.0f%% fragmented", label, pool.FragmentationPercent),
+				pool.FragmentationPercent, threshold)
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+// PortProbe checks that a TCP or UDP port is listening, or that it is not
+type PortProbe struct {
+	Name     string `json:"name"`     // label used in alerts, unique among all probes
+	Address  string `json:"address"`  // host:port, e.g. "localhost:5432"
+	Protocol string `json:"protocol"` // "tcp" (default) or "udp"
+
+	// "open" (default) or "closed"; a closed probe fails when something
+	// accepts connections on the port
+	Expect string `json:"expect"`
+
+	TimeoutSeconds int `json:"timeout"` // 5 by default
+
+	// Consecutive failed snapshots before alerting, 3 by default
+	Failures int `json:"failures"`
+}
+
+func (p PortProbe) protocol() string {
+	if p.Protocol == "" {
+		return "tcp"
+	}
+	return p.Protocol
+}
+
+func (p PortProbe) expectOpen() bool {
+	return p.Expect != "closed"
+}
+
+func (p PortProbe) failures() int {
+	if p.Failures <= 0 {
+		return 3
+	}
+	return p.Failures
+}
+
+func (p PortProbe) validate() error {
+	var errs []error
+	if p.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if _, _, err := net.SplitHostPort(p.Address); err != nil {
+		errs = append(errs, fmt.Errorf("address: %w", err))
+	}
+	if p.Protocol != "" && p.Protocol != "tcp" && p.Protocol != "udp" {
+		errs = append(errs, fmt.Errorf(`protocol must be "tcp" or "udp", got %q`, p.Protocol))
+	}
+	if p.Expect != "" && p.Expect != "open" && p.Expect != "closed" {
+		errs = append(errs, fmt.Errorf(`expect must be "open" or "closed", got %q`, p.Expect))
+	}
+	if p.TimeoutSeconds < 0 || p.Failures < 0 {
+		errs = append(errs, errors.New("timeout and failures must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+func (p PortProbe) run(ctx context.Context) ProbeMetrics {
+	result := ProbeMetrics{Name: p.Name, Type: p.protocol(), Target: p.Address}
+	if p.validate() != nil {
+		result.Error = "invalid probe"
+		return result
+	}
+	timeout := 5 * time.Second
+	if p.TimeoutSeconds > 0 {
+		timeout = time.Duration(p.TimeoutSeconds) * time.Second
+	}
+
+	start := time.Now()
+	open, err := portOpen(ctx, p.protocol(), p.Address, timeout)
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	switch {
+	case p.expectOpen() && !open:
+		result.Error = fmt.Sprintf("%s port not open: %v", p.protocol(), err)
+	case !p.expectOpen() && open:
+		result.Error = fmt.Sprintf("%s port is open but expected closed", p.protocol())
+	default:
+		result.Up = true
+	}
+	return result
+}
+
+// portOpen reports whether something listens on a port, and why not.
+// UDP has no handshake, so a UDP port counts as open unless the host
+// rejects a datagram with an ICMP port unreachable.
+func portOpen(ctx context.Context, protocol, address string, timeout time.Duration) (bool, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, protocol, address)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if protocol == "tcp" {
+		return true, nil
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write([]byte{0}); err != nil {
+		return !errors.Is(err, syscall.ECONNREFUSED), err
+	}
+	if _, err := conn.Read(make([]byte, 1)); errors.Is(err, syscall.ECONNREFUSED) {
+		return false, err
+	}
+	return true, nil
+}
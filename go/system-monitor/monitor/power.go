@@ -0,0 +1,91 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+)
+
+// PowerMetrics is the state of a laptop's or edge device's power supply.
+// Hosts without a battery or AC adapter that reports itself, like most
+// servers and VMs, report none.
+type PowerMetrics struct {
+	OnAC      bool             `json:"on_ac"` // external power present
+	Batteries []BatteryMetrics `json:"batteries,omitempty"`
+}
+
+// BatteryMetrics is the charge of one system battery
+type BatteryMetrics struct {
+	Name    string  `json:"name"` // "BAT0", "InternalBattery-0"
+	Percent float64 `json:"percent"`
+
+	// "charging", "discharging", "full", "not_charging" or "unknown"
+	Status string `json:"status"`
+
+	// Estimated minutes until empty while discharging, or until full
+	// while charging; zero when there is no estimate
+	MinutesLeft float64 `json:"minutes_left,omitempty"`
+
+	// Full charge capacity against the design capacity, where reported
+	HealthPercent float64 `json:"health_percent,omitempty"`
+}
+
+// collectPower reads the power supplies the platform reports
+func (c *Collector) collectPower(ctx context.Context) (*PowerMetrics, error) {
+	return readPowerSupplies(ctx)
+}
+
+// checkPower alerts when the host loses external power since the previous
+// snapshot and when a discharging battery runs low: a warning below
+// Config.BatteryThreshold, critical below half of it or with less than 10
+// minutes left
+func (a *Analyzer) checkPower(metrics *SystemMetrics) []Alert {
+	power := metrics.Power
+	if power == nil {
+		return nil
+	}
+
+	var alerts []Alert
+	if n := a.history.len(); n >= 2 && !power.OnAC {
+		if prev := a.history.at(n - 2).Power; prev != nil && prev.OnAC {
+			message := "AC power lost, running on battery"
+			if len(power.Batteries) > 0 {
+				message += fmt.Sprintf(" at %.0f%%", power.Batteries[0].Percent)
+			}
+			alerts = append(alerts, Alert{
+				Level:     "critical",
+				Category:  "power",
+				Resource:  "ac",
+				Message:   message,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+
+	threshold := a.config.BatteryThreshold
+	if threshold <= 0 {
+		return alerts
+	}
+	for _, battery := range power.Batteries {
+		if battery.Status != "discharging" || battery.Percent >= threshold {
+			continue
+		}
+		level := "warning"
+		if battery.Percent < threshold/2 || battery.MinutesLeft > 0 && battery.MinutesLeft < 10 {
+			level = "critical"
+		}
+		message := fmt.Sprintf("Battery %s is at %.0f%% and discharging", battery.Name, battery.Percent)
+		if battery.MinutesLeft > 0 {
+			message += fmt.Sprintf(", about %.0f min left", battery.MinutesLeft)
+		}
+		alerts = append(alerts, Alert{
+			Level:     level,
+			Category:  "power",
+			Resource:  battery.Name,
+			Message:   message,
+			Value:     battery.Percent,
+			Threshold: threshold,
+			Timestamp: metrics.Timestamp,
+		})
+	}
+	return alerts
+}
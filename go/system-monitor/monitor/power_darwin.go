@@ -0,0 +1,59 @@
+//go:build darwin
+
+package monitor
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// " -InternalBattery-0 (id=4653155)	82%; discharging; 3:12 remaining present: true"
+var pmsetBattery = regexp.MustCompile(`^\s*-(\S+).*\t(\d+)%; ([^;]+);(?: (\d+):(\d+) remaining)?`)
+
+// readPowerSupplies parses `pmset -g batt`. Macs without a battery only
+// report the power source, and are left out like desktops elsewhere.
+func readPowerSupplies(ctx context.Context) (*PowerMetrics, error) {
+	out, err := exec.CommandContext(ctx, "pmset", "-g", "batt").Output()
+	if err != nil {
+		return nil, nil
+	}
+
+	power := &PowerMetrics{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "Now drawing from") {
+			power.OnAC = strings.Contains(line, "AC Power")
+			continue
+		}
+		match := pmsetBattery.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		battery := BatteryMetrics{Name: match[1]}
+		battery.Percent, _ = strconv.ParseFloat(match[2], 64)
+		switch state := strings.TrimSpace(match[3]); state {
+		case "charging", "finishing charge":
+			battery.Status = "charging"
+		case "discharging":
+			battery.Status = "discharging"
+		case "charged":
+			battery.Status = "full"
+		case "AC attached":
+			battery.Status = "not_charging"
+		default:
+			battery.Status = "unknown"
+		}
+		if match[4] != "" {
+			hours, _ := strconv.ParseFloat(match[4], 64)
+			minutes, _ := strconv.ParseFloat(match[5], 64)
+			battery.MinutesLeft = hours*60 + minutes
+		}
+		power.Batteries = append(power.Batteries, battery)
+	}
+	if len(power.Batteries) == 0 {
+		return nil, nil
+	}
+	return power, nil
+}
@@ -0,0 +1,98 @@
+//go:build linux
+
+package monitor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readPowerSupplies reads the power_supply sysfs class. Batteries of
+// peripherals such as wireless mice are left out.
+func readPowerSupplies(ctx context.Context) (*PowerMetrics, error) {
+	dirs, _ := filepath.Glob("/sys/class/power_supply/*")
+
+	power := &PowerMetrics{}
+	adapters := 0
+	for _, dir := range dirs {
+		read := func(name string) string {
+			data, _ := os.ReadFile(filepath.Join(dir, name))
+			return strings.TrimSpace(string(data))
+		}
+		if read("scope") == "Device" {
+			continue
+		}
+
+		switch read("type") {
+		case "Mains", "USB":
+			adapters++
+			if read("online") == "1" {
+				power.OnAC = true
+			}
+		case "Battery":
+			power.Batteries = append(power.Batteries, readBattery(dir, read))
+		}
+	}
+	if adapters == 0 && len(power.Batteries) == 0 {
+		return nil, nil
+	}
+
+	// Without an adapter that reports itself, a battery that isn't
+	// discharging is taken to be on external power
+	if adapters == 0 {
+		power.OnAC = true
+		for _, battery := range power.Batteries {
+			if battery.Status == "discharging" {
+				power.OnAC = false
+			}
+		}
+	}
+	return power, nil
+}
+
+// readBattery reads a battery's charge in energy (µWh, µW) or, on some
+// hardware, charge (µAh, µA) units
+func readBattery(dir string, read func(string) string) BatteryMetrics {
+	battery := BatteryMetrics{
+		Name:   filepath.Base(dir),
+		Status: strings.ReplaceAll(strings.ToLower(read("status")), " ", "_"),
+	}
+	if battery.Status == "" {
+		battery.Status = "unknown"
+	}
+
+	value := func(names ...string) float64 {
+		for _, name := range names {
+			if v, ok := readSysfsFloat(filepath.Join(dir, name)); ok && v > 0 {
+				return v
+			}
+		}
+		return 0
+	}
+	now := value("energy_now", "charge_now")
+	full := value("energy_full", "charge_full")
+	design := value("energy_full_design", "charge_full_design")
+	rate := value("power_now", "current_now")
+
+	if capacity, ok := readSysfsFloat(filepath.Join(dir, "capacity")); ok {
+		battery.Percent = capacity
+	} else if full > 0 {
+		battery.Percent = now / full * 100
+	}
+	if full > 0 && design > 0 {
+		battery.HealthPercent = full / design * 100
+	}
+	if rate > 0 {
+		switch battery.Status {
+		case "discharging":
+			battery.MinutesLeft = now / rate * 60
+		case "charging":
+			if full > now {
+				battery.MinutesLeft = (full - now) / rate * 60
+			}
+		}
+	}
+	return battery
+}
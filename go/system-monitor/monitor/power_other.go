@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package monitor
+
+import "context"
+
+// readPowerSupplies is only supported on Linux and macOS
+func readPowerSupplies(ctx context.Context) (*PowerMetrics, error) {
+	return nil, nil
+}
@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pressureDir holds the system-wide pressure stall information, on Linux
+// 4.20 and later built with CONFIG_PSI
+const pressureDir = "/proc/pressure"
+
+// PressureMetrics is the share of time tasks were stalled waiting for
+// CPU, memory or I/O (Linux pressure stall information). Unlike usage
+// percentages, it measures the work actually delayed.
+type PressureMetrics struct {
+	Source string         `json:"source"` // "/proc/pressure", or the container's cgroup
+	CPU    *PressureStall `json:"cpu,omitempty"`
+	Memory *PressureStall `json:"memory,omitempty"`
+	IO     *PressureStall `json:"io,omitempty"`
+}
+
+// PressureStall is the percentage of time over the last 10, 60 and 300
+// seconds that some runnable tasks were stalled, and that all of them
+// were at once, leaving the resource doing nothing useful
+type PressureStall struct {
+	Some10  float64 `json:"some_avg10"`
+	Some60  float64 `json:"some_avg60"`
+	Some300 float64 `json:"some_avg300"`
+	Full10  float64 `json:"full_avg10"`
+	Full60  float64 `json:"full_avg60"`
+	Full300 float64 `json:"full_avg300"`
+}
+
+// collectPressure reads the pressure stall information of the container's
+// cgroup inside a container, and the system's otherwise. Kernels without
+// PSI, and other platforms, report nothing.
+func (c *Collector) collectPressure(ctx context.Context) (*PressureMetrics, error) {
+	dir, suffix := pressureDir, ""
+	if cgroup, _, ok := cgroupDir(); ok {
+		if _, err := os.Stat(filepath.Join(cgroup, "cpu.pressure")); err == nil {
+			dir, suffix = cgroup, ".pressure"
+		}
+	}
+
+	pressure := &PressureMetrics{Source: dir}
+	pressure.CPU = readPressureStall(filepath.Join(dir, "cpu"+suffix))
+	pressure.Memory = readPressureStall(filepath.Join(dir, "memory"+suffix))
+	pressure.IO = readPressureStall(filepath.Join(dir, "io"+suffix))
+	if pressure.CPU == nil && pressure.Memory == nil && pressure.IO == nil {
+		return nil, nil
+	}
+	return pressure, nil
+}
+
+// readPressureStall parses a pressure file:
+//
+//	some avg10=2.38 avg60=2.26 avg300=1.94 total=174473535
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func readPressureStall(path string) *PressureStall {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	stall := &PressureStall{}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		avg := func(field string) float64 {
+			_, value, _ := strings.Cut(field, "=")
+			f, _ := strconv.ParseFloat(value, 64)
+			return f
+		}
+		switch fields[0] {
+		case "some":
+			stall.Some10, stall.Some60, stall.Some300 = avg(fields[1]), avg(fields[2]), avg(fields[3])
+		case "full":
+			stall.Full10, stall.Full60, stall.Full300 = avg(fields[1]), avg(fields[2]), avg(fields[3])
+		}
+	}
+	return stall
+}
+
+// checkPressure raises a warning when some tasks were stalled on CPU,
+// memory or I/O for Config.PressureThreshold percent of the last minute,
+// and a critical alert when all of them were, for memory and I/O: the
+// host is thrashing rather than merely busy. CPU's "full" line is left
+// out, as it only means something for cgroups.
+func (a *Analyzer) checkPressure(metrics *SystemMetrics) []Alert {
+	pressure := metrics.Pressure
+	threshold := a.config.PressureThreshold
+	if pressure == nil || threshold <= 0 {
+		return nil
+	}
+
+	var alerts []Alert
+	for _, resource := range []struct {
+		name, label string
+		stall       *PressureStall
+		full        bool
+	}{
+		{"cpu", "CPU", pressure.CPU, false},
+		{"memory", "memory", pressure.Memory, true},
+		{"io", "I/O", pressure.IO, true},
+	} {
+		stall := resource.stall
+		if stall == nil {
+			continue
+		}
+		switch {
+		case resource.full && stall.Full60 >= threshold:
+			alerts = append(alerts, Alert{
+				Level:    "critical",
+				Category: "pressure",
+				Resource: resource.name,
+				Message: fmt.Sprintf("All tasks were stalled on %s %.1f%% of the last minute (some: %.1f%%)",
+					resource.label, stall.Full60, stall.Some60),
+				Value:     stall.Full60,
+				Threshold: threshold,
+				Timestamp: metrics.Timestamp,
+			})
+		case stall.Some60 >= threshold:
+			alerts = append(alerts, Alert{
+				Level:    "warning",
+				Category: "pressure",
+				Resource: resource.name,
+				Message: fmt.Sprintf("Tasks were stalled on %s %.1f%% of the last minute (%.1f%% over 5 minutes)",
+					resource.label, stall.Some60, stall.Some300),
+				Value:     stall.Some60,
+				Threshold: threshold,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,277 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPProbe is an HTTP(S) health check run on every snapshot, typically
+// against services on the monitored host itself
+type HTTPProbe struct {
+	Name   string `json:"name"` // label used in alerts, unique
+	URL    string `json:"url"`
+	Method string `json:"method"` // GET by default
+
+	// Status codes that count as up; by default any below 400
+	ExpectStatus []int `json:"expect_status"`
+
+	// Slower responses count as failures; 0 means no limit
+	MaxLatencyMs float64 `json:"max_latency_ms"`
+
+	// Regular expression the response body must match
+	BodyMatch string `json:"body_match"`
+
+	Headers            map[string]string `json:"headers"`
+	InsecureSkipVerify bool              `json:"insecure_skip_verify"`
+	TimeoutSeconds     int               `json:"timeout"` // 10 by default
+
+	// Consecutive failed snapshots before alerting, 3 by default
+	Failures int `json:"failures"`
+}
+
+// ProbeMetrics is the outcome of one HTTPProbe, PortProbe or DNSProbe
+type ProbeMetrics struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`   // "http", "tcp", "udp" or "dns"
+	Target     string  `json:"target"` // the URL, address or name probed
+	Up         bool    `json:"up"`
+	StatusCode int     `json:"status_code,omitempty"`
+	LatencyMs  float64 `json:"latency_ms"`
+	Error      string  `json:"error,omitempty"` // why the probe failed
+
+	// Addresses a DNS probe resolved to
+	Answers []string `json:"answers,omitempty"`
+}
+
+// probeBodyLimit caps how much of a response body is read for BodyMatch
+const probeBodyLimit = 1 << 20
+
+func (p HTTPProbe) failures() int {
+	if p.Failures <= 0 {
+		return 3
+	}
+	return p.Failures
+}
+
+func (p HTTPProbe) validate() error {
+	var errs []error
+	if p.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if !strings.HasPrefix(p.URL, "http://") && !strings.HasPrefix(p.URL, "https://") {
+		errs = append(errs, fmt.Errorf("url must be an http or https URL, got %q", p.URL))
+	}
+	if _, err := regexp.Compile(p.BodyMatch); err != nil {
+		errs = append(errs, fmt.Errorf("body_match: %w", err))
+	}
+	for _, status := range p.ExpectStatus {
+		if status < 100 || status > 599 {
+			errs = append(errs, fmt.Errorf("expect_status: %d is not an HTTP status", status))
+		}
+	}
+	if p.MaxLatencyMs < 0 || p.TimeoutSeconds < 0 || p.Failures < 0 {
+		errs = append(errs, errors.New("max_latency_ms, timeout and failures must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// httpProber is a compiled HTTPProbe
+type httpProber struct {
+	probe  HTTPProbe
+	body   *regexp.Regexp // nil accepts any body
+	client *http.Client
+}
+
+// newHTTPProbers compiles the probes. Invalid probes are skipped; use
+// Config.Validate to report them.
+func newHTTPProbers(probes []HTTPProbe) []httpProber {
+	var probers []httpProber
+	for _, probe := range probes {
+		if probe.validate() != nil {
+			continue
+		}
+		timeout := 10 * time.Second
+		if probe.TimeoutSeconds > 0 {
+			timeout = time.Duration(probe.TimeoutSeconds) * time.Second
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: probe.InsecureSkipVerify}
+		// Every probe opens a fresh connection, as a real client would
+		transport.DisableKeepAlives = true
+
+		p := httpProber{probe: probe, client: &http.Client{Timeout: timeout, Transport: transport}}
+		if probe.BodyMatch != "" {
+			p.body = regexp.MustCompile(probe.BodyMatch)
+		}
+		probers = append(probers, p)
+	}
+	return probers
+}
+
+// collectProbes runs every HTTP, port and DNS probe concurrently
+func (c *Collector) collectProbes(ctx context.Context) ([]ProbeMetrics, error) {
+	results := make([]ProbeMetrics, len(c.probers)+len(c.config.PortProbes)+len(c.config.DNSProbes))
+	var wg sync.WaitGroup
+	run := func(i int, probe func(context.Context) ProbeMetrics) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = probe(ctx)
+		}()
+	}
+	for i, prober := range c.probers {
+		run(i, prober.run)
+	}
+	for i, probe := range c.config.PortProbes {
+		run(len(c.probers)+i, probe.run)
+	}
+	for i, probe := range c.config.DNSProbes {
+		run(len(c.probers)+len(c.config.PortProbes)+i, probe.run)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+func (p httpProber) run(ctx context.Context) ProbeMetrics {
+	result := ProbeMetrics{Name: p.probe.Name, Type: "http", Target: p.probe.URL}
+	fail := func(format string, args ...interface{}) ProbeMetrics {
+		result.Error = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	method := p.probe.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	request, err := http.NewRequestWithContext(ctx, method, p.probe.URL, nil)
+	if err != nil {
+		return fail("%v", err)
+	}
+	request.Header.Set("User-Agent", "eywa-system-monitor")
+	for key, value := range p.probe.Headers {
+		request.Header.Set(key, value)
+	}
+
+	start := time.Now()
+	response, err := p.client.Do(request)
+	if err != nil {
+		result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+		return fail("%v", err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, probeBodyLimit))
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	result.StatusCode = response.StatusCode
+	if err != nil {
+		return fail("reading body: %v", err)
+	}
+
+	if !p.statusOK(response.StatusCode) {
+		return fail("unexpected status %d", response.StatusCode)
+	}
+	if limit := p.probe.MaxLatencyMs; limit > 0 && result.LatencyMs > limit {
+		return fail("took %.0f ms, over %.0f ms", result.LatencyMs, limit)
+	}
+	if p.body != nil && !p.body.Match(body) {
+		return fail("body does not match %q", p.probe.BodyMatch)
+	}
+
+	result.Up = true
+	return result
+}
+
+func (p httpProber) statusOK(status int) bool {
+	if len(p.probe.ExpectStatus) == 0 {
+		return status < 400
+	}
+	for _, expected := range p.probe.ExpectStatus {
+		if status == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// probeFailures returns how many consecutive failures of a probe raise an
+// alert and its latency limit, or false for a probe that is no longer
+// configured
+func (c Config) probeFailures(name string) (int, float64, bool) {
+	for _, probe := range c.HTTPProbes {
+		if probe.Name == name {
+			return probe.failures(), probe.MaxLatencyMs, true
+		}
+	}
+	for _, probe := range c.PortProbes {
+		if probe.Name == name {
+			return probe.failures(), 0, true
+		}
+	}
+	for _, probe := range c.DNSProbes {
+		if probe.Name == name {
+			return probe.failures(), probe.MaxLatencyMs, true
+		}
+	}
+	return 0, 0, false
+}
+
+// checkProbes alerts on probes that failed in each of their last Failures
+// snapshots. Failing DNS probes get a category of their own, as a broken
+// resolver breaks every other service on the host.
+func (a *Analyzer) checkProbes(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+	for _, result := range metrics.Probes {
+		if result.Up {
+			continue
+		}
+		failures, maxLatency, ok := a.config.probeFailures(result.Name)
+		if !ok {
+			continue
+		}
+
+		// The history ends with this snapshot
+		failed := 0
+		for i := a.history.len() - 1; i >= 0 && failed < failures; i-- {
+			if !probeFailed(a.history.at(i), result.Name) {
+				break
+			}
+			failed++
+		}
+		if failed < failures {
+			continue
+		}
+
+		category := "probe"
+		if result.Type == "dns" {
+			category = "dns"
+		}
+		alerts = append(alerts, Alert{
+			Level:     "critical",
+			Category:  category,
+			Resource:  result.Name,
+			Message:   fmt.Sprintf("Probe %s (%s) is failing: %s (%d consecutive failures)", result.Name, result.Target, result.Error, failed),
+			Value:     result.LatencyMs,
+			Threshold: maxLatency,
+			Timestamp: metrics.Timestamp,
+		})
+	}
+	return alerts
+}
+
+func probeFailed(metrics SystemMetrics, name string) bool {
+	for _, result := range metrics.Probes {
+		if result.Name == name {
+			return !result.Up
+		}
+	}
+	return false
+}
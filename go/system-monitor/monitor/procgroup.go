@@ -0,0 +1,213 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ProcessGroupMetrics sums the processes of one service, so a service
+// forking many small workers shows up as one entry instead of none
+type ProcessGroupMetrics struct {
+	// The cgroup path or systemd slice, or the name of the process at the
+	// root of the tree when grouped by parent
+	Name string `json:"name"`
+	PID  int32  `json:"pid,omitempty"` // the root process, when grouped by parent
+
+	Processes  int     `json:"processes"`
+	CPUPercent float64 `json:"cpu_percent"`
+
+	// Summed resident memory, which counts pages the processes share once
+	// per process
+	MemoryMB      float64 `json:"memory_mb"`
+	MemoryPercent float64 `json:"memory_percent"`
+
+	ReadMBPerSec  float64 `json:"read_mb_per_sec,omitempty"`
+	WriteMBPerSec float64 `json:"write_mb_per_sec,omitempty"`
+}
+
+func (c Config) validateProcessGroups() []error {
+	switch c.ProcessGroupBy {
+	case "", "parent", "cgroup", "slice":
+		return nil
+	}
+	return []error{fmt.Errorf("process_group_by must be parent, cgroup or slice, got %q", c.ProcessGroupBy)}
+}
+
+// readGroupKey reads what grouping needs beyond the process's metrics:
+// its parent, or its cgroup
+func (c *Collector) readGroupKey(ctx context.Context, p *process.Process, pm *ProcessMetrics) {
+	switch c.config.ProcessGroupBy {
+	case "parent":
+		pm.parent, _ = p.PpidWithContext(ctx)
+	case "cgroup", "slice":
+		pm.cgroup = readCgroup(p.Pid)
+	}
+}
+
+// readCgroup returns a process's cgroup: the unified (v2) hierarchy's
+// path, or the systemd hierarchy's under cgroup v1. It is empty outside
+// Linux and for processes that exited.
+func readCgroup(pid int32) string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return ""
+	}
+	var path string
+	for _, line := range strings.Split(string(data), "\n") {
+		// "0::/system.slice/nginx.service" or "1:name=systemd:/user.slice"
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[0] == "0" && fields[1] == "" {
+			return fields[2]
+		}
+		if fields[1] == "name=systemd" {
+			path = fields[2]
+		}
+	}
+	return path
+}
+
+// cgroupSlice is the innermost systemd slice of a cgroup path, such as
+// "system.slice" or "user-1000.slice"
+func cgroupSlice(path string) string {
+	slice := ""
+	for _, part := range strings.Split(path, "/") {
+		if strings.HasSuffix(part, ".slice") {
+			slice = part
+		}
+	}
+	if slice == "" {
+		return path
+	}
+	return slice
+}
+
+// groupProcesses sums processes by Config.ProcessGroupBy. Processes
+// without a cgroup, outside Linux, are their own group.
+func (c *Collector) groupProcesses(processes []ProcessMetrics) []ProcessGroupMetrics {
+	by := c.config.ProcessGroupBy
+	if by == "" {
+		return nil
+	}
+
+	byPID := make(map[int32]*ProcessMetrics, len(processes))
+	for i := range processes {
+		byPID[processes[i].PID] = &processes[i]
+	}
+
+	var groups []ProcessGroupMetrics
+	index := make(map[string]int)
+	for _, p := range processes {
+		var key string
+		var root *ProcessMetrics
+		switch {
+		case by == "parent":
+			root = treeRoot(byPID, &p)
+			key = fmt.Sprint(root.PID)
+		case p.cgroup == "":
+			key = "process " + p.Name
+		case by == "slice":
+			key = cgroupSlice(p.cgroup)
+		default:
+			key = p.cgroup
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			group := ProcessGroupMetrics{Name: key}
+			if root != nil {
+				group.Name, group.PID = root.Name, root.PID
+			} else if p.cgroup == "" {
+				group.Name = p.Name
+			}
+			groups = append(groups, group)
+		}
+		group := &groups[i]
+		group.Processes++
+		group.CPUPercent += p.CPUPercent
+		group.MemoryMB += p.MemoryMB
+		group.MemoryPercent += p.MemoryPercent
+		group.ReadMBPerSec += p.ReadMBPerSec
+		group.WriteMBPerSec += p.WriteMBPerSec
+	}
+
+	// The busiest by CPU and the largest by memory are reported
+	n := c.config.TopProcessCount
+	top := rankGroups(groups, false)
+	if len(top) > n {
+		top = top[:n:n]
+		seen := make(map[ProcessGroupMetrics]bool, n)
+		for _, group := range top {
+			seen[group] = true
+		}
+		for _, group := range rankGroups(groups, true)[:n] {
+			if !seen[group] {
+				top = append(top, group)
+			}
+		}
+	}
+
+	// Group names are hidden like process names: a cgroup or slice names
+	// the service running in it
+	for i := range top {
+		top[i].Name, _ = c.redactor.name(top[i].Name)
+	}
+	return top
+}
+
+// treeRoot walks up from a process to the ancestor whose parent is init,
+// or is not known. Kernel threads end up under kthreadd.
+func treeRoot(byPID map[int32]*ProcessMetrics, p *ProcessMetrics) *ProcessMetrics {
+	// The depth bound guards against parent loops from PID reuse
+	for depth := 0; depth < 64 && p.parent > 1; depth++ {
+		parent, ok := byPID[p.parent]
+		if !ok {
+			break
+		}
+		p = parent
+	}
+	return p
+}
+
+func rankGroups(groups []ProcessGroupMetrics, byMemory bool) []ProcessGroupMetrics {
+	ranked := append([]ProcessGroupMetrics(nil), groups...)
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i].CPUPercent, ranked[j].CPUPercent
+		if byMemory {
+			a, b = ranked[i].MemoryMB, ranked[j].MemoryMB
+		}
+		if a != b {
+			return a > b
+		}
+		if ranked[i].Name != ranked[j].Name {
+			return ranked[i].Name < ranked[j].Name
+		}
+		return ranked[i].PID < ranked[j].PID
+	})
+	return ranked
+}
+
+// TopProcessGroup returns the group using the most CPU, or memory, when
+// it has more than one process and uses more than the top process alone
+func TopProcessGroup(metrics *SystemMetrics, byMemory bool) (ProcessGroupMetrics, bool) {
+	groups := rankGroups(metrics.ProcessGroups, byMemory)
+	if len(groups) == 0 || groups[0].Processes < 2 {
+		return ProcessGroupMetrics{}, false
+	}
+	group := groups[0]
+	if top := GetTopProcesses(metrics, byMemory, 1); len(top) > 0 {
+		if byMemory && top[0].MemoryMB >= group.MemoryMB || !byMemory && top[0].CPUPercent >= group.CPUPercent {
+			return ProcessGroupMetrics{}, false
+		}
+	}
+	return group, true
+}
@@ -0,0 +1,45 @@
+package monitor
+
+import "time"
+
+// procIO is a process's cumulative disk I/O at a sample
+type procIO struct {
+	read, write uint64
+	at          time.Time
+}
+
+// addProcessIORates sets each process's disk read and write rates from
+// its counters at the previous sample. Processes seen for the first time,
+// or whose counters went backwards because the PID was reused, are left
+// at zero.
+func (c *Collector) addProcessIORates(processes []ProcessMetrics, now time.Time) {
+	current := make(map[int32]procIO, len(processes))
+	for i := range processes {
+		p := &processes[i]
+		if !p.ioKnown {
+			continue
+		}
+		current[p.PID] = procIO{read: p.ioRead, write: p.ioWrite, at: now}
+
+		prev, ok := c.prevProcIO[p.PID]
+		elapsed := now.Sub(prev.at).Seconds()
+		if !ok || elapsed <= 0 || p.ioRead < prev.read || p.ioWrite < prev.write {
+			continue
+		}
+		p.ReadMBPerSec = float64(p.ioRead-prev.read) / (1024 * 1024) / elapsed
+		p.WriteMBPerSec = float64(p.ioWrite-prev.write) / (1024 * 1024) / elapsed
+	}
+	c.prevProcIO = current
+}
+
+// busiestIOProcesses returns up to n processes doing disk I/O, busiest
+// first
+func busiestIOProcesses(processes []ProcessMetrics, n int) []ProcessMetrics {
+	var busiest []ProcessMetrics
+	for _, p := range processes {
+		if p.ReadMBPerSec+p.WriteMBPerSec > 0 {
+			busiest = append(busiest, p)
+		}
+	}
+	return RankProcesses(busiest, SortByIO, n)
+}
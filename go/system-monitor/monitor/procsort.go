@@ -0,0 +1,162 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ProcessSort is the metric processes are ranked by
+type ProcessSort string
+
+const (
+	SortByCPU     ProcessSort = "cpu"
+	SortByMemory  ProcessSort = "memory"
+	SortByIO      ProcessSort = "io"      // disk reads and writes together
+	SortByFDs     ProcessSort = "fds"     // open file descriptors
+	SortByThreads ProcessSort = "threads" // thread count
+	SortByAge     ProcessSort = "age"     // longest running first
+)
+
+func (s ProcessSort) valid() bool {
+	switch s {
+	case "", SortByCPU, SortByMemory, SortByIO, SortByFDs, SortByThreads, SortByAge:
+		return true
+	}
+	return false
+}
+
+// greater reports whether a ranks above b. Processes whose start time
+// is unknown rank last by age.
+func (s ProcessSort) greater(a, b *ProcessMetrics) (bool, bool) {
+	switch s {
+	case SortByMemory:
+		return a.MemoryMB > b.MemoryMB, a.MemoryMB != b.MemoryMB
+	case SortByIO:
+		x, y := a.ReadMBPerSec+a.WriteMBPerSec, b.ReadMBPerSec+b.WriteMBPerSec
+		return x > y, x != y
+	case SortByFDs:
+		return a.OpenFDs > b.OpenFDs, a.OpenFDs != b.OpenFDs
+	case SortByThreads:
+		return a.Threads > b.Threads, a.Threads != b.Threads
+	case SortByAge:
+		if a.StartedAt.IsZero() || b.StartedAt.IsZero() {
+			return b.StartedAt.IsZero(), a.StartedAt.IsZero() != b.StartedAt.IsZero()
+		}
+		return a.StartedAt.Before(b.StartedAt), !a.StartedAt.Equal(b.StartedAt)
+	default:
+		return a.CPUPercent > b.CPUPercent, a.CPUPercent != b.CPUPercent
+	}
+}
+
+// RankProcesses returns the top count processes by the given metric,
+// leaving the input untouched. PID breaks ties so the result doesn't
+// depend on the order processes were read in.
+func RankProcesses(processes []ProcessMetrics, by ProcessSort, count int) []ProcessMetrics {
+	ranked := append([]ProcessMetrics(nil), processes...)
+	sort.Slice(ranked, func(i, j int) bool {
+		if greater, differ := by.greater(&ranked[i], &ranked[j]); differ {
+			return greater
+		}
+		return ranked[i].PID < ranked[j].PID
+	})
+	if count >= 0 && len(ranked) > count {
+		ranked = ranked[:count]
+	}
+	return ranked
+}
+
+// processFilter narrows the reported processes by name. Watches and
+// process state counts always see every process.
+type processFilter struct {
+	include, exclude []*regexp.Regexp
+}
+
+// newProcessFilter compiles the include and exclude patterns. Invalid
+// patterns are ignored; use Config.Validate to report them.
+func newProcessFilter(include, exclude []string) processFilter {
+	compile := func(patterns []string) []*regexp.Regexp {
+		var compiled []*regexp.Regexp
+		for _, pattern := range patterns {
+			if re, err := regexp.Compile(pattern); err == nil {
+				compiled = append(compiled, re)
+			}
+		}
+		return compiled
+	}
+	return processFilter{include: compile(include), exclude: compile(exclude)}
+}
+
+// matches reports whether a process name is reported: it must match an
+// include pattern, when there are any, and no exclude pattern
+func (f processFilter) matches(name string) bool {
+	included := len(f.include) == 0
+	for _, re := range f.include {
+		if re.MatchString(name) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+	for _, re := range f.exclude {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns the processes the filter lets through
+func (f processFilter) apply(processes []ProcessMetrics) []ProcessMetrics {
+	if len(f.include) == 0 && len(f.exclude) == 0 {
+		return processes
+	}
+	var kept []ProcessMetrics
+	for _, p := range processes {
+		if f.matches(p.Name) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+func (c Config) validateProcessRanking() []error {
+	var errs []error
+	if !c.ProcessSort.valid() {
+		errs = append(errs, fmt.Errorf("process_sort must be cpu, memory, io, fds, threads or age, got %q", c.ProcessSort))
+	}
+	for _, pattern := range c.ProcessInclude {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("process_include pattern %q: %w", pattern, err))
+		}
+	}
+	for _, pattern := range c.ProcessExclude {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("process_exclude pattern %q: %w", pattern, err))
+		}
+	}
+	return errs
+}
+
+// addProcessDetails reads thread counts and start times. Like FD counts
+// they are only gathered for the processes that need them.
+func addProcessDetails(ctx context.Context, processes []ProcessMetrics) {
+	for i := range processes {
+		p, err := process.NewProcessWithContext(ctx, processes[i].PID)
+		if err != nil {
+			continue
+		}
+		if threads, err := p.NumThreadsWithContext(ctx); err == nil {
+			processes[i].Threads = threads
+		}
+		if created, err := p.CreateTimeWithContext(ctx); err == nil {
+			processes[i].StartedAt = time.UnixMilli(created)
+		}
+	}
+}
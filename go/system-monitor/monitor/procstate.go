@@ -0,0 +1,156 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// maxZombieParents bounds how many parents of zombies are reported
+const maxZombieParents = 5
+
+// ProcessStateMetrics counts processes by scheduler state. It is nil on
+// platforms that don't report process states (Windows).
+type ProcessStateMetrics struct {
+	Total    int `json:"total"`
+	Running  int `json:"running"`
+	Sleeping int `json:"sleeping"` // includes idle kernel threads
+	Blocked  int `json:"blocked"`  // uninterruptible, usually waiting on I/O
+	Stopped  int `json:"stopped"`
+	Zombie   int `json:"zombie"`
+
+	// Parents that have not reaped their zombie children, most first
+	ZombieParents []ZombieParent `json:"zombie_parents,omitempty"`
+}
+
+// ZombieParent is a process with defunct children it has not reaped
+type ZombieParent struct {
+	PID     int32  `json:"pid"`
+	Name    string `json:"name"`
+	Zombies int    `json:"zombies"`
+}
+
+// processStates accumulates process states while the process table is
+// sampled
+type processStates struct {
+	counts  ProcessStateMetrics
+	parents map[int32]int // parent PID -> zombie children
+}
+
+// read returns a process's state, and its parent if it is a zombie. It
+// is read before the process's other metrics since zombies fail most
+// other reads.
+func (s *processStates) read(ctx context.Context, p *process.Process) (state string, parent int32) {
+	status, err := p.StatusWithContext(ctx)
+	if err != nil || len(status) == 0 {
+		return "", 0
+	}
+	if status[0] == process.Zombie {
+		parent, _ = p.PpidWithContext(ctx)
+	}
+	return status[0], parent
+}
+
+// add counts a state returned by read. The caller serializes calls.
+func (s *processStates) add(state string, parent int32) {
+	if state == "" {
+		return
+	}
+
+	s.counts.Total++
+	switch state {
+	case process.Running:
+		s.counts.Running++
+	case process.Sleep, process.Idle, process.Wait:
+		s.counts.Sleeping++
+	case process.Blocked, process.Lock:
+		s.counts.Blocked++
+	case process.Stop:
+		s.counts.Stopped++
+	case process.Zombie:
+		s.counts.Zombie++
+		if parent > 0 {
+			if s.parents == nil {
+				s.parents = make(map[int32]int)
+			}
+			s.parents[parent]++
+		}
+	}
+}
+
+// metrics returns the counts, naming the parents of zombies through the
+// redactor. It is nil if no state could be read.
+func (s *processStates) metrics(ctx context.Context, r *redactor) *ProcessStateMetrics {
+	if s.counts.Total == 0 {
+		return nil
+	}
+
+	counts := s.counts
+	for pid, zombies := range s.parents {
+		counts.ZombieParents = append(counts.ZombieParents, ZombieParent{PID: pid, Zombies: zombies})
+	}
+	sort.Slice(counts.ZombieParents, func(i, j int) bool {
+		a, b := counts.ZombieParents[i], counts.ZombieParents[j]
+		if a.Zombies != b.Zombies {
+			return a.Zombies > b.Zombies
+		}
+		return a.PID < b.PID
+	})
+	if len(counts.ZombieParents) > maxZombieParents {
+		counts.ZombieParents = counts.ZombieParents[:maxZombieParents]
+	}
+
+	for i := range counts.ZombieParents {
+		parent := &counts.ZombieParents[i]
+		if p, err := process.NewProcessWithContext(ctx, parent.PID); err == nil {
+			if name, err := p.NameWithContext(ctx); err == nil {
+				parent.Name, _ = r.name(name)
+			}
+		}
+	}
+
+	return &counts
+}
+
+// checkZombies alerts when defunct processes pile up, naming the parents
+// that fail to reap them. Zombies hold no memory but each keeps a PID.
+func (a *Analyzer) checkZombies(metrics *SystemMetrics) *Alert {
+	states := metrics.ProcessStates
+	if a.config.ZombieThreshold <= 0 || states == nil || states.Zombie <= a.config.ZombieThreshold {
+		return nil
+	}
+
+	threshold := float64(a.config.ZombieThreshold)
+	zombies := func(m SystemMetrics) float64 {
+		if m.ProcessStates == nil {
+			return 0
+		}
+		return float64(m.ProcessStates.Zombie)
+	}
+	level := "warning"
+	if a.isSustained(zombies, threshold) {
+		level = "critical"
+	}
+
+	message := fmt.Sprintf("%d zombie processes", states.Zombie)
+	if len(states.ZombieParents) > 0 {
+		parents := make([]string, len(states.ZombieParents))
+		for i, parent := range states.ZombieParents {
+			parents[i] = fmt.Sprintf("%s (PID %d): %d", parent.Name, parent.PID, parent.Zombies)
+		}
+		message += ", not reaped by " + strings.Join(parents, ", ")
+	}
+
+	return &Alert{
+		Level:     level,
+		Category:  "zombies",
+		Resource:  "system",
+		Message:   message,
+		Value:     float64(states.Zombie),
+		Threshold: threshold,
+		Timestamp: metrics.Timestamp,
+	}
+}
@@ -0,0 +1,279 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// raidToolTimeout bounds a single storcli or megacli invocation
+const raidToolTimeout = 15 * time.Second
+
+// RAIDMetrics is the health of one software (md) or hardware RAID array
+type RAIDMetrics struct {
+	Device string `json:"device"` // "md0", or the controller's "/c0/v1"
+	Source string `json:"source"` // "mdstat", "storcli" or "megacli"
+	Level  string `json:"level,omitempty"`
+
+	// "active", "inactive" or "degraded" for md arrays; the controller's
+	// own state, such as "Optl" or "Dgrd", for hardware arrays
+	State    string `json:"state"`
+	Degraded bool   `json:"degraded"`
+
+	// Member disks the array should have and those working; zero when the
+	// source doesn't say, as for RAID 0 and hardware arrays
+	Disks       int      `json:"disks,omitempty"`
+	ActiveDisks int      `json:"active_disks,omitempty"`
+	Failed      []string `json:"failed,omitempty"` // failed member devices
+
+	// A running "recovery", "resync", "reshape" or "check", with its
+	// progress and the kernel's estimate of the time left
+	SyncAction      string  `json:"sync_action,omitempty"`
+	SyncPercent     float64 `json:"sync_percent,omitempty"`
+	SyncMinutesLeft float64 `json:"sync_minutes_left,omitempty"`
+
+	// Set on a placeholder entry when a controller tool failed
+	Error string `json:"error,omitempty"`
+}
+
+var (
+	// "md0 : active raid1 sdb1[1] sda1[0](F)"
+	mdstatArray = regexp.MustCompile(`^(md\S+) : (\S+)(?: \((?:auto-)?read-only\))?(.*)$`)
+
+	// "[2/1] [U_]"
+	mdstatDisks = regexp.MustCompile(`\[(\d+)/(\d+)\] \[[U_]+\]`)
+
+	// "recovery =  8.5% (89088/1048512) finish=0.3min speed=44544K/sec"
+	mdstatSync = regexp.MustCompile(`(recovery|resync|reshape|check|repair)\s*=\s*([\d.]+)%.*?finish=([\d.]+)min`)
+)
+
+// collectRAID reports the md arrays in /proc/mdstat and, when enabled, the
+// virtual drives of hardware RAID controllers. Hosts without either report
+// none.
+func (c *Collector) collectRAID(ctx context.Context) ([]RAIDMetrics, error) {
+	var arrays []RAIDMetrics
+	if data, err := os.ReadFile("/proc/mdstat"); err == nil {
+		arrays = parseMdstat(data)
+	}
+	if c.config.CollectHardwareRAID {
+		arrays = append(arrays, collectControllerRAID(ctx)...)
+	}
+	return arrays, nil
+}
+
+func parseMdstat(data []byte) []RAIDMetrics {
+	var arrays []RAIDMetrics
+	var current *RAIDMetrics
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := mdstatArray.FindStringSubmatch(line); match != nil {
+			arrays = append(arrays, RAIDMetrics{Device: match[1], Source: "mdstat", State: match[2]})
+			current = &arrays[len(arrays)-1]
+			members := strings.Fields(match[3])
+			if len(members) > 0 && !strings.Contains(members[0], "[") {
+				current.Level, members = members[0], members[1:]
+			}
+			for _, member := range members {
+				if name, ok := strings.CutSuffix(member, "(F)"); ok {
+					current.Failed = append(current.Failed, name[:strings.IndexByte(name, '[')])
+				}
+			}
+			continue
+		}
+		if current == nil || !strings.HasPrefix(line, " ") {
+			current = nil
+			continue
+		}
+		if match := mdstatDisks.FindStringSubmatch(line); match != nil {
+			current.Disks, _ = strconv.Atoi(match[1])
+			current.ActiveDisks, _ = strconv.Atoi(match[2])
+		}
+		if match := mdstatSync.FindStringSubmatch(line); match != nil {
+			current.SyncAction = match[1]
+			current.SyncPercent, _ = strconv.ParseFloat(match[2], 64)
+			current.SyncMinutesLeft, _ = strconv.ParseFloat(match[3], 64)
+		}
+	}
+
+	for i := range arrays {
+		array := &arrays[i]
+		if array.ActiveDisks < array.Disks || len(array.Failed) > 0 {
+			array.Degraded = true
+			if array.State == "active" {
+				array.State = "degraded"
+			}
+		}
+	}
+	return arrays
+}
+
+// collectControllerRAID reads virtual drive states through storcli (or
+// Dell's perccli, which shares its interface) or, failing that, the older
+// megacli. Both usually need root.
+func collectControllerRAID(ctx context.Context) []RAIDMetrics {
+	ctx, cancel := context.WithTimeout(ctx, raidToolTimeout)
+	defer cancel()
+
+	for _, tool := range []string{"storcli64", "storcli", "perccli64", "perccli"} {
+		if path, err := exec.LookPath(tool); err == nil {
+			arrays, err := runStorcli(ctx, path)
+			if err != nil {
+				return []RAIDMetrics{{Device: "controller", Source: "storcli", Error: err.Error()}}
+			}
+			return arrays
+		}
+	}
+	for _, tool := range []string{"MegaCli64", "megacli", "MegaCli"} {
+		if path, err := exec.LookPath(tool); err == nil {
+			arrays, err := runMegacli(ctx, path)
+			if err != nil {
+				return []RAIDMetrics{{Device: "controller", Source: "megacli", Error: err.Error()}}
+			}
+			return arrays
+		}
+	}
+	return nil
+}
+
+// storcliOutput is the part of `storcli /call/vall show J` that is used
+type storcliOutput struct {
+	Controllers []struct {
+		CommandStatus struct {
+			Controller  int    `json:"Controller"`
+			Status      string `json:"Status"`
+			Description string `json:"Description"`
+		} `json:"Command Status"`
+		ResponseData struct {
+			VirtualDrives []struct {
+				DGVD  string `json:"DG/VD"`
+				Type  string `json:"TYPE"`
+				State string `json:"State"`
+			} `json:"Virtual Drives"`
+		} `json:"Response Data"`
+	} `json:"Controllers"`
+}
+
+func runStorcli(ctx context.Context, path string) ([]RAIDMetrics, error) {
+	// storcli exits non-zero when any controller fails the command; the
+	// JSON still describes the others
+	out, err := exec.CommandContext(ctx, path, "/call/vall", "show", "J").Output()
+	var output storcliOutput
+	if jsonErr := json.Unmarshal(out, &output); jsonErr != nil {
+		if err != nil {
+			return nil, fmt.Errorf("storcli: %w", err)
+		}
+		return nil, fmt.Errorf("storcli: %w", jsonErr)
+	}
+
+	var arrays []RAIDMetrics
+	for _, controller := range output.Controllers {
+		if controller.CommandStatus.Status != "Success" {
+			// Controllers without virtual drives fail the command too
+			if strings.Contains(controller.CommandStatus.Description, "No VD") {
+				continue
+			}
+			return nil, fmt.Errorf("storcli: controller %d: %s", controller.CommandStatus.Controller, controller.CommandStatus.Description)
+		}
+		for _, drive := range controller.ResponseData.VirtualDrives {
+			_, vd, _ := strings.Cut(drive.DGVD, "/")
+			arrays = append(arrays, RAIDMetrics{
+				Device:   fmt.Sprintf("/c%d/v%s", controller.CommandStatus.Controller, vd),
+				Source:   "storcli",
+				Level:    strings.ToLower(drive.Type),
+				State:    drive.State,
+				Degraded: drive.State != "Optl",
+			})
+		}
+	}
+	return arrays, nil
+}
+
+func runMegacli(ctx context.Context, path string) ([]RAIDMetrics, error) {
+	out, err := exec.CommandContext(ctx, path, "-LDInfo", "-Lall", "-aALL", "-NoLog").Output()
+	if err != nil {
+		return nil, fmt.Errorf("megacli: %w", err)
+	}
+
+	var arrays []RAIDMetrics
+	adapter := 0
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(key, "Adapter "):
+			// "Adapter 0 -- Virtual Drive Information"
+			fmt.Sscanf(key, "Adapter %d", &adapter)
+		case ok && key == "Virtual Drive":
+			// "Virtual Drive: 0 (Target Id: 0)"
+			vd, _, _ := strings.Cut(value, " ")
+			arrays = append(arrays, RAIDMetrics{Device: fmt.Sprintf("/c%d/v%s", adapter, vd), Source: "megacli"})
+		case ok && len(arrays) > 0 && key == "RAID Level":
+			// "Primary-1, Secondary-0, RAID Level Qualifier-0"
+			primary, _, _ := strings.Cut(value, ",")
+			arrays[len(arrays)-1].Level = "raid" + strings.TrimPrefix(primary, "Primary-")
+		case ok && len(arrays) > 0 && key == "State":
+			arrays[len(arrays)-1].State = value
+			arrays[len(arrays)-1].Degraded = value != "Optimal"
+		}
+	}
+	if len(arrays) == 0 && !bytes.Contains(out, []byte("Virtual Drive")) && bytes.Contains(out, []byte("rror")) {
+		return nil, errors.New("megacli: " + strings.TrimSpace(string(out)))
+	}
+	return arrays, nil
+}
+
+// checkRAID raises critical alerts for degraded and inactive arrays, and
+// warnings for arrays resyncing or reshaping and controllers that could
+// not be read. Scheduled checks are not alerted on.
+func (a *Analyzer) checkRAID(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+	for _, array := range metrics.RAID {
+		alert := Alert{
+			Level:     "critical",
+			Category:  "raid",
+			Resource:  array.Device,
+			Timestamp: metrics.Timestamp,
+		}
+		switch {
+		case array.Error != "":
+			alert.Level = "warning"
+			alert.Message = fmt.Sprintf("RAID controller state unknown: %s", array.Error)
+		case array.State == "inactive":
+			alert.Message = fmt.Sprintf("RAID array %s is inactive", array.Device)
+		case array.Degraded:
+			alert.Message = fmt.Sprintf("RAID array %s (%s) is degraded", array.Device, array.Level)
+			if array.Disks > 0 {
+				alert.Message += fmt.Sprintf(": %d of %d disks active", array.ActiveDisks, array.Disks)
+				alert.Value, alert.Threshold = float64(array.ActiveDisks), float64(array.Disks)
+			} else if array.Source != "mdstat" {
+				alert.Message += ": controller reports " + array.State
+			}
+			if len(array.Failed) > 0 {
+				alert.Message += ", failed: " + strings.Join(array.Failed, ", ")
+			}
+			if array.SyncAction == "recovery" {
+				alert.Message += fmt.Sprintf("; rebuilding, %.1f%% done, about %.1f min left", array.SyncPercent, array.SyncMinutesLeft)
+			}
+		case array.SyncAction == "resync" || array.SyncAction == "reshape":
+			alert.Level = "warning"
+			alert.Message = fmt.Sprintf("RAID array %s is in %s, %.1f%% done, about %.1f min left",
+				array.Device, array.SyncAction, array.SyncPercent, array.SyncMinutesLeft)
+			alert.Value = array.SyncPercent
+		default:
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
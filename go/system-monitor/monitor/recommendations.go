@@ -0,0 +1,483 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Levels at which load and swap produce recommendations without an alert
+const (
+	loadPerCoreWarning  = 1.0
+	loadPerCoreCritical = 2.0
+	swapWarning         = 50.0
+	swapCritical        = 80.0
+)
+
+// recommendation is a single line of advice for one category
+type recommendation struct {
+	category string
+	severity int
+	text     string
+}
+
+// severityRank orders alert levels; unknown and "info" levels rank lowest
+func severityRank(level string) int {
+	switch level {
+	case "critical":
+		return 2
+	case "warning":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// GenerateRecommendations generates recommendations based on system state.
+// Each category contributes at most one line, based on its most severe
+// alert, and lines are ordered by severity with critical ones first.
+func (a *Analyzer) GenerateRecommendations(metrics *SystemMetrics, alerts []Alert) []string {
+	// Keep the most severe (then highest value) alert per category
+	worst := make(map[string]Alert)
+	var order []string
+	for _, alert := range alerts {
+		if severityRank(alert.Level) == 0 {
+			continue
+		}
+		current, seen := worst[alert.Category]
+		if !seen {
+			order = append(order, alert.Category)
+		}
+		if !seen || severityRank(alert.Level) > severityRank(current.Level) ||
+			(alert.Level == current.Level && alert.Value > current.Value) {
+			worst[alert.Category] = alert
+		}
+	}
+
+	var recs []recommendation
+	for _, category := range order {
+		alert := worst[category]
+		if text := recommendForAlert(metrics, alert); text != "" {
+			recs = append(recs, recommendation{category, severityRank(alert.Level), text})
+		}
+	}
+
+	// Load pressure doesn't raise alerts of its own, and swap only does
+	// once it thrashes
+	if rec, ok := recommendForLoad(metrics); ok {
+		recs = append(recs, rec)
+	}
+	if _, alerted := worst["swap"]; !alerted {
+		if rec, ok := recommendForSwap(metrics); ok {
+			recs = append(recs, rec)
+		}
+	}
+
+	sort.SliceStable(recs, func(i, j int) bool {
+		return recs[i].severity > recs[j].severity
+	})
+
+	recommendations := make([]string, 0, len(recs))
+	for _, rec := range recs {
+		recommendations = append(recommendations, rec.text)
+	}
+	return recommendations
+}
+
+func recommendForAlert(metrics *SystemMetrics, alert Alert) string {
+	switch alert.Category {
+	case "cpu":
+		if group, ok := TopProcessGroup(metrics, false); ok {
+			return fmt.Sprintf("Consider scaling down or optimizing %s: %d processes using %.1f%% CPU together",
+				group.Name, group.Processes, group.CPUPercent)
+		}
+		topProcesses := GetTopProcesses(metrics, false, 1)
+		if len(topProcesses) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("Consider terminating or optimizing high CPU process: %s (%.1f%% CPU)",
+			topProcesses[0].Name, topProcesses[0].CPUPercent)
+
+	case "memory":
+		if group, ok := TopProcessGroup(metrics, true); ok {
+			return fmt.Sprintf("High memory consumer: %s, %d processes using %.1f MB together; reduce its workers or add memory",
+				group.Name, group.Processes, group.MemoryMB)
+		}
+		topMemProcesses := GetTopProcesses(metrics, true, 1)
+		if len(topMemProcesses) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("High memory consumer: %s (%.1f MB); restart it if it is leaking or add memory",
+			topMemProcesses[0].Name, topMemProcesses[0].MemoryMB)
+
+	case "disk":
+		if alert.Level == "critical" {
+			return fmt.Sprintf("Critical: Clean up disk space on %s immediately to prevent system issues (%s)",
+				alert.Resource, alert.Message)
+		}
+		return fmt.Sprintf("Disk %s is filling up: find the largest directories with `du -xh --max-depth=2 %s | sort -rh | head` and clean up logs, caches or old artifacts",
+			alert.Resource, alert.Resource)
+
+	case "disk_forecast":
+		return fmt.Sprintf("Disk %s is filling steadily: find what is growing with `du -xh --max-depth=2 %s | sort -rh | head` and set up log rotation or retention before it runs out",
+			alert.Resource, alert.Resource)
+
+	case "inodes":
+		return fmt.Sprintf("Disk %s is running out of inodes: look for directories holding huge numbers of small files (caches, sessions, mail spools) with `du --inodes -x %s | sort -rn | head`",
+			alert.Resource, alert.Resource)
+
+	case "smart":
+		device := strings.SplitN(alert.Resource, "/", 2)[0]
+		if alert.Level == "critical" {
+			return fmt.Sprintf("Disk %s is failing: back up its data now and schedule a replacement; check the full report with `smartctl -a %s`",
+				device, device)
+		}
+		return fmt.Sprintf("Disk %s is degrading: check `smartctl -a %s`, run a long self-test (`smartctl -t long %s`) and plan a replacement",
+			device, device, device)
+
+	case "raid":
+		switch {
+		case strings.HasPrefix(alert.Message, "RAID controller"):
+			return "Run the controller tool (`storcli /call/vall show`, or `megacli -LDInfo -Lall -aALL`) by hand as root to see why it fails; the monitor needs root to read controllers"
+		case strings.HasPrefix(alert.Resource, "/c"):
+			return fmt.Sprintf("Find the failed drive with `storcli %s show all` (or megacli), replace it and check the rebuild starts; back up the array's data now, as another drive failure may lose it",
+				alert.Resource)
+		case strings.Contains(alert.Message, "inactive"):
+			return fmt.Sprintf("Check `mdadm --detail /dev/%s` and the kernel log for missing members, then reassemble with `mdadm --assemble --scan`", alert.Resource)
+		case alert.Level == "warning":
+			return fmt.Sprintf("Array %s is usable but slower until the sync completes; follow it in /proc/mdstat, and check the kernel log if it follows an unclean shutdown",
+				alert.Resource)
+		}
+		return fmt.Sprintf("Back up the data on /dev/%s now: another disk failure may lose it. Find the failed member with `mdadm --detail /dev/%s`, remove it (`mdadm /dev/%s --remove`), and add its replacement (`mdadm /dev/%s --add /dev/sdX`)",
+			alert.Resource, alert.Resource, alert.Resource, alert.Resource)
+
+	case "filesystem":
+		return fmt.Sprintf("The kernel usually remounts a filesystem read-only after I/O errors: find them with `dmesg | grep -iE 'I/O error|remount|fs error'` and check the disk with smartctl. Then unmount %s, run fsck, and remount it read-write only once it is clean",
+			alert.Resource)
+
+	case "pool":
+		name, aspect := alert.Resource, ""
+		for _, suffix := range []string{"data", "errors", "capacity", "fragmentation"} {
+			if trimmed, ok := strings.CutSuffix(alert.Resource, ":"+suffix); ok {
+				name, aspect = trimmed, suffix
+			}
+		}
+		zfs := strings.HasPrefix(alert.Message, "ZFS")
+		switch {
+		case strings.Contains(alert.Message, "health unknown"):
+			return "Pool health needs `zpool` or `btrfs` and, for Btrfs device statistics, root: install btrfs-progs or run the monitor with the privileges to read them"
+		case aspect == "" && zfs:
+			return fmt.Sprintf("Find the faulted or missing device with `zpool status -v %s`, then replace it with `zpool replace %s <old> <new>`; back up the pool first if it has no redundancy left", name, name)
+		case aspect == "":
+			return fmt.Sprintf("A Btrfs filesystem mounted degraded is missing a device: find it with `btrfs filesystem show %s`, add a replacement with `btrfs replace start` and remount without -o degraded", name)
+		case aspect == "data":
+			return fmt.Sprintf("List the damaged files with `zpool status -v %s` and restore them from backup, then run `zpool scrub %s` and `zpool clear %s`", name, name, name)
+		case aspect == "errors" && zfs:
+			return fmt.Sprintf("Check the disks with errors in `zpool status %s` and with smartctl; once resolved, clear the counters with `zpool clear %s` and scrub", name, name)
+		case aspect == "errors":
+			return fmt.Sprintf("Check `btrfs device stats %s` and the disks with smartctl; once resolved, reset the counters with `btrfs device stats -z %s` and run `btrfs scrub start %s`", name, name, name)
+		case aspect == "fragmentation":
+			return fmt.Sprintf("ZFS cannot defragment in place: free space in %s, or grow the pool, so new writes find contiguous space", name)
+		case zfs:
+			return fmt.Sprintf("ZFS slows down as a pool fills: free space in %s, destroying old snapshots (`zfs list -t snapshot -o name,used -s used`), or add a vdev", name)
+		}
+		return fmt.Sprintf("Free space on %s or add a device; if `btrfs filesystem usage %s` shows little unallocated space, reclaim it with `btrfs balance start -dusage=50 %s`", name, name, name)
+
+	case "disk_saturation":
+		writer := "find the writer with `iotop -o`"
+		if top := GetTopIOProcesses(metrics, 1); len(top) > 0 {
+			writer = fmt.Sprintf("%s (PID %d) is writing %.1f MB/s", top[0].Name, top[0].PID, top[0].WriteMBPerSec)
+		}
+		return fmt.Sprintf("Processes are queueing on I/O to a full %s: %s; find what is growing with `du -xh --max-depth=2 %s | sort -rh | head`, then free space before writes start failing",
+			alert.Resource, writer, alert.Resource)
+
+	case "memory_exhaustion":
+		text := "Memory is exhausted and the system is thrashing swap"
+		if top := GetTopProcesses(metrics, true, 1); len(top) > 0 {
+			text += fmt.Sprintf(": %s (%.1f MB) is the largest consumer", top[0].Name, top[0].MemoryMB)
+		}
+		return text + "; stop or restart it, or add RAM"
+
+	case "cpu_starvation":
+		return "The CPU looks busy because the hypervisor is withholding it: move this VM to a less loaded host or a larger instance type rather than tuning processes"
+
+	case "iowait":
+		text := fmt.Sprintf("CPU is waiting on I/O %.1f%% of the time: the system is disk-bound, not CPU-bound; check the busiest devices with `iostat -x` before adding CPU",
+			alert.Value)
+		if top := GetTopIOProcesses(metrics, 1); len(top) > 0 {
+			text += fmt.Sprintf(". %s (PID %d) does the most disk I/O: %.1f MB/s read, %.1f MB/s written",
+				top[0].Name, top[0].PID, top[0].ReadMBPerSec, top[0].WriteMBPerSec)
+		}
+		return text
+
+	case "steal":
+		if metrics.CPU.Virtualization == "xen" || metrics.CPU.Virtualization == "kvm" {
+			return fmt.Sprintf("%.1f%% of CPU time is stolen by the %s hypervisor: on cloud burstable instances this means CPU credits ran out; otherwise move this VM to a less loaded host or a larger instance type",
+				alert.Value, metrics.CPU.Virtualization)
+		}
+		return fmt.Sprintf("%.1f%% of CPU time is stolen by the hypervisor: move this VM to a less loaded host or a larger instance type",
+			alert.Value)
+
+	case "power":
+		if alert.Resource == "ac" {
+			return "Running on battery since AC power was lost: check the power supply or charger, and save work or shut down cleanly before the battery runs out"
+		}
+		return fmt.Sprintf("Battery %s is running low: connect it to power or dock the device, or shut it down cleanly before it dies",
+			alert.Resource)
+
+	case "reboot":
+		return "Find out why the host rebooted: check the end of the previous boot's log (`journalctl -b -1 -e`), kernel crash dumps in `/var/crash`, and power or thermal events in the hardware log (`ipmitool sel list`)"
+
+	case "throttling":
+		return fmt.Sprintf("The container is throttled in %.0f%% of CPU periods: raise its CPU limit (cpu.max, resources.limits.cpu in Kubernetes) or spread the work across more replicas",
+			alert.Value)
+
+	case "file_descriptors":
+		if alert.Resource == "system" {
+			return "System-wide open file limit is nearly exhausted: raise fs.file-max or find the process leaking descriptors"
+		}
+		if name, ok := strings.CutSuffix(alert.Resource, "/fds"); ok {
+			return fmt.Sprintf("Watched process %s is close to its open file limit: check it for descriptor leaks, or raise the limit (LimitNOFILE= in its systemd unit, `ulimit -n` otherwise)",
+				name)
+		}
+		return fmt.Sprintf("Process %s is close to its open file limit: check it for descriptor leaks or raise its `ulimit -n`",
+			alert.Resource)
+
+	case "process":
+		if alert.Value < alert.Threshold {
+			return fmt.Sprintf("Restart %s and check its logs for why it exited; a process supervisor (systemd Restart=on-failure) keeps it up meanwhile",
+				strings.SplitN(alert.Resource, "/", 2)[0])
+		}
+		return fmt.Sprintf("%s is over its budget: check it for a runaway loop or leak, or raise the budget if the load is legitimate",
+			strings.SplitN(alert.Resource, "/", 2)[0])
+
+	case "systemd":
+		return fmt.Sprintf("Find why %s stopped with `systemctl status %s` and `journalctl -u %s -b`, then `systemctl restart %s`; set Restart=on-failure in the unit if it should come back on its own",
+			alert.Resource, alert.Resource, alert.Resource, alert.Resource)
+
+	case "login":
+		if alert.Resource == "failed_logins" {
+			return "Someone is guessing passwords: check `lastb` for the sources, block them at the firewall or with fail2ban, and disable SSH password logins (PasswordAuthentication no) in favour of keys"
+		}
+		return "Check with `last` and the auth log who logged in as root and why; set PermitRootLogin no in sshd_config and have admins log in as themselves and use sudo"
+
+	case "pressure":
+		switch alert.Resource {
+		case "memory":
+			return "Tasks are waiting on memory reclaim or swap-in: find the largest processes with `ps aux --sort=-rss` and cap or restart them, or add memory"
+		case "io":
+			return "Tasks are waiting on disk I/O: find the busiest processes with `iotop -o` and the saturated device with `iostat -x 1`, then spread or throttle the load or move it to faster storage"
+		}
+		if metrics.Cgroup != nil && metrics.Cgroup.CPULimitCores > 0 {
+			return "Tasks are waiting for CPU within the container's limit: raise its CPU limit or reduce the concurrent work"
+		}
+		return fmt.Sprintf("Tasks are waiting for CPU (load %.2f per core): reduce concurrent work, move jobs off peak hours or add cores",
+			metrics.Load.Load1PerCore)
+
+	case "interrupts":
+		if metrics.Activity != nil && metrics.Activity.TopInterrupt != "" {
+			return fmt.Sprintf("Find the device behind %s in /proc/interrupts: check its link or driver for errors, enable interrupt coalescing for a NIC (`ethtool -C`), and spread its IRQs across cores with irqbalance",
+				metrics.Activity.TopInterrupt)
+		}
+		return "Watch /proc/interrupts for the source that climbs fastest: check that device's link or driver for errors, enable interrupt coalescing for a NIC (`ethtool -C`), and spread its IRQs across cores with irqbalance"
+
+	case "forks":
+		return "Find the parent spawning processes with `ps -eo ppid,comm --sort=ppid | uniq -c | sort -rn | head`, stop the runaway script or service, and cap users' processes with `ulimit -u` or the service's TasksMax"
+
+	case "numa":
+		return fmt.Sprintf("Check how the busy processes spread across nodes with `numastat -p <pid>`: interleave the database's memory (`numactl --interleave=all`), bind it and its threads to one node, or enable numa_balancing, so %s stops spilling onto remote memory",
+			alert.Resource)
+
+	case "hugepages":
+		if alert.Threshold > 0 {
+			return fmt.Sprintf("Raise vm.nr_hugepages (or the %s pool in /sys/kernel/mm/hugepages) to what the database's shared buffers need, or shrink those, so its huge page mappings don't fail",
+				alert.Resource)
+		}
+		return "Check that the database is configured to use huge pages (huge_pages=on in PostgreSQL, use_large_pages in Oracle) and started after they were allocated, or lower vm.nr_hugepages to give the memory back"
+
+	case "entropy":
+		if metrics.CPU.Virtualization != "" {
+			return "Give the VM a virtio-rng device backed by the host's /dev/urandom, or run rngd (rng-tools) or haveged in it, so TLS handshakes don't stall waiting for entropy"
+		}
+		return "Run rngd (rng-tools) to feed the kernel from the CPU's or TPM's hardware RNG, or haveged, so TLS handshakes don't stall waiting for entropy; kernels 5.6 and later no longer block once seeded"
+
+	case "exposure":
+		return fmt.Sprintf("Find what opened %s (`ss -lntup` or `netstat -abno` on Windows): bind it to localhost or block it at the firewall if it shouldn't be reachable, or add it to allowed_ports if it should",
+			alert.Resource)
+
+	case "updates":
+		switch alert.Resource {
+		case "reboot_required":
+			return "Schedule a reboot so the installed kernel and library updates take effect; until then the running system still has the old, possibly vulnerable, code"
+		case "security":
+			if updates := metrics.Updates; updates != nil && updates.Manager == "apt-get" {
+				return "Install the security updates with `apt-get upgrade` (or let unattended-upgrades do it daily), then check whether a reboot is required"
+			}
+			return "Install the security updates (`dnf upgrade --security`, `zypper patch --category security` or `winget upgrade --all`), then check whether a reboot is required"
+		}
+		return "Apply the pending updates in the next maintenance window; a long backlog makes each upgrade riskier"
+
+	case "windows_service":
+		return fmt.Sprintf("Check why %s stopped in Event Viewer (System log, Service Control Manager events) and start it with `Start-Service %s`; set its recovery actions to restart on failure if it should come back on its own",
+			alert.Resource, alert.Resource)
+
+	case "eventlog":
+		return fmt.Sprintf("Review the recent errors with `Get-WinEvent -LogName %s -MaxEvents 20` or in Event Viewer, starting with the provider named in the alert",
+			alert.Resource)
+
+	case "log":
+		return fmt.Sprintf("Read the errors around the latest one in log %s to find what started failing; a spike that began with a deploy or config change points at it",
+			alert.Resource)
+
+	case "path":
+		return fmt.Sprintf("Find what is writing to %s with `du -ah %s | sort -rh | head` and `lsof +D %s`; rotate or clean up old files, and cap whatever produces them",
+			alert.Resource, alert.Resource, alert.Resource)
+
+	case "memory_leak":
+		return fmt.Sprintf("%s keeps growing: restart it to reclaim the memory before it exhausts RAM, and capture a heap profile or core dump first so the leak can be fixed",
+			strings.SplitN(alert.Resource, "/", 2)[0])
+
+	case "swap":
+		text := "The system is thrashing: memory demand exceeds RAM and pages are constantly swapped back in"
+		if top := GetTopProcesses(metrics, true, 1); len(top) > 0 {
+			text += fmt.Sprintf("; largest consumer is %s (%.1f MB)", top[0].Name, top[0].MemoryMB)
+		}
+		return text + ". Reduce its memory use or add RAM; more swap will not help"
+
+	case "zombies":
+		return "Zombies disappear once their parent reaps them: fix or restart the parent process named in the alert (killing the zombies themselves has no effect), or run containers with an init such as `docker run --init`"
+
+	case "container":
+		return fmt.Sprintf("Check container %s with `docker logs %s` and `docker inspect %s`; raise its memory limit if it is being OOM killed",
+			alert.Resource, alert.Resource, alert.Resource)
+
+	case "kubernetes":
+		if node, ok := strings.CutPrefix(alert.Resource, "node/"); ok {
+			node = strings.TrimSuffix(node, "/conditions")
+			return fmt.Sprintf("Check the node with `kubectl describe node %s` and the kubelet with `journalctl -u kubelet`; under memory or disk pressure the kubelet evicts pods until usage drops, so free up the resource or cordon the node and drain it",
+				node)
+		}
+		namespace, pod, _ := strings.Cut(alert.Resource, "/")
+		if strings.Contains(alert.Message, "evicted") {
+			return fmt.Sprintf("Pod %s was evicted by the kubelet under node pressure: check `kubectl describe pod -n %s %s`, set resource requests so it is not the first to go, and delete the evicted pod once understood", pod, namespace, pod)
+		}
+		if strings.Contains(alert.Message, "pending") {
+			return fmt.Sprintf("Find why pod %s cannot start in the events of `kubectl describe pod -n %s %s`: image pulls, missing volumes or secrets, or no node with room for its requests", pod, namespace, pod)
+		}
+		return fmt.Sprintf("Check why pod %s exits with `kubectl logs -n %s %s --previous` and `kubectl describe pod -n %s %s`; raise its memory limit if it is being OOM killed",
+			pod, namespace, pod, namespace, pod)
+
+	case "connections":
+		return "Sockets in CLOSE_WAIT are connections the peer closed but the application never did: find the owner with `ss -tanp state close-wait` and fix its connection handling, restarting it only buys time"
+
+	case "conntrack":
+		return "The conntrack table drops new connections when full: raise net.netfilter.nf_conntrack_max, or shorten nf_conntrack_tcp_timeout_time_wait on busy proxies"
+
+	case "tcp":
+		switch alert.Resource {
+		case "listen_overflows":
+			return "Find the listener whose accept queue is full with `ss -ltn` (Recv-Q at Send-Q): the application isn't accepting connections fast enough, so add workers or raise its backlog along with net.core.somaxconn"
+		case "socket_memory":
+			return "The kernel ran out of TCP socket memory: check usage against net.ipv4.tcp_mem in /proc/net/sockstat, find the sockets with large queues with `ss -tm`, and raise tcp_mem if the load is legitimate"
+		}
+		return "Retransmits mean packets are being lost: check the interfaces for errors and drops with `ip -s link`, the path to the busiest peers with `mtr`, and for a duplex mismatch or failing cable or NIC"
+
+	case "dns":
+		return "Check the resolvers in /etc/resolv.conf (or `resolvectl status`) are reachable and answering, e.g. with `dig`; an NXDOMAIN means the record itself is missing or was changed"
+
+	case "ping":
+		return fmt.Sprintf("Trace the path to %s with `mtr` to find the hop where packets are lost or delayed, and check this host's uplink and interface errors first",
+			alert.Resource)
+
+	case "clock":
+		if strings.Contains(alert.Message, "none of") {
+			return "Allow outbound UDP port 123 to the configured NTP servers, or point ntp_servers at a server reachable from this host"
+		}
+		return "Check the time sync daemon with `timedatectl status` or `chronyc tracking`; enable it (`timedatectl set-ntp true`) if it is off, and step the clock with `chronyc makestep` when the offset is large"
+
+	case "certificate":
+		if strings.Contains(alert.Message, "could not be checked") {
+			return fmt.Sprintf("Make certificate %s readable by the monitor, or fix its endpoint or path", alert.Resource)
+		}
+		if strings.Contains(alert.Message, "does not verify") {
+			return fmt.Sprintf("Certificate %s is not trusted by clients: serve the full chain including intermediates and check it covers the host name, e.g. with `openssl s_client -showcerts`", alert.Resource)
+		}
+		return fmt.Sprintf("Renew certificate %s and reload the services using it; if renewal is automated (certbot, cert-manager), check why it has not run", alert.Resource)
+
+	case "probe":
+		if strings.Contains(alert.Message, "expected closed") {
+			return fmt.Sprintf("Something is listening where %s expects nothing: find it with `ss -tulpn` and stop it, or firewall the port", alert.Resource)
+		}
+		return fmt.Sprintf("Endpoint %s keeps failing its health check: confirm the service behind it is running and check its logs, then its dependencies such as the database or upstream APIs",
+			alert.Resource)
+
+	case "network":
+		return fmt.Sprintf("Interface %s is saturated: find the heaviest connections with `iftop -i %s` or `ss -tin`, and consider rate limiting or a faster link",
+			alert.Resource, alert.Resource)
+
+	case "self":
+		if alert.Resource == "latency" {
+			return "Collections are slow: disable collectors that hang on this host (smart, containers, processes) through `collectors`, lower `collector_timeout`, or raise the interval"
+		}
+		return "The monitor itself is leaking: restart the task to reclaim the memory and report the leak with its `self` metrics"
+	}
+
+	return ""
+}
+
+// recommendForLoad explains high load per core, distinguishing processes
+// blocked on I/O from genuine CPU saturation
+func recommendForLoad(metrics *SystemMetrics) (recommendation, bool) {
+	if metrics.CPU.Cores == 0 {
+		return recommendation{}, false
+	}
+
+	loadPerCore := metrics.Load.Load1 / float64(metrics.CPU.Cores)
+	if loadPerCore <= loadPerCoreWarning {
+		return recommendation{}, false
+	}
+
+	severity := 1
+	if loadPerCore > loadPerCoreCritical {
+		severity = 2
+	}
+
+	breakdown := metrics.CPU.Breakdown
+	var text string
+	if breakdown.IOWait > breakdown.User+breakdown.System {
+		text = fmt.Sprintf("Load is %.2f per core but mostly I/O wait (%.1f%%): processes are blocked on disk or network storage, not CPU",
+			loadPerCore, breakdown.IOWait)
+	} else if top := GetTopProcesses(metrics, false, 1); len(top) > 0 {
+		text = fmt.Sprintf("Load is %.2f per core and CPU-bound (%.1f%% user, %.1f%% system): top consumer is %s (%.1f%% CPU)",
+			loadPerCore, breakdown.User, breakdown.System, top[0].Name, top[0].CPUPercent)
+	} else {
+		text = fmt.Sprintf("Load is %.2f per core and CPU-bound (%.1f%% user, %.1f%% system): reduce concurrent work or add CPU",
+			loadPerCore, breakdown.User, breakdown.System)
+	}
+
+	return recommendation{"load", severity, text}, true
+}
+
+// recommendForSwap flags heavy swap usage as memory pressure, but only
+// while pages are still moving; full but idle swap holds stale pages
+func recommendForSwap(metrics *SystemMetrics) (recommendation, bool) {
+	if metrics.Memory.SwapTotalGB == 0 || metrics.Memory.SwapPercent <= swapWarning {
+		return recommendation{}, false
+	}
+	if metrics.Memory.SwapInPerSec == 0 && metrics.Memory.SwapOutPerSec == 0 {
+		return recommendation{}, false
+	}
+
+	severity := 1
+	if metrics.Memory.SwapPercent > swapCritical {
+		severity = 2
+	}
+
+	text := fmt.Sprintf("Swap is %.1f%% used (%.1f GB): the system is under memory pressure",
+		metrics.Memory.SwapPercent, metrics.Memory.SwapUsedGB)
+	if top := GetTopProcesses(metrics, true, 1); len(top) > 0 {
+		text += fmt.Sprintf("; largest consumer is %s (%.1f MB)", top[0].Name, top[0].MemoryMB)
+	}
+
+	return recommendation{"swap", severity, text}, true
+}
@@ -0,0 +1,298 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MetricCollector is a named source of metrics that Collector runs
+// concurrently with the others on every snapshot. The built-in collectors
+// fill their own section of SystemMetrics; any other result is stored in
+// SystemMetrics.Custom under the collector's name.
+type MetricCollector interface {
+	Name() string
+	Collect(ctx context.Context) (interface{}, error)
+}
+
+// collectorFunc adapts a function to MetricCollector
+type collectorFunc struct {
+	name    string
+	collect func(ctx context.Context) (interface{}, error)
+}
+
+func (f collectorFunc) Name() string {
+	return f.name
+}
+
+func (f collectorFunc) Collect(ctx context.Context) (interface{}, error) {
+	return f.collect(ctx)
+}
+
+// builtin wraps one of Collector's typed collect methods
+func builtin[T any](name string, collect func(context.Context) (T, error)) MetricCollector {
+	return collectorFunc{name: name, collect: func(ctx context.Context) (interface{}, error) {
+		return collect(ctx)
+	}}
+}
+
+// registerBuiltins adds the built-in collectors, in report order
+func (c *Collector) registerBuiltins() {
+	c.collectors = []MetricCollector{
+		builtin("cpu", c.collectCPUMetrics),
+		builtin("memory", c.collectMemoryMetrics),
+		builtin("numa", c.collectNUMA),
+		builtin("cgroup", c.collectCgroup),
+		builtin("disk", c.collectDiskMetrics),
+		builtin("load", c.collectLoadMetrics),
+		builtin("pressure", c.collectPressure),
+		builtin("activity", c.collectActivity),
+		builtin("uptime", c.collectUptime),
+		builtin("sessions", c.collectSessions),
+		builtin("entropy", c.collectEntropy),
+		builtin("processes", c.collectProcessMetrics),
+		builtin("gpu", c.collectGPUMetrics),
+		builtin("network", c.collectNetworkMetrics),
+		builtin("connections", c.collectConnectionMetrics),
+		builtin("tcp", c.collectTCP),
+		builtin("containers", c.collectContainerMetrics),
+		builtin("kubernetes", c.collectKubernetes),
+		builtin("sensors", c.collectSensorMetrics),
+		builtin("power", c.collectPower),
+		builtin("smart", c.collectDiskHealth),
+		builtin("raid", c.collectRAID),
+		builtin("updates", c.collectUpdates),
+		builtin("pools", c.collectPools),
+		builtin("file_descriptors", func(context.Context) (*FileDescriptorMetrics, error) {
+			return readFileDescriptorMetrics()
+		}),
+		builtin("self", collectSelfMetrics),
+		builtin("probes", c.collectProbes),
+		builtin("exposure", c.collectExposure),
+		builtin("certificates", c.collectCertificates),
+		builtin("ping", c.collectPing),
+		builtin("systemd", c.collectSystemd),
+		builtin("windows_services", c.collectWindowsServices),
+		builtin("windows_events", c.collectWindowsEvents),
+		builtin("logs", c.collectLogs),
+		builtin("paths", c.collectPaths),
+		builtin("clock", c.collectClock),
+	}
+}
+
+// Register adds a collector to run on every snapshot, unless
+// Config.Collectors disables it. Names must be unique, and collectors
+// must be registered before collection starts.
+func (c *Collector) Register(collector MetricCollector) error {
+	for _, existing := range c.collectors {
+		if existing.Name() == collector.Name() {
+			return fmt.Errorf("collector %q is already registered", collector.Name())
+		}
+	}
+	c.collectors = append(c.collectors, collector)
+	return nil
+}
+
+// EnabledCollectors returns the names of the collectors that run on each
+// snapshot, in registration order
+func (c *Collector) EnabledCollectors() []string {
+	var names []string
+	for _, collector := range c.collectors {
+		if c.config.collectorEnabled(collector.Name()) {
+			names = append(names, collector.Name())
+		}
+	}
+	return names
+}
+
+// startCollector marks a collector as running. It reports false if the
+// collector is still running from a previous snapshot.
+func (c *Collector) startCollector(name string) bool {
+	c.runningMu.Lock()
+	defer c.runningMu.Unlock()
+
+	if c.running[name] {
+		return false
+	}
+	if c.running == nil {
+		c.running = make(map[string]bool)
+	}
+	c.running[name] = true
+	return true
+}
+
+func (c *Collector) finishCollector(name string) {
+	c.runningMu.Lock()
+	defer c.runningMu.Unlock()
+	delete(c.running, name)
+}
+
+// CollectorFailure describes a collector that failed or timed out while
+// taking a snapshot
+type CollectorFailure struct {
+	Collector string `json:"collector"`
+	Message   string `json:"message"`
+	TimedOut  bool   `json:"timed_out,omitempty"`
+
+	// Stack of the goroutine when the collector panicked
+	Stack string `json:"stack,omitempty"`
+}
+
+// PanicError is a panic recovered from a collector, which fails that
+// collector instead of crashing the process
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// CollectionError is returned by CollectMetrics when some collectors
+// failed. The snapshot lists the same failures.
+type CollectionError struct {
+	Failures []CollectorFailure
+}
+
+func (e *CollectionError) Error() string {
+	parts := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		parts[i] = failure.Collector + ": " + failure.Message
+	}
+	return "collection errors: " + strings.Join(parts, "; ")
+}
+
+// collectorEnabled applies Config.Collectors on top of a collector's
+// default. GPU, container and SMART collection are opt-in, probes, pings,
+// services, logs, paths, NTP and certificate checks run when configured,
+// everything else runs unless disabled.
+func (c Config) collectorEnabled(name string) bool {
+	if enabled, ok := c.Collectors[name]; ok {
+		return enabled
+	}
+	switch name {
+	case "gpu":
+		return c.CollectGPU
+	case "containers":
+		return c.CollectContainers
+	case "kubernetes":
+		return c.CollectKubernetes
+	case "smart":
+		return c.CollectSMART
+	case "updates":
+		return c.CollectUpdates
+	case "probes":
+		return len(c.HTTPProbes) > 0 || len(c.PortProbes) > 0 || len(c.DNSProbes) > 0
+	case "exposure":
+		return c.AuditPorts
+	case "certificates":
+		return len(c.CertificateChecks) > 0
+	case "ping":
+		return len(c.PingTargets) > 0
+	case "systemd":
+		return len(c.SystemdUnits) > 0
+	case "windows_services":
+		return len(c.WindowsServices) > 0
+	case "windows_events":
+		return len(c.WindowsEventLogs) > 0
+	case "logs":
+		return len(c.LogWatches) > 0
+	case "paths":
+		return len(c.PathWatches) > 0
+	case "clock":
+		return len(c.NTPServers) > 0
+	}
+	return true
+}
+
+// storeResult places a collector's result in its section of the snapshot.
+// The caller holds the snapshot's lock.
+func storeResult(metrics *SystemMetrics, name string, result interface{}) {
+	switch v := result.(type) {
+	case nil:
+	case CPUMetrics:
+		metrics.CPU = v
+	case MemoryMetrics:
+		metrics.Memory = v
+	case []DiskMetrics:
+		metrics.Disk = v
+	case LoadMetrics:
+		metrics.Load = v
+	case []ProcessMetrics:
+		metrics.Processes = v
+	case processSnapshot:
+		metrics.Processes = v.top
+		metrics.LargestProcesses = v.largest
+		metrics.ProcessGroups = v.groups
+		metrics.WatchedProcesses = v.watched
+		metrics.ProcessStates = v.states
+	case []GPUMetrics:
+		metrics.GPU = v
+	case []NetworkMetrics:
+		metrics.Network = v
+	case *ConnectionMetrics:
+		metrics.Connections = v
+	case *CgroupMetrics:
+		metrics.Cgroup = v
+	case *PowerMetrics:
+		metrics.Power = v
+	case *UptimeMetrics:
+		metrics.Uptime = v
+	case *SessionMetrics:
+		metrics.Sessions = v
+	case *UpdateMetrics:
+		metrics.Updates = v
+	case *ExposureMetrics:
+		metrics.Exposure = v
+	case *EntropyMetrics:
+		metrics.Entropy = v
+	case *NUMAMetrics:
+		metrics.NUMA = v
+	case *PressureMetrics:
+		metrics.Pressure = v
+	case *ActivityMetrics:
+		metrics.Activity = v
+	case *TCPMetrics:
+		metrics.TCP = v
+	case *KubernetesMetrics:
+		metrics.Kubernetes = v
+	case []PoolMetrics:
+		metrics.Pools = v
+	case []RAIDMetrics:
+		metrics.RAID = v
+	case []ContainerMetrics:
+		metrics.Containers = v
+	case *SensorsMetrics:
+		metrics.Sensors = v
+	case []DiskHealthMetrics:
+		metrics.DiskHealth = v
+	case *FileDescriptorMetrics:
+		metrics.FileDescriptors = v
+	case *SelfMetrics:
+		metrics.Self = v
+	case []ProbeMetrics:
+		metrics.Probes = v
+	case []PingMetrics:
+		metrics.Ping = v
+	case *SystemdMetrics:
+		metrics.Systemd = v
+	case []WindowsServiceMetrics:
+		metrics.WindowsServices = v
+	case []WindowsEvent:
+		metrics.WindowsEvents = v
+	case []LogMetrics:
+		metrics.Logs = v
+	case []PathMetrics:
+		metrics.Paths = v
+	case []ClockMetrics:
+		metrics.Clock = v
+	case []CertificateMetrics:
+		metrics.Certificates = v
+	default:
+		if metrics.Custom == nil {
+			metrics.Custom = make(map[string]interface{})
+		}
+		metrics.Custom[name] = v
+	}
+}
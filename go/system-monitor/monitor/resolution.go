@@ -0,0 +1,74 @@
+package monitor
+
+import "time"
+
+// Condition is an alert condition that has escalated and not yet cleared.
+// A condition is identified by category and resource, so a warning that
+// becomes critical is the same condition.
+type Condition struct {
+	Category string    `json:"category"`
+	Resource string    `json:"resource"`
+	Level    string    `json:"level"`   // most severe level reached
+	Message  string    `json:"message"` // latest alert message
+	RaisedAt time.Time `json:"raised_at"`
+}
+
+// Resolution reports that a condition has cleared
+type Resolution struct {
+	Condition
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// Duration returns how long the condition was active
+func (r Resolution) Duration() time.Duration {
+	return r.ResolvedAt.Sub(r.RaisedAt)
+}
+
+// conditionKey identifies the condition an alert belongs to
+func conditionKey(alert Alert) string {
+	return alert.Category + "/" + alert.Resource
+}
+
+// Resolve tracks alert conditions across snapshots and returns those that
+// cleared since the previous one. Pass every alert of the snapshot,
+// including suppressed ones: a condition silenced by a quiet window has
+// not cleared. Only conditions that escalated are tracked.
+func (a *Analyzer) Resolve(metrics *SystemMetrics, alerts []Alert) []Resolution {
+	if a.conditions == nil {
+		a.conditions = make(map[string]Condition)
+	}
+
+	present := make(map[string]bool, len(alerts))
+	for _, alert := range alerts {
+		key := conditionKey(alert)
+		present[key] = true
+		// A condition folded into a composite alert has not cleared
+		for _, contributing := range alert.Contributing {
+			present[conditionKey(contributing)] = true
+		}
+
+		condition, active := a.conditions[key]
+		escalating := !alert.Suppressed && severityRank(alert.Level) > 0
+		if !active && !escalating {
+			continue
+		}
+		if !active {
+			condition = Condition{Category: alert.Category, Resource: alert.Resource, RaisedAt: alert.Timestamp}
+		}
+		if severityRank(alert.Level) > severityRank(condition.Level) {
+			condition.Level = alert.Level
+		}
+		condition.Message = alert.Message
+		a.conditions[key] = condition
+	}
+
+	var resolved []Resolution
+	for key, condition := range a.conditions {
+		if present[key] {
+			continue
+		}
+		resolved = append(resolved, Resolution{Condition: condition, ResolvedAt: metrics.Timestamp})
+		delete(a.conditions, key)
+	}
+	return resolved
+}
@@ -0,0 +1,177 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// AlertRule is a user-defined alert: an expression over the snapshot (see
+// expr.go) that raises an alert while it holds
+type AlertRule struct {
+	Name  string `json:"name"` // alert resource, unique
+	Expr  string `json:"expr"`
+	Level string `json:"level"` // "warning" (default) or "critical"
+
+	// Message may embed expressions in braces, e.g.
+	// "load {load.load1} on {cores} cores"; by default it names the rule
+	Message string `json:"message"`
+
+	// Consecutive snapshots the expression must hold before alerting,
+	// 1 by default
+	For int `json:"for"`
+}
+
+// compiledRule is an AlertRule with its expressions parsed
+type compiledRule struct {
+	AlertRule
+	expr expr
+
+	// Operands of a top-level comparison, reported as the alert's value
+	// and threshold
+	value, threshold expr
+}
+
+// messagePlaceholder matches an expression embedded in a rule message
+var messagePlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+func (r AlertRule) validate() error {
+	var errs []error
+	if r.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if _, err := r.compile(); err != nil {
+		errs = append(errs, err)
+	}
+	if r.Level != "" && r.Level != "warning" && r.Level != "critical" {
+		errs = append(errs, fmt.Errorf(`level must be "warning" or "critical", got %q`, r.Level))
+	}
+	if r.For < 0 {
+		errs = append(errs, fmt.Errorf("for must not be negative, got %d", r.For))
+	}
+	return errors.Join(errs...)
+}
+
+func (r AlertRule) compile() (compiledRule, error) {
+	if r.Expr == "" {
+		return compiledRule{}, errors.New("expr is required")
+	}
+	e, err := compileExpr(r.Expr)
+	if err != nil {
+		return compiledRule{}, fmt.Errorf("expr: %w", err)
+	}
+	for _, match := range messagePlaceholder.FindAllStringSubmatch(r.Message, -1) {
+		if _, err := compileExpr(match[1]); err != nil {
+			return compiledRule{}, fmt.Errorf("message {%s}: %w", match[1], err)
+		}
+	}
+
+	rule := compiledRule{AlertRule: r, expr: e}
+	if b, ok := e.(binaryExpr); ok && isComparison(b.op) {
+		rule.value, rule.threshold = b.left, b.right
+	}
+	return rule, nil
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case ">", ">=", "<", "<=", "==", "!=":
+		return true
+	}
+	return false
+}
+
+// compileRules compiles the configured rules, skipping invalid ones; use
+// Config.Validate to report them
+func compileRules(config Config) []compiledRule {
+	var rules []compiledRule
+	for _, r := range config.AlertRules {
+		if rule, err := r.compile(); err == nil {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// checkRules evaluates the alert rules. A rule whose metrics are missing
+// from the snapshot, or that fails to evaluate, does not hold.
+func (a *Analyzer) checkRules(metrics *SystemMetrics) []Alert {
+	if len(a.rules) == 0 {
+		return nil
+	}
+
+	root, err := snapshotTree(metrics)
+	if err != nil {
+		return nil
+	}
+
+	var alerts []Alert
+	for _, rule := range a.rules {
+		held, err := evalNumber(rule.expr, root)
+		if err != nil || held == 0 {
+			delete(a.ruleStreaks, rule.Name)
+			continue
+		}
+
+		if a.ruleStreaks == nil {
+			a.ruleStreaks = make(map[string]int)
+		}
+		a.ruleStreaks[rule.Name]++
+		if a.ruleStreaks[rule.Name] < max(rule.For, 1) {
+			continue
+		}
+
+		alert := Alert{
+			Level:     rule.Level,
+			Category:  "rule",
+			Resource:  rule.Name,
+			Message:   rule.message(root),
+			Timestamp: metrics.Timestamp,
+		}
+		if alert.Level == "" {
+			alert.Level = "warning"
+		}
+		if rule.value != nil {
+			alert.Value, _ = evalNumber(rule.value, root)
+			alert.Threshold, _ = evalNumber(rule.threshold, root)
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+// message renders the rule's message, replacing each {expression} with
+// its value
+func (r compiledRule) message(root map[string]interface{}) string {
+	if r.Message == "" {
+		return fmt.Sprintf("Rule %s: %s", r.Name, r.Expr)
+	}
+	return messagePlaceholder.ReplaceAllStringFunc(r.Message, func(placeholder string) string {
+		e, err := compileExpr(placeholder[1 : len(placeholder)-1])
+		if err != nil {
+			return placeholder
+		}
+		v, err := e.eval(root)
+		if err != nil {
+			return "?"
+		}
+		if n, err := toNumber(v); err == nil {
+			return strconv.FormatFloat(math.Round(n*100)/100, 'f', -1, 64)
+		}
+		return fmt.Sprint(v)
+	})
+}
+
+// snapshotTree is the JSON form of a snapshot that rule paths refer to
+func snapshotTree(metrics *SystemMetrics) (map[string]interface{}, error) {
+	data, err := json.Marshal(metrics)
+	if err != nil {
+		return nil, err
+	}
+	var root map[string]interface{}
+	err = json.Unmarshal(data, &root)
+	return root, err
+}
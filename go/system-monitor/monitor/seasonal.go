@@ -0,0 +1,125 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+)
+
+// Seasonal baselines need samples from this many different days (or
+// weeks) in a slot before anomalies are scored against it; until then the
+// EWMA baseline is used
+const seasonalMinPeriods = 2
+
+// seasonalMaxSamples caps the weight of a slot's history, so a slot keeps
+// adapting as a host's usual load changes
+const seasonalMaxSamples = 2000
+
+// SeasonalSlot is a metric's usual level during one hour of the day or
+// week, in local time
+type SeasonalSlot struct {
+	Samples  int       `json:"samples"`
+	Periods  int       `json:"periods"` // days or weeks the samples came from
+	Mean     float64   `json:"mean"`
+	Variance float64   `json:"variance"`
+	Last     time.Time `json:"last"`
+}
+
+func (s *SeasonalSlot) add(value float64, at time.Time) {
+	// The slot recurs a day or a week later, so a gap longer than the
+	// slot itself starts a new period
+	if gap := at.Sub(s.Last); s.Samples == 0 || gap > time.Hour || gap < -time.Hour {
+		s.Periods++
+	}
+	if at.After(s.Last) {
+		s.Last = at
+	}
+
+	s.Samples++
+	n := float64(min(s.Samples, seasonalMaxSamples))
+	diff := value - s.Mean
+	s.Mean += diff / n
+	s.Variance += (diff*(value-s.Mean) - s.Variance) / n
+}
+
+func (s SeasonalSlot) ready() bool {
+	return s.Periods >= seasonalMinPeriods && s.Samples >= anomalyWarmup
+}
+
+// seasonalSlots returns how many slots a seasonality has, 0 when off
+func seasonalSlots(seasonality string) int {
+	switch seasonality {
+	case "daily":
+		return 24
+	case "weekly":
+		return 7 * 24
+	}
+	return 0
+}
+
+// seasonalIndex returns the slot of a timestamp and its label, such as
+// "02:00" or "Tue 02:00"
+func seasonalIndex(seasonality string, at time.Time) (int, string) {
+	at = at.Local()
+	label := at.Format("15:00")
+	if seasonality == "weekly" {
+		return int(at.Weekday())*24 + at.Hour(), at.Format("Mon ") + label
+	}
+	return at.Hour(), label
+}
+
+// seasonalSlot returns the slot of a metric category for a timestamp, or
+// nil when seasonal baselines are off
+func (a *Analyzer) seasonalSlot(category string, at time.Time) (*SeasonalSlot, string) {
+	slots := seasonalSlots(a.config.Seasonality)
+	if slots == 0 {
+		return nil, ""
+	}
+	if a.seasonal == nil {
+		a.seasonal = make(map[string][]SeasonalSlot)
+	}
+	if len(a.seasonal[category]) != slots {
+		a.seasonal[category] = make([]SeasonalSlot, slots)
+	}
+	index, label := seasonalIndex(a.config.Seasonality, at)
+	return &a.seasonal[category][index], label
+}
+
+// LearnSeasonal folds past snapshots, such as those of a store or of
+// earlier runs logged to EYWA, into the seasonal baselines. It does
+// nothing unless Config.Seasonality is set.
+func (a *Analyzer) LearnSeasonal(snapshots []SystemMetrics) {
+	for _, metrics := range snapshots {
+		for _, metric := range anomalyMetrics {
+			if slot, _ := a.seasonalSlot(metric.category, metrics.Timestamp); slot != nil {
+				slot.add(metric.value(metrics), metrics.Timestamp)
+			}
+		}
+	}
+}
+
+// LearnSeasonalFromStore is LearnSeasonal for the snapshots a store holds
+// in [from, to], read one at a time
+func (a *Analyzer) LearnSeasonalFromStore(store SnapshotStore, from, to time.Time) error {
+	return store.Each(from, to, func(metrics SystemMetrics) {
+		a.LearnSeasonal([]SystemMetrics{metrics})
+	})
+}
+
+// SeasonalSpan is how much history is worth learning seasonal baselines
+// from: four cycles of the seasonality
+func SeasonalSpan(seasonality string) time.Duration {
+	switch seasonality {
+	case "daily":
+		return 4 * 24 * time.Hour
+	case "weekly":
+		return 4 * 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+func (c Config) validateSeasonality() []error {
+	if c.Seasonality != "" && seasonalSlots(c.Seasonality) == 0 {
+		return []error{fmt.Errorf(`seasonality must be "daily" or "weekly", got %q`, c.Seasonality)}
+	}
+	return nil
+}
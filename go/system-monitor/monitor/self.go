@@ -0,0 +1,185 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// SelfMetrics is the monitor's own resource usage, so that it catches its
+// own leaks
+type SelfMetrics struct {
+	RSSMB       float64 `json:"rss_mb"`
+	HeapAllocMB float64 `json:"heap_alloc_mb"`
+	HeapObjects uint64  `json:"heap_objects"`
+	Goroutines  int     `json:"goroutines"`
+
+	GCCycles       uint32  `json:"gc_cycles"`
+	GCPauseTotalMs float64 `json:"gc_pause_total_ms"`
+	LastGCPauseMs  float64 `json:"last_gc_pause_ms"`
+
+	// Wall time the whole snapshot took to collect
+	CollectionSeconds float64 `json:"collection_seconds"`
+}
+
+// selfWatchSamples is how many snapshots of history the watchdog needs
+// before judging growth
+const selfWatchSamples = 8
+
+func collectSelfMetrics(ctx context.Context) (*SelfMetrics, error) {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	self := &SelfMetrics{
+		HeapAllocMB:    float64(stats.HeapAlloc) / 1024 / 1024,
+		HeapObjects:    stats.HeapObjects,
+		Goroutines:     runtime.NumGoroutine(),
+		GCCycles:       stats.NumGC,
+		GCPauseTotalMs: float64(stats.PauseTotalNs) / 1e6,
+	}
+	if stats.NumGC > 0 {
+		self.LastGCPauseMs = float64(stats.PauseNs[(stats.NumGC+255)%256]) / 1e6
+	}
+
+	// RSS is best effort; the runtime figures are always available
+	if p, err := process.NewProcessWithContext(ctx, int32(os.Getpid())); err == nil {
+		if info, err := p.MemoryInfoWithContext(ctx); err == nil {
+			self.RSSMB = float64(info.RSS) / 1024 / 1024
+		}
+	}
+	return self, nil
+}
+
+// checkSelf is the monitor's watchdog on itself: memory or goroutines
+// growing across the whole history, and collections taking a large share
+// of the interval or getting steadily slower
+func (a *Analyzer) checkSelf(metrics *SystemMetrics) []Alert {
+	self := metrics.Self
+	if self == nil {
+		return nil
+	}
+
+	var alerts []Alert
+	// The heap stands in where RSS can't be read
+	memory := func(m SystemMetrics) float64 {
+		if m.Self == nil {
+			return math.NaN()
+		}
+		if m.Self.RSSMB == 0 {
+			return m.Self.HeapAllocMB
+		}
+		return m.Self.RSSMB
+	}
+	if first, last, ok := a.growth(memory, 1.5, 50); ok {
+		level := "warning"
+		if last >= 3*first {
+			level = "critical"
+		}
+		alerts = append(alerts, Alert{
+			Level:     level,
+			Category:  "self",
+			Resource:  "memory",
+			Message:   fmt.Sprintf("Monitor memory keeps growing: %.0f MB, up from %.0f MB", last, first),
+			Value:     last,
+			Threshold: first * 1.5,
+			Timestamp: metrics.Timestamp,
+		})
+	}
+
+	goroutines := func(m SystemMetrics) float64 {
+		if m.Self == nil {
+			return math.NaN()
+		}
+		return float64(m.Self.Goroutines)
+	}
+	if first, last, ok := a.growth(goroutines, 2, 50); ok {
+		alerts = append(alerts, Alert{
+			Level:     "warning",
+			Category:  "self",
+			Resource:  "goroutines",
+			Message:   fmt.Sprintf("Monitor goroutines keep growing: %.0f, up from %.0f", last, first),
+			Value:     last,
+			Threshold: first * 2,
+			Timestamp: metrics.Timestamp,
+		})
+	}
+
+	if alert := a.checkSelfLatency(metrics); alert != nil {
+		alerts = append(alerts, *alert)
+	}
+	return alerts
+}
+
+// checkSelfLatency alerts when a collection takes more than half of the
+// interval, critical beyond the whole interval, or when collections got
+// steadily slower to twice their earlier duration
+func (a *Analyzer) checkSelfLatency(metrics *SystemMetrics) *Alert {
+	latency := metrics.Self.CollectionSeconds
+	interval := float64(a.config.Interval)
+
+	alert := &Alert{
+		Level:     "warning",
+		Category:  "self",
+		Resource:  "latency",
+		Value:     latency,
+		Timestamp: metrics.Timestamp,
+	}
+	switch {
+	case interval > 0 && latency > interval:
+		alert.Level = "critical"
+		alert.Threshold = interval
+		alert.Message = fmt.Sprintf("Monitor collection took %.1fs, longer than the %.0fs interval", latency, interval)
+	case interval > 0 && latency > interval/2:
+		alert.Threshold = interval / 2
+		alert.Message = fmt.Sprintf("Monitor collection took %.1fs, over half the %.0fs interval", latency, interval)
+	default:
+		seconds := func(m SystemMetrics) float64 {
+			if m.Self == nil {
+				return math.NaN()
+			}
+			return m.Self.CollectionSeconds
+		}
+		first, last, ok := a.growth(seconds, 2, 1)
+		if !ok {
+			return nil
+		}
+		alert.Threshold = first * 2
+		alert.Message = fmt.Sprintf("Monitor collections keep getting slower: %.1fs, up from %.1fs", last, first)
+	}
+	return alert
+}
+
+// growth splits the history into quarters and reports whether the
+// quarters' averages of value rose every time, with the newest at least
+// factor times and minDelta above the oldest. It returns the oldest and
+// newest averages. Histories with snapshots where value is NaN, such as
+// those restored from before self metrics existed, never show growth.
+func (a *Analyzer) growth(value func(SystemMetrics) float64, factor, minDelta float64) (first, last float64, ok bool) {
+	n := a.history.len()
+	if n < selfWatchSamples {
+		return 0, 0, false
+	}
+
+	var quarters [4]float64
+	for q := range quarters {
+		from, to := q*n/4, (q+1)*n/4
+		for i := from; i < to; i++ {
+			v := value(a.history.at(i))
+			if math.IsNaN(v) {
+				return 0, 0, false
+			}
+			quarters[q] += v
+		}
+		quarters[q] /= float64(to - from)
+		if q > 0 && quarters[q] <= quarters[q-1] {
+			return 0, 0, false
+		}
+	}
+
+	first, last = quarters[0], quarters[3]
+	return first, last, last >= first*factor && last-first >= minDelta
+}
@@ -0,0 +1,58 @@
+package monitor
+
+import (
+	"context"
+	"sort"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// collectSensorMetrics reads hardware temperature and fan sensors. Hosts
+// without sensors (most VMs and containers) report none rather than
+// failing the collection, as do partial reads.
+func (c *Collector) collectSensorMetrics(ctx context.Context) (*SensorsMetrics, error) {
+	sensors := &SensorsMetrics{}
+
+	temperatures, _ := host.SensorsTemperaturesWithContext(ctx)
+	for _, t := range temperatures {
+		if t.Temperature <= 0 {
+			continue
+		}
+		sensors.Temperatures = append(sensors.Temperatures, TemperatureReading{
+			Sensor:       t.SensorKey,
+			TemperatureC: t.Temperature,
+			HighC:        t.High,
+			CriticalC:    t.Critical,
+		})
+	}
+	sort.Slice(sensors.Temperatures, func(i, j int) bool {
+		return sensors.Temperatures[i].Sensor < sensors.Temperatures[j].Sensor
+	})
+
+	sensors.Fans = readFans()
+
+	if len(sensors.Temperatures) == 0 && len(sensors.Fans) == 0 {
+		return nil, nil
+	}
+	return sensors, nil
+}
+
+// checkSensors raises thermal alerts for hardware temperature sensors
+func (a *Analyzer) checkSensors(metrics *SystemMetrics) []Alert {
+	if metrics.Sensors == nil {
+		return nil
+	}
+
+	var alerts []Alert
+	for _, sensor := range metrics.Sensors.Temperatures {
+		if alert := temperatureAlert(sensor.Sensor, "Sensor "+sensor.Sensor,
+			sensor.TemperatureC, a.config.TemperatureThreshold, metrics.Timestamp); alert != nil {
+			// Trust the hardware's own critical trip point when it is lower
+			if sensor.CriticalC > 0 && sensor.TemperatureC >= sensor.CriticalC {
+				alert.Level = "critical"
+			}
+			alerts = append(alerts, *alert)
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,46 @@
+//go:build linux
+
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readFans reads fan speeds from the hwmon sysfs interface, naming each
+// fan after its chip and label, e.g. "nct6775_cpu_fan"
+func readFans() []FanReading {
+	inputs, _ := filepath.Glob("/sys/class/hwmon/hwmon*/fan*_input")
+
+	var fans []FanReading
+	for _, input := range inputs {
+		rpm, ok := readSysfsFloat(input)
+		if !ok {
+			continue
+		}
+
+		dir := filepath.Dir(input)
+		fan := strings.TrimSuffix(filepath.Base(input), "_input")
+		if label, err := os.ReadFile(filepath.Join(dir, fan+"_label")); err == nil {
+			fan = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(string(label))), " ", "_")
+		}
+		chip := filepath.Base(dir)
+		if name, err := os.ReadFile(filepath.Join(dir, "name")); err == nil {
+			chip = strings.TrimSpace(string(name))
+		}
+
+		fans = append(fans, FanReading{Sensor: chip + "_" + fan, RPM: rpm})
+	}
+	return fans
+}
+
+func readSysfsFloat(path string) (float64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	return value, err == nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package monitor
+
+// readFans is only supported on Linux
+func readFans() []FanReading {
+	return nil
+}
@@ -0,0 +1,166 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// maxFailedLoginSources caps the sources reported with failed logins
+const maxFailedLoginSources = 10
+
+// SessionMetrics is who is logged in, and who failed to
+type SessionMetrics struct {
+	Sessions []LoginSession `json:"sessions"`
+
+	// Failed logins within Config.AuthFailureWindow, by source, busiest
+	// first (Linux, from btmp, which only root can read)
+	FailedLogins       int                 `json:"failed_logins"`
+	FailedLoginSources []FailedLoginSource `json:"failed_login_sources,omitempty"`
+	FailedLoginError   string              `json:"failed_login_error,omitempty"` // why btmp could not be read
+}
+
+// LoginSession is one interactive login
+type LoginSession struct {
+	User     string    `json:"user"`
+	Terminal string    `json:"terminal"`       // "pts/0", "tty1", "console"
+	Host     string    `json:"host,omitempty"` // remote host, for remote logins
+	Started  time.Time `json:"started"`
+}
+
+// FailedLoginSource is the failed logins from one remote host
+type FailedLoginSource struct {
+	Host  string   `json:"host"` // empty for local logins
+	Count int      `json:"count"`
+	Users []string `json:"users"` // distinct user names tried
+}
+
+// failedLogin is one btmp record
+type failedLogin struct {
+	User string
+	Host string
+	Time time.Time
+}
+
+func (c Config) validateSessions() []error {
+	var errs []error
+	if c.AuthFailureThreshold < 0 {
+		errs = append(errs, fmt.Errorf("auth_failure_threshold must not be negative, got %d", c.AuthFailureThreshold))
+	}
+	if c.AuthFailureWindow < 1 {
+		errs = append(errs, fmt.Errorf("auth_failure_window must be at least 1 minute, got %d", c.AuthFailureWindow))
+	}
+	return errs
+}
+
+// collectSessions reads the current logins and the failed logins within
+// Config.AuthFailureWindow. Failing to read btmp, usually for lack of
+// root, is reported on the metrics rather than failing the collector.
+func (c *Collector) collectSessions(ctx context.Context) (*SessionMetrics, error) {
+	sessions, err := readSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics := &SessionMetrics{Sessions: sessions}
+
+	since := c.clock.Now().Add(-time.Duration(c.config.AuthFailureWindow) * time.Minute)
+	failures, err := readFailedLogins(since)
+	if err != nil {
+		metrics.FailedLoginError = err.Error()
+		return metrics, nil
+	}
+	metrics.FailedLogins = len(failures)
+	metrics.FailedLoginSources = groupFailedLogins(failures)
+	return metrics, nil
+}
+
+// groupFailedLogins counts failed logins by source host, busiest first
+func groupFailedLogins(failures []failedLogin) []FailedLoginSource {
+	byHost := make(map[string]*FailedLoginSource)
+	seen := make(map[[2]string]bool)
+	var sources []*FailedLoginSource
+	for _, failure := range failures {
+		source := byHost[failure.Host]
+		if source == nil {
+			source = &FailedLoginSource{Host: failure.Host}
+			byHost[failure.Host] = source
+			sources = append(sources, source)
+		}
+		source.Count++
+		if key := [2]string{failure.Host, failure.User}; !seen[key] {
+			seen[key] = true
+			source.Users = append(source.Users, failure.User)
+		}
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Count > sources[j].Count
+	})
+	if len(sources) > maxFailedLoginSources {
+		sources = sources[:maxFailedLoginSources]
+	}
+
+	result := make([]FailedLoginSource, len(sources))
+	for i, source := range sources {
+		sort.Strings(source.Users)
+		result[i] = *source
+	}
+	return result
+}
+
+// checkSessions raises a warning when the failed logins within
+// Config.AuthFailureWindow reach Config.AuthFailureThreshold (critical at
+// five times it), and, with Config.AlertRootLogins, one for every
+// interactive root login
+func (a *Analyzer) checkSessions(metrics *SystemMetrics) []Alert {
+	sessions := metrics.Sessions
+	if sessions == nil {
+		return nil
+	}
+
+	var alerts []Alert
+	if threshold := a.config.AuthFailureThreshold; threshold > 0 && sessions.FailedLogins >= threshold {
+		level := "warning"
+		if sessions.FailedLogins >= threshold*5 {
+			level = "critical"
+		}
+		message := fmt.Sprintf("%d failed logins in the last %d min", sessions.FailedLogins, a.config.AuthFailureWindow)
+		if len(sessions.FailedLoginSources) > 0 {
+			top := sessions.FailedLoginSources[0]
+			from := top.Host
+			if from == "" {
+				from = "local terminals"
+			}
+			message += fmt.Sprintf(", %d from %s", top.Count, from)
+		}
+		alerts = append(alerts, Alert{
+			Level:     level,
+			Category:  "login",
+			Resource:  "failed_logins",
+			Message:   message,
+			Value:     float64(sessions.FailedLogins),
+			Threshold: float64(threshold),
+			Timestamp: metrics.Timestamp,
+		})
+	}
+
+	if a.config.AlertRootLogins {
+		for _, session := range sessions.Sessions {
+			if session.User != "root" {
+				continue
+			}
+			message := fmt.Sprintf("Root logged in on %s since %s", session.Terminal, session.Started.Format("2006-01-02 15:04 MST"))
+			if session.Host != "" {
+				message += " from " + session.Host
+			}
+			alerts = append(alerts, Alert{
+				Level:     "warning",
+				Category:  "login",
+				Resource:  "root@" + session.Terminal,
+				Message:   message,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+	return alerts
+}
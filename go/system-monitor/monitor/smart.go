@@ -0,0 +1,224 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// smartctlTimeout bounds a single smartctl invocation; drives in standby
+// can take a while to answer
+const smartctlTimeout = 10 * time.Second
+
+// DiskHealthMetrics holds SMART health for one physical disk. Counters a
+// drive does not report are zero, and WearPercent is only set for SSDs.
+type DiskHealthMetrics struct {
+	Device             string  `json:"device"`
+	Model              string  `json:"model,omitempty"`
+	Protocol           string  `json:"protocol,omitempty"` // "ATA", "NVMe", "SCSI"
+	Passed             bool    `json:"passed"`             // overall SMART self-assessment
+	ReallocatedSectors int64   `json:"reallocated_sectors"`
+	PendingSectors     int64   `json:"pending_sectors"`
+	MediaErrors        int64   `json:"media_errors"`
+	WearPercent        float64 `json:"wear_percent,omitempty"` // rated endurance used
+	TemperatureC       float64 `json:"temperature_c,omitempty"`
+	PowerOnHours       int64   `json:"power_on_hours,omitempty"`
+}
+
+// smartctlScan is the output of `smartctl --scan -j`
+type smartctlScan struct {
+	Devices []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"devices"`
+}
+
+// smartctlReport is the part of `smartctl -j -H -A -i` that is used
+type smartctlReport struct {
+	Device struct {
+		Protocol string `json:"protocol"`
+	} `json:"device"`
+	ModelName   string `json:"model_name"`
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	ATAAttributes struct {
+		Table []struct {
+			ID    int    `json:"id"`
+			Name  string `json:"name"`
+			Value int    `json:"value"`
+			Raw   struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeHealth *struct {
+		PercentageUsed float64 `json:"percentage_used"`
+		MediaErrors    int64   `json:"media_errors"`
+	} `json:"nvme_smart_health_information_log"`
+	SCSIGrownDefects *int64 `json:"scsi_grown_defect_list"`
+	Temperature      struct {
+		Current float64 `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+}
+
+// ATA attributes read from the SMART table
+const (
+	ataReallocatedSectors = 5
+	ataWearLeveling       = 177 // Samsung and most SATA SSDs
+	ataPendingSectors     = 197
+	ataSSDLifeLeft        = 231
+	ataMediaWearout       = 233 // Intel
+)
+
+// collectDiskHealth reads SMART health for every disk smartctl can find.
+// It needs smartmontools 7 or later for JSON output and usually root;
+// hosts without it report no disks.
+func (c *Collector) collectDiskHealth(ctx context.Context) ([]DiskHealthMetrics, error) {
+	path, err := exec.LookPath("smartctl")
+	if err != nil {
+		return nil, nil
+	}
+
+	var scan smartctlScan
+	if err := runSmartctl(ctx, path, &scan, "--scan", "-j"); err != nil {
+		return nil, err
+	}
+
+	disks := make([]DiskHealthMetrics, 0, len(scan.Devices))
+	for _, device := range scan.Devices {
+		var report smartctlReport
+		args := []string{"-j", "-H", "-A", "-i", device.Name}
+		if device.Type != "" {
+			args = append([]string{"-d", device.Type}, args...)
+		}
+		if err := runSmartctl(ctx, path, &report, args...); err != nil || report.SmartStatus == nil {
+			// Virtual disks and USB bridges often have no SMART support
+			continue
+		}
+		disks = append(disks, report.metrics(device.Name))
+	}
+
+	return disks, nil
+}
+
+// runSmartctl runs smartctl and decodes its JSON output. smartctl's exit
+// status is a bit mask that is non-zero for failing drives too, so only
+// the two low bits (bad arguments, device could not be opened) are errors.
+func runSmartctl(ctx context.Context, path string, v interface{}, args ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, smartctlTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, args...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode()&0x3 == 0 {
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("smartctl %s: %w", strings.Join(args, " "), err)
+	}
+	return json.Unmarshal(out, v)
+}
+
+func (r smartctlReport) metrics(device string) DiskHealthMetrics {
+	disk := DiskHealthMetrics{
+		Device:       device,
+		Model:        r.ModelName,
+		Protocol:     r.Device.Protocol,
+		Passed:       r.SmartStatus.Passed,
+		TemperatureC: r.Temperature.Current,
+		PowerOnHours: r.PowerOnTime.Hours,
+	}
+
+	for _, attr := range r.ATAAttributes.Table {
+		switch attr.ID {
+		case ataReallocatedSectors:
+			disk.ReallocatedSectors = attr.Raw.Value
+		case ataPendingSectors:
+			disk.PendingSectors = attr.Raw.Value
+		case ataWearLeveling, ataSSDLifeLeft, ataMediaWearout:
+			// Normalized values count down from 100 as the drive wears
+			if attr.Value > 0 && attr.Value <= 100 {
+				disk.WearPercent = max(disk.WearPercent, float64(100-attr.Value))
+			}
+		}
+	}
+	if r.NVMeHealth != nil {
+		disk.WearPercent = r.NVMeHealth.PercentageUsed
+		disk.MediaErrors = r.NVMeHealth.MediaErrors
+	}
+	if r.SCSIGrownDefects != nil {
+		disk.ReallocatedSectors = *r.SCSIGrownDefects
+	}
+
+	return disk
+}
+
+// checkDiskHealth alerts on drives that fail their SMART self-assessment,
+// have bad sectors or media errors, or are close to their rated
+// endurance. Sectors waiting to be remapped and media errors mean data is
+// already unreadable, so they are critical; remapped sectors are a
+// warning that the drive is degrading.
+func (a *Analyzer) checkDiskHealth(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+
+	for _, disk := range metrics.DiskHealth {
+		name := disk.Device
+		if disk.Model != "" {
+			name += " (" + disk.Model + ")"
+		}
+
+		if !disk.Passed {
+			alerts = append(alerts, Alert{
+				Level:     "critical",
+				Category:  "smart",
+				Resource:  disk.Device,
+				Message:   fmt.Sprintf("Disk %s failed its SMART health check; replace it and verify backups", name),
+				Timestamp: metrics.Timestamp,
+			})
+		}
+
+		if bad := disk.ReallocatedSectors + disk.PendingSectors + disk.MediaErrors; bad > 0 {
+			level := "warning"
+			if disk.PendingSectors > 0 || disk.MediaErrors > 0 {
+				level = "critical"
+			}
+
+			alerts = append(alerts, Alert{
+				Level:    level,
+				Category: "smart",
+				Resource: disk.Device + "/sectors",
+				Message: fmt.Sprintf("Disk %s has %d reallocated and %d pending sectors, %d media errors",
+					name, disk.ReallocatedSectors, disk.PendingSectors, disk.MediaErrors),
+				Value:     float64(bad),
+				Timestamp: metrics.Timestamp,
+			})
+		}
+
+		if threshold := a.config.SMARTWearThreshold; threshold > 0 && disk.WearPercent >= threshold {
+			level := "warning"
+			if disk.WearPercent >= 100 {
+				level = "critical"
+			}
+
+			alerts = append(alerts, Alert{
+				Level:     level,
+				Category:  "smart",
+				Resource:  disk.Device + "/wear",
+				Message:   fmt.Sprintf("Disk %s has used %.0f%% of its rated endurance", name, disk.WearPercent),
+				Value:     disk.WearPercent,
+				Threshold: threshold,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+
+	return alerts
+}
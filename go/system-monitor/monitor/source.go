@@ -0,0 +1,53 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// MetricsSource produces system metrics snapshots. Collector is the real
+// implementation; ScriptedSource replays canned snapshots. A source may
+// return a partial snapshot together with an error.
+type MetricsSource interface {
+	CollectMetrics(ctx context.Context) (*SystemMetrics, error)
+}
+
+var _ MetricsSource = (*Collector)(nil)
+
+// ErrScriptExhausted is returned by ScriptedSource once every snapshot
+// has been replayed
+var ErrScriptExhausted = errors.New("scripted source exhausted")
+
+// ScriptedSource replays a fixed sequence of snapshots, stamping each with
+// the current time of its clock
+type ScriptedSource struct {
+	mu        sync.Mutex
+	clock     Clock
+	snapshots []SystemMetrics
+	next      int
+}
+
+// NewScriptedSource creates a source that returns snapshots in order
+func NewScriptedSource(clock Clock, snapshots ...SystemMetrics) *ScriptedSource {
+	return &ScriptedSource{clock: clock, snapshots: snapshots}
+}
+
+// CollectMetrics returns the next snapshot in the script
+func (s *ScriptedSource) CollectMetrics(ctx context.Context) (*SystemMetrics, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next >= len(s.snapshots) {
+		return nil, ErrScriptExhausted
+	}
+
+	metrics := s.snapshots[s.next]
+	metrics.Timestamp = s.clock.Now()
+	s.next++
+	return &metrics, nil
+}
@@ -0,0 +1,193 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State is the analyzer and cooldown state carried between runs, so that
+// scheduled one-shot runs still see a rolling history for sustained-usage
+// and anomaly detection
+type State struct {
+	Host     string               `json:"host"`
+	SavedAt  time.Time            `json:"saved_at"`
+	History  []SystemMetrics      `json:"history"`
+	Incident *Incident            `json:"incident,omitempty"`
+	Cooldown map[string]time.Time `json:"cooldown,omitempty"`
+
+	// Disk usage trends by mount point, for disk-full forecasts
+	DiskTrends map[string][]DiskSample `json:"disk_trends,omitempty"`
+
+	// Conditions still active, so their resolution is noticed next run
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// Seasonal baselines by anomaly category
+	Seasonal map[string][]SeasonalSlot `json:"seasonal,omitempty"`
+
+	// The host's boot time at the last snapshot, and when that was taken,
+	// so a reboot between runs is noticed. Unlike the history they are
+	// kept however old they are.
+	BootTime time.Time `json:"boot_time,omitempty"`
+	LastSeen time.Time `json:"last_seen,omitempty"`
+}
+
+// LoadState reads state saved by SaveState. A missing file yields empty
+// state. State saved for another host is discarded, as is anything older
+// than maxAge relative to now; a maxAge of zero keeps everything.
+func LoadState(path, host string, maxAge time.Duration, now time.Time) (State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return State{Host: host}, nil
+	}
+	if err != nil {
+		return State{Host: host}, fmt.Errorf("read state: %w", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return State{Host: host}, fmt.Errorf("decode state %s: %w", path, err)
+	}
+	if state.Host != host {
+		return State{Host: host}, nil
+	}
+
+	if maxAge > 0 {
+		stale := func(t time.Time) bool { return now.Sub(t) > maxAge }
+
+		var history []SystemMetrics
+		for _, metrics := range state.History {
+			if !stale(metrics.Timestamp) {
+				history = append(history, metrics)
+			}
+		}
+		state.History = history
+
+		if state.Incident != nil && stale(state.Incident.UpdatedAt) {
+			state.Incident = nil
+		}
+		for key, sent := range state.Cooldown {
+			if stale(sent) {
+				delete(state.Cooldown, key)
+			}
+		}
+		for mount, samples := range state.DiskTrends {
+			if len(samples) == 0 || stale(samples[len(samples)-1].Timestamp) {
+				delete(state.DiskTrends, mount)
+			}
+		}
+	}
+
+	return state, nil
+}
+
+// SaveState writes state to path, replacing the previous file atomically
+// so an interrupted run never leaves a truncated state file behind
+func SaveState(path string, state State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encode state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// SaveState records the analyzer's history, disk trends, seasonal
+// baselines, open incident and the host's boot time in state
+func (a *Analyzer) SaveState(state *State) {
+	state.History = a.history.snapshots()
+	state.BootTime, state.LastSeen = a.bootTime, a.lastSeen
+	state.DiskTrends = make(map[string][]DiskSample, len(a.diskTrends))
+	for mount, samples := range a.diskTrends {
+		state.DiskTrends[mount] = append([]DiskSample(nil), samples...)
+	}
+	state.Seasonal = make(map[string][]SeasonalSlot, len(a.seasonal))
+	for category, slots := range a.seasonal {
+		state.Seasonal[category] = append([]SeasonalSlot(nil), slots...)
+	}
+	state.Conditions = nil
+	for _, condition := range a.conditions {
+		state.Conditions = append(state.Conditions, condition)
+	}
+	state.Incident = nil
+	if a.incident != nil {
+		incident := *a.incident
+		state.Incident = &incident
+	}
+}
+
+// RestoreState seeds the analyzer with previously saved history, disk
+// trends, seasonal baselines, the open incident and the host's boot time.
+// History from a store or EYWA, newer than the state, also tells the boot
+// time.
+func (a *Analyzer) RestoreState(state State) {
+	a.bootTime, a.lastSeen = state.BootTime, state.LastSeen
+	for _, snapshot := range state.History {
+		if snapshot.Uptime != nil && snapshot.Timestamp.After(a.lastSeen) {
+			a.bootTime, a.lastSeen = snapshot.Uptime.BootTime, snapshot.Timestamp
+		}
+	}
+
+	a.diskTrends = make(map[string][]DiskSample, len(state.DiskTrends))
+	for mount, samples := range state.DiskTrends {
+		a.diskTrends[mount] = append([]DiskSample(nil), samples...)
+	}
+
+	a.history.reset(state.History)
+	a.rebuildBaselines()
+
+	a.seasonal = make(map[string][]SeasonalSlot, len(state.Seasonal))
+	for category, slots := range state.Seasonal {
+		a.seasonal[category] = append([]SeasonalSlot(nil), slots...)
+	}
+
+	a.conditions = make(map[string]Condition, len(state.Conditions))
+	a.raised = make(map[string]bool, len(state.Conditions))
+	for _, condition := range state.Conditions {
+		a.conditions[condition.Category+"/"+condition.Resource] = condition
+		a.raised[condition.Category+"/"+condition.Resource] = true
+	}
+
+	a.incident = nil
+	if state.Incident != nil {
+		incident := *state.Incident
+		a.incident = &incident
+	}
+}
+
+// SaveState records when each alert was last sent in state
+func (c *Cooldown) SaveState(state *State) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state.Cooldown = make(map[string]time.Time, len(c.lastSent))
+	for key, sent := range c.lastSent {
+		state.Cooldown[key] = sent
+	}
+}
+
+// RestoreState resumes the cooldowns recorded in state
+func (c *Cooldown) RestoreState(state State) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, sent := range state.Cooldown {
+		c.lastSent[key] = sent
+	}
+}
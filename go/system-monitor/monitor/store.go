@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SnapshotStore is a record of past snapshots, such as a store.Store,
+// that the analyzer can learn from
+type SnapshotStore interface {
+	// Each calls fn with every snapshot taken in [from, to], oldest first
+	Each(from, to time.Time, fn func(SystemMetrics)) error
+}
+
+// SeriesPoint summarizes one metric over a downsampling bucket
+type SeriesPoint struct {
+	Time  time.Time `json:"time"` // start of the bucket
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Avg   float64   `json:"avg"`
+	Count int       `json:"count"`
+}
+
+// SeriesValue extracts one metric from a snapshot; ok is false when the
+// snapshot does not have it (e.g. a mount that was not mounted yet)
+type SeriesValue func(metrics SystemMetrics) (value float64, ok bool)
+
+// SeriesValueFor returns the extractor for a metric name: "cpu",
+// "memory", "swap", "iowait", "load1", "load5", "load15",
+// "load_per_core", "disk:<mount point>" for a mount's used percent, or
+// "pressure:<cpu|memory|io>" for the share of the last minute some tasks
+// were stalled
+func SeriesValueFor(name string) (SeriesValue, error) {
+	simple := func(get func(SystemMetrics) float64) SeriesValue {
+		return func(m SystemMetrics) (float64, bool) { return get(m), true }
+	}
+
+	switch name {
+	case "cpu":
+		return simple(func(m SystemMetrics) float64 { return m.CPU.UsagePercent }), nil
+	case "memory":
+		return simple(func(m SystemMetrics) float64 { return m.Memory.UsedPercent }), nil
+	case "swap":
+		return simple(func(m SystemMetrics) float64 { return m.Memory.SwapPercent }), nil
+	case "iowait":
+		return simple(func(m SystemMetrics) float64 { return m.CPU.Breakdown.IOWait }), nil
+	case "load1":
+		return simple(func(m SystemMetrics) float64 { return m.Load.Load1 }), nil
+	case "load5":
+		return simple(func(m SystemMetrics) float64 { return m.Load.Load5 }), nil
+	case "load15":
+		return simple(func(m SystemMetrics) float64 { return m.Load.Load15 }), nil
+	case "load_per_core":
+		return simple(func(m SystemMetrics) float64 { return m.Load.Load1PerCore }), nil
+	}
+
+	if resource, ok := strings.CutPrefix(name, "pressure:"); ok {
+		stall := map[string]func(*PressureMetrics) *PressureStall{
+			"cpu":    func(p *PressureMetrics) *PressureStall { return p.CPU },
+			"memory": func(p *PressureMetrics) *PressureStall { return p.Memory },
+			"io":     func(p *PressureMetrics) *PressureStall { return p.IO },
+		}[resource]
+		if stall == nil {
+			return nil, fmt.Errorf("unknown series %q", name)
+		}
+		return func(m SystemMetrics) (float64, bool) {
+			if m.Pressure == nil || stall(m.Pressure) == nil {
+				return 0, false
+			}
+			return stall(m.Pressure).Some60, true
+		}, nil
+	}
+
+	if mount, ok := strings.CutPrefix(name, "disk:"); ok && mount != "" {
+		return func(m SystemMetrics) (float64, bool) {
+			for _, disk := range m.Disk {
+				if disk.MountPoint == mount {
+					return disk.UsedPercent, true
+				}
+			}
+			return 0, false
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown series %q", name)
+}
+
+// Downsample summarizes a metric over buckets of step, aligned to step
+// since the zero time, e.g. hourly points from a day of raw snapshots.
+// Snapshots must be oldest first; empty buckets are left out.
+func Downsample(snapshots []SystemMetrics, value SeriesValue, step time.Duration) []SeriesPoint {
+	var points []SeriesPoint
+	var sum float64
+	for _, metrics := range snapshots {
+		v, ok := value(metrics)
+		if !ok {
+			continue
+		}
+
+		bucket := metrics.Timestamp.Truncate(step)
+		if n := len(points); n == 0 || !points[n-1].Time.Equal(bucket) {
+			if n > 0 {
+				points[n-1].Avg = sum / float64(points[n-1].Count)
+			}
+			points = append(points, SeriesPoint{Time: bucket, Min: v, Max: v})
+			sum = 0
+		}
+
+		p := &points[len(points)-1]
+		p.Min = min(p.Min, v)
+		p.Max = max(p.Max, v)
+		p.Count++
+		sum += v
+	}
+	if n := len(points); n > 0 {
+		points[n-1].Avg = sum / float64(points[n-1].Count)
+	}
+	return points
+}
@@ -0,0 +1,179 @@
+// Package store keeps raw metrics snapshots in an embedded SQLite
+// database. It is separate from package monitor so that library users who
+// don't need a store don't link the SQLite driver.
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"system-monitor/monitor"
+
+	_ "modernc.org/sqlite" // pure Go, so the robot still builds without cgo
+)
+
+// dbFile is the SQLite database in a store directory
+const dbFile = "metrics.db"
+
+// pruneEvery is how often Append deletes snapshots past the retention
+const pruneEvery = time.Hour
+
+// schema keeps each snapshot as JSON, indexed by its time in Unix
+// nanoseconds, so new metrics need no migration
+const schema = `
+CREATE TABLE IF NOT EXISTS snapshots (
+	timestamp INTEGER NOT NULL,
+	metrics   BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS snapshots_timestamp ON snapshots (timestamp);
+`
+
+// Store keeps raw snapshots in an embedded SQLite database on local disk,
+// so history outlives both the process and the analyzer's window.
+// Snapshots older than the retention are deleted as new ones arrive.
+type Store struct {
+	db        *sql.DB
+	retention time.Duration
+
+	mu       sync.Mutex
+	prunedAt time.Time
+}
+
+// Open opens or creates a store in dir. A retention of zero keeps
+// every snapshot.
+func Open(dir string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+
+	// WAL lets the export-history command read while the robot writes
+	dsn := "file:" + filepath.Join(dir, dbFile) + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+	return &Store{db: db, retention: retention}, nil
+}
+
+// Append stores a snapshot
+func (s *Store) Append(metrics monitor.SystemMetrics) error {
+	data, err := json.Marshal(metrics)
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+	if _, err := s.db.Exec("INSERT INTO snapshots (timestamp, metrics) VALUES (?, ?)", metrics.Timestamp.UnixNano(), data); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.retention > 0 && metrics.Timestamp.Sub(s.prunedAt) >= pruneEvery {
+		if err := s.prune(metrics.Timestamp); err != nil {
+			return err
+		}
+		s.prunedAt = metrics.Timestamp
+	}
+	return nil
+}
+
+// Range returns the stored snapshots taken in [from, to], oldest first.
+// Snapshots that cannot be decoded are skipped.
+func (s *Store) Range(from, to time.Time) ([]monitor.SystemMetrics, error) {
+	var snapshots []monitor.SystemMetrics
+	if err := s.Each(from, to, func(metrics monitor.SystemMetrics) {
+		snapshots = append(snapshots, metrics)
+	}); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// Each calls fn with every stored snapshot taken in [from, to], oldest
+// first, without holding them all in memory
+func (s *Store) Each(from, to time.Time, fn func(monitor.SystemMetrics)) error {
+	rows, err := s.db.Query("SELECT metrics FROM snapshots WHERE timestamp BETWEEN ? AND ? ORDER BY timestamp",
+		unixNanos(from), unixNanos(to))
+	if err != nil {
+		return fmt.Errorf("read store: %w", err)
+	}
+	return scanSnapshots(rows, fn)
+}
+
+// Latest returns up to n of the newest snapshots taken within maxAge of
+// now, oldest first; a maxAge of zero accepts any age
+func (s *Store) Latest(n int, maxAge time.Duration, now time.Time) ([]monitor.SystemMetrics, error) {
+	from := time.Time{}
+	if maxAge > 0 {
+		from = now.Add(-maxAge)
+	}
+	rows, err := s.db.Query("SELECT metrics FROM snapshots WHERE timestamp BETWEEN ? AND ? ORDER BY timestamp DESC LIMIT ?",
+		unixNanos(from), unixNanos(now), n)
+	if err != nil {
+		return nil, fmt.Errorf("read store: %w", err)
+	}
+
+	var snapshots []monitor.SystemMetrics
+	if err := scanSnapshots(rows, func(metrics monitor.SystemMetrics) {
+		snapshots = append(snapshots, metrics)
+	}); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(snapshots)-1; i < j; i, j = i+1, j-1 {
+		snapshots[i], snapshots[j] = snapshots[j], snapshots[i]
+	}
+	return snapshots, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// prune deletes the snapshots taken more than the retention before now
+func (s *Store) prune(now time.Time) error {
+	if _, err := s.db.Exec("DELETE FROM snapshots WHERE timestamp < ?", now.Add(-s.retention).UnixNano()); err != nil {
+		return fmt.Errorf("prune store: %w", err)
+	}
+	return nil
+}
+
+// scanSnapshots decodes every row of a metrics query and closes it
+func scanSnapshots(rows *sql.Rows, fn func(monitor.SystemMetrics)) error {
+	defer rows.Close()
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return fmt.Errorf("read store: %w", err)
+		}
+		var metrics monitor.SystemMetrics
+		if err := json.Unmarshal(data, &metrics); err == nil {
+			fn(metrics)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read store: %w", err)
+	}
+	return nil
+}
+
+// unixNanos is t in Unix nanoseconds, clamped to the range they can
+// represent, so the zero time means "from the start"
+func unixNanos(t time.Time) int64 {
+	switch {
+	case t.Before(time.Unix(0, 0)):
+		return 0
+	case t.After(time.Unix(0, math.MaxInt64)):
+		return math.MaxInt64
+	}
+	return t.UnixNano()
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"system-monitor/monitor"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, 48*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	at := func(i int) time.Time { return start.Add(time.Duration(i) * time.Hour) }
+	// Stored out of order, as a clock stepping back would
+	for _, i := range []int{0, 2, 1, 3, 4} {
+		if err := store.Append(monitor.SystemMetrics{Timestamp: at(i), CPU: monitor.CPUMetrics{UsagePercent: float64(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshots, err := store.Range(at(1), at(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 3 {
+		t.Fatalf("Range returned %d snapshots, want 3", len(snapshots))
+	}
+	for i, snapshot := range snapshots {
+		if !snapshot.Timestamp.Equal(at(i+1)) || snapshot.CPU.UsagePercent != float64(i+1) {
+			t.Errorf("Range()[%d] = %s with CPU %g, want %s", i, snapshot.Timestamp, snapshot.CPU.UsagePercent, at(i+1))
+		}
+	}
+
+	latest, err := store.Latest(2, 0, at(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(latest) != 2 || !latest[0].Timestamp.Equal(at(3)) || !latest[1].Timestamp.Equal(at(4)) {
+		t.Errorf("Latest(2) = %v, want the snapshots of hours 3 and 4, oldest first", latest)
+	}
+	if latest, _ := store.Latest(10, 90*time.Minute, at(4)); len(latest) != 2 {
+		t.Errorf("Latest within 90 minutes returned %d snapshots, want 2", len(latest))
+	}
+
+	// Reopening keeps the history, and the next append prunes what is
+	// past the retention
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	store, err = Open(dir, 48*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err := store.Append(monitor.SystemMetrics{Timestamp: at(50)}); err != nil {
+		t.Fatal(err)
+	}
+	all, err := store.Range(time.Time{}, at(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 {
+		t.Fatalf("after pruning the store holds %d snapshots, want 4", len(all))
+	}
+	if !all[0].Timestamp.Equal(at(2)) {
+		t.Errorf("after pruning the oldest snapshot is from %s, want %s", all[0].Timestamp, at(2))
+	}
+}
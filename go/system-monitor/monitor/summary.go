@@ -0,0 +1,153 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+)
+
+// Summary describes a host's health over a period, for periodic reports
+type Summary struct {
+	Host      string    `json:"host"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Snapshots int       `json:"snapshots"`
+
+	// Health scores of the period's snapshots
+	AverageHealth float64 `json:"average_health"`
+	WorstHealth   float64 `json:"worst_health"`
+
+	// Usage trends, downsampled to the summary's step
+	CPU    []SeriesPoint            `json:"cpu"`
+	Memory []SeriesPoint            `json:"memory"`
+	Load   []SeriesPoint            `json:"load"`
+	Disks  map[string][]SeriesPoint `json:"disks"` // used percent by mount point
+
+	// Alert conditions, most severe then most frequent first
+	TopAlerts []AlertCount `json:"top_alerts"`
+
+	// Recommendations by how often they were made, most frequent first
+	Recommendations []string `json:"recommendations"`
+}
+
+// AlertCount is how often one alert condition was raised over a period
+type AlertCount struct {
+	Category string    `json:"category"`
+	Resource string    `json:"resource,omitempty"`
+	Level    string    `json:"level"` // most severe level raised
+	Count    int       `json:"count"`
+	Peak     float64   `json:"peak"`
+	Message  string    `json:"message"` // of the most severe, then latest, alert
+	First    time.Time `json:"first"`
+	Last     time.Time `json:"last"`
+}
+
+// Summaries list at most this many alert conditions and recommendations
+const (
+	maxSummaryAlerts          = 10
+	maxSummaryRecommendations = 10
+)
+
+// Summarize replays snapshots, oldest first, through a fresh analyzer
+// with the given config, so alerts and recommendations are judged as the
+// live robot would have judged them, and downsamples the usage trends to
+// buckets of step
+func Summarize(host string, snapshots []SystemMetrics, config Config, step time.Duration) Summary {
+	summary := Summary{Host: host, Snapshots: len(snapshots), Disks: make(map[string][]SeriesPoint)}
+	if len(snapshots) == 0 {
+		return summary
+	}
+	summary.From = snapshots[0].Timestamp
+	summary.To = snapshots[len(snapshots)-1].Timestamp
+
+	clock := NewFakeClock(summary.From)
+	analyzer := NewAnalyzer(config)
+	analyzer.SetClock(clock)
+
+	alerts := make(map[string]*AlertCount)
+	recommendations := make(map[string]int)
+	var recommendationOrder []string
+	var healthSum float64
+	summary.WorstHealth = 100
+
+	for i := range snapshots {
+		metrics := &snapshots[i]
+		clock.Set(metrics.Timestamp)
+		raised := analyzer.AnalyzeMetrics(metrics)
+
+		health := ComputeHealthScore(metrics, raised, config)
+		healthSum += health
+		summary.WorstHealth = min(summary.WorstHealth, health)
+
+		for _, alert := range raised {
+			if alert.Suppressed {
+				continue
+			}
+			// Warnings that escalated count toward the same condition
+			key := alert.Category + "/" + alert.Resource
+			count, ok := alerts[key]
+			if !ok {
+				count = &AlertCount{Category: alert.Category, Resource: alert.Resource, First: alert.Timestamp}
+				alerts[key] = count
+			}
+			count.Count++
+			count.Last = alert.Timestamp
+			count.Peak = max(count.Peak, alert.Value)
+			if severityRank(alert.Level) >= severityRank(count.Level) {
+				count.Level = alert.Level
+				count.Message = alert.Message
+			}
+		}
+
+		if len(raised) > 0 {
+			for _, text := range analyzer.GenerateRecommendations(metrics, raised) {
+				if recommendations[text] == 0 {
+					recommendationOrder = append(recommendationOrder, text)
+				}
+				recommendations[text]++
+			}
+		}
+	}
+	summary.AverageHealth = healthSum / float64(len(snapshots))
+
+	for _, count := range alerts {
+		summary.TopAlerts = append(summary.TopAlerts, *count)
+	}
+	sort.Slice(summary.TopAlerts, func(i, j int) bool {
+		a, b := summary.TopAlerts[i], summary.TopAlerts[j]
+		if severityRank(a.Level) != severityRank(b.Level) {
+			return severityRank(a.Level) > severityRank(b.Level)
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Category+a.Resource < b.Category+b.Resource
+	})
+	if len(summary.TopAlerts) > maxSummaryAlerts {
+		summary.TopAlerts = summary.TopAlerts[:maxSummaryAlerts]
+	}
+
+	sort.SliceStable(recommendationOrder, func(i, j int) bool {
+		return recommendations[recommendationOrder[i]] > recommendations[recommendationOrder[j]]
+	})
+	if len(recommendationOrder) > maxSummaryRecommendations {
+		recommendationOrder = recommendationOrder[:maxSummaryRecommendations]
+	}
+	summary.Recommendations = recommendationOrder
+
+	series := func(name string) []SeriesPoint {
+		value, _ := SeriesValueFor(name)
+		return Downsample(snapshots, value, step)
+	}
+	summary.CPU = series("cpu")
+	summary.Memory = series("memory")
+	summary.Load = series("load1")
+	for _, metrics := range snapshots {
+		for _, disk := range metrics.Disk {
+			if _, ok := summary.Disks[disk.MountPoint]; !ok {
+				summary.Disks[disk.MountPoint] = series("disk:" + disk.MountPoint)
+			}
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,246 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// Suppression window actions
+const (
+	SuppressDowngrade = "downgrade" // alerts are reported at "info" level
+	SuppressDrop      = "suppress"  // alerts are only logged
+)
+
+// SuppressionWindow is a quiet period during which alerts of the given
+// categories are downgraded or suppressed, e.g. nightly batch jobs or
+// maintenance. It recurs daily from Start to End, starts on a Cron
+// schedule and lasts Duration, or runs once From one time Until another.
+type SuppressionWindow struct {
+	Name       string   `json:"name"`
+	Start      string   `json:"start"`      // "HH:MM", inclusive
+	End        string   `json:"end"`        // "HH:MM", exclusive; may wrap past midnight
+	Weekdays   []string `json:"weekdays"`   // "mon".."sun" the window starts on; empty means every day
+	Timezone   string   `json:"timezone"`   // IANA name, empty means local time
+	Categories []string `json:"categories"` // empty means every category
+	Action     string   `json:"action"`     // "downgrade" (default) or "suppress"
+
+	Cron     string `json:"cron"`     // "minute hour day month weekday", e.g. "0 2 * * sun"
+	Duration string `json:"duration"` // of each cron window, e.g. "2h"
+	From     string `json:"from"`     // RFC 3339, inclusive
+	Until    string `json:"until"`    // RFC 3339, exclusive
+
+	// Resources limits the window to alerts whose resource (mount point,
+	// process, interface, ...) matches one of these path.Match globs
+	Resources []string `json:"resources"`
+
+	// PierceAbove maps a category to the alert value at or above which the
+	// alert escalates normally despite the window (e.g. {"disk": 98})
+	PierceAbove map[string]float64 `json:"pierce_above"`
+}
+
+// suppressionWindow is a SuppressionWindow with its times pre-parsed
+type suppressionWindow struct {
+	SuppressionWindow
+	start    int // minutes after midnight
+	end      int
+	weekdays map[time.Weekday]bool
+	location *time.Location
+
+	cron        *cronSchedule
+	duration    time.Duration
+	from, until time.Time
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseSuppressionWindows parses the configured windows, skipping
+// invalid ones
+func parseSuppressionWindows(config Config) []suppressionWindow {
+	var windows []suppressionWindow
+	for _, w := range config.SuppressionWindows {
+		if parsed, err := parseSuppressionWindow(w); err == nil {
+			windows = append(windows, parsed)
+		}
+	}
+	return windows
+}
+
+func parseSuppressionWindow(w SuppressionWindow) (suppressionWindow, error) {
+	parsed := suppressionWindow{SuppressionWindow: w, location: time.Local}
+
+	var err error
+	switch {
+	case w.Cron != "":
+		if w.Start != "" || w.End != "" || w.From != "" || w.Until != "" || len(w.Weekdays) > 0 {
+			return parsed, errors.New("cron windows take a duration instead of start, end, from, until or weekdays")
+		}
+		if parsed.cron, err = parseCron(w.Cron); err != nil {
+			return parsed, err
+		}
+		if parsed.duration, err = time.ParseDuration(w.Duration); err != nil || parsed.duration <= 0 {
+			return parsed, fmt.Errorf("duration: expected a positive duration such as \"2h\", got %q", w.Duration)
+		}
+
+	case w.From != "" || w.Until != "":
+		if w.Start != "" || w.End != "" || len(w.Weekdays) > 0 {
+			return parsed, errors.New("from/until windows don't take start, end or weekdays")
+		}
+		if parsed.from, err = time.Parse(time.RFC3339, w.From); err != nil {
+			return parsed, fmt.Errorf("from: expected an RFC 3339 time, got %q", w.From)
+		}
+		if parsed.until, err = time.Parse(time.RFC3339, w.Until); err != nil {
+			return parsed, fmt.Errorf("until: expected an RFC 3339 time, got %q", w.Until)
+		}
+		if !parsed.until.After(parsed.from) {
+			return parsed, errors.New("until must be after from")
+		}
+
+	default:
+		if parsed.start, err = parseClockTime(w.Start); err != nil {
+			return parsed, fmt.Errorf("start: %w", err)
+		}
+		if parsed.end, err = parseClockTime(w.End); err != nil {
+			return parsed, fmt.Errorf("end: %w", err)
+		}
+	}
+
+	for _, pattern := range w.Resources {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return parsed, fmt.Errorf("resources: invalid pattern %q", pattern)
+		}
+	}
+
+	if w.Timezone != "" {
+		if parsed.location, err = time.LoadLocation(w.Timezone); err != nil {
+			return parsed, fmt.Errorf("timezone: %w", err)
+		}
+	}
+
+	if len(w.Weekdays) > 0 {
+		parsed.weekdays = make(map[time.Weekday]bool)
+		for _, name := range w.Weekdays {
+			lower := strings.ToLower(name)
+			day, ok := weekdayNames[lower[:min(3, len(lower))]]
+			if !ok {
+				return parsed, fmt.Errorf("unknown weekday %q", name)
+			}
+			parsed.weekdays[day] = true
+		}
+	}
+
+	switch w.Action {
+	case "":
+		parsed.Action = SuppressDowngrade
+	case SuppressDowngrade, SuppressDrop:
+	default:
+		return parsed, fmt.Errorf("action must be %q or %q, got %q", SuppressDowngrade, SuppressDrop, w.Action)
+	}
+
+	return parsed, nil
+}
+
+func parseClockTime(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// active reports whether the window covers t. For windows that wrap past
+// midnight, the weekday is that of the day the window started.
+func (w suppressionWindow) active(t time.Time) bool {
+	switch {
+	case w.cron != nil:
+		return w.cron.firedWithin(t, w.duration, w.location)
+	case !w.from.IsZero():
+		return !t.Before(w.from) && t.Before(w.until)
+	}
+
+	t = t.In(w.location)
+	minute := t.Hour()*60 + t.Minute()
+
+	if w.start <= w.end {
+		return minute >= w.start && minute < w.end && w.onDay(t.Weekday())
+	}
+
+	if minute >= w.start {
+		return w.onDay(t.Weekday())
+	}
+	if minute < w.end {
+		return w.onDay(t.AddDate(0, 0, -1).Weekday())
+	}
+	return false
+}
+
+func (w suppressionWindow) onDay(day time.Weekday) bool {
+	return w.weekdays == nil || w.weekdays[day]
+}
+
+// matches reports whether the window applies to the alert
+func (w suppressionWindow) matches(alert Alert) bool {
+	if limit, ok := w.PierceAbove[alert.Category]; ok && alert.Value >= limit {
+		return false
+	}
+	if len(w.Resources) > 0 && !matchAny(w.Resources, alert.Resource) {
+		return false
+	}
+
+	if len(w.Categories) == 0 {
+		return true
+	}
+	for _, category := range w.Categories {
+		if category == alert.Category {
+			return true
+		}
+	}
+	return false
+}
+
+// applySuppression downgrades or suppresses alerts covered by a window
+// active at time now. Downgraded alerts keep flowing at "info" level;
+// suppressed alerts are flagged so callers only log them.
+func applySuppression(windows []suppressionWindow, alerts []Alert, now time.Time) []Alert {
+	var active []suppressionWindow
+	for _, w := range windows {
+		if w.active(now) {
+			active = append(active, w)
+		}
+	}
+
+	for i := range alerts {
+		for _, w := range active {
+			if !w.matches(alerts[i]) {
+				continue
+			}
+
+			alerts[i].SuppressedBy = w.Name
+			if w.Action == SuppressDrop {
+				alerts[i].Suppressed = true
+			} else {
+				alerts[i].Level = "info"
+			}
+			break
+		}
+	}
+	return alerts
+}
+
+// PartitionSuppressed splits alerts into those to act on and those that
+// were suppressed by a quiet window and should only be logged
+func PartitionSuppressed(alerts []Alert) (active, suppressed []Alert) {
+	for _, alert := range alerts {
+		if alert.Suppressed {
+			suppressed = append(suppressed, alert)
+		} else {
+			active = append(active, alert)
+		}
+	}
+	return active, suppressed
+}
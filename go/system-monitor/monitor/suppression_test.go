@@ -0,0 +1,217 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // the timezone cases must not depend on the host's zoneinfo
+)
+
+func mustParseWindow(t *testing.T, w SuppressionWindow) suppressionWindow {
+	t.Helper()
+	parsed, err := parseSuppressionWindow(w)
+	if err != nil {
+		t.Fatalf("parseSuppressionWindow(%+v): %v", w, err)
+	}
+	return parsed
+}
+
+func TestSuppressionWindowActive(t *testing.T) {
+	// 2024-03-01 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+	nightly := SuppressionWindow{Start: "22:00", End: "02:00", Timezone: "UTC"}
+	fridayNights := SuppressionWindow{Start: "22:00", End: "02:00", Weekdays: []string{"fri"}, Timezone: "UTC"}
+	weekdays := SuppressionWindow{Start: "09:00", End: "17:00", Weekdays: []string{"mon", "tue", "wed", "thu", "fri"}, Timezone: "UTC"}
+	newYork := SuppressionWindow{Start: "09:00", End: "17:00", Timezone: "America/New_York"}
+
+	tests := []struct {
+		name   string
+		window SuppressionWindow
+		now    time.Time
+		want   bool
+	}{
+		{"before a midnight-crossing window", nightly, at(1, 21, 59), false},
+		{"at its start", nightly, at(1, 22, 0), true},
+		{"past midnight", nightly, at(2, 1, 59), true},
+		{"at its end", nightly, at(2, 2, 0), false},
+		{"midday", nightly, at(2, 12, 0), false},
+
+		{"Friday night", fridayNights, at(1, 23, 30), true},
+		{"early Saturday, started Friday", fridayNights, at(2, 1, 0), true},
+		{"Saturday night", fridayNights, at(2, 23, 30), false},
+		{"early Friday, started Thursday", fridayNights, at(1, 1, 0), false},
+
+		{"weekday", weekdays, at(1, 10, 0), true},
+		{"Saturday", weekdays, at(2, 10, 0), false},
+		{"Sunday", weekdays, at(3, 10, 0), false},
+		{"Monday", weekdays, at(4, 16, 59), true},
+
+		// New York is UTC-5 in winter and UTC-4 from 2024-03-10
+		{"09:00 in New York, in UTC", newYork, at(1, 14, 0), true},
+		{"09:00 UTC is 04:00 in New York", newYork, at(1, 9, 0), false},
+		{"16:59 in New York", newYork, at(1, 21, 59), true},
+		{"17:00 in New York", newYork, at(1, 22, 0), false},
+		{"09:00 in New York after DST", newYork, time.Date(2024, time.March, 11, 13, 0, 0, 0, time.UTC), true},
+		{"same UTC time before DST", newYork, time.Date(2024, time.March, 8, 13, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := mustParseWindow(t, tt.window)
+			if got := w.active(tt.now); got != tt.want {
+				t.Errorf("active(%s) = %v, want %v", tt.now.Format(time.RFC3339), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSuppressionWindowWeekdays(t *testing.T) {
+	tests := []struct {
+		weekday string
+		want    time.Weekday
+		wantErr bool
+	}{
+		{"mon", time.Monday, false},
+		{"Saturday", time.Saturday, false},
+		{"SUN", time.Sunday, false},
+		{"xyz", 0, true},
+		{"", 0, true},
+		{"\u212A", 0, true}, // KELVIN SIGN, 3 bytes, lowercases to the 1-byte "k"
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.weekday, func(t *testing.T) {
+			w, err := parseSuppressionWindow(SuppressionWindow{Start: "22:00", End: "06:00", Weekdays: []string{tt.weekday}})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("weekday %q parsed, want an error", tt.weekday)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("weekday %q: %v", tt.weekday, err)
+			}
+			if !w.weekdays[tt.want] || len(w.weekdays) != 1 {
+				t.Errorf("weekday %q parsed as %v, want %s", tt.weekday, w.weekdays, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplySuppression(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 23, 0, 0, 0, time.UTC)
+	disk := Alert{Level: "critical", Category: "disk", Resource: "/data", Value: 96}
+	cpu := Alert{Level: "warning", Category: "cpu", Value: 90}
+
+	tests := []struct {
+		name           string
+		window         SuppressionWindow
+		alert          Alert
+		wantLevel      string
+		wantSuppressed bool
+		wantBy         string
+	}{
+		{
+			name:      "downgrade by default",
+			window:    SuppressionWindow{Name: "batch", Start: "22:00", End: "06:00", Timezone: "UTC"},
+			alert:     disk,
+			wantLevel: "info",
+			wantBy:    "batch",
+		},
+		{
+			name:           "suppress",
+			window:         SuppressionWindow{Name: "batch", Start: "22:00", End: "06:00", Timezone: "UTC", Action: SuppressDrop},
+			alert:          disk,
+			wantLevel:      "critical",
+			wantSuppressed: true,
+			wantBy:         "batch",
+		},
+		{
+			name:      "other category",
+			window:    SuppressionWindow{Name: "batch", Start: "22:00", End: "06:00", Timezone: "UTC", Categories: []string{"cpu"}},
+			alert:     disk,
+			wantLevel: "critical",
+		},
+		{
+			name:      "matching category",
+			window:    SuppressionWindow{Name: "batch", Start: "22:00", End: "06:00", Timezone: "UTC", Categories: []string{"cpu"}},
+			alert:     cpu,
+			wantLevel: "info",
+			wantBy:    "batch",
+		},
+		{
+			name:      "inactive window",
+			window:    SuppressionWindow{Name: "day", Start: "09:00", End: "17:00", Timezone: "UTC"},
+			alert:     disk,
+			wantLevel: "critical",
+		},
+		{
+			name:      "critical alert pierces the window",
+			window:    SuppressionWindow{Name: "batch", Start: "22:00", End: "06:00", Timezone: "UTC", Action: SuppressDrop, PierceAbove: map[string]float64{"disk": 95}},
+			alert:     disk,
+			wantLevel: "critical",
+		},
+		{
+			name:           "below the piercing value",
+			window:         SuppressionWindow{Name: "batch", Start: "22:00", End: "06:00", Timezone: "UTC", Action: SuppressDrop, PierceAbove: map[string]float64{"disk": 98}},
+			alert:          disk,
+			wantLevel:      "critical",
+			wantSuppressed: true,
+			wantBy:         "batch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows := []suppressionWindow{mustParseWindow(t, tt.window)}
+			got := applySuppression(windows, []Alert{tt.alert}, now)[0]
+			if got.Level != tt.wantLevel || got.Suppressed != tt.wantSuppressed || got.SuppressedBy != tt.wantBy {
+				t.Errorf("got level %q, suppressed %v by %q; want %q, %v by %q",
+					got.Level, got.Suppressed, got.SuppressedBy, tt.wantLevel, tt.wantSuppressed, tt.wantBy)
+			}
+		})
+	}
+}
+
+func TestAnalyzerSuppressionFollowsClock(t *testing.T) {
+	config := DefaultConfig()
+	config.SuppressionWindows = []SuppressionWindow{{
+		Name:        "backup",
+		Start:       "01:00",
+		End:         "03:00",
+		Timezone:    "Europe/Berlin",
+		Categories:  []string{"disk"},
+		PierceAbove: map[string]float64{"disk": 99},
+	}}
+	analyzer := NewAnalyzer(config)
+	clock := NewFakeClock(time.Date(2024, time.March, 1, 0, 30, 0, 0, time.UTC)) // 01:30 in Berlin
+	analyzer.SetClock(clock)
+
+	snapshot := func(used float64) *SystemMetrics {
+		return &SystemMetrics{
+			Timestamp: clock.Now(),
+			Disk:      []DiskMetrics{{MountPoint: "/backup", UsedPercent: used}},
+		}
+	}
+	diskLevel := func(alerts []Alert) string {
+		for _, alert := range alerts {
+			if alert.Category == "disk" {
+				return alert.Level
+			}
+		}
+		return ""
+	}
+
+	if got := diskLevel(analyzer.AnalyzeMetrics(snapshot(97))); got != "info" {
+		t.Errorf("inside the window: level %q, want info", got)
+	}
+	if got := diskLevel(analyzer.AnalyzeMetrics(snapshot(99.5))); got != "critical" {
+		t.Errorf("above pierce_above: level %q, want critical", got)
+	}
+
+	clock.Advance(2 * time.Hour) // 03:30 in Berlin
+	if got := diskLevel(analyzer.AnalyzeMetrics(snapshot(97))); got != "critical" {
+		t.Errorf("after the window: level %q, want critical", got)
+	}
+}
@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SystemdMetrics is the state of the units in Config.SystemdUnits, and
+// every unit systemd considers failed
+type SystemdMetrics struct {
+	Units  []SystemdUnitMetrics `json:"units"`
+	Failed []string             `json:"failed,omitempty"`
+}
+
+// SystemdUnitMetrics is one unit as reported by `systemctl show`
+type SystemdUnitMetrics struct {
+	Name        string `json:"name"`
+	LoadState   string `json:"load_state"`   // loaded, not-found, masked, ...
+	ActiveState string `json:"active_state"` // active, inactive, failed, activating, ...
+	SubState    string `json:"sub_state"`    // running, exited, dead, ...
+	Result      string `json:"result,omitempty"`
+	Restarts    int    `json:"restarts"` // automatic restarts, since the unit was loaded
+}
+
+// active reports whether a unit is up. Oneshot services that completed
+// stay active with the exited sub-state, so they count as up too.
+func (u SystemdUnitMetrics) active() bool {
+	return u.ActiveState == "active" || u.ActiveState == "reloading"
+}
+
+func (c Config) validateSystemd() []error {
+	var errs []error
+	for i, unit := range c.SystemdUnits {
+		if unit == "" || strings.ContainsAny(unit, " \t\n") {
+			errs = append(errs, fmt.Errorf("systemd_units[%d]: invalid unit name %q", i, unit))
+		}
+	}
+	return errs
+}
+
+// checkSystemd alerts critically on required units that are not active,
+// and with a warning on units that restarted since the previous sample
+// and on other failed units
+func (a *Analyzer) checkSystemd(metrics *SystemMetrics) []Alert {
+	if metrics.Systemd == nil {
+		return nil
+	}
+	var alerts []Alert
+
+	previous := make(map[string]int)
+	if a.history.len() >= 2 {
+		if before := a.history.at(a.history.len() - 2).Systemd; before != nil {
+			for _, unit := range before.Units {
+				previous[unit.Name] = unit.Restarts
+			}
+		}
+	}
+
+	required := make(map[string]bool, len(metrics.Systemd.Units))
+	for _, unit := range metrics.Systemd.Units {
+		required[unit.Name] = true
+		if !unit.active() {
+			message := fmt.Sprintf("Required unit %s is %s (%s)", unit.Name, unit.ActiveState, unit.SubState)
+			if unit.LoadState != "loaded" {
+				message = fmt.Sprintf("Required unit %s is %s", unit.Name, unit.LoadState)
+			}
+			if unit.Result != "" && unit.Result != "success" {
+				message += ", result " + unit.Result
+			}
+			alerts = append(alerts, Alert{
+				Level:     "critical",
+				Category:  "systemd",
+				Resource:  unit.Name,
+				Message:   message,
+				Timestamp: metrics.Timestamp,
+			})
+			continue
+		}
+
+		if before, ok := previous[unit.Name]; ok && unit.Restarts > before {
+			alerts = append(alerts, Alert{
+				Level:    "warning",
+				Category: "systemd",
+				Resource: unit.Name,
+				Message: fmt.Sprintf("Unit %s restarted %d time(s) since the last sample, %d in total",
+					unit.Name, unit.Restarts-before, unit.Restarts),
+				Value:     float64(unit.Restarts - before),
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+
+	for _, name := range metrics.Systemd.Failed {
+		if required[name] {
+			continue
+		}
+		alerts = append(alerts, Alert{
+			Level:     "warning",
+			Category:  "systemd",
+			Resource:  name,
+			Message:   fmt.Sprintf("Unit %s has failed", name),
+			Timestamp: metrics.Timestamp,
+		})
+	}
+	return alerts
+}
@@ -0,0 +1,78 @@
+//go:build linux
+
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// collectSystemd asks systemctl for the state of the configured units and
+// the list of failed units. Hosts without systemd report nothing.
+func (c *Collector) collectSystemd(ctx context.Context) (*SystemdMetrics, error) {
+	path, err := exec.LookPath("systemctl")
+	if err != nil {
+		return nil, nil
+	}
+
+	metrics := &SystemdMetrics{}
+	if len(c.config.SystemdUnits) > 0 {
+		args := append([]string{"show", "--property=Id,LoadState,ActiveState,SubState,Result,NRestarts", "--"},
+			c.config.SystemdUnits...)
+		out, err := exec.CommandContext(ctx, path, args...).Output()
+		if err != nil {
+			return nil, err
+		}
+		metrics.Units = parseSystemctlShow(out, c.config.SystemdUnits)
+	}
+
+	out, err := exec.CommandContext(ctx, path, "list-units", "--state=failed", "--plain", "--no-legend", "--no-pager").Output()
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			metrics.Failed = append(metrics.Failed, fields[0])
+		}
+	}
+	return metrics, nil
+}
+
+// parseSystemctlShow parses `systemctl show` output, one block of
+// Key=Value lines per unit in the order they were asked for
+func parseSystemctlShow(out []byte, names []string) []SystemdUnitMetrics {
+	var units []SystemdUnitMetrics
+	for i, block := range bytes.Split(bytes.TrimSpace(out), []byte("\n\n")) {
+		if i >= len(names) {
+			break
+		}
+		unit := SystemdUnitMetrics{Name: names[i]}
+		scanner := bufio.NewScanner(bytes.NewReader(block))
+		for scanner.Scan() {
+			key, value, _ := strings.Cut(scanner.Text(), "=")
+			switch key {
+			case "Id":
+				// The full name, as in the failed list: nginx.service for nginx
+				if value != "" {
+					unit.Name = value
+				}
+			case "LoadState":
+				unit.LoadState = value
+			case "ActiveState":
+				unit.ActiveState = value
+			case "SubState":
+				unit.SubState = value
+			case "Result":
+				unit.Result = value
+			case "NRestarts":
+				unit.Restarts, _ = strconv.Atoi(value)
+			}
+		}
+		units = append(units, unit)
+	}
+	return units
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package monitor
+
+import "context"
+
+// collectSystemd is only supported on Linux
+func (c *Collector) collectSystemd(ctx context.Context) (*SystemdMetrics, error) {
+	return nil, nil
+}
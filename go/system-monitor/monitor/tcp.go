@@ -0,0 +1,190 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// minRetransmitSegments is the segments that must have been sent since the
+// previous snapshot for the retransmit percentage to mean anything; a few
+// retransmits of an idle host's keepalives would read as a high rate
+const minRetransmitSegments = 1000
+
+// TCPMetrics is the kernel's TCP/IP error counters as rates since the
+// previous snapshot (Linux). They show packet loss, full accept queues and
+// exhausted socket memory that interface counters and ping checks miss.
+type TCPMetrics struct {
+	// Segments retransmitted, also as a percentage of those sent, zero
+	// while fewer than minRetransmitSegments were sent
+	RetransmitsPerSec float64 `json:"retransmits_per_sec"`
+	RetransmitPercent float64 `json:"retransmit_percent"`
+	TimeoutsPerSec    float64 `json:"timeouts_per_sec"` // retransmission timer expiries
+
+	// Connections dropped because a listener's accept queue was full, and
+	// SYNs dropped for any reason, overflows included
+	ListenOverflowsPerSec float64 `json:"listen_overflows_per_sec"`
+	ListenDropsPerSec     float64 `json:"listen_drops_per_sec"`
+
+	// Failed connection attempts, resets sent, and segments received with
+	// errors such as bad checksums
+	AttemptFailsPerSec float64 `json:"attempt_fails_per_sec"`
+	ResetsSentPerSec   float64 `json:"resets_sent_per_sec"`
+	InErrorsPerSec     float64 `json:"in_errors_per_sec"`
+
+	// Times TCP entered memory pressure, and connections aborted because
+	// socket memory ran out (net.ipv4.tcp_mem)
+	MemoryPressuresPerSec float64 `json:"memory_pressures_per_sec"`
+	MemoryAbortsPerSec    float64 `json:"memory_aborts_per_sec"`
+
+	// UDP datagrams dropped because a socket's receive or send buffer was
+	// full
+	UDPBufferErrorsPerSec float64 `json:"udp_buffer_errors_per_sec"`
+}
+
+// tcpCounters is the cumulative counters at a sample
+type tcpCounters struct {
+	outSegments     uint64
+	retransmits     uint64
+	timeouts        uint64
+	listenOverflows uint64
+	listenDrops     uint64
+	attemptFails    uint64
+	resetsSent      uint64
+	inErrors        uint64
+	memoryPressures uint64
+	memoryAborts    uint64
+	udpBufferErrors uint64
+	at              time.Time
+}
+
+func (c Config) validateTCP() []error {
+	var errs []error
+	if c.ListenOverflowThreshold < 0 {
+		errs = append(errs, fmt.Errorf("listen_overflow_threshold must not be negative, got %g", c.ListenOverflowThreshold))
+	}
+	return errs
+}
+
+// collectTCP derives the rates from the counters at the previous snapshot,
+// so the first snapshot reports nothing. Other platforms report nothing
+// either.
+func (c *Collector) collectTCP(ctx context.Context) (*TCPMetrics, error) {
+	current, err := readTCPCounters()
+	if current == nil || err != nil {
+		return nil, err
+	}
+	current.at = c.clock.Now()
+	prev := c.prevTCP
+	c.prevTCP = current
+	if prev == nil {
+		return nil, nil
+	}
+	elapsed := current.at.Sub(prev.at).Seconds()
+	if elapsed <= 0 {
+		return nil, nil
+	}
+
+	// Counters reset when the network namespace is recreated
+	delta := func(now, before uint64) uint64 {
+		if now < before {
+			return 0
+		}
+		return now - before
+	}
+	rate := func(now, before uint64) float64 {
+		return float64(delta(now, before)) / elapsed
+	}
+	tcp := &TCPMetrics{
+		RetransmitsPerSec:     rate(current.retransmits, prev.retransmits),
+		TimeoutsPerSec:        rate(current.timeouts, prev.timeouts),
+		ListenOverflowsPerSec: rate(current.listenOverflows, prev.listenOverflows),
+		ListenDropsPerSec:     rate(current.listenDrops, prev.listenDrops),
+		AttemptFailsPerSec:    rate(current.attemptFails, prev.attemptFails),
+		ResetsSentPerSec:      rate(current.resetsSent, prev.resetsSent),
+		InErrorsPerSec:        rate(current.inErrors, prev.inErrors),
+		MemoryPressuresPerSec: rate(current.memoryPressures, prev.memoryPressures),
+		MemoryAbortsPerSec:    rate(current.memoryAborts, prev.memoryAborts),
+		UDPBufferErrorsPerSec: rate(current.udpBufferErrors, prev.udpBufferErrors),
+	}
+	if sent := delta(current.outSegments, prev.outSegments); sent >= minRetransmitSegments {
+		tcp.RetransmitPercent = float64(delta(current.retransmits, prev.retransmits)) / float64(sent) * 100
+	}
+	return tcp, nil
+}
+
+// checkTCP warns when retransmitted segments stay above
+// Config.RetransmitThreshold percent of those sent, or listen queue
+// overflows above Config.ListenOverflowThreshold per second, for 3
+// snapshots in a row, critical at five times either. Connections aborted
+// for lack of socket memory are critical straight away, since each is a
+// connection the kernel gave up on.
+func (a *Analyzer) checkTCP(metrics *SystemMetrics) []Alert {
+	tcp := metrics.TCP
+	if tcp == nil {
+		return nil
+	}
+
+	var alerts []Alert
+	if threshold := a.config.RetransmitThreshold; threshold > 0 {
+		retransmits := func(m SystemMetrics) float64 {
+			if m.TCP == nil {
+				return 0
+			}
+			return m.TCP.RetransmitPercent
+		}
+		if a.isSustained(retransmits, threshold) {
+			level := "warning"
+			if tcp.RetransmitPercent > threshold*5 {
+				level = "critical"
+			}
+			alerts = append(alerts, Alert{
+				Level:    level,
+				Category: "tcp",
+				Resource: "retransmits",
+				Message: fmt.Sprintf("%.1f%% of TCP segments are retransmitted (%.0f/s, %.1f timeouts/s), a sign of packet loss",
+					tcp.RetransmitPercent, tcp.RetransmitsPerSec, tcp.TimeoutsPerSec),
+				Value:     tcp.RetransmitPercent,
+				Threshold: threshold,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+
+	if threshold := a.config.ListenOverflowThreshold; threshold > 0 {
+		overflows := func(m SystemMetrics) float64 {
+			if m.TCP == nil {
+				return 0
+			}
+			return m.TCP.ListenOverflowsPerSec
+		}
+		if a.isSustained(overflows, threshold) {
+			level := "warning"
+			if tcp.ListenOverflowsPerSec > threshold*5 {
+				level = "critical"
+			}
+			alerts = append(alerts, Alert{
+				Level:    level,
+				Category: "tcp",
+				Resource: "listen_overflows",
+				Message: fmt.Sprintf("%.1f connections/s dropped because a listener's accept queue is full (%.1f SYNs dropped/s)",
+					tcp.ListenOverflowsPerSec, tcp.ListenDropsPerSec),
+				Value:     tcp.ListenOverflowsPerSec,
+				Threshold: threshold,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+	}
+
+	if tcp.MemoryAbortsPerSec > 0 {
+		alerts = append(alerts, Alert{
+			Level:     "critical",
+			Category:  "tcp",
+			Resource:  "socket_memory",
+			Message:   fmt.Sprintf("TCP connections are being aborted for lack of socket memory (%.2f/s)", tcp.MemoryAbortsPerSec),
+			Value:     tcp.MemoryAbortsPerSec,
+			Timestamp: metrics.Timestamp,
+		})
+	}
+	return alerts
+}
@@ -0,0 +1,75 @@
+//go:build linux
+
+package monitor
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readTCPCounters reads the cumulative TCP and UDP counters from
+// /proc/net/snmp and the extended ones from /proc/net/netstat. Both hold
+// pairs of lines, a header of names and a line of values, per protocol.
+// Inside a container they are the container's network namespace's.
+func readTCPCounters() (*tcpCounters, error) {
+	snmp, err := readProcNetCounters("/proc/net/snmp")
+	if err != nil {
+		return nil, err
+	}
+	// Older kernels and some namespaces have no netstat file
+	netstat, _ := readProcNetCounters("/proc/net/netstat")
+
+	tcp, udp, ext := snmp["Tcp"], snmp["Udp"], netstat["TcpExt"]
+	return &tcpCounters{
+		outSegments:     tcp["OutSegs"],
+		retransmits:     tcp["RetransSegs"],
+		timeouts:        ext["TCPTimeouts"],
+		listenOverflows: ext["ListenOverflows"],
+		listenDrops:     ext["ListenDrops"],
+		attemptFails:    tcp["AttemptFails"],
+		resetsSent:      tcp["OutRsts"],
+		inErrors:        tcp["InErrs"] + tcp["InCsumErrors"],
+		memoryPressures: ext["TCPMemoryPressures"],
+		memoryAborts:    ext["TCPAbortOnMemory"],
+		udpBufferErrors: udp["RcvbufErrors"] + udp["SndbufErrors"],
+	}, nil
+}
+
+// readProcNetCounters parses /proc/net/snmp or netstat into counters by
+// protocol and name. Negative values, such as Tcp MaxConn's -1, are left
+// out.
+func readProcNetCounters(path string) (map[string]map[string]uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	counters := make(map[string]map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var names []string
+	for scanner.Scan() {
+		// "Tcp: RtoAlgorithm RtoMin ..." then "Tcp: 1 200 ..."
+		protocol, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if names == nil {
+			names = fields
+			continue
+		}
+		values := make(map[string]uint64, len(fields))
+		for i := 0; i < len(fields) && i < len(names); i++ {
+			if value, err := strconv.ParseUint(fields[i], 10, 64); err == nil {
+				values[names[i]] = value
+			}
+		}
+		counters[protocol] = values
+		names = nil
+	}
+	return counters, scanner.Err()
+}
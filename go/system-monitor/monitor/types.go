@@ -10,13 +10,145 @@ type SystemMetrics struct {
 	Disk      []DiskMetrics    `json:"disk"`
 	Load      LoadMetrics      `json:"load"`
 	Processes []ProcessMetrics `json:"processes"`
+
+	// Uptime is nil where the boot time can't be read
+	Uptime *UptimeMetrics `json:"uptime,omitempty"`
+
+	// Updates is nil unless update collection is enabled
+	Updates *UpdateMetrics `json:"updates,omitempty"`
+
+	// Logged in users and recent failed logins
+	Sessions *SessionMetrics `json:"sessions,omitempty"`
+
+	// Entropy is nil on other platforms than Linux
+	Entropy *EntropyMetrics `json:"entropy,omitempty"`
+
+	// NUMA is nil on other platforms than Linux
+	NUMA *NUMAMetrics `json:"numa,omitempty"`
+
+	// Pressure is nil where the kernel has no pressure stall information
+	Pressure *PressureMetrics `json:"pressure,omitempty"`
+
+	// Activity is nil on other platforms than Linux
+	Activity *ActivityMetrics `json:"activity,omitempty"`
+
+	// The largest processes by memory, collected while
+	// Config.ProcessLeakMB is set
+	LargestProcesses []ProcessMetrics `json:"largest_processes,omitempty"`
+
+	// Processes summed by Config.ProcessGroupBy, the busiest by CPU and
+	// the largest by memory
+	ProcessGroups []ProcessGroupMetrics `json:"process_groups,omitempty"`
+
+	// ProcessStates is nil where process states are not reported
+	ProcessStates *ProcessStateMetrics `json:"process_states,omitempty"`
+
+	// One entry per Config.ProcessWatches rule
+	WatchedProcesses []WatchedProcessMetrics `json:"watched_processes,omitempty"`
+
+	// One entry per Config.HTTPProbes, PortProbes and DNSProbes probe
+	Probes []ProbeMetrics `json:"probes,omitempty"`
+
+	// Exposure is nil unless Config.AuditPorts is set
+	Exposure *ExposureMetrics `json:"exposure,omitempty"`
+
+	// One entry per Config.PingTargets target
+	Ping []PingMetrics `json:"ping,omitempty"`
+
+	// Configured Windows services, and the Error and Critical events
+	// logged since the previous snapshot; empty on other platforms
+	WindowsServices []WindowsServiceMetrics `json:"windows_services,omitempty"`
+	WindowsEvents   []WindowsEvent          `json:"windows_events,omitempty"`
+
+	// The clock's offset from each of Config.NTPServers
+	Clock []ClockMetrics `json:"clock,omitempty"`
+
+	// One entry per Config.PathWatches path
+	Paths []PathMetrics `json:"paths,omitempty"`
+
+	// One entry per Config.LogWatches watch
+	Logs []LogMetrics `json:"logs,omitempty"`
+
+	// Systemd is nil unless systemd units are monitored on a systemd host
+	Systemd *SystemdMetrics `json:"systemd,omitempty"`
+
+	// One entry per Config.CertificateChecks check
+	Certificates []CertificateMetrics `json:"certificates,omitempty"`
+
+	// FileDescriptors is nil on platforms without system-wide FD accounting
+	FileDescriptors *FileDescriptorMetrics `json:"file_descriptors,omitempty"`
+
+	// GPU is empty unless GPU collection is enabled and a GPU is present
+	GPU []GPUMetrics `json:"gpu,omitempty"`
+
+	// Network holds one entry per interface, loopback excluded
+	Network []NetworkMetrics `json:"network,omitempty"`
+
+	// Connections is nil when socket states could not be read
+	Connections *ConnectionMetrics `json:"connections,omitempty"`
+
+	// TCP is nil on the first snapshot and on other platforms than Linux
+	TCP *TCPMetrics `json:"tcp,omitempty"`
+
+	// Sensors is nil on hosts without hardware sensors
+	Sensors *SensorsMetrics `json:"sensors,omitempty"`
+
+	// Power is nil on hosts without a battery or AC adapter that reports
+	// itself
+	Power *PowerMetrics `json:"power,omitempty"`
+
+	// Containers is empty unless container collection is enabled
+	Containers []ContainerMetrics `json:"containers,omitempty"`
+
+	// Cgroup is nil outside a container, or without cgroup v2
+	Cgroup *CgroupMetrics `json:"cgroup,omitempty"`
+
+	// Kubernetes is nil unless Kubernetes collection is enabled and the
+	// host has a cluster configuration
+	Kubernetes *KubernetesMetrics `json:"kubernetes,omitempty"`
+
+	// DiskHealth is empty unless SMART collection is enabled
+	DiskHealth []DiskHealthMetrics `json:"disk_health,omitempty"`
+
+	// ZFS pools and Btrfs filesystems
+	Pools []PoolMetrics `json:"pools,omitempty"`
+
+	// Software RAID arrays, and hardware ones when enabled
+	RAID []RAIDMetrics `json:"raid,omitempty"`
+
+	// The monitor's own resource usage
+	Self *SelfMetrics `json:"self,omitempty"`
+
+	// Results of registered collectors, by collector name
+	Custom map[string]interface{} `json:"custom,omitempty"`
+
+	// Collectors that failed or timed out; their sections are left empty
+	CollectorFailures []CollectorFailure `json:"collector_failures,omitempty"`
 }
 
 // CPUMetrics holds CPU-related metrics
 type CPUMetrics struct {
-	UsagePercent float64   `json:"usage_percent"`
-	Cores        int       `json:"cores"`
-	PerCore      []float64 `json:"per_core"`
+	UsagePercent float64      `json:"usage_percent"`
+	Cores        int          `json:"cores"`
+	PerCore      []float64    `json:"per_core"`
+	Breakdown    CPUBreakdown `json:"breakdown"`
+
+	// Virtualization names the platform when running as a guest, e.g.
+	// "kvm", "xen", "vmware" or "docker"; empty on bare metal or when
+	// it can't be detected
+	Virtualization string `json:"virtualization,omitempty"`
+}
+
+// CPUBreakdown splits CPU time into its components, as percentages of
+// total CPU time since the previous sample. Fields a platform does not
+// report (e.g. iowait and steal on Windows) stay zero.
+type CPUBreakdown struct {
+	User   float64 `json:"user"` // includes nice
+	System float64 `json:"system"`
+	Idle   float64 `json:"idle"`
+	IOWait float64 `json:"iowait"`
+	Steal  float64 `json:"steal"`
+	IRQ    float64 `json:"irq"` // hard and soft interrupts
 }
 
 // MemoryMetrics holds memory-related metrics
@@ -28,6 +160,11 @@ type MemoryMetrics struct {
 	SwapTotalGB  float64 `json:"swap_total_gb"`
 	SwapUsedGB   float64 `json:"swap_used_gb"`
 	SwapPercent  float64 `json:"swap_percent"`
+
+	// Pages swapped in and out per second since the previous sample; zero
+	// on the first sample and where the platform doesn't report them
+	SwapInPerSec  float64 `json:"swap_in_per_sec"`
+	SwapOutPerSec float64 `json:"swap_out_per_sec"`
 }
 
 // DiskMetrics holds disk-related metrics for a single partition
@@ -38,6 +175,18 @@ type DiskMetrics struct {
 	UsedGB       float64 `json:"used_gb"`
 	FreeGB       float64 `json:"free_gb"`
 	UsedPercent  float64 `json:"percent"`
+
+	// Inode counts are zero on filesystems that don't report them
+	InodesTotal       uint64  `json:"inodes_total"`
+	InodesUsed        uint64  `json:"inodes_used"`
+	InodesFree        uint64  `json:"inodes_free"`
+	InodesUsedPercent float64 `json:"inodes_percent"`
+
+	// ReadOnly is set for filesystems mounted read-only, and Remounted too
+	// when they were seen read-write earlier or /etc/fstab mounts them
+	// read-write: typically the kernel remounted them after I/O errors
+	ReadOnly  bool `json:"read_only,omitempty"`
+	Remounted bool `json:"remounted_read_only,omitempty"`
 }
 
 // LoadMetrics holds system load averages
@@ -45,6 +194,80 @@ type LoadMetrics struct {
 	Load1  float64 `json:"load1"`
 	Load5  float64 `json:"load5"`
 	Load15 float64 `json:"load15"`
+
+	// The load averages divided by the logical cores available, so 1.0
+	// means every core busy whatever the host's size
+	Load1PerCore  float64 `json:"load1_per_core"`
+	Load5PerCore  float64 `json:"load5_per_core"`
+	Load15PerCore float64 `json:"load15_per_core"`
+}
+
+// FileDescriptorMetrics holds system-wide open file handle usage
+type FileDescriptorMetrics struct {
+	Open        uint64  `json:"open"`
+	Max         uint64  `json:"max"`
+	UsedPercent float64 `json:"percent"`
+}
+
+// GPUMetrics holds utilization metrics for a single GPU
+type GPUMetrics struct {
+	Index              int     `json:"index"`
+	Name               string  `json:"name"`
+	Vendor             string  `json:"vendor"`
+	UtilizationPercent float64 `json:"utilization_percent"`
+	MemoryUsedMB       float64 `json:"memory_used_mb"`
+	MemoryTotalMB      float64 `json:"memory_total_mb"`
+	MemoryPercent      float64 `json:"memory_percent"`
+	TemperatureC       float64 `json:"temperature_c"`
+
+	// Board power draw; zero where the driver doesn't report it
+	PowerWatts      float64 `json:"power_watts"`
+	PowerLimitWatts float64 `json:"power_limit_watts,omitempty"`
+}
+
+// NetworkMetrics holds traffic counters for a single network interface.
+// Counters are totals since boot; rates are averaged since the previous
+// sample and zero on the first one.
+type NetworkMetrics struct {
+	Interface   string `json:"interface"`
+	BytesSent   uint64 `json:"bytes_sent"`
+	BytesRecv   uint64 `json:"bytes_recv"`
+	PacketsSent uint64 `json:"packets_sent"`
+	PacketsRecv uint64 `json:"packets_recv"`
+	ErrorsIn    uint64 `json:"errors_in"`
+	ErrorsOut   uint64 `json:"errors_out"`
+	DropsIn     uint64 `json:"drops_in"`
+	DropsOut    uint64 `json:"drops_out"`
+
+	SentMbps float64 `json:"sent_mbps"`
+	RecvMbps float64 `json:"recv_mbps"`
+
+	// SpeedMbps is the negotiated link speed, zero where unknown (virtual
+	// interfaces, non-Linux platforms). UtilizationPercent is the busier
+	// direction relative to it, since links are full duplex.
+	SpeedMbps          float64 `json:"speed_mbps,omitempty"`
+	UtilizationPercent float64 `json:"utilization_percent,omitempty"`
+}
+
+// SensorsMetrics holds hardware sensor readings
+type SensorsMetrics struct {
+	Temperatures []TemperatureReading `json:"temperatures,omitempty"`
+	Fans         []FanReading         `json:"fans,omitempty"` // Linux only
+}
+
+// TemperatureReading is a single temperature sensor. HighC and CriticalC
+// are the hardware's own trip points, zero when not reported.
+type TemperatureReading struct {
+	Sensor       string  `json:"sensor"`
+	TemperatureC float64 `json:"temperature_c"`
+	HighC        float64 `json:"high_c,omitempty"`
+	CriticalC    float64 `json:"critical_c,omitempty"`
+}
+
+// FanReading is a single fan speed sensor
+type FanReading struct {
+	Sensor string  `json:"sensor"`
+	RPM    float64 `json:"rpm"`
 }
 
 // ProcessMetrics holds metrics for a single process
@@ -54,16 +277,53 @@ type ProcessMetrics struct {
 	CPUPercent   float64 `json:"cpu_percent"`
 	MemoryMB     float64 `json:"memory_mb"`
 	MemoryPercent float64 `json:"memory_percent"`
+	OpenFDs       int32   `json:"open_fds,omitempty"`
+	FDSoftLimit   uint64  `json:"fd_soft_limit,omitempty"`
+	FDHardLimit   uint64  `json:"fd_hard_limit,omitempty"`
+
+	// Only read for the reported processes, like the FD counts
+	Threads   int32     `json:"threads,omitempty"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+
+	// Disk I/O since the previous sample; zero on the first one and where
+	// the process's I/O counters can't be read (other users' processes
+	// without root, macOS)
+	ReadMBPerSec  float64 `json:"read_mb_per_sec,omitempty"`
+	WriteMBPerSec float64 `json:"write_mb_per_sec,omitempty"`
+
+	// Only collected with Config.CollectCmdline, and always scrubbed
+	Cmdline string `json:"cmdline,omitempty"`
+
+	// Cumulative disk I/O in bytes, for the rates above
+	ioRead, ioWrite uint64
+	ioKnown         bool
+
+	// Read for Config.ProcessGroupBy
+	parent int32
+	cgroup string
 }
 
 // Alert represents a system alert
 type Alert struct {
 	Level     string    `json:"level"` // "warning", "critical"
 	Category  string    `json:"category"` // "cpu", "memory", "disk"
+	Resource  string    `json:"resource,omitempty"` // mount point, process name, ...
 	Message   string    `json:"message"`
 	Value     float64   `json:"value"`
 	Threshold float64   `json:"threshold"`
 	Timestamp time.Time `json:"timestamp"`
+
+	// Set when a quiet window downgraded or suppressed the alert
+	Suppressed   bool   `json:"suppressed,omitempty"`
+	SuppressedBy string `json:"suppressed_by,omitempty"`
+
+	// Set on composite alerts: the conditions they stand for
+	Contributing []Alert `json:"contributing,omitempty"`
+}
+
+// Key identifies an alert across iterations, independent of its current value
+func (a Alert) Key() string {
+	return a.Level + "/" + a.Category + "/" + a.Resource
 }
 
 // Config holds monitoring configuration
@@ -71,7 +331,247 @@ type Config struct {
 	CPUThreshold    float64 `json:"cpu_threshold"`
 	MemoryThreshold float64 `json:"memory_threshold"`
 	DiskThreshold   float64 `json:"disk_threshold"`
+	InodeThreshold  float64 `json:"inode_threshold"`
+	IOWaitThreshold float64 `json:"iowait_threshold"`
+	StealThreshold  float64 `json:"steal_threshold"`
+	FDThreshold     float64 `json:"fd_threshold"` // percent of the FD limit
 	TopProcessCount int     `json:"top_process_count"`
+	ProcessWorkers  int     `json:"process_workers"` // concurrent process readers
+	AlertCooldown   int     `json:"alert_cooldown"` // seconds between repeat notifications
+
+	// Metric the top processes are picked by, and name regexps narrowing
+	// the reported processes: when ProcessInclude is set only matches are
+	// reported, and ProcessExclude matches never are
+	ProcessSort    ProcessSort `json:"process_sort"`
+	ProcessInclude []string    `json:"process_include"`
+	ProcessExclude []string    `json:"process_exclude"`
+
+	// Percentage of a container's CPU quota periods it may be throttled
+	// in before a warning; 0 disables
+	CPUThrottleThreshold float64 `json:"cpu_throttle_threshold"`
+
+	// Sums processes by "parent" (the process tree below init), "cgroup"
+	// or systemd "slice"; empty disables
+	ProcessGroupBy string `json:"process_group_by"`
+
+	// Enables or disables collectors by name, overriding their defaults
+	Collectors map[string]bool `json:"collectors"`
+
+	// Seconds each collector may take before the snapshot goes ahead
+	// without it
+	CollectorTimeout int `json:"collector_timeout"`
+
+	// GPU collection shells out to nvidia-smi and rocm-smi, so it is opt-in
+	CollectGPU       bool    `json:"collect_gpu"`
+	GPUThreshold     float64 `json:"gpu_threshold"`
+	GPUTempThreshold float64 `json:"gpu_temperature_threshold"` // degrees Celsius
+
+	HealthWeights HealthWeights `json:"health_weights"`
+
+	SuppressionWindows []SuppressionWindow `json:"suppression_windows"`
+
+	// Seconds between collections in Monitor.Run
+	Interval int `json:"interval"`
+
+	// Snapshots the analyzer keeps for trend and anomaly detection, e.g.
+	// 720 for an hour at 5s intervals
+	HistoryWindow int `json:"history_window"`
+
+	// Adaptive mode shortens the interval towards MinInterval under
+	// pressure and lengthens it towards MaxInterval while calm
+	AdaptiveInterval bool `json:"adaptive_interval"`
+	MinInterval      int  `json:"min_interval"`
+	MaxInterval      int  `json:"max_interval"`
+
+	// Per-mount-point overrides of DiskThreshold and InodeThreshold, and the mount points to
+	// monitor at all; keys and patterns use path.Match globs
+	DiskThresholds  map[string]float64 `json:"disk_thresholds"`
+	InodeThresholds map[string]float64 `json:"inode_thresholds"`
+	DiskInclude     []string           `json:"disk_include"`
+	DiskExclude     []string           `json:"disk_exclude"`
+
+	// Alert when a disk's usage trend reaches 100% within this many hours;
+	// 0 disables forecasting
+	DiskForecastHours int `json:"disk_forecast_hours"`
+
+	// Percent of a ZFS pool's free space that is fragmented; 0 disables.
+	// Pool capacity alerts use DiskThreshold and DiskThresholds, keyed by
+	// pool name or Btrfs mount point.
+	PoolFragmentationThreshold float64 `json:"pool_fragmentation_threshold"`
+
+	// Docker container collection talks to the Docker socket, so it is opt-in
+	CollectContainers bool   `json:"collect_containers"`
+	DockerSocket      string `json:"docker_socket"`
+
+	// Kubernetes node and pod collection is opt-in too. Kubeconfig and
+	// KubernetesNode default to the in-cluster configuration (or
+	// $KUBECONFIG, ~/.kube/config) and to $NODE_NAME or the host name.
+	// Pods pending for longer than PodPendingMinutes alert; 0 disables.
+	CollectKubernetes bool   `json:"collect_kubernetes"`
+	Kubeconfig        string `json:"kubeconfig"`
+	KubernetesNode    string `json:"kubernetes_node"`
+	PodPendingMinutes int    `json:"pod_pending_minutes"`
+
+	// Hardware temperature sensor alert threshold, degrees Celsius
+	TemperatureThreshold float64 `json:"temperature_threshold"`
+
+	// Charge percentage below which a discharging battery raises a
+	// warning, critical below half of it; 0 disables
+	BatteryThreshold float64 `json:"battery_threshold"`
+
+	// Failed logins within AuthFailureWindow minutes that raise a warning,
+	// critical at five times it; 0 disables. AlertRootLogins warns about
+	// every interactive root login, for machines where admins are meant
+	// to log in as themselves and use sudo.
+	AuthFailureThreshold int  `json:"auth_failure_threshold"`
+	AuthFailureWindow    int  `json:"auth_failure_window"`
+	AlertRootLogins      bool `json:"alert_root_logins"`
+
+	// Bits of kernel entropy below which a warning is raised when low for
+	// 3 snapshots in a row; 0 disables
+	EntropyThreshold int `json:"entropy_threshold"`
+
+	// Percent of the last minute tasks were stalled on CPU, memory or I/O
+	// that raises a warning; critical when all tasks were stalled that
+	// long on memory or I/O. 0 disables.
+	PressureThreshold float64 `json:"pressure_threshold"`
+
+	// Interrupts per core per second, and processes created per second,
+	// that raise a warning when exceeded for 3 snapshots in a row;
+	// critical at five times them. 0 disables.
+	InterruptThreshold float64 `json:"interrupt_threshold"`
+	ForkRateThreshold  float64 `json:"fork_rate_threshold"`
+
+	// SMART collection shells out to smartctl and usually needs root, so
+	// it is opt-in. Wear is the percent of rated SSD endurance used.
+	CollectSMART       bool    `json:"collect_smart"`
+	SMARTWearThreshold float64 `json:"smart_wear_threshold"`
+
+	// Software RAID is read from /proc/mdstat; hardware RAID shells out to
+	// storcli or megacli, which usually need root, so it is opt-in
+	CollectHardwareRAID bool `json:"collect_hardware_raid"`
+
+	// Pending OS updates shell out to the package manager, which can take
+	// minutes, so they are opt-in and queried every UpdateCheckHours or
+	// when packages change. Security updates and a required reboot warn,
+	// as do UpdateThreshold pending updates (0 disables); with
+	// UpdateAlertSchedule, a cron expression, only when it fires.
+	CollectUpdates      bool   `json:"collect_updates"`
+	UpdateCheckHours    int    `json:"update_check_hours"`
+	UpdateThreshold     int    `json:"update_threshold"`
+	UpdateAlertSchedule string `json:"update_alert_schedule"`
+
+	// Percent of link speed; interfaces of unknown speed never alert
+	BandwidthThreshold float64 `json:"bandwidth_threshold"`
+
+	// TCP sockets in CLOSE_WAIT (0 disables), and percent of the conntrack
+	// table in use
+	CloseWaitThreshold int     `json:"close_wait_threshold"`
+	ConntrackThreshold float64 `json:"conntrack_threshold"`
+
+	// Percent of TCP segments retransmitted, and connections per second
+	// dropped by full accept queues, that raise a warning when exceeded
+	// for 3 snapshots in a row; critical at five times them. 0 disables.
+	RetransmitThreshold     float64 `json:"retransmit_threshold"`
+	ListenOverflowThreshold float64 `json:"listen_overflow_threshold"`
+
+	// Pages swapped in and out per second that count as thrashing; swap
+	// that is merely occupied never alerts. 0 disables.
+	SwapRateThreshold float64 `json:"swap_rate_threshold"`
+
+	// Zombie processes tolerated before alerting; 0 disables
+	ZombieThreshold int `json:"zombie_threshold"`
+
+	// MB a process's memory must grow by, steadily across the history
+	// window, to be reported as leaking; 0 disables
+	ProcessLeakMB float64 `json:"process_leak_mb"`
+
+	// Standard deviations above its moving average at which a metric is
+	// anomalous, with per-category (cpu, memory, iowait) overrides; 0
+	// disables
+	AnomalySigma  float64            `json:"anomaly_sigma"`
+	AnomalySigmas map[string]float64 `json:"anomaly_sigmas"`
+
+	// Score anomalies against the usual level for the hour of the day
+	// ("daily") or of the week ("weekly") rather than the recent average,
+	// once that hour has been seen on two different days or weeks
+	Seasonality string `json:"seasonality"`
+
+	// Levels below which a raised condition clears, by alert category
+	// (cpu, memory, disk, ...); until then it keeps alerting. Without one,
+	// a condition clears as soon as it is back under its threshold.
+	ClearThresholds map[string]float64 `json:"clear_thresholds"`
+
+	// Systemd units that must be active, such as "nginx" or
+	// "backup.timer"; setting any also reports every failed unit
+	SystemdUnits []string `json:"systemd_units"`
+
+	// NTP servers to measure the system clock against, as host or
+	// host:port, and the offset in ms that raises a warning (critical at
+	// ten times it)
+	NTPServers           []string `json:"ntp_servers"`
+	ClockOffsetThreshold float64  `json:"clock_offset_threshold"`
+
+	// Files and directories whose size and growth are watched
+	PathWatches []PathWatch `json:"path_watches"`
+
+	// Log files and journal streams whose error rate is watched
+	LogWatches []LogWatch `json:"log_watches"`
+
+	// Windows services that must be running, by service name (not
+	// display name), and event logs to watch for Error and Critical events
+	WindowsServices  []string `json:"windows_services"`
+	WindowsEventLogs []string `json:"windows_event_logs"`
+
+	// Processes that must be running, with optional resource budgets
+	ProcessWatches []ProcessWatch `json:"process_watches"`
+
+	// HTTP(S) endpoints checked on every snapshot
+	HTTPProbes []HTTPProbe `json:"http_probes"`
+
+	// TCP and UDP ports that must be listening, or must not be
+	PortProbes []PortProbe `json:"port_probes"`
+
+	// Audit the ports listening on public addresses against AllowedPorts
+	// ("22", "tcp/443", "udp/53", "tcp/8000-8100"), alerting on any
+	// other. AuditPrivateAddresses audits private and link-local
+	// addresses too.
+	AuditPorts            bool     `json:"audit_ports"`
+	AllowedPorts          []string `json:"allowed_ports"`
+	AuditPrivateAddresses bool     `json:"audit_private_addresses"`
+
+	// Names that must resolve, optionally to given addresses
+	DNSProbes []DNSProbe `json:"dns_probes"`
+
+	// Hosts whose round-trip time and packet loss are measured, and the
+	// loss (percent) and average round trip (ms) that alert; 0 disables
+	PingTargets          []PingTarget `json:"ping_targets"`
+	PingLossThreshold    float64      `json:"ping_loss_threshold"`
+	PingLatencyThreshold float64      `json:"ping_latency_threshold"`
+
+	// TLS certificates to watch for expiry, and the days before expiry at
+	// which they raise a warning and a critical alert
+	CertificateChecks []CertificateCheck `json:"certificate_checks"`
+	CertWarningDays   int                `json:"cert_warning_days"`
+	CertCriticalDays  int                `json:"cert_critical_days"`
+
+	// User-defined alerts as expressions over the snapshot
+	AlertRules []AlertRule `json:"alert_rules"`
+
+	// Thresholds relative to percentiles of each metric's own history,
+	// computed from the store given to Analyzer.UseStore
+	DynamicThresholds []DynamicThreshold `json:"dynamic_thresholds"`
+
+	// Fold alerts that describe one problem, such as high iowait and a
+	// full disk, into a single composite alert
+	CompositeAlerts bool `json:"composite_alerts"`
+
+	// Escalates warnings that persist to critical
+	Escalation EscalationPolicy `json:"escalation"`
+
+	// Process details that may carry secrets
+	CollectCmdline bool            `json:"collect_cmdline"`
+	Redaction      RedactionConfig `json:"redaction"`
 }
 
 // DefaultConfig returns default monitoring configuration
@@ -80,6 +580,79 @@ func DefaultConfig() Config {
 		CPUThreshold:    80.0,
 		MemoryThreshold: 90.0,
 		DiskThreshold:   90.0,
+		InodeThreshold:  90.0,
+		IOWaitThreshold: 20.0,
+		StealThreshold:  5.0,
+		FDThreshold:     80.0,
 		TopProcessCount: 10,
+		ProcessSort:     SortByCPU,
+		ProcessWorkers:  8,
+		AlertCooldown:   300,
+		HealthWeights:   DefaultHealthWeights(),
+
+		CPUThrottleThreshold: 25.0,
+
+		GPUThreshold:     95.0,
+		GPUTempThreshold: 85.0,
+
+		BandwidthThreshold: 90.0,
+
+		ZombieThreshold: 10,
+
+		ProcessLeakMB: 100,
+
+		AnomalySigma: 3,
+
+		CompositeAlerts: true,
+
+		SwapRateThreshold: 500,
+
+		CloseWaitThreshold: 200,
+		ConntrackThreshold: 80.0,
+
+		RetransmitThreshold:     5.0,
+		ListenOverflowThreshold: 1.0,
+
+		TemperatureThreshold: 85.0,
+
+		BatteryThreshold: 20.0,
+
+		AuthFailureThreshold: 20,
+		AuthFailureWindow:    10,
+
+		EntropyThreshold: 200,
+
+		PressureThreshold: 20.0,
+
+		InterruptThreshold: 100000.0,
+		ForkRateThreshold:  1000.0,
+
+		SMARTWearThreshold: 90.0,
+
+		UpdateCheckHours: 24,
+
+		DockerSocket: "/var/run/docker.sock",
+
+		PodPendingMinutes: 5,
+
+		CollectorTimeout: 15,
+
+		DiskForecastHours: 48,
+
+		PoolFragmentationThreshold: 70,
+
+		ClockOffsetThreshold: 500,
+
+		PingLossThreshold:    20,
+		PingLatencyThreshold: 200,
+
+		CertWarningDays:  30,
+		CertCriticalDays: 7,
+
+		HistoryWindow: 10,
+
+		Interval:    30,
+		MinInterval: 5,
+		MaxInterval: 300,
 	}
 }
@@ -0,0 +1,553 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// updateCheckTimeout bounds one query of the package manager, which may
+// refresh repository metadata over the network first
+const updateCheckTimeout = 10 * time.Minute
+
+// maxUpdatePackages caps the packages listed with pending updates;
+// security updates are listed first
+const maxUpdatePackages = 50
+
+// Package databases whose modification means updates were installed (or
+// removed) since the last check
+var packageDatabases = []string{
+	"/var/lib/dpkg/status",
+	"/var/lib/rpm/rpmdb.sqlite",
+	"/var/lib/rpm/Packages",
+	"/usr/lib/sysimage/rpm/rpmdb.sqlite",
+	"/usr/lib/sysimage/rpm/Packages",
+}
+
+// UpdateMetrics is the updates the OS package manager has pending
+type UpdateMetrics struct {
+	Manager   string    `json:"manager"`    // "apt-get", "dnf", "yum", "zypper" or "winget"
+	CheckedAt time.Time `json:"checked_at"` // when the package manager was last queried
+
+	Pending  int `json:"pending"`
+	Security int `json:"security"` // security updates; advisories on zypper
+
+	// Packages with updates, security updates first, at most
+	// maxUpdatePackages of them
+	Packages []PackageUpdate `json:"packages,omitempty"`
+
+	// Installed updates that take effect after a reboot, and on
+	// Debian and Ubuntu the packages that asked for it
+	RebootRequired bool     `json:"reboot_required"`
+	RebootPackages []string `json:"reboot_packages,omitempty"`
+
+	Error string `json:"error,omitempty"` // why the package manager could not be queried
+}
+
+// PackageUpdate is one package with an update available
+type PackageUpdate struct {
+	Name      string `json:"name"`              // the package Id on winget
+	Current   string `json:"current,omitempty"` // installed version, where the manager tells
+	Available string `json:"available"`
+	Security  bool   `json:"security,omitempty"`
+}
+
+// updateState caches the last query of the package manager between
+// snapshots. Queries run in the background, since they can take minutes.
+type updateState struct {
+	mu      sync.Mutex
+	last    *UpdateMetrics
+	checked time.Time
+	stamp   time.Time     // package databases' modification time when checked
+	running chan struct{} // closed when the running query finishes
+}
+
+func (c Config) validateUpdates() []error {
+	var errs []error
+	if c.UpdateCheckHours < 1 {
+		errs = append(errs, fmt.Errorf("update_check_hours must be at least 1, got %d", c.UpdateCheckHours))
+	}
+	if c.UpdateThreshold < 0 {
+		errs = append(errs, fmt.Errorf("update_threshold must not be negative, got %d", c.UpdateThreshold))
+	}
+	if c.UpdateAlertSchedule != "" {
+		if _, err := parseCron(c.UpdateAlertSchedule); err != nil {
+			errs = append(errs, fmt.Errorf("update_alert_schedule: %w", err))
+		}
+	}
+	return errs
+}
+
+// collectUpdates reports the pending updates, querying the package
+// manager every Config.UpdateCheckHours or as soon as packages were
+// installed. The query runs in the background; when it outlasts the
+// collector timeout, the previous result is reported until it finishes.
+// Hosts without a supported package manager report nothing.
+func (c *Collector) collectUpdates(ctx context.Context) (*UpdateMetrics, error) {
+	u := &c.updates
+	now := c.clock.Now()
+	stamp := packageDatabaseStamp()
+
+	u.mu.Lock()
+	done := u.running
+	every := time.Duration(c.config.UpdateCheckHours) * time.Hour
+	if done == nil && (u.checked.IsZero() || now.Sub(u.checked) >= every || !stamp.Equal(u.stamp)) {
+		done = make(chan struct{})
+		u.running = done
+		go func() {
+			defer close(done)
+			ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+			defer cancel()
+			result := queryPackageManager(ctx)
+			if result != nil {
+				result.CheckedAt = now
+			}
+
+			u.mu.Lock()
+			defer u.mu.Unlock()
+			u.last, u.checked, u.stamp, u.running = result, now, stamp, nil
+		}()
+	}
+	u.mu.Unlock()
+
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.last, nil
+}
+
+// packageDatabaseStamp is the latest modification time of the package
+// databases present
+func packageDatabaseStamp() time.Time {
+	var stamp time.Time
+	for _, path := range packageDatabases {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(stamp) {
+			stamp = info.ModTime()
+		}
+	}
+	return stamp
+}
+
+// queryPackageManager asks the first package manager found for pending
+// updates and whether a reboot is required, or returns nil when there is
+// none
+func queryPackageManager(ctx context.Context) *UpdateMetrics {
+	managers := []string{"apt-get", "dnf", "yum", "zypper"}
+	if runtime.GOOS == "windows" {
+		managers = []string{"winget"}
+	}
+	for _, manager := range managers {
+		path, err := exec.LookPath(manager)
+		if err != nil {
+			continue
+		}
+
+		var packages []PackageUpdate
+		var security int
+		updates := &UpdateMetrics{Manager: manager}
+		switch manager {
+		case "apt-get":
+			packages, err = aptUpdates(ctx, path)
+			updates.RebootRequired, updates.RebootPackages = aptRebootRequired()
+		case "dnf", "yum":
+			packages, err = dnfUpdates(ctx, path)
+			updates.RebootRequired = dnfRebootRequired(ctx, path)
+		case "zypper":
+			packages, security, err = zypperUpdates(ctx, path)
+			updates.RebootRequired = zypperRebootRequired(ctx, path)
+		case "winget":
+			packages, err = wingetUpdates(ctx, path)
+			updates.RebootRequired = windowsRebootRequired(ctx)
+		}
+		if err != nil {
+			updates.Error = err.Error()
+			return updates
+		}
+
+		updates.Pending = len(packages)
+		for _, pkg := range packages {
+			if pkg.Security {
+				security++
+			}
+		}
+		updates.Security = security
+		sort.SliceStable(packages, func(i, j int) bool {
+			return packages[i].Security && !packages[j].Security
+		})
+		if len(packages) > maxUpdatePackages {
+			packages = packages[:maxUpdatePackages]
+		}
+		updates.Packages = packages
+		return updates
+	}
+	return nil
+}
+
+// runPackageManager runs a package manager in the C locale, whose output
+// is parsed. Exit codes in ok, which package managers use to say updates
+// are pending, are not errors.
+func runPackageManager(ctx context.Context, path string, ok []int, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		for _, code := range ok {
+			if exitErr.ExitCode() == code {
+				return out, nil
+			}
+		}
+		if stderr := strings.TrimSpace(string(exitErr.Stderr)); stderr != "" {
+			err = fmt.Errorf("%w: %s", err, truncate(stderr, 200))
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", path, strings.Join(args, " "), err)
+	}
+	return out, nil
+}
+
+// "Inst openssl [3.0.2-0ubuntu1.10] (3.0.2-0ubuntu1.12 Ubuntu:22.04/jammy-updates, Ubuntu:22.04/jammy-security [amd64])"
+var aptInst = regexp.MustCompile(`^Inst (\S+) (?:\[([^\]]*)\] )?\((\S+) ([^\[]*)\[`)
+
+// aptUpdates simulates a full upgrade, which needs no root. It uses the
+// package lists as apt last downloaded them, which apt's daily timer or
+// unattended-upgrades keep fresh. Packages from a -security suite are
+// security updates.
+func aptUpdates(ctx context.Context, path string) ([]PackageUpdate, error) {
+	out, err := runPackageManager(ctx, path, nil, "-s", "-o", "Debug::NoLocking=true", "dist-upgrade")
+	if err != nil {
+		return nil, err
+	}
+	var packages []PackageUpdate
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		match := aptInst.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		packages = append(packages, PackageUpdate{
+			Name:      match[1],
+			Current:   match[2],
+			Available: match[3],
+			Security:  strings.Contains(strings.ToLower(match[4]), "security"),
+		})
+	}
+	return packages, nil
+}
+
+// aptRebootRequired reads the flag file update-notifier's hooks leave
+// when an installed package needs a reboot, and the packages that did
+func aptRebootRequired() (bool, []string) {
+	if _, err := os.Stat("/var/run/reboot-required"); err != nil {
+		return false, nil
+	}
+	data, _ := os.ReadFile("/var/run/reboot-required.pkgs")
+	var packages []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Fields(string(data)) {
+		if !seen[name] {
+			seen[name] = true
+			packages = append(packages, name)
+		}
+	}
+	return true, packages
+}
+
+// dnfUpdates lists updates with check-update, which exits 100 when there
+// are some, and marks those named by a security advisory. yum takes the
+// same arguments.
+func dnfUpdates(ctx context.Context, path string) ([]PackageUpdate, error) {
+	out, err := runPackageManager(ctx, path, []int{100}, "-q", "check-update")
+	if err != nil {
+		return nil, err
+	}
+
+	var packages []PackageUpdate
+	var arches []string
+	var wrapped string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Obsoleting") {
+			break
+		}
+		// Long package names are wrapped onto a line of their own
+		fields := strings.Fields(wrapped + " " + line)
+		wrapped = ""
+		switch len(fields) {
+		case 1:
+			wrapped = fields[0]
+			continue
+		case 3:
+		default:
+			continue
+		}
+		dot := strings.LastIndexByte(fields[0], '.')
+		if dot <= 0 {
+			continue
+		}
+		packages = append(packages, PackageUpdate{Name: fields[0][:dot], Available: fields[1]})
+		arches = append(arches, fields[0][dot+1:])
+	}
+	if len(packages) == 0 {
+		return nil, nil
+	}
+
+	// "RHSA-2024:1234 Important/Sec. openssl-1:3.0.7-25.el9.x86_64"
+	out, err = runPackageManager(ctx, path, nil, "-q", "updateinfo", "list", "--security")
+	if err != nil {
+		// Repositories without advisories, such as CentOS Stream's, leave
+		// every update unflagged rather than failing the check
+		return packages, nil
+	}
+	scanner = bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		nevra := fields[len(fields)-1]
+		for i := range packages {
+			if strings.HasPrefix(nevra, packages[i].Name+"-") && strings.HasSuffix(nevra, "."+arches[i]) {
+				packages[i].Security = true
+			}
+		}
+	}
+	return packages, nil
+}
+
+// dnfRebootRequired runs `needs-restarting -r` from dnf-utils or
+// yum-utils, which exits 1 when the kernel or core libraries were updated
+// since boot. Without it the reboot status is unknown and reported as not
+// required.
+func dnfRebootRequired(ctx context.Context, manager string) bool {
+	path, args := "needs-restarting", []string{"-r"}
+	if _, err := exec.LookPath(path); err != nil {
+		path, args = manager, []string{"needs-restarting", "-r"}
+	}
+	err := exec.CommandContext(ctx, path, args...).Run()
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == 1
+}
+
+// zypperList is the part of zypper's --xmlout used
+type zypperList struct {
+	Updates []struct {
+		Kind     string `xml:"kind,attr"`
+		Name     string `xml:"name,attr"`
+		Edition  string `xml:"edition,attr"`
+		Category string `xml:"category,attr"`
+	} `xml:"update-status>update-list>update"`
+}
+
+// zypperUpdates lists package updates, and counts the security patches
+// (advisories) pending, which zypper doesn't map back to packages
+func zypperUpdates(ctx context.Context, path string) ([]PackageUpdate, int, error) {
+	// zypper exits 100 when updates are needed, 101 for security updates
+	ok := []int{100, 101}
+	out, err := runPackageManager(ctx, path, ok, "-n", "-q", "--xmlout", "list-updates")
+	if err != nil {
+		return nil, 0, err
+	}
+	var list zypperList
+	if err := xml.Unmarshal(out, &list); err != nil {
+		return nil, 0, fmt.Errorf("zypper list-updates: %w", err)
+	}
+	var packages []PackageUpdate
+	for _, update := range list.Updates {
+		if update.Kind == "package" {
+			packages = append(packages, PackageUpdate{Name: update.Name, Available: update.Edition})
+		}
+	}
+
+	out, err = runPackageManager(ctx, path, ok, "-n", "-q", "--xmlout", "list-patches", "--category", "security")
+	if err != nil {
+		return nil, 0, err
+	}
+	list = zypperList{}
+	if err := xml.Unmarshal(out, &list); err != nil {
+		return nil, 0, fmt.Errorf("zypper list-patches: %w", err)
+	}
+	security := 0
+	for _, update := range list.Updates {
+		if update.Kind == "patch" {
+			security++
+		}
+	}
+	return packages, security, nil
+}
+
+// zypperRebootRequired checks the flag file libzypp leaves when an
+// installed update needs a reboot, or asks zypper, which exits 102 then
+func zypperRebootRequired(ctx context.Context, path string) bool {
+	for _, flag := range []string{"/run/reboot-needed", "/var/run/reboot-needed"} {
+		if _, err := os.Stat(flag); err == nil {
+			return true
+		}
+	}
+	err := exec.CommandContext(ctx, path, "-n", "needs-rebooting").Run()
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == 102
+}
+
+// wingetUpdates parses the table `winget upgrade` prints. winget has no
+// notion of security updates, and is only installed for interactive
+// users, not for services running as SYSTEM.
+func wingetUpdates(ctx context.Context, path string) ([]PackageUpdate, error) {
+	out, err := runPackageManager(ctx, path, nil, "upgrade", "--accept-source-agreements", "--disable-interactivity")
+	if err != nil {
+		return nil, err
+	}
+
+	// Columns are found by the header's titles; progress spinners before
+	// it are overwritten with carriage returns
+	var packages []PackageUpdate
+	var columns []int
+	for _, line := range strings.Split(strings.ReplaceAll(string(out), "\r\n", "\n"), "\n") {
+		if i := strings.LastIndexByte(line, '\r'); i >= 0 {
+			line = line[i+1:]
+		}
+		if columns == nil {
+			id, version, available := strings.Index(line, " Id "), strings.Index(line, " Version "), strings.Index(line, " Available")
+			if id > 0 && version > id && available > version {
+				at := func(i int) int { return utf8.RuneCountInString(line[:i+1]) }
+				end := utf8.RuneCountInString(line)
+				if source := strings.Index(line, " Source"); source > available {
+					end = at(source)
+				}
+				columns = []int{at(id), at(version), at(available), end}
+			}
+			continue
+		}
+		runes := []rune(line)
+		if strings.HasPrefix(line, "---") {
+			continue
+		}
+		if len(runes) < columns[2] || strings.TrimSpace(line) == "" {
+			break // "3 upgrades available." follows the table
+		}
+		field := func(from, to int) string {
+			if to > len(runes) {
+				to = len(runes)
+			}
+			return strings.TrimSpace(string(runes[from:to]))
+		}
+		packages = append(packages, PackageUpdate{
+			Name:      field(columns[0], columns[1]),
+			Current:   field(columns[1], columns[2]),
+			Available: field(columns[2], columns[3]),
+		})
+	}
+	return packages, nil
+}
+
+// windowsRebootRequired looks for the registry keys Windows Update and
+// component servicing create while a reboot is pending
+func windowsRebootRequired(ctx context.Context) bool {
+	for _, key := range []string{
+		`HKLM\SOFTWARE\Microsoft\Windows\CurrentVersion\WindowsUpdate\Auto Update\RebootRequired`,
+		`HKLM\SOFTWARE\Microsoft\Windows\CurrentVersion\Component Based Servicing\RebootPending`,
+	} {
+		if exec.CommandContext(ctx, "reg", "query", key).Run() == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUpdates warns about pending security updates, a required reboot,
+// and more than Config.UpdateThreshold pending updates. With
+// Config.UpdateAlertSchedule, say weekly, the alerts are only raised on
+// the snapshot after the schedule fires, as a reminder rather than a
+// standing condition.
+func (a *Analyzer) checkUpdates(metrics *SystemMetrics) []Alert {
+	updates := metrics.Updates
+	if updates == nil || updates.Error != "" {
+		return nil
+	}
+
+	if a.config.UpdateAlertSchedule != "" {
+		schedule, err := parseCron(a.config.UpdateAlertSchedule)
+		if err != nil {
+			return nil
+		}
+		// The schedule fires at the start of a matching minute, which
+		// must fall after the previous snapshot; a week back at most
+		prev := metrics.Timestamp.Add(-time.Minute)
+		if n := a.history.len(); n >= 2 {
+			prev = a.history.at(n - 2).Timestamp
+		}
+		if week := metrics.Timestamp.Add(-7 * 24 * time.Hour); prev.Before(week) {
+			prev = week
+		}
+		fired := false
+		for t := metrics.Timestamp.Truncate(time.Minute); t.After(prev) && !fired; t = t.Add(-time.Minute) {
+			fired = schedule.matches(t.In(time.Local))
+		}
+		if !fired {
+			return nil
+		}
+	}
+
+	alert := func(resource, message string, value, threshold float64) Alert {
+		return Alert{
+			Level:     "warning",
+			Category:  "updates",
+			Resource:  resource,
+			Message:   message,
+			Value:     value,
+			Threshold: threshold,
+			Timestamp: metrics.Timestamp,
+		}
+	}
+
+	var alerts []Alert
+	if updates.Security > 0 {
+		message := fmt.Sprintf("%d security updates pending", updates.Security)
+		var names []string
+		for _, pkg := range updates.Packages {
+			if pkg.Security && len(names) < 5 {
+				names = append(names, pkg.Name)
+			}
+		}
+		if len(names) > 0 {
+			message += fmt.Sprintf(" (%s", strings.Join(names, ", "))
+			if updates.Security > len(names) {
+				message += ", ..."
+			}
+			message += ")"
+		}
+		alerts = append(alerts, alert("security", message, float64(updates.Security), 0))
+	}
+	if updates.RebootRequired {
+		message := "A reboot is required to finish installing updates"
+		if len(updates.RebootPackages) > 0 {
+			message += fmt.Sprintf(" (%s)", strings.Join(updates.RebootPackages, ", "))
+		}
+		alerts = append(alerts, alert("reboot_required", message, 0, 0))
+	}
+	if threshold := a.config.UpdateThreshold; threshold > 0 && updates.Pending >= threshold {
+		alerts = append(alerts, alert("pending", fmt.Sprintf("%d package updates pending via %s", updates.Pending, updates.Manager),
+			float64(updates.Pending), float64(threshold)))
+	}
+	return alerts
+}
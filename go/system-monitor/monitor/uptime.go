@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// bootTimeTolerance absorbs the drift of boot times derived from uptime
+const bootTimeTolerance = time.Minute
+
+// UptimeMetrics is when the host booted
+type UptimeMetrics struct {
+	BootTime    time.Time `json:"boot_time"`
+	UptimeHours float64   `json:"uptime_hours"`
+
+	// The last clean shutdown wtmp recorded before this boot (Linux)
+	LastShutdown time.Time `json:"last_shutdown,omitempty"`
+}
+
+// collectUptime reads the boot time. The last clean shutdown can't change
+// while the host is up, so wtmp is only read once.
+func (c *Collector) collectUptime(ctx context.Context) (*UptimeMetrics, error) {
+	seconds, err := host.BootTimeWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	uptime := &UptimeMetrics{BootTime: time.Unix(int64(seconds), 0).UTC()}
+	uptime.UptimeHours = c.clock.Now().Sub(uptime.BootTime).Hours()
+
+	c.shutdownOnce.Do(func() {
+		c.lastShutdown = lastCleanShutdown(uptime.BootTime)
+	})
+	uptime.LastShutdown = c.lastShutdown
+	return uptime, nil
+}
+
+// checkReboot raises a critical alert when the host booted since the last
+// snapshot seen, in this run or, through State, a previous one, unless a
+// clean shutdown was recorded in between
+func (a *Analyzer) checkReboot(metrics *SystemMetrics) []Alert {
+	uptime := metrics.Uptime
+	if uptime == nil {
+		return nil
+	}
+	prevBoot, lastSeen := a.bootTime, a.lastSeen
+	a.bootTime, a.lastSeen = uptime.BootTime, metrics.Timestamp
+
+	if prevBoot.IsZero() || uptime.BootTime.Sub(prevBoot) < bootTimeTolerance {
+		return nil
+	}
+	if uptime.LastShutdown.After(lastSeen) {
+		return nil
+	}
+
+	// The host went down some time after it was last seen
+	down := uptime.BootTime.Sub(lastSeen)
+	if down < 0 {
+		down = 0
+	}
+	return []Alert{{
+		Level:    "critical",
+		Category: "reboot",
+		Resource: "host",
+		Message: fmt.Sprintf("Host rebooted unexpectedly at %s after %s up; down for up to %s",
+			uptime.BootTime.Format("2006-01-02 15:04 MST"), formatUptime(lastSeen.Sub(prevBoot)), formatUptime(down)),
+		Value:     down.Minutes(),
+		Timestamp: metrics.Timestamp,
+	}}
+}
+
+func formatUptime(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%.1f days", d.Hours()/24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%.1f hours", d.Hours())
+	default:
+		return d.Round(time.Second).String()
+	}
+}
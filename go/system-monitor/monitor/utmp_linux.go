@@ -0,0 +1,172 @@
+//go:build linux
+
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	utmpPath = "/var/run/utmp" // current logins
+	wtmpPath = "/var/log/wtmp" // login and boot history
+	btmpPath = "/var/log/btmp" // failed logins, readable by root only
+)
+
+// glibc's struct utmp, which has the same 384-byte layout on 32- and
+// 64-bit platforms
+const (
+	utmpSize = 384
+
+	utmpRunLevel    = 1
+	utmpUserProcess = 7
+)
+
+// utmpRecord is one record of utmp or wtmp
+type utmpRecord struct {
+	Type int16
+	PID  int32
+	Line string // terminal, "pts/0"; "~" for run level changes
+	User string
+	Host string // remote host, for remote logins
+	Time time.Time
+}
+
+// readUtmp reads every record of a utmp or wtmp file
+func readUtmp(path string) ([]utmpRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]utmpRecord, 0, len(data)/utmpSize)
+	for off := 0; off+utmpSize <= len(data); off += utmpSize {
+		records = append(records, parseUtmp(data[off:off+utmpSize]))
+	}
+	return records, nil
+}
+
+// readUtmpSince reads the records of a utmp or wtmp file from the end back
+// to the first one older than since, so a large btmp under a brute force
+// attack isn't read whole on every snapshot. Records are returned newest
+// first.
+func readUtmpSince(path string, since time.Time) ([]utmpRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	const chunk = 256 * utmpSize
+	var records []utmpRecord
+	buf := make([]byte, chunk)
+	for end := info.Size() / utmpSize * utmpSize; end > 0; {
+		start := end - chunk
+		if start < 0 {
+			start = 0
+		}
+		b := buf[:end-start]
+		if _, err := f.ReadAt(b, start); err != nil && err != io.EOF {
+			return nil, err
+		}
+		for off := len(b) - utmpSize; off >= 0; off -= utmpSize {
+			record := parseUtmp(b[off : off+utmpSize])
+			if record.Time.Before(since) {
+				return records, nil
+			}
+			records = append(records, record)
+		}
+		end = start
+	}
+	return records, nil
+}
+
+// parseUtmp decodes one utmpSize record
+func parseUtmp(b []byte) utmpRecord {
+	text := func(b []byte) string {
+		if i := bytes.IndexByte(b, 0); i >= 0 {
+			b = b[:i]
+		}
+		return string(b)
+	}
+	return utmpRecord{
+		Type: int16(binary.NativeEndian.Uint16(b[0:])),
+		PID:  int32(binary.NativeEndian.Uint32(b[4:])),
+		Line: text(b[8:40]),
+		User: text(b[44:76]),
+		Host: text(b[76:332]),
+		Time: time.Unix(int64(int32(binary.NativeEndian.Uint32(b[340:]))), 0).UTC(),
+	}
+}
+
+// readSessions lists the logins in utmp whose session process is still
+// alive; a crashed sshd can leave its entry behind. Hosts without utmp,
+// where only logind tracks sessions, report none.
+func readSessions(ctx context.Context) ([]LoginSession, error) {
+	records, err := readUtmp(utmpPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sessions []LoginSession
+	for _, record := range records {
+		if record.Type != utmpUserProcess || record.User == "" {
+			continue
+		}
+		if _, err := os.Stat("/proc/" + strconv.Itoa(int(record.PID))); err != nil {
+			continue
+		}
+		sessions = append(sessions, LoginSession{
+			User:     record.User,
+			Terminal: record.Line,
+			Host:     record.Host,
+			Started:  record.Time,
+		})
+	}
+	return sessions, nil
+}
+
+// readFailedLogins reads the failed logins btmp recorded since, newest
+// first. A missing btmp means none were recorded.
+func readFailedLogins(since time.Time) ([]failedLogin, error) {
+	records, err := readUtmpSince(btmpPath, since)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	failures := make([]failedLogin, 0, len(records))
+	for _, record := range records {
+		failures = append(failures, failedLogin{User: record.User, Host: record.Host, Time: record.Time})
+	}
+	return failures, nil
+}
+
+// lastCleanShutdown is the time of the last shutdown wtmp recorded before
+// boot, or zero when there is none or wtmp can't be read
+func lastCleanShutdown(boot time.Time) time.Time {
+	records, err := readUtmp(wtmpPath)
+	if err != nil {
+		return time.Time{}
+	}
+	var last time.Time
+	for _, record := range records {
+		if record.Type == utmpRunLevel && record.User == "shutdown" && record.Time.Before(boot) && record.Time.After(last) {
+			last = record.Time
+		}
+	}
+	return last
+}
@@ -0,0 +1,40 @@
+//go:build !linux
+
+package monitor
+
+import (
+	"context"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// lastCleanShutdown is only supported on Linux, elsewhere every reboot
+// counts as unexpected
+func lastCleanShutdown(boot time.Time) time.Time {
+	return time.Time{}
+}
+
+// readSessions lists logins through gopsutil, which reads utmpx on macOS
+// and the BSDs. Windows isn't supported and reports none.
+func readSessions(ctx context.Context) ([]LoginSession, error) {
+	users, err := host.UsersWithContext(ctx)
+	if err != nil {
+		return nil, nil
+	}
+	sessions := make([]LoginSession, 0, len(users))
+	for _, user := range users {
+		sessions = append(sessions, LoginSession{
+			User:     user.User,
+			Terminal: user.Terminal,
+			Host:     user.Host,
+			Started:  time.Unix(int64(user.Started), 0).UTC(),
+		})
+	}
+	return sessions, nil
+}
+
+// readFailedLogins is only supported on Linux, which records them in btmp
+func readFailedLogins(since time.Time) ([]failedLogin, error) {
+	return nil, nil
+}
@@ -0,0 +1,208 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ProcessWatch is a process that must be running, matched by regular
+// expressions on its name and/or command line. When both are set a
+// process must match both. Limits apply to all matching processes
+// together; zero means no limit.
+type ProcessWatch struct {
+	Name          string  `json:"name"` // label used in alerts
+	Match         string  `json:"match"`
+	CmdlineMatch  string  `json:"cmdline_match"`
+	MinCount      int     `json:"min_count"` // defaults to 1
+	MaxCPUPercent float64 `json:"max_cpu_percent"`
+	MaxMemoryMB   float64 `json:"max_memory_mb"`
+}
+
+// WatchedProcessMetrics is the usage of the processes matching a
+// ProcessWatch. Only the watch's own name is reported, never the matched
+// processes' names or command lines.
+type WatchedProcessMetrics struct {
+	Name       string  `json:"name"`
+	Count      int     `json:"count"`
+	PIDs       []int32 `json:"pids,omitempty"`
+	CPUPercent float64 `json:"cpu_percent"`
+	MemoryMB   float64 `json:"memory_mb"`
+
+	// Open file descriptors of all matches, and the highest share any one
+	// of them uses of its own RLIMIT_NOFILE soft limit (Linux only)
+	OpenFDs   int32   `json:"open_fds,omitempty"`
+	FDPercent float64 `json:"fd_percent,omitempty"`
+	FDLimit   uint64  `json:"fd_limit,omitempty"` // limit of the process at FDPercent
+}
+
+func (w ProcessWatch) minCount() int {
+	if w.MinCount <= 0 {
+		return 1
+	}
+	return w.MinCount
+}
+
+func (w ProcessWatch) validate() error {
+	var errs []error
+	if w.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if w.Match == "" && w.CmdlineMatch == "" {
+		errs = append(errs, errors.New("match or cmdline_match is required"))
+	}
+	for _, pattern := range []string{w.Match, w.CmdlineMatch} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("pattern %q: %w", pattern, err))
+		}
+	}
+	if w.MinCount < 0 || w.MaxCPUPercent < 0 || w.MaxMemoryMB < 0 {
+		errs = append(errs, errors.New("min_count and limits must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// processWatcher is a compiled ProcessWatch
+type processWatcher struct {
+	watch   ProcessWatch
+	name    *regexp.Regexp // nil matches any name
+	cmdline *regexp.Regexp // nil matches any command line
+}
+
+// newProcessWatchers compiles the watches. Invalid patterns are skipped;
+// use Config.Validate to report them.
+func newProcessWatchers(watches []ProcessWatch) []processWatcher {
+	var watchers []processWatcher
+	for _, watch := range watches {
+		if watch.validate() != nil {
+			continue
+		}
+		w := processWatcher{watch: watch}
+		if watch.Match != "" {
+			w.name = regexp.MustCompile(watch.Match)
+		}
+		if watch.CmdlineMatch != "" {
+			w.cmdline = regexp.MustCompile(watch.CmdlineMatch)
+		}
+		watchers = append(watchers, w)
+	}
+	return watchers
+}
+
+// matchWatches sums up the processes matching each watch. Command lines
+// are only read for processes whose name already matched.
+func (c *Collector) matchWatches(ctx context.Context, processes []ProcessMetrics) []WatchedProcessMetrics {
+	if len(c.watchers) == 0 {
+		return nil
+	}
+
+	cmdlines := make(map[int32]string)
+	cmdline := func(pid int32) string {
+		if line, ok := cmdlines[pid]; ok {
+			return line
+		}
+		var line string
+		if p, err := process.NewProcessWithContext(ctx, pid); err == nil {
+			line, _ = p.CmdlineWithContext(ctx)
+		}
+		cmdlines[pid] = line
+		return line
+	}
+
+	watched := make([]WatchedProcessMetrics, len(c.watchers))
+	for i, w := range c.watchers {
+		watched[i].Name = w.watch.Name
+		for _, p := range processes {
+			if w.name != nil && !w.name.MatchString(p.Name) {
+				continue
+			}
+			if w.cmdline != nil && !w.cmdline.MatchString(cmdline(p.PID)) {
+				continue
+			}
+			watched[i].Count++
+			watched[i].PIDs = append(watched[i].PIDs, p.PID)
+			watched[i].CPUPercent += p.CPUPercent
+			watched[i].MemoryMB += p.MemoryMB
+		}
+		addWatchedFileDescriptors(&watched[i])
+	}
+	return watched
+}
+
+// addWatchedFileDescriptors reads the FD counts and limits of a watch's
+// matches. Unlike for other processes they are read whether or not the
+// matches are among the top processes, as a watched service running out of
+// descriptors fails silently.
+func addWatchedFileDescriptors(watched *WatchedProcessMetrics) {
+	processes := make([]ProcessMetrics, len(watched.PIDs))
+	for i, pid := range watched.PIDs {
+		processes[i].PID = pid
+	}
+	addProcessFileDescriptors(processes)
+
+	for _, p := range processes {
+		watched.OpenFDs += p.OpenFDs
+		if p.FDSoftLimit == 0 || p.OpenFDs <= 0 {
+			continue
+		}
+		if percent := float64(p.OpenFDs) / float64(p.FDSoftLimit) * 100; percent > watched.FDPercent {
+			watched.FDPercent, watched.FDLimit = percent, p.FDSoftLimit
+		}
+	}
+}
+
+// checkWatchedProcesses raises critical alerts for watched processes that
+// are missing or over their CPU or memory budget
+func (a *Analyzer) checkWatchedProcesses(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+
+	for _, watched := range metrics.WatchedProcesses {
+		var watch ProcessWatch
+		for _, w := range a.config.ProcessWatches {
+			if w.Name == watched.Name {
+				watch = w
+				break
+			}
+		}
+		if watch.Name == "" {
+			continue
+		}
+
+		alert := func(resource, message string, value, threshold float64) {
+			alerts = append(alerts, Alert{
+				Level:     "critical",
+				Category:  "process",
+				Resource:  resource,
+				Message:   message,
+				Value:     value,
+				Threshold: threshold,
+				Timestamp: metrics.Timestamp,
+			})
+		}
+
+		if want := watch.minCount(); watched.Count < want {
+			if watched.Count == 0 {
+				alert(watch.Name, fmt.Sprintf("Watched process %s is not running", watch.Name), 0, float64(want))
+			} else {
+				alert(watch.Name, fmt.Sprintf("Only %d of at least %d %s processes are running", watched.Count, want, watch.Name),
+					float64(watched.Count), float64(want))
+			}
+			continue
+		}
+
+		// Budgets are separate conditions from the process missing
+		if watch.MaxCPUPercent > 0 && watched.CPUPercent > watch.MaxCPUPercent {
+			alert(watch.Name+"/cpu", fmt.Sprintf("Watched process %s is using %.1f%% CPU (budget %.1f%%)",
+				watch.Name, watched.CPUPercent, watch.MaxCPUPercent), watched.CPUPercent, watch.MaxCPUPercent)
+		}
+		if watch.MaxMemoryMB > 0 && watched.MemoryMB > watch.MaxMemoryMB {
+			alert(watch.Name+"/memory", fmt.Sprintf("Watched process %s is using %.0f MB of memory (budget %.0f MB)",
+				watch.Name, watched.MemoryMB, watch.MaxMemoryMB), watched.MemoryMB, watch.MaxMemoryMB)
+		}
+	}
+
+	return alerts
+}
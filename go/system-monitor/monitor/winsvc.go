@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WindowsServiceMetrics is the state of one service in
+// Config.WindowsServices
+type WindowsServiceMetrics struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name,omitempty"`
+	State       string `json:"state"`                // running, stopped, start_pending, ... or not_found
+	StartType   string `json:"start_type,omitempty"` // automatic, manual, disabled, ...
+	PID         uint32 `json:"pid,omitempty"`
+	ExitCode    uint32 `json:"exit_code,omitempty"` // Win32 exit code of a stopped service
+}
+
+// WindowsEvent is an Error or Critical entry of a Windows event log
+type WindowsEvent struct {
+	Log      string    `json:"log"`
+	Level    string    `json:"level"` // "critical" or "error"
+	Provider string    `json:"provider"`
+	EventID  int       `json:"event_id"`
+	Time     time.Time `json:"time"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// windowsEventLimit caps the events read from each log per snapshot
+const windowsEventLimit = 50
+
+func (c Config) validateWindows() []error {
+	var errs []error
+	for i, name := range c.WindowsServices {
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, fmt.Errorf("windows_services[%d]: name is required", i))
+		}
+	}
+	for i, log := range c.WindowsEventLogs {
+		if strings.TrimSpace(log) == "" {
+			errs = append(errs, fmt.Errorf("windows_event_logs[%d]: name is required", i))
+		}
+	}
+	return errs
+}
+
+// checkWindowsServices alerts critically on configured services that are
+// not running
+func (a *Analyzer) checkWindowsServices(metrics *SystemMetrics) []Alert {
+	var alerts []Alert
+	for _, service := range metrics.WindowsServices {
+		if service.State == "running" {
+			continue
+		}
+		message := fmt.Sprintf("Required service %s is %s", service.Name, strings.ReplaceAll(service.State, "_", " "))
+		if service.StartType == "disabled" {
+			message += " and disabled"
+		}
+		if service.ExitCode != 0 {
+			message += fmt.Sprintf(" (exit code %d)", service.ExitCode)
+		}
+		alerts = append(alerts, Alert{
+			Level:     "critical",
+			Category:  "windows_service",
+			Resource:  service.Name,
+			Message:   message,
+			Timestamp: metrics.Timestamp,
+		})
+	}
+	return alerts
+}
+
+// checkWindowsEvents raises one alert per event log that logged Error or
+// Critical events since the previous snapshot, critical if any was
+// Critical
+func (a *Analyzer) checkWindowsEvents(metrics *SystemMetrics) []Alert {
+	byLog := make(map[string][]WindowsEvent)
+	var logs []string
+	for _, event := range metrics.WindowsEvents {
+		if _, ok := byLog[event.Log]; !ok {
+			logs = append(logs, event.Log)
+		}
+		byLog[event.Log] = append(byLog[event.Log], event)
+	}
+
+	var alerts []Alert
+	for _, log := range logs {
+		events := byLog[log]
+		level := "warning"
+		latest := events[0]
+		for _, event := range events {
+			if event.Level == "critical" {
+				level = "critical"
+			}
+			if event.Time.After(latest.Time) {
+				latest = event
+			}
+		}
+
+		message := fmt.Sprintf("%d error event(s) in the %s event log since the last sample, latest from %s (event %d)",
+			len(events), log, latest.Provider, latest.EventID)
+		if latest.Message != "" {
+			message += ": " + firstLine(latest.Message)
+		}
+		alerts = append(alerts, Alert{
+			Level:     level,
+			Category:  "eventlog",
+			Resource:  log,
+			Message:   message,
+			Value:     float64(len(events)),
+			Timestamp: metrics.Timestamp,
+		})
+	}
+	return alerts
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package monitor
+
+import "context"
+
+// collectWindowsServices is only supported on Windows
+func (c *Collector) collectWindowsServices(ctx context.Context) ([]WindowsServiceMetrics, error) {
+	return nil, nil
+}
+
+// collectWindowsEvents is only supported on Windows
+func (c *Collector) collectWindowsEvents(ctx context.Context) ([]WindowsEvent, error) {
+	return nil, nil
+}
@@ -0,0 +1,158 @@
+//go:build windows
+
+package monitor
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// collectWindowsServices queries the service control manager for the
+// configured services. It asks only for query rights, so it works without
+// administrator privileges.
+func (c *Collector) collectWindowsServices(ctx context.Context) ([]WindowsServiceMetrics, error) {
+	manager, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return nil, fmt.Errorf("opening service manager: %w", err)
+	}
+	defer windows.CloseServiceHandle(manager)
+
+	services := make([]WindowsServiceMetrics, 0, len(c.config.WindowsServices))
+	for _, name := range c.config.WindowsServices {
+		services = append(services, queryWindowsService(manager, name))
+	}
+	return services, nil
+}
+
+func queryWindowsService(manager windows.Handle, name string) WindowsServiceMetrics {
+	metrics := WindowsServiceMetrics{Name: name, State: "not_found"}
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return metrics
+	}
+	handle, err := windows.OpenService(manager, namePtr, windows.SERVICE_QUERY_STATUS|windows.SERVICE_QUERY_CONFIG)
+	if err != nil {
+		return metrics
+	}
+	service := &mgr.Service{Name: name, Handle: handle}
+	defer service.Close()
+
+	if status, err := service.Query(); err == nil {
+		metrics.State = windowsServiceStates[status.State]
+		metrics.PID = status.ProcessId
+		if status.State == svc.Stopped {
+			metrics.ExitCode = status.Win32ExitCode
+		}
+	}
+	if config, err := service.Config(); err == nil {
+		metrics.DisplayName = config.DisplayName
+		metrics.StartType = windowsStartTypes[config.StartType]
+		if config.StartType == mgr.StartAutomatic && config.DelayedAutoStart {
+			metrics.StartType = "automatic_delayed"
+		}
+	}
+	return metrics
+}
+
+var windowsServiceStates = map[svc.State]string{
+	svc.Stopped:         "stopped",
+	svc.StartPending:    "start_pending",
+	svc.StopPending:     "stop_pending",
+	svc.Running:         "running",
+	svc.ContinuePending: "continue_pending",
+	svc.PausePending:    "pause_pending",
+	svc.Paused:          "paused",
+}
+
+var windowsStartTypes = map[uint32]string{
+	windows.SERVICE_BOOT_START:   "boot",
+	windows.SERVICE_SYSTEM_START: "system",
+	mgr.StartAutomatic:           "automatic",
+	mgr.StartManual:              "manual",
+	mgr.StartDisabled:            "disabled",
+}
+
+// collectWindowsEvents reads the Error and Critical events logged since
+// the previous snapshot with wevtutil, which ships with Windows
+func (c *Collector) collectWindowsEvents(ctx context.Context) ([]WindowsEvent, error) {
+	now := c.clock.Now()
+	since := c.eventsSince
+	if since.IsZero() {
+		since = now.Add(-time.Duration(c.config.Interval) * time.Second)
+	}
+
+	var events []WindowsEvent
+	for _, log := range c.config.WindowsEventLogs {
+		logged, err := queryEventLog(ctx, log, since, now)
+		if err != nil {
+			// The same span is read again next time
+			return nil, err
+		}
+		events = append(events, logged...)
+	}
+	c.eventsSince = now
+	return events, nil
+}
+
+// renderedEvent is the part of wevtutil's RenderedXml output we report
+type renderedEvent struct {
+	System struct {
+		Provider struct {
+			Name string `xml:"Name,attr"`
+		} `xml:"Provider"`
+		EventID     int `xml:"EventID"`
+		Level       int `xml:"Level"`
+		TimeCreated struct {
+			SystemTime time.Time `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+	} `xml:"System"`
+	Message string `xml:"RenderingInfo>Message"`
+}
+
+// queryEventLog reads the Error and Critical events of a log logged in
+// (since, until], newest first
+func queryEventLog(ctx context.Context, log string, since, until time.Time) ([]WindowsEvent, error) {
+	const layout = "2006-01-02T15:04:05.000Z"
+	query := fmt.Sprintf("*[System[(Level=1 or Level=2) and TimeCreated[@SystemTime>'%s' and @SystemTime<='%s']]]",
+		since.UTC().Format(layout), until.UTC().Format(layout))
+	out, err := exec.CommandContext(ctx, "wevtutil", "qe", log, "/q:"+query,
+		"/f:RenderedXml", "/rd:true", "/c:"+strconv.Itoa(windowsEventLimit)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("wevtutil qe %s: %w", log, err)
+	}
+
+	// wevtutil prints the events one after another, without a root element
+	var parsed struct {
+		Events []renderedEvent `xml:"Event"`
+	}
+	document := "<Events>" + strings.TrimSpace(string(out)) + "</Events>"
+	if err := xml.Unmarshal([]byte(document), &parsed); err != nil {
+		return nil, fmt.Errorf("parsing %s events: %w", log, err)
+	}
+
+	events := make([]WindowsEvent, 0, len(parsed.Events))
+	for _, event := range parsed.Events {
+		level := "error"
+		if event.System.Level == 1 {
+			level = "critical"
+		}
+		events = append(events, WindowsEvent{
+			Log:      log,
+			Level:    level,
+			Provider: event.System.Provider.Name,
+			EventID:  event.System.EventID,
+			Time:     event.System.TimeCreated.SystemTime,
+			Message:  strings.TrimSpace(event.Message),
+		})
+	}
+	return events, nil
+}
@@ -0,0 +1,409 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"system-monitor/monitor"
+)
+
+// Connection security for EmailConfig.TLS
+const (
+	TLSStartTLS = "starttls"
+	TLSImplicit = "tls"
+	TLSNone     = "none"
+)
+
+// emailTimeout bounds a single SMTP conversation
+const emailTimeout = 30 * time.Second
+
+// EmailConfig configures the SMTP notifier. The password is never part of
+// the task input: it is read from the environment variable PasswordEnv
+// (SMTP_PASSWORD by default) or from PasswordFile, e.g. a mounted secret.
+type EmailConfig struct {
+	Host         string   `json:"host"`
+	Port         int      `json:"port"` // defaults to 465 for tls, 25 for none, else 587
+	TLS          string   `json:"tls"`  // "starttls" (default), "tls" or "none"
+	Username     string   `json:"username"`
+	PasswordEnv  string   `json:"password_env"`
+	PasswordFile string   `json:"password_file"`
+	From         string   `json:"from"`
+	To           []string `json:"to"`
+
+	// Seconds between digests of warnings and resolutions; critical alerts
+	// are mailed immediately. Defaults to 900.
+	DigestInterval int `json:"digest_interval"`
+
+	// Emails sent per hour at most, digests included; further ones are
+	// dropped and reported. Defaults to 12.
+	MaxPerHour int `json:"max_per_hour"`
+}
+
+func (c EmailConfig) withDefaults() EmailConfig {
+	if c.TLS == "" {
+		c.TLS = TLSStartTLS
+	}
+	if c.Port == 0 {
+		switch c.TLS {
+		case TLSImplicit:
+			c.Port = 465
+		case TLSNone:
+			c.Port = 25
+		default:
+			c.Port = 587
+		}
+	}
+	if c.PasswordEnv == "" {
+		c.PasswordEnv = "SMTP_PASSWORD"
+	}
+	if c.DigestInterval == 0 {
+		c.DigestInterval = 900
+	}
+	if c.MaxPerHour == 0 {
+		c.MaxPerHour = 12
+	}
+	return c
+}
+
+// Validate checks the server, addresses and limits, and that a password
+// is available when authenticating
+func (c EmailConfig) Validate() error {
+	c = c.withDefaults()
+
+	var errs []error
+	if c.Host == "" {
+		errs = append(errs, errors.New("host is required"))
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port must be between 1 and 65535, got %d", c.Port))
+	}
+	switch c.TLS {
+	case TLSStartTLS, TLSImplicit, TLSNone:
+	default:
+		errs = append(errs, fmt.Errorf("unknown tls mode %q, expected %q, %q or %q", c.TLS, TLSStartTLS, TLSImplicit, TLSNone))
+	}
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		errs = append(errs, fmt.Errorf("from: invalid address %q", c.From))
+	}
+	if len(c.To) == 0 {
+		errs = append(errs, errors.New("to needs at least one address"))
+	}
+	for _, to := range c.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			errs = append(errs, fmt.Errorf("to: invalid address %q", to))
+		}
+	}
+	if c.DigestInterval < 0 {
+		errs = append(errs, fmt.Errorf("digest_interval must not be negative, got %d", c.DigestInterval))
+	}
+	if c.MaxPerHour < 0 {
+		errs = append(errs, fmt.Errorf("max_per_hour must not be negative, got %d", c.MaxPerHour))
+	}
+	if c.Username != "" {
+		if _, err := c.password(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// password reads the SMTP password from PasswordFile, or else from the
+// PasswordEnv environment variable
+func (c EmailConfig) password() (string, error) {
+	if c.PasswordFile != "" {
+		data, err := os.ReadFile(c.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("password_file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	password, ok := os.LookupEnv(c.PasswordEnv)
+	if !ok {
+		return "", fmt.Errorf("password: environment variable %s is not set", c.PasswordEnv)
+	}
+	return password, nil
+}
+
+// Email mails critical alerts as they arrive and a periodic digest of
+// warnings and resolutions. Mail is sent from a background goroutine so a
+// slow SMTP server never blocks the caller.
+type Email struct {
+	config   EmailConfig
+	password string
+
+	queue chan Digest
+	done  chan struct{}
+
+	// Owned by the delivery goroutine
+	host     string
+	warnings map[string]monitor.Alert // by Alert.Key, latest wins
+	resolved []monitor.Resolution
+	sent     []time.Time // within the last hour, for MaxPerHour
+
+	// OnError is called for every failed or dropped email
+	OnError func(err error)
+}
+
+// NewEmail creates an email notifier and starts its delivery worker
+func NewEmail(config EmailConfig) (*Email, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config = config.withDefaults()
+
+	e := &Email{
+		config:   config,
+		queue:    make(chan Digest, 16),
+		done:     make(chan struct{}),
+		warnings: make(map[string]monitor.Alert),
+	}
+	if config.Username != "" {
+		e.password, _ = config.password()
+	}
+
+	go e.run()
+	return e, nil
+}
+
+// Send queues a digest. If the queue is full the digest is dropped and
+// reported through OnError.
+func (e *Email) Send(digest Digest) {
+	if len(digest.Alerts) == 0 && len(digest.Resolved) == 0 {
+		return
+	}
+
+	select {
+	case e.queue <- digest:
+	default:
+		e.reportError(fmt.Errorf("email queue full, dropping %d alert(s)", len(digest.Alerts)))
+	}
+}
+
+// Close stops accepting digests, mails any pending digest and waits up to
+// timeout for delivery to finish
+func (e *Email) Close(timeout time.Duration) {
+	close(e.queue)
+
+	select {
+	case <-e.done:
+	case <-time.After(timeout):
+		e.reportError(fmt.Errorf("email flush timed out after %s", timeout))
+	}
+}
+
+func (e *Email) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(time.Duration(e.config.DigestInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case digest, ok := <-e.queue:
+			if !ok {
+				e.flushDigest()
+				return
+			}
+			e.handle(digest)
+		case <-ticker.C:
+			e.flushDigest()
+		}
+	}
+}
+
+// handle mails the critical alerts of a digest right away and holds the
+// rest for the next periodic digest
+func (e *Email) handle(digest Digest) {
+	e.host = digest.Host
+
+	critical := digest
+	critical.Alerts = nil
+	critical.Resolved = nil
+	for _, alert := range digest.Alerts {
+		if alert.Level == "critical" {
+			critical.Alerts = append(critical.Alerts, alert)
+		} else {
+			e.warnings[alert.Key()] = alert
+		}
+	}
+	e.resolved = append(e.resolved, digest.Resolved...)
+
+	if len(critical.Alerts) > 0 {
+		subject := fmt.Sprintf("[CRITICAL] %s: %s", digest.Host, critical.Alerts[0].Message)
+		if digest.Incident != "" {
+			subject = fmt.Sprintf("[CRITICAL] %s: %s (%s)", digest.Host, digest.PrimaryCause, digest.Incident)
+		}
+		e.mail(subject, emailBody(critical))
+	}
+}
+
+// flushDigest mails the warnings and resolutions gathered since the last
+// digest, if any
+func (e *Email) flushDigest() {
+	if len(e.warnings) == 0 && len(e.resolved) == 0 {
+		return
+	}
+
+	digest := Digest{Host: e.host, Timestamp: time.Now(), Resolved: e.resolved}
+	for _, alert := range e.warnings {
+		digest.Alerts = append(digest.Alerts, alert)
+	}
+	sort.Slice(digest.Alerts, func(i, j int) bool {
+		return digest.Alerts[i].Timestamp.Before(digest.Alerts[j].Timestamp)
+	})
+	e.warnings = make(map[string]monitor.Alert)
+	e.resolved = nil
+
+	subject := fmt.Sprintf("[DIGEST] %s: %d warning(s), %d resolved", digest.Host, len(digest.Alerts), len(digest.Resolved))
+	e.mail(subject, emailBody(digest))
+}
+
+// mail sends one email unless the hourly limit has been reached
+func (e *Email) mail(subject, body string) {
+	now := time.Now()
+	recent := e.sent[:0]
+	for _, sent := range e.sent {
+		if now.Sub(sent) < time.Hour {
+			recent = append(recent, sent)
+		}
+	}
+	e.sent = recent
+	if e.config.MaxPerHour > 0 && len(e.sent) >= e.config.MaxPerHour {
+		e.reportError(fmt.Errorf("email rate limit of %d per hour reached, dropping %q", e.config.MaxPerHour, subject))
+		return
+	}
+
+	if err := e.deliver(subject, body); err != nil {
+		e.reportError(err)
+		return
+	}
+	e.sent = append(e.sent, now)
+}
+
+func (e *Email) deliver(subject, body string) error {
+	config := e.config
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	tlsConfig := &tls.Config{ServerName: config.Host}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if config.TLS == TLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("email delivery: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(emailTimeout))
+
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("email delivery: %w", err)
+	}
+	defer client.Close()
+
+	if config.TLS == TLSStartTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("email delivery: starttls: %w", err)
+		}
+	}
+	if config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", config.Username, e.password, config.Host)); err != nil {
+			return fmt.Errorf("email delivery: auth: %w", err)
+		}
+	}
+
+	from, _ := mail.ParseAddress(config.From)
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("email delivery: %w", err)
+	}
+	for _, to := range config.To {
+		address, _ := mail.ParseAddress(to)
+		if err := client.Rcpt(address.Address); err != nil {
+			return fmt.Errorf("email delivery: recipient %s: %w", address.Address, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("email delivery: %w", err)
+	}
+	if _, err := w.Write(e.message(subject, body)); err != nil {
+		return fmt.Errorf("email delivery: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("email delivery: %w", err)
+	}
+	return client.Quit()
+}
+
+// message builds the RFC 5322 message
+func (e *Email) message(subject, body string) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	msg.WriteString("\r\n")
+	return msg.Bytes()
+}
+
+// emailBody lists the digest's alerts with their readings, the top
+// processes and the resolved conditions
+func emailBody(d Digest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Host: %s\n", d.Host)
+	if d.Incident != "" {
+		fmt.Fprintf(&b, "Incident: %s (%s)\n", d.Incident, d.PrimaryCause)
+	}
+
+	if len(d.Alerts) > 0 {
+		b.WriteString("\nAlerts:\n")
+	}
+	for _, alert := range d.Alerts {
+		fmt.Fprintf(&b, "  [%s] %s", strings.ToUpper(alert.Level), alert.Category)
+		if alert.Resource != "" {
+			fmt.Fprintf(&b, " %s", alert.Resource)
+		}
+		fmt.Fprintf(&b, ": %s\n    %s at %s\n", alert.Message, alertReading(alert), alert.Timestamp.Format(time.RFC3339))
+	}
+
+	if len(d.TopCPU) > 0 {
+		fmt.Fprintf(&b, "\nTop CPU: %s\n", processList(d.TopCPU, false))
+	}
+	if len(d.TopMemory) > 0 {
+		fmt.Fprintf(&b, "Top memory: %s\n", processList(d.TopMemory, true))
+	}
+
+	if len(d.Resolved) > 0 {
+		b.WriteString("\nResolved:\n")
+	}
+	for _, r := range d.Resolved {
+		fmt.Fprintf(&b, "  %s %s after %s\n", r.Category, r.Resource, r.Duration().Round(time.Second))
+	}
+	return b.String()
+}
+
+func (e *Email) reportError(err error) {
+	if e.OnError != nil {
+		e.OnError(err)
+	}
+}
@@ -0,0 +1,258 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"system-monitor/monitor"
+)
+
+// Chat messages list at most this many alerts; the rest are counted
+const maxChatAlerts = 20
+
+// Embed colors for Discord, by the digest's most severe level
+const (
+	colorCritical = 0xE01E5A
+	colorWarning  = 0xECB22E
+	colorResolved = 0x2EB67D
+)
+
+// detectFormat picks the chat format from well-known webhook hosts
+func detectFormat(url string) string {
+	switch {
+	case strings.Contains(url, "hooks.slack.com"):
+		return FormatSlack
+	case strings.Contains(url, "discord.com/api/webhooks"), strings.Contains(url, "discordapp.com/api/webhooks"):
+		return FormatDiscord
+	case strings.Contains(url, ".webhook.office.com"), strings.Contains(url, ".logic.azure.com"):
+		return FormatTeams
+	}
+	return FormatJSON
+}
+
+// title is the headline of a chat message
+func (d Digest) title() string {
+	if len(d.Alerts) == 0 {
+		return fmt.Sprintf("%s: %d condition(s) resolved", d.Host, len(d.Resolved))
+	}
+	return fmt.Sprintf("%s %s: %d alert(s)", levelIcon(d.level()), d.Host, len(d.Alerts))
+}
+
+// level is the most severe level among the alerts, "resolved" if none
+func (d Digest) level() string {
+	level := "resolved"
+	for _, alert := range d.Alerts {
+		if alert.Level == "critical" {
+			return "critical"
+		}
+		level = alert.Level
+	}
+	return level
+}
+
+func levelIcon(level string) string {
+	switch level {
+	case "critical":
+		return "🔴"
+	case "warning":
+		return "🟠"
+	case "resolved":
+		return "✅"
+	}
+	return "ℹ️"
+}
+
+// chatAlerts returns the alerts to list and how many were left out
+func (d Digest) chatAlerts() ([]monitor.Alert, int) {
+	if len(d.Alerts) <= maxChatAlerts {
+		return d.Alerts, 0
+	}
+	return d.Alerts[:maxChatAlerts], len(d.Alerts) - maxChatAlerts
+}
+
+// alertHeading names an alert's level, category and resource
+func alertHeading(alert monitor.Alert) string {
+	heading := fmt.Sprintf("%s %s %s", levelIcon(alert.Level), strings.ToUpper(alert.Level), alert.Category)
+	if alert.Resource != "" {
+		heading += " · " + alert.Resource
+	}
+	return heading
+}
+
+// alertReading is an alert's value against its threshold
+func alertReading(alert monitor.Alert) string {
+	return fmt.Sprintf("value %.1f, threshold %.1f", alert.Value, alert.Threshold)
+}
+
+// processList describes the top processes, e.g. "java (85.0% CPU), nginx (12.3% CPU)"
+func processList(processes []monitor.ProcessMetrics, byMemory bool) string {
+	parts := make([]string, len(processes))
+	for i, p := range processes {
+		if byMemory {
+			parts[i] = fmt.Sprintf("%s (%.0f MB)", p.Name, p.MemoryMB)
+		} else {
+			parts[i] = fmt.Sprintf("%s (%.1f%% CPU)", p.Name, p.CPUPercent)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// resolvedList describes the resolved conditions, one per line
+func (d Digest) resolvedList() string {
+	lines := make([]string, len(d.Resolved))
+	for i, r := range d.Resolved {
+		lines[i] = fmt.Sprintf("%s %s %s after %s", levelIcon("resolved"), r.Category, r.Resource,
+			r.Duration().Round(time.Second))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// slackPayload renders the digest as Slack Block Kit, with the plain
+// summary as the notification fallback
+func slackPayload(d Digest) map[string]interface{} {
+	mrkdwn := func(text string) map[string]interface{} {
+		return map[string]interface{}{"type": "section", "text": map[string]interface{}{"type": "mrkdwn", "text": text}}
+	}
+
+	blocks := []map[string]interface{}{
+		{"type": "header", "text": map[string]interface{}{"type": "plain_text", "text": d.title()}},
+	}
+	if d.Incident != "" {
+		blocks = append(blocks, mrkdwn(fmt.Sprintf("*Incident %s*: %s", d.Incident, d.PrimaryCause)))
+	}
+
+	alerts, more := d.chatAlerts()
+	for _, alert := range alerts {
+		blocks = append(blocks, mrkdwn(fmt.Sprintf("*%s*\n%s\n_%s_", alertHeading(alert), alert.Message, alertReading(alert))))
+	}
+	if more > 0 {
+		blocks = append(blocks, mrkdwn(fmt.Sprintf("…and %d more alert(s)", more)))
+	}
+
+	var context []string
+	if len(d.TopCPU) > 0 {
+		context = append(context, "*Top CPU:* "+processList(d.TopCPU, false))
+	}
+	if len(d.TopMemory) > 0 {
+		context = append(context, "*Top memory:* "+processList(d.TopMemory, true))
+	}
+	if len(context) > 0 {
+		blocks = append(blocks, map[string]interface{}{"type": "divider"}, mrkdwn(strings.Join(context, "\n")))
+	}
+	if len(d.Resolved) > 0 {
+		blocks = append(blocks, mrkdwn(d.resolvedList()))
+	}
+
+	return map[string]interface{}{
+		"text":   d.Summary(),
+		"blocks": blocks,
+	}
+}
+
+// teamsPayload renders the digest as a Microsoft Teams adaptive card
+func teamsPayload(d Digest) map[string]interface{} {
+	text := func(text string, extra map[string]interface{}) map[string]interface{} {
+		block := map[string]interface{}{"type": "TextBlock", "text": text, "wrap": true}
+		for key, value := range extra {
+			block[key] = value
+		}
+		return block
+	}
+
+	body := []map[string]interface{}{
+		text(d.title(), map[string]interface{}{"size": "Large", "weight": "Bolder"}),
+	}
+	if d.Incident != "" {
+		body = append(body, text(fmt.Sprintf("**Incident %s**: %s", d.Incident, d.PrimaryCause), nil))
+	}
+
+	alerts, more := d.chatAlerts()
+	if len(alerts) > 0 {
+		facts := make([]map[string]interface{}, len(alerts))
+		for i, alert := range alerts {
+			facts[i] = map[string]interface{}{
+				"title": alertHeading(alert),
+				"value": fmt.Sprintf("%s (%s)", alert.Message, alertReading(alert)),
+			}
+		}
+		body = append(body, map[string]interface{}{"type": "FactSet", "facts": facts})
+	}
+	if more > 0 {
+		body = append(body, text(fmt.Sprintf("…and %d more alert(s)", more), map[string]interface{}{"isSubtle": true}))
+	}
+	if len(d.TopCPU) > 0 {
+		body = append(body, text("**Top CPU:** "+processList(d.TopCPU, false), map[string]interface{}{"separator": true}))
+	}
+	if len(d.TopMemory) > 0 {
+		body = append(body, text("**Top memory:** "+processList(d.TopMemory, true), nil))
+	}
+	if len(d.Resolved) > 0 {
+		body = append(body, text(d.resolvedList(), map[string]interface{}{"separator": true}))
+	}
+
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]interface{}{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
+
+// discordPayload renders the digest as a Discord embed, colored by its
+// most severe level
+func discordPayload(d Digest) map[string]interface{} {
+	color := colorResolved
+	switch d.level() {
+	case "critical":
+		color = colorCritical
+	case "warning":
+		color = colorWarning
+	}
+
+	// maxChatAlerts plus the process and resolved fields stays within
+	// Discord's 25 fields per embed
+	alerts, more := d.chatAlerts()
+
+	var fields []map[string]interface{}
+	for _, alert := range alerts {
+		fields = append(fields, map[string]interface{}{
+			"name":  alertHeading(alert),
+			"value": fmt.Sprintf("%s\n*%s*", alert.Message, alertReading(alert)),
+		})
+	}
+	if len(d.TopCPU) > 0 {
+		fields = append(fields, map[string]interface{}{"name": "Top CPU", "value": processList(d.TopCPU, false), "inline": true})
+	}
+	if len(d.TopMemory) > 0 {
+		fields = append(fields, map[string]interface{}{"name": "Top memory", "value": processList(d.TopMemory, true), "inline": true})
+	}
+	if len(d.Resolved) > 0 {
+		fields = append(fields, map[string]interface{}{"name": "Resolved", "value": d.resolvedList()})
+	}
+
+	description := ""
+	if d.Incident != "" {
+		description = fmt.Sprintf("**Incident %s**: %s", d.Incident, d.PrimaryCause)
+	}
+	if more > 0 {
+		description += fmt.Sprintf("\n…and %d more alert(s)", more)
+	}
+
+	return map[string]interface{}{
+		"embeds": []map[string]interface{}{{
+			"title":       d.title(),
+			"description": strings.TrimSpace(description),
+			"color":       color,
+			"fields":      fields,
+			"footer":      map[string]interface{}{"text": "System Monitor on " + d.Host},
+			"timestamp":   d.Timestamp.UTC().Format(time.RFC3339),
+		}},
+	}
+}
@@ -0,0 +1,11 @@
+package notify
+
+import "time"
+
+// Notifier delivers alert digests to people or incident tooling. Send
+// must not block; delivery failures are reported through the notifier's
+// OnError.
+type Notifier interface {
+	Send(digest Digest)
+	Close(timeout time.Duration)
+}
@@ -0,0 +1,456 @@
+package notify
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"system-monitor/monitor"
+)
+
+// DefaultEnterpriseOID roots the SYSTEM-MONITOR-MIB in the experimental
+// arc; sites with their own enterprise number should set EnterpriseOID
+const DefaultEnterpriseOID = "1.3.6.1.3.5571"
+
+// Standard trap varbinds (SNMPv2-MIB)
+const (
+	oidSysUpTime   = "1.3.6.1.2.1.1.3.0"
+	oidSnmpTrapOID = "1.3.6.1.6.3.1.1.4.1.0"
+)
+
+// smAlertLevel values
+const (
+	snmpLevelWarning  = 1
+	snmpLevelCritical = 2
+	snmpLevelCleared  = 3
+)
+
+// SNMPConfig configures the SNMP trap notifier. SNMPv3 passwords are read
+// from the environment variables AuthPasswordEnv and PrivPasswordEnv,
+// never from the task input.
+type SNMPConfig struct {
+	Target        string `json:"target"`         // host[:port], port 162 by default
+	Version       string `json:"version"`        // "2c" (default) or "3"
+	Community     string `json:"community"`      // v2c, "public" by default
+	EnterpriseOID string `json:"enterprise_oid"` // root of the MIB, see DefaultEnterpriseOID
+	MinLevel      string `json:"min_level"`      // "critical" (default) or "warning"
+
+	// SNMPv3 user security: authPriv when a privacy protocol is set,
+	// authNoPriv otherwise. EngineID is this sender's engine ID in hex,
+	// as configured for the user on the trap receiver. BootsFile keeps
+	// the engine's boot count across runs, in the user cache directory
+	// by default.
+	User            string `json:"user"`
+	EngineID        string `json:"engine_id"`
+	BootsFile       string `json:"boots_file"`
+	AuthProtocol    string `json:"auth_protocol"` // "SHA" (default) or "MD5"
+	AuthPasswordEnv string `json:"auth_password_env"`
+	PrivProtocol    string `json:"priv_protocol"` // "AES" or empty for none
+	PrivPasswordEnv string `json:"priv_password_env"`
+}
+
+func (c SNMPConfig) withDefaults() SNMPConfig {
+	if c.Version == "" {
+		c.Version = "2c"
+	}
+	if c.Community == "" {
+		c.Community = "public"
+	}
+	if c.EnterpriseOID == "" {
+		c.EnterpriseOID = DefaultEnterpriseOID
+	}
+	if c.MinLevel == "" {
+		c.MinLevel = "critical"
+	}
+	if c.AuthProtocol == "" {
+		c.AuthProtocol = "SHA"
+	}
+	if c.AuthPasswordEnv == "" {
+		c.AuthPasswordEnv = "SNMP_AUTH_PASSWORD"
+	}
+	if c.PrivPasswordEnv == "" {
+		c.PrivPasswordEnv = "SNMP_PRIV_PASSWORD"
+	}
+	if c.BootsFile == "" && c.Version == "3" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			dir = os.TempDir()
+		}
+		c.BootsFile = filepath.Join(dir, "system-monitor", "snmp-engine-"+strings.ToLower(c.EngineID)+".boots")
+	}
+	if _, _, err := net.SplitHostPort(c.Target); err != nil && c.Target != "" {
+		c.Target = net.JoinHostPort(c.Target, "162")
+	}
+	return c
+}
+
+// Validate checks the target, MIB root and, for SNMPv3, the user, engine
+// ID, protocols and that the passwords are set
+func (c SNMPConfig) Validate() error {
+	c = c.withDefaults()
+
+	var errs []error
+	if c.Target == "" {
+		errs = append(errs, errors.New("target is required"))
+	}
+	if _, err := parseOID(c.EnterpriseOID); err != nil {
+		errs = append(errs, fmt.Errorf("enterprise_oid: %w", err))
+	}
+	if c.MinLevel != "critical" && c.MinLevel != "warning" {
+		errs = append(errs, fmt.Errorf(`min_level must be "critical" or "warning", got %q`, c.MinLevel))
+	}
+
+	switch c.Version {
+	case "2c":
+	case "3":
+		if c.User == "" {
+			errs = append(errs, errors.New("user is required for SNMPv3"))
+		}
+		if id, err := hex.DecodeString(c.EngineID); err != nil || len(id) < 5 || len(id) > 32 {
+			errs = append(errs, fmt.Errorf("engine_id must be 5 to 32 bytes in hex, got %q", c.EngineID))
+		}
+		if c.AuthProtocol != "SHA" && c.AuthProtocol != "MD5" {
+			errs = append(errs, fmt.Errorf(`auth_protocol must be "SHA" or "MD5", got %q`, c.AuthProtocol))
+		}
+		if len(os.Getenv(c.AuthPasswordEnv)) < 8 {
+			errs = append(errs, fmt.Errorf("auth password in $%s must be at least 8 characters", c.AuthPasswordEnv))
+		}
+		switch c.PrivProtocol {
+		case "":
+		case "AES":
+			if len(os.Getenv(c.PrivPasswordEnv)) < 8 {
+				errs = append(errs, fmt.Errorf("privacy password in $%s must be at least 8 characters", c.PrivPasswordEnv))
+			}
+		default:
+			errs = append(errs, fmt.Errorf(`priv_protocol must be "AES" or empty, got %q`, c.PrivProtocol))
+		}
+	default:
+		errs = append(errs, fmt.Errorf(`version must be "2c" or "3", got %q`, c.Version))
+	}
+	return errors.Join(errs...)
+}
+
+// SNMP sends an SNMPv2c or SNMPv3 trap for every alert at or above the
+// configured level and for every such condition that clears. The traps
+// and their varbinds are described by SYSTEM-MONITOR-MIB.txt.
+type SNMP struct {
+	config SNMPConfig
+	conn   net.Conn
+	start  time.Time
+	queue  chan Digest
+	done   chan struct{}
+
+	// SNMPv3 keys localized to the engine ID, and snmpEngineBoots for
+	// this run
+	engineID []byte
+	authKey  []byte
+	privKey  []byte
+	boots    int32
+
+	// OnError is called for every failed or dropped trap
+	OnError func(err error)
+}
+
+// NewSNMP creates an SNMP trap notifier
+func NewSNMP(config SNMPConfig) (*SNMP, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config = config.withDefaults()
+
+	var boots int32
+	if config.Version == "3" {
+		var err error
+		if boots, err = nextEngineBoots(config.BootsFile); err != nil {
+			return nil, fmt.Errorf("snmp: engine boots: %w", err)
+		}
+	}
+
+	conn, err := net.Dial("udp", config.Target)
+	if err != nil {
+		return nil, fmt.Errorf("snmp: %w", err)
+	}
+
+	s := &SNMP{
+		config: config,
+		conn:   conn,
+		start:  time.Now(),
+		queue:  make(chan Digest, 16),
+		done:   make(chan struct{}),
+		boots:  boots,
+	}
+	if config.Version == "3" {
+		s.engineID, _ = hex.DecodeString(config.EngineID)
+		newHash := usmHash(config.AuthProtocol)
+		s.authKey = usmLocalizedKey(newHash, os.Getenv(config.AuthPasswordEnv), s.engineID)
+		if config.PrivProtocol != "" {
+			s.privKey = usmLocalizedKey(newHash, os.Getenv(config.PrivPasswordEnv), s.engineID)
+		}
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// nextEngineBoots increments the snmpEngineBoots kept in path, starting
+// at 1, and returns it. Receivers drop traps whose boots and engine time
+// are older than the last they saw, so a run that restarted the engine
+// time at 0 under the same boots would be ignored. The count stops at
+// 2147483647 as RFC 3414 requires.
+func nextEngineBoots(path string) (int32, error) {
+	var boots int64
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if boots, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32); err != nil || boots < 0 {
+			return 0, fmt.Errorf("%s: not a boot count", path)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return 0, err
+	}
+	if boots < math.MaxInt32 {
+		boots++
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(strconv.FormatInt(boots, 10) + "\n"); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	return int32(boots), os.Rename(tmp.Name(), path)
+}
+
+// Send queues a digest's traps
+func (s *SNMP) Send(digest Digest) {
+	select {
+	case s.queue <- digest:
+	default:
+		s.reportError(fmt.Errorf("snmp queue full, dropping %d alert(s)", len(digest.Alerts)))
+	}
+}
+
+// Close sends pending traps, waiting up to timeout
+func (s *SNMP) Close(timeout time.Duration) {
+	close(s.queue)
+
+	select {
+	case <-s.done:
+		s.conn.Close()
+	case <-time.After(timeout):
+		s.reportError(fmt.Errorf("snmp flush timed out after %s", timeout))
+	}
+}
+
+func (s *SNMP) run() {
+	defer close(s.done)
+
+	for digest := range s.queue {
+		for _, alert := range digest.Alerts {
+			if severityRank(alert.Level) < severityRank(s.config.MinLevel) {
+				continue
+			}
+			level := snmpLevelWarning
+			if alert.Level == "critical" {
+				level = snmpLevelCritical
+			}
+			s.send(snmpNotification(alert.Category), level, digest, alert)
+		}
+		for _, resolution := range digest.Resolved {
+			if severityRank(resolution.Level) < severityRank(s.config.MinLevel) {
+				continue
+			}
+			cleared := monitor.Alert{
+				Category: resolution.Category,
+				Resource: resolution.Resource,
+				Message:  resolution.Message,
+			}
+			s.send(smAlertCleared, snmpLevelCleared, digest, cleared)
+		}
+	}
+}
+
+// severityRank orders alert levels
+func severityRank(level string) int {
+	switch level {
+	case "critical":
+		return 2
+	case "warning":
+		return 1
+	}
+	return 0
+}
+
+// Notifications of the SYSTEM-MONITOR-MIB, under <enterprise>.0
+const (
+	smCPUAlert     = 1
+	smMemoryAlert  = 2
+	smDiskAlert    = 3
+	smOtherAlert   = 4
+	smAlertCleared = 5
+)
+
+// snmpNotification returns the MIB notification for an alert category
+func snmpNotification(category string) int {
+	switch category {
+	case "cpu", "iowait", "steal":
+		return smCPUAlert
+	case "memory", "swap":
+		return smMemoryAlert
+	case "disk", "inodes", "disk_forecast", "smart":
+		return smDiskAlert
+	}
+	return smOtherAlert
+}
+
+// send builds and sends one trap describing alert
+func (s *SNMP) send(notification, level int, digest Digest, alert monitor.Alert) {
+	pdu, err := s.trapPDU(notification, level, digest, alert)
+	if err == nil {
+		var packet []byte
+		if s.config.Version == "3" {
+			packet, err = s.v3Message(pdu)
+		} else {
+			packet = berTLV(berSequence, berInt(berInteger, 1), berString([]byte(s.config.Community)), pdu)
+		}
+		if err == nil {
+			s.conn.SetWriteDeadline(time.Now().Add(time.Second))
+			_, err = s.conn.Write(packet)
+		}
+	}
+	if err != nil {
+		s.reportError(fmt.Errorf("snmp: %w", err))
+	}
+}
+
+// trapPDU builds an SNMPv2-Trap-PDU with the SYSTEM-MONITOR-MIB varbinds
+func (s *SNMP) trapPDU(notification, level int, digest Digest, alert monitor.Alert) ([]byte, error) {
+	root := s.config.EnterpriseOID
+	varbind := func(oid string, value []byte) ([]byte, error) {
+		name, err := berObjectID(oid)
+		if err != nil {
+			return nil, err
+		}
+		return berTLV(berSequence, name, value), nil
+	}
+
+	trapOID, err := berObjectID(fmt.Sprintf("%s.0.%d", root, notification))
+	if err != nil {
+		return nil, err
+	}
+	uptime := time.Since(s.start).Milliseconds() / 10
+
+	type binding struct {
+		oid   string
+		value []byte
+	}
+	bindings := []binding{
+		{oidSysUpTime, berInt(berTimeTicks, uptime&0xffffffff)},
+		{oidSnmpTrapOID, trapOID},
+		{root + ".1.1.0", berInt(berInteger, int64(level))},
+		{root + ".1.2.0", berString([]byte(digest.Host))},
+		{root + ".1.3.0", berString([]byte(alert.Category))},
+		{root + ".1.4.0", berString([]byte(alert.Resource))},
+		{root + ".1.5.0", berString([]byte(alert.Message))},
+		{root + ".1.6.0", berString([]byte(strconv.FormatFloat(alert.Value, 'f', 1, 64)))},
+		{root + ".1.7.0", berString([]byte(strconv.FormatFloat(alert.Threshold, 'f', 1, 64)))},
+		{root + ".1.8.0", berString([]byte(digest.Incident))},
+	}
+
+	var varbinds [][]byte
+	for _, b := range bindings {
+		vb, err := varbind(b.oid, b.value)
+		if err != nil {
+			return nil, err
+		}
+		varbinds = append(varbinds, vb)
+	}
+
+	requestID := make([]byte, 4)
+	rand.Read(requestID)
+	return berTLV(berTrapV2PDU,
+		berInt(berInteger, int64(binary.BigEndian.Uint32(requestID)&0x7fffffff)),
+		berInt(berInteger, 0),
+		berInt(berInteger, 0),
+		berTLV(berSequence, varbinds...),
+	), nil
+}
+
+// v3Message wraps a PDU in an SNMPv3 message secured by the user-based
+// security model, with this sender as the authoritative engine
+func (s *SNMP) v3Message(pdu []byte) ([]byte, error) {
+	boots := s.boots
+	engineTime := int32(time.Since(s.start).Seconds())
+
+	scoped := berTLV(berSequence, berString(s.engineID), berString(nil), pdu)
+	flags := byte(0x01) // auth
+	privParams := []byte{}
+	data := scoped
+	if s.privKey != nil {
+		flags |= 0x02
+		privParams = make([]byte, 8)
+		rand.Read(privParams)
+		encrypted, err := usmEncryptAES(s.privKey, boots, engineTime, privParams, scoped)
+		if err != nil {
+			return nil, err
+		}
+		data = berString(encrypted)
+	}
+
+	msgIDBytes := make([]byte, 4)
+	rand.Read(msgIDBytes)
+	msgID := int64(binary.BigEndian.Uint32(msgIDBytes) & 0x7fffffff)
+
+	security := func(authParams []byte) []byte {
+		return berString(berTLV(berSequence,
+			berString(s.engineID),
+			berInt(berInteger, int64(boots)),
+			berInt(berInteger, int64(engineTime)),
+			berString([]byte(s.config.User)),
+			berString(authParams),
+			berString(privParams),
+		))
+	}
+	message := func(authParams []byte) []byte {
+		return berTLV(berSequence,
+			berInt(berInteger, 3),
+			berTLV(berSequence,
+				berInt(berInteger, msgID),
+				berInt(berInteger, 65507),
+				berString([]byte{flags}),
+				berInt(berInteger, 3), // USM
+			),
+			security(authParams),
+			data,
+		)
+	}
+
+	// The HMAC covers the whole message with zeroed authentication
+	// parameters of the final length
+	authParams := usmAuthenticate(usmHash(s.config.AuthProtocol), s.authKey, message(make([]byte, 12)))
+	return message(authParams), nil
+}
+
+func (s *SNMP) reportError(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
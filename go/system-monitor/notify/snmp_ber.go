@@ -0,0 +1,159 @@
+package notify
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// BER tags used by SNMP messages
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berNull        = 0x05
+	berOID         = 0x06
+	berSequence    = 0x30
+	berTimeTicks   = 0x43
+	berTrapV2PDU   = 0xa7
+)
+
+// berTLV encodes a tag, the length of content and content
+func berTLV(tag byte, content ...[]byte) []byte {
+	var n int
+	for _, c := range content {
+		n += len(c)
+	}
+
+	out := []byte{tag}
+	switch {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n < 0x100:
+		out = append(out, 0x81, byte(n))
+	case n < 0x10000:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	default:
+		out = append(out, 0x83, byte(n>>16), byte(n>>8), byte(n))
+	}
+	for _, c := range content {
+		out = append(out, c...)
+	}
+	return out
+}
+
+// berInt encodes a signed integer in the fewest two's complement bytes
+func berInt(tag byte, v int64) []byte {
+	b := []byte{byte(v)}
+	for v >= 0x80 || v < -0x80 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return berTLV(tag, b)
+}
+
+func berString(s []byte) []byte {
+	return berTLV(berOctetString, s)
+}
+
+// berObjectID encodes a dotted OID such as "1.3.6.1.2.1.1.3.0"
+func berObjectID(oid string) ([]byte, error) {
+	arcs, err := parseOID(oid)
+	if err != nil {
+		return nil, err
+	}
+
+	content := base128(arcs[0]*40 + arcs[1])
+	for _, arc := range arcs[2:] {
+		content = append(content, base128(arc)...)
+	}
+	return berTLV(berOID, content), nil
+}
+
+// parseOID splits a dotted OID into its arcs
+func parseOID(oid string) ([]uint64, error) {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID %q", oid)
+	}
+	arcs := make([]uint64, len(parts))
+	for i, part := range parts {
+		arc, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q", oid)
+		}
+		arcs[i] = arc
+	}
+	if arcs[0] > 2 || (arcs[0] < 2 && arcs[1] >= 40) {
+		return nil, fmt.Errorf("invalid OID %q", oid)
+	}
+	return arcs, nil
+}
+
+func base128(v uint64) []byte {
+	b := []byte{byte(v & 0x7f)}
+	for v >>= 7; v > 0; v >>= 7 {
+		b = append([]byte{byte(v&0x7f) | 0x80}, b...)
+	}
+	return b
+}
+
+// usmHash returns the hash of a USM authentication protocol
+func usmHash(protocol string) func() hash.Hash {
+	if protocol == "MD5" {
+		return md5.New
+	}
+	return sha1.New
+}
+
+// usmLocalizedKey derives a user's key for one engine from a password
+// (RFC 3414, A.2)
+func usmLocalizedKey(newHash func() hash.Hash, password string, engineID []byte) []byte {
+	h := newHash()
+	const size = 1 << 20
+	buf := make([]byte, 64)
+	for written := 0; written < size; written += len(buf) {
+		for i := range buf {
+			buf[i] = password[(written+i)%len(password)]
+		}
+		h.Write(buf)
+	}
+	ku := h.Sum(nil)
+
+	h.Reset()
+	h.Write(ku)
+	h.Write(engineID)
+	h.Write(ku)
+	return h.Sum(nil)
+}
+
+// usmAuthenticate computes the 12 byte HMAC-96 of a whole message whose
+// authentication parameters are still zero (RFC 3414, 6.3 and 7.3)
+func usmAuthenticate(newHash func() hash.Hash, key, message []byte) []byte {
+	mac := hmac.New(newHash, key)
+	mac.Write(message)
+	return mac.Sum(nil)[:12]
+}
+
+// usmEncryptAES encrypts a scoped PDU with AES-128 in CFB mode; the IV is
+// the engine boots and time followed by the salt (RFC 3826, 3.1.2)
+func usmEncryptAES(key []byte, boots, engineTime int32, salt, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	iv := []byte{
+		byte(boots >> 24), byte(boots >> 16), byte(boots >> 8), byte(boots),
+		byte(engineTime >> 24), byte(engineTime >> 16), byte(engineTime >> 8), byte(engineTime),
+	}
+	iv = append(iv, salt...)
+
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(ciphertext, plaintext)
+	return ciphertext, nil
+}
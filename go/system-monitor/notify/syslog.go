@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"system-monitor/monitor"
+)
+
+// syslogFacilities are the facilities SyslogConfig.Facility accepts
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news", "uucp", "cron", "authpriv", "ftp",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// SyslogConfig configures the syslog notifier. Without a network the
+// local syslog daemon is used; otherwise messages go to the remote
+// collector at address over udp or tcp.
+type SyslogConfig struct {
+	Network  string `json:"network"`
+	Address  string `json:"address"`
+	Facility string `json:"facility"` // daemon by default
+	Tag      string `json:"tag"`      // system-monitor by default
+}
+
+func (c SyslogConfig) withDefaults() SyslogConfig {
+	if c.Facility == "" {
+		c.Facility = "daemon"
+	}
+	if c.Tag == "" {
+		c.Tag = "system-monitor"
+	}
+	return c
+}
+
+// Validate checks the transport and facility
+func (c SyslogConfig) Validate() error {
+	c = c.withDefaults()
+	switch c.Network {
+	case "":
+		if c.Address != "" {
+			return fmt.Errorf(`address requires network "udp" or "tcp"`)
+		}
+	case "udp", "tcp":
+		if c.Address == "" {
+			return fmt.Errorf("network %s requires an address", c.Network)
+		}
+	default:
+		return fmt.Errorf(`network must be "udp" or "tcp", got %q`, c.Network)
+	}
+
+	for _, facility := range syslogFacilities {
+		if c.Facility == facility {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown facility %q", c.Facility)
+}
+
+// syslogAlert formats an alert as key=value pairs, which SIEM pipelines
+// parse without a custom grok pattern
+func syslogAlert(digest Digest, alert monitor.Alert) string {
+	fields := []string{
+		"level=" + alert.Level,
+		"category=" + alert.Category,
+	}
+	if alert.Resource != "" {
+		fields = append(fields, "resource="+strconv.Quote(alert.Resource))
+	}
+	fields = append(fields,
+		"value="+strconv.FormatFloat(alert.Value, 'f', 1, 64),
+		"threshold="+strconv.FormatFloat(alert.Threshold, 'f', 1, 64),
+		"host="+digest.Host,
+	)
+	if digest.Incident != "" {
+		fields = append(fields, "incident="+digest.Incident)
+	}
+	fields = append(fields, "msg="+strconv.Quote(alert.Message))
+	return strings.Join(fields, " ")
+}
+
+// syslogResolution formats a resolution like syslogAlert
+func syslogResolution(digest Digest, resolution monitor.Resolution) string {
+	fields := []string{
+		"level=resolved",
+		"category=" + resolution.Category,
+	}
+	if resolution.Resource != "" {
+		fields = append(fields, "resource="+strconv.Quote(resolution.Resource))
+	}
+	fields = append(fields,
+		"duration="+strconv.FormatFloat(resolution.Duration().Seconds(), 'f', 0, 64)+"s",
+		"host="+digest.Host,
+		"msg="+strconv.Quote(resolution.Message),
+	)
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,25 @@
+//go:build windows || plan9
+
+package notify
+
+import (
+	"errors"
+	"time"
+)
+
+// Syslog is only supported on Unix-like systems
+type Syslog struct {
+	// OnError is called for every failed or dropped message
+	OnError func(err error)
+}
+
+// NewSyslog always fails: there is no syslog on this platform
+func NewSyslog(config SyslogConfig) (*Syslog, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
+
+// Send does nothing
+func (s *Syslog) Send(digest Digest) {}
+
+// Close does nothing
+func (s *Syslog) Close(timeout time.Duration) {}
@@ -0,0 +1,104 @@
+//go:build !windows && !plan9
+
+package notify
+
+import (
+	"fmt"
+	"log/syslog"
+	"time"
+)
+
+var syslogFacilityPriorities = map[string]syslog.Priority{
+	"kern": syslog.LOG_KERN, "user": syslog.LOG_USER, "mail": syslog.LOG_MAIL, "daemon": syslog.LOG_DAEMON,
+	"auth": syslog.LOG_AUTH, "syslog": syslog.LOG_SYSLOG, "lpr": syslog.LOG_LPR, "news": syslog.LOG_NEWS,
+	"uucp": syslog.LOG_UUCP, "cron": syslog.LOG_CRON, "authpriv": syslog.LOG_AUTHPRIV, "ftp": syslog.LOG_FTP,
+	"local0": syslog.LOG_LOCAL0, "local1": syslog.LOG_LOCAL1, "local2": syslog.LOG_LOCAL2, "local3": syslog.LOG_LOCAL3,
+	"local4": syslog.LOG_LOCAL4, "local5": syslog.LOG_LOCAL5, "local6": syslog.LOG_LOCAL6, "local7": syslog.LOG_LOCAL7,
+}
+
+// Syslog writes every alert and resolution to syslog, one message each:
+// critical alerts at LOG_CRIT, warnings at LOG_WARNING, resolutions at
+// LOG_NOTICE and anything else at LOG_INFO
+type Syslog struct {
+	writer *syslog.Writer
+	queue  chan Digest
+	done   chan struct{}
+
+	// OnError is called for every failed or dropped message
+	OnError func(err error)
+}
+
+// NewSyslog connects to the syslog daemon or remote collector
+func NewSyslog(config SyslogConfig) (*Syslog, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config = config.withDefaults()
+
+	writer, err := syslog.Dial(config.Network, config.Address, syslogFacilityPriorities[config.Facility]|syslog.LOG_INFO, config.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("syslog: %w", err)
+	}
+
+	s := &Syslog{
+		writer: writer,
+		queue:  make(chan Digest, 16),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Send queues a digest for writing
+func (s *Syslog) Send(digest Digest) {
+	select {
+	case s.queue <- digest:
+	default:
+		s.reportError(fmt.Errorf("syslog queue full, dropping %d alert(s)", len(digest.Alerts)))
+	}
+}
+
+// Close writes pending digests, waiting up to timeout, and disconnects
+func (s *Syslog) Close(timeout time.Duration) {
+	close(s.queue)
+
+	select {
+	case <-s.done:
+		s.writer.Close()
+	case <-time.After(timeout):
+		s.reportError(fmt.Errorf("syslog flush timed out after %s", timeout))
+	}
+}
+
+func (s *Syslog) run() {
+	defer close(s.done)
+
+	for digest := range s.queue {
+		for _, alert := range digest.Alerts {
+			message := syslogAlert(digest, alert)
+			var err error
+			switch alert.Level {
+			case "critical":
+				err = s.writer.Crit(message)
+			case "warning":
+				err = s.writer.Warning(message)
+			default:
+				err = s.writer.Info(message)
+			}
+			if err != nil {
+				s.reportError(fmt.Errorf("syslog: %w", err))
+			}
+		}
+		for _, resolution := range digest.Resolved {
+			if err := s.writer.Notice(syslogResolution(digest, resolution)); err != nil {
+				s.reportError(fmt.Errorf("syslog: %w", err))
+			}
+		}
+	}
+}
+
+func (s *Syslog) reportError(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
@@ -0,0 +1,274 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"system-monitor/monitor"
+)
+
+// Payload formats understood by Webhook
+const (
+	FormatJSON    = "json"
+	FormatSlack   = "slack"
+	FormatTeams   = "teams"
+	FormatDiscord = "discord"
+)
+
+// Digest is the batch of alerts raised during a single monitoring interval
+type Digest struct {
+	Host      string          `json:"host"`
+	Timestamp time.Time       `json:"timestamp"`
+	Alerts    []monitor.Alert `json:"alerts"`
+
+	// Incident the alerts belong to, if any
+	Incident     string `json:"incident,omitempty"`
+	PrimaryCause string `json:"primary_cause,omitempty"`
+
+	// Conditions that cleared during the interval
+	Resolved []monitor.Resolution `json:"resolved,omitempty"`
+
+	// Heaviest processes at the time, the likely offenders
+	TopCPU    []monitor.ProcessMetrics `json:"top_cpu,omitempty"`
+	TopMemory []monitor.ProcessMetrics `json:"top_memory,omitempty"`
+}
+
+// Summary returns a short human readable description of the digest
+func (d Digest) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "System Monitor on %s: %d alert(s)", d.Host, len(d.Alerts))
+	if d.Incident != "" {
+		fmt.Fprintf(&b, " in incident %s (%s)", d.Incident, d.PrimaryCause)
+	}
+	for _, alert := range d.Alerts {
+		fmt.Fprintf(&b, "\n• [%s] %s: %s", strings.ToUpper(alert.Level), alert.Category, alert.Message)
+	}
+	for _, resolution := range d.Resolved {
+		fmt.Fprintf(&b, "\n• [RESOLVED] %s %s after %s", resolution.Category, resolution.Resource,
+			resolution.Duration().Round(time.Second))
+	}
+	return b.String()
+}
+
+// Webhook posts alert digests to an HTTP endpoint. Deliveries happen on a
+// background goroutine so a slow or dead endpoint never blocks the caller.
+type Webhook struct {
+	url        string
+	authHeader string
+	format     string
+	template   *template.Template
+	client     *http.Client
+
+	queue chan Digest
+	wg    sync.WaitGroup
+
+	// OnError is called for every failed or dropped delivery
+	OnError func(err error)
+}
+
+// NewWebhook creates a webhook sink and starts its delivery worker.
+// An empty format is detected from the URL: Slack, Microsoft Teams and
+// Discord webhook URLs get their chat format, everything else gets plain
+// JSON.
+func NewWebhook(url, authHeader, format string) *Webhook {
+	return newWebhook(url, authHeader, format, nil)
+}
+
+func newWebhook(url, authHeader, format string, tmpl *template.Template) *Webhook {
+	if format == "" {
+		format = detectFormat(url)
+	}
+
+	w := &Webhook{
+		url:        url,
+		authHeader: authHeader,
+		format:     format,
+		template:   tmpl,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		queue:      make(chan Digest, 16),
+	}
+
+	w.wg.Add(1)
+	go w.run()
+
+	return w
+}
+
+// WebhookConfig describes one webhook endpoint
+type WebhookConfig struct {
+	URL        string `json:"url"`
+	AuthHeader string `json:"auth_header"`
+	Format     string `json:"format"`
+
+	// Template renders the request body from the Digest with text/template,
+	// replacing Format. The json function encodes a value, e.g.
+	// {"summary": {{json .Summary}}, "count": {{len .Alerts}}}
+	Template string `json:"template"`
+}
+
+// templateFuncs are available to webhook templates
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"upper": strings.ToUpper,
+}
+
+// Validate checks the URL, format and template
+func (c WebhookConfig) Validate() error {
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL, got %q", c.URL)
+	}
+	switch c.Format {
+	case "", FormatJSON, FormatSlack, FormatTeams, FormatDiscord:
+	default:
+		return fmt.Errorf("unknown format %q, expected %q, %q, %q or %q", c.Format,
+			FormatJSON, FormatSlack, FormatTeams, FormatDiscord)
+	}
+	if _, err := c.parseTemplate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Endpoint returns the URL's scheme and host, for logging without the
+// secret tokens that webhook URLs often carry in their path
+func (c WebhookConfig) Endpoint() string {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+func (c WebhookConfig) parseTemplate() (*template.Template, error) {
+	if c.Template == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("webhook").Funcs(templateFuncs).Parse(c.Template)
+	if err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// New creates a webhook sink from the configuration and starts its
+// delivery worker
+func (c WebhookConfig) New() (*Webhook, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	tmpl, _ := c.parseTemplate()
+	return newWebhook(c.URL, c.AuthHeader, c.Format, tmpl), nil
+}
+
+// Send queues a digest for delivery. If the queue is full the digest is
+// dropped and reported through OnError.
+func (w *Webhook) Send(digest Digest) {
+	if len(digest.Alerts) == 0 && len(digest.Resolved) == 0 {
+		return
+	}
+
+	select {
+	case w.queue <- digest:
+	default:
+		w.reportError(fmt.Errorf("webhook queue full, dropping %d alert(s)", len(digest.Alerts)))
+	}
+}
+
+// Close stops accepting digests and waits up to timeout for queued
+// deliveries to finish
+func (w *Webhook) Close(timeout time.Duration) {
+	close(w.queue)
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		w.reportError(fmt.Errorf("webhook flush timed out after %s", timeout))
+	}
+}
+
+func (w *Webhook) run() {
+	defer w.wg.Done()
+	for digest := range w.queue {
+		if err := w.deliver(digest); err != nil {
+			w.reportError(err)
+		}
+	}
+}
+
+func (w *Webhook) deliver(digest Digest) error {
+	body, err := w.encode(digest)
+	if err != nil {
+		return fmt.Errorf("encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.authHeader != "" {
+		req.Header.Set("Authorization", w.authHeader)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook delivery: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook delivery: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (w *Webhook) encode(digest Digest) ([]byte, error) {
+	if w.template != nil {
+		var body bytes.Buffer
+		if err := w.template.Execute(&body, digest); err != nil {
+			return nil, err
+		}
+		return body.Bytes(), nil
+	}
+
+	switch w.format {
+	case FormatSlack:
+		return json.Marshal(slackPayload(digest))
+	case FormatTeams:
+		return json.Marshal(teamsPayload(digest))
+	case FormatDiscord:
+		return json.Marshal(discordPayload(digest))
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"text":          digest.Summary(),
+		"host":          digest.Host,
+		"timestamp":     digest.Timestamp,
+		"alerts":        digest.Alerts,
+		"incident":      digest.Incident,
+		"primary_cause": digest.PrimaryCause,
+		"resolved":      digest.Resolved,
+	})
+}
+
+func (w *Webhook) reportError(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+	}
+}
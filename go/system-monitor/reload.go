@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"system-monitor/monitor"
+
+	eywa "github.com/neyho/eywa-go"
+)
+
+// liveConfig is the running monitor's configuration. Remote commands and
+// config reloads change it from their own goroutines.
+type liveConfig struct {
+	mu     sync.Mutex
+	mon    *monitor.Monitor
+	config monitor.Config
+}
+
+func newLiveConfig(mon *monitor.Monitor) *liveConfig {
+	return &liveConfig{mon: mon, config: mon.Config()}
+}
+
+// update applies fn to a copy of the current config and hands the result
+// to the monitor. The copy shares no maps or slices with the running
+// config, so fn may modify it freely.
+func (l *liveConfig) update(fn func(config *monitor.Config) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current, err := json.Marshal(l.config)
+	if err != nil {
+		return err
+	}
+	var config monitor.Config
+	if err := json.Unmarshal(current, &config); err != nil {
+		return err
+	}
+	if err := fn(&config); err != nil {
+		return err
+	}
+
+	if err := l.mon.SetConfig(config); err != nil {
+		return err
+	}
+	l.config = config
+	return nil
+}
+
+// configReloader re-reads the task input and its config file, on SIGHUP
+// or when the file changes, and applies the resulting thresholds without
+// restarting the loop. Settings only read at startup, such as collectors,
+// exporters and notifiers, keep their values.
+type configReloader struct {
+	task       interface{}
+	configPath string // the -config flag
+	live       *liveConfig
+
+	// Config file in use, the input's config_file or else configPath
+	file     string
+	modified time.Time
+}
+
+func newConfigReloader(task interface{}, configPath, file string, live *liveConfig) *configReloader {
+	r := &configReloader{task: task, configPath: configPath, live: live, file: file}
+	if info, err := os.Stat(file); err == nil {
+		r.modified = info.ModTime()
+	}
+	return r
+}
+
+// run reloads on every signal from hangup and, when every is positive,
+// whenever the config file's modification time changes, until ctx is
+// cancelled
+func (r *configReloader) run(ctx context.Context, hangup <-chan os.Signal, every time.Duration) {
+	var tick <-chan time.Time
+	if every > 0 && r.file != "" {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			r.reload("SIGHUP")
+		case <-tick:
+			info, err := os.Stat(r.file)
+			if err != nil || info.ModTime().Equal(r.modified) {
+				continue
+			}
+			r.modified = info.ModTime()
+			r.reload("config file changed")
+		}
+	}
+}
+
+// reload rebuilds the config and applies it, keeping the running config
+// when the new one is invalid
+func (r *configReloader) reload(reason string) {
+	input, err := ParseTaskInput(r.task, r.configPath)
+	if err != nil {
+		eywa.Warn("Config reload failed, keeping the running config", map[string]interface{}{
+			"reason": reason,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	config, err := buildConfig(input)
+	err = errors.Join(err, input.validate())
+	if err == nil {
+		err = r.live.update(func(current *monitor.Config) error {
+			*current = config
+			return nil
+		})
+	}
+	if err != nil {
+		eywa.Warn("Config reload failed, keeping the running config", map[string]interface{}{
+			"reason": reason,
+			"error":  err.Error(),
+			"errors": inputErrors(err),
+		})
+		return
+	}
+
+	eywa.Info(fmt.Sprintf("Config reloaded (%s)", reason), map[string]interface{}{
+		"config": config,
+	})
+}
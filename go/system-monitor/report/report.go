@@ -0,0 +1,177 @@
+// Package report renders a monitor.Summary as a self-contained HTML
+// health report: charts are inline SVG and styles are embedded, so the
+// file can be mailed, attached or printed to PDF as is.
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"system-monitor/monitor"
+)
+
+// Chart dimensions in SVG user units
+const (
+	chartWidth  = 720
+	chartHeight = 160
+	chartMargin = 32
+)
+
+// chart is one trend chart of the report
+type chart struct {
+	Title string
+	SVG   template.HTML
+}
+
+// HTML writes the report for summary to w
+func HTML(w io.Writer, summary monitor.Summary) error {
+	charts := []chart{
+		{Title: "CPU usage (%)", SVG: svgChart(summary.CPU, 100)},
+		{Title: "Memory usage (%)", SVG: svgChart(summary.Memory, 100)},
+		{Title: "Load average (1 min)", SVG: svgChart(summary.Load, 0)},
+	}
+
+	mounts := make([]string, 0, len(summary.Disks))
+	for mount := range summary.Disks {
+		mounts = append(mounts, mount)
+	}
+	sort.Strings(mounts)
+	for _, mount := range mounts {
+		charts = append(charts, chart{Title: "Disk usage " + mount + " (%)", SVG: svgChart(summary.Disks[mount], 100)})
+	}
+
+	return page.Execute(w, map[string]interface{}{
+		"Summary":   summary,
+		"Grade":     monitor.HealthGrade(summary.AverageHealth),
+		"Charts":    charts,
+		"Generated": time.Now().UTC(),
+	})
+}
+
+// svgChart draws the average of each point as a line over a band from
+// its minimum to its maximum. The y axis runs from zero to ceiling, or
+// to the highest maximum when ceiling is zero.
+func svgChart(points []monitor.SeriesPoint, ceiling float64) template.HTML {
+	if len(points) == 0 {
+		return template.HTML(`<p class="empty">No data</p>`)
+	}
+
+	top := ceiling
+	if top == 0 {
+		for _, p := range points {
+			top = max(top, p.Max)
+		}
+		if top == 0 {
+			top = 1
+		}
+	}
+
+	from, to := points[0].Time, points[len(points)-1].Time
+	span := to.Sub(from).Seconds()
+	plotWidth := float64(chartWidth - 2*chartMargin)
+	plotHeight := float64(chartHeight - 2*chartMargin)
+
+	x := func(t time.Time) float64 {
+		if span == 0 {
+			return chartMargin + plotWidth/2
+		}
+		return chartMargin + t.Sub(from).Seconds()/span*plotWidth
+	}
+	y := func(v float64) float64 {
+		return chartMargin + plotHeight - math.Min(v, top)/top*plotHeight
+	}
+
+	var band, line []string
+	for _, p := range points {
+		band = append(band, fmt.Sprintf("%.1f,%.1f", x(p.Time), y(p.Max)))
+		line = append(line, fmt.Sprintf("%.1f,%.1f", x(p.Time), y(p.Avg)))
+	}
+	for i := len(points) - 1; i >= 0; i-- {
+		band = append(band, fmt.Sprintf("%.1f,%.1f", x(points[i].Time), y(points[i].Min)))
+	}
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">`, chartWidth, chartHeight)
+	for _, fraction := range []float64{0, 0.5, 1} {
+		gy := y(top * fraction)
+		fmt.Fprintf(&svg, `<line class="grid" x1="%d" y1="%.1f" x2="%d" y2="%.1f"/>`, chartMargin, gy, chartWidth-chartMargin, gy)
+		fmt.Fprintf(&svg, `<text class="axis" x="%d" y="%.1f" text-anchor="end">%s</text>`, chartMargin-4, gy+4, axisLabel(top*fraction))
+	}
+	fmt.Fprintf(&svg, `<polygon class="band" points="%s"/>`, strings.Join(band, " "))
+	fmt.Fprintf(&svg, `<polyline class="line" points="%s"/>`, strings.Join(line, " "))
+	fmt.Fprintf(&svg, `<text class="axis" x="%d" y="%d">%s</text>`, chartMargin, chartHeight-8, from.Format("Jan 2 15:04"))
+	fmt.Fprintf(&svg, `<text class="axis" x="%d" y="%d" text-anchor="end">%s</text>`, chartWidth-chartMargin, chartHeight-8, to.Format("Jan 2 15:04"))
+	svg.WriteString(`</svg>`)
+
+	// Every interpolated value is a number or a formatted time
+	return template.HTML(svg.String())
+}
+
+func axisLabel(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.1f", v)
+}
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"time": func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 UTC") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Health report: {{.Summary.Host}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1d1d1f; max-width: 800px; margin: 2em auto; padding: 0 1em; }
+h1 { margin-bottom: 0.2em; }
+.period, .empty, .generated { color: #6e6e73; }
+.scores { display: flex; gap: 1em; margin: 1.5em 0; }
+.score { flex: 1; border: 1px solid #d2d2d7; border-radius: 8px; padding: 0.8em; }
+.score b { display: block; font-size: 1.6em; }
+svg { width: 100%; height: auto; }
+.grid { stroke: #e5e5ea; }
+.axis { font-size: 10px; fill: #6e6e73; }
+.band { fill: #0a84ff; fill-opacity: 0.15; }
+.line { fill: none; stroke: #0a84ff; stroke-width: 1.5; }
+table { width: 100%; border-collapse: collapse; }
+th, td { text-align: left; padding: 0.4em; border-bottom: 1px solid #e5e5ea; vertical-align: top; }
+.critical { color: #d70015; font-weight: bold; }
+.warning { color: #c93400; font-weight: bold; }
+@media print { h2 { break-before: auto; } .chart { break-inside: avoid; } }
+</style>
+</head>
+<body>
+<h1>Health report: {{.Summary.Host}}</h1>
+<p class="period">{{time .Summary.From}} to {{time .Summary.To}}, {{.Summary.Snapshots}} snapshots</p>
+
+<div class="scores">
+<div class="score">Average health<b>{{printf "%.0f" .Summary.AverageHealth}} ({{.Grade}})</b></div>
+<div class="score">Worst health<b>{{printf "%.0f" .Summary.WorstHealth}}</b></div>
+<div class="score">Alert conditions<b>{{len .Summary.TopAlerts}}</b></div>
+</div>
+
+<h2>Trends</h2>
+{{range .Charts}}<div class="chart"><h3>{{.Title}}</h3>{{.SVG}}</div>
+{{end}}
+<h2>Top alerts</h2>
+{{if .Summary.TopAlerts}}<table>
+<tr><th>Level</th><th>Condition</th><th>Raised</th><th>Peak</th><th>Last seen</th></tr>
+{{range .Summary.TopAlerts}}<tr><td class="{{.Level}}">{{.Level}}</td><td>{{.Category}}{{if .Resource}} · {{.Resource}}{{end}}<br>{{.Message}}</td><td>{{.Count}}×</td><td>{{printf "%.1f" .Peak}}</td><td>{{time .Last}}</td></tr>
+{{end}}</table>
+{{else}}<p class="empty">No alerts were raised.</p>
+{{end}}
+<h2>Recommendations</h2>
+{{if .Summary.Recommendations}}<ul>
+{{range .Summary.Recommendations}}<li>{{.}}</li>
+{{end}}</ul>
+{{else}}<p class="empty">Nothing to recommend.</p>
+{{end}}
+<p class="generated">Generated by system-monitor at {{time .Generated}}</p>
+</body>
+</html>
+`))
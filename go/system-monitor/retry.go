@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	eywa "github.com/neyho/eywa-go"
+)
+
+// retryPolicy bounds retries of a failing call: at most attempts calls,
+// waiting a random time of up to base, 2*base, 4*base, ... capped at max
+// between them
+type retryPolicy struct {
+	attempts int
+	base     time.Duration
+	max      time.Duration
+}
+
+// graphQLRetry rides out EYWA restarts and network blips of a few seconds
+// without holding up a monitoring iteration for long
+var graphQLRetry = retryPolicy{attempts: 4, base: 500 * time.Millisecond, max: 8 * time.Second}
+
+// startupRetry polls for the task while the EYWA pipe comes up; GetTask
+// fails until it does
+var startupRetry = retryPolicy{attempts: 30, base: 100 * time.Millisecond, max: 2 * time.Second}
+
+// backoff returns the wait before the given retry, counting from 1. Full
+// jitter keeps robots on many hosts from retrying in lockstep.
+func (p retryPolicy) backoff(retry int) time.Duration {
+	limit := p.max
+	if shift := retry - 1; shift < 32 {
+		limit = min(p.base<<shift, p.max)
+	}
+	return time.Duration(rand.Int63n(int64(limit) + 1))
+}
+
+// do calls fn until it succeeds or the attempts run out. Cancelling ctx
+// stops further retries but never the first attempt, so shutdown still
+// gets a chance to record its final state.
+func (p retryPolicy) do(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= p.attempts {
+			break
+		}
+
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+	if err != nil && p.attempts > 1 {
+		return fmt.Errorf("after %d attempts: %w", p.attempts, err)
+	}
+	return err
+}
+
+// getTask fetches the task once the EYWA pipe opened by OpenPipe is
+// ready, retrying under startupRetry. A GetTask call that never returns
+// is abandoned when timeout expires.
+func getTask(timeout time.Duration) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type result struct {
+		task interface{}
+		err  error
+	}
+	var task interface{}
+	err := startupRetry.do(ctx, func() error {
+		done := make(chan result, 1)
+		go func() {
+			task, err := eywa.GetTask()
+			done <- result{task, err}
+		}()
+
+		select {
+		case r := <-done:
+			task = r.task
+			return r.err
+		case <-ctx.Done():
+			return fmt.Errorf("no response from EYWA within %s", timeout)
+		}
+	})
+	return task, err
+}
+
+// graphQL runs an EYWA GraphQL request under graphQLRetry
+func graphQL(ctx context.Context, query string, variables map[string]interface{}) (interface{}, error) {
+	var result interface{}
+	err := graphQLRetry.do(ctx, func() error {
+		var err error
+		result, err = eywa.GraphQL(query, variables)
+		return err
+	})
+	return result, err
+}